@@ -0,0 +1,83 @@
+// -------------------------------------------------
+// Package main
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gcnote/server"
+	"gcnote/server/consistency"
+	"os"
+	"slices"
+	"strings"
+)
+
+// runCheck 一致性检查：gcnote check [-repair] [-kinds kind1,kind2] [-json]
+// 没有问题（或者全部修复）时返回0，还有问题时返回1，检查失败返回2
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "修复发现的问题（重新导入或者删除）")
+	kinds := fs.String("kinds", "", "只修复这些类型的问题，逗号分隔，为空时修复全部；可选: "+strings.Join(consistency.Kinds, ","))
+	asJson := fs.Bool("json", false, "以json格式输出")
+	_ = fs.Parse(args)
+
+	var opts consistency.Options
+	opts.Repair = *repair
+	if *kinds != "" {
+		for _, kind := range strings.Split(*kinds, ",") {
+			if !slices.Contains(consistency.Kinds, kind) {
+				fmt.Fprintf(os.Stderr, "unknown kind %s\n", kind)
+				return 2
+			}
+			opts.Kinds = append(opts.Kinds, kind)
+		}
+	}
+
+	server.InitConfig()
+	server.InitLogger()
+	server.InitMysql()
+	server.InitRedis()
+	server.InitElasticSearch()
+	report, err := consistency.Run(context.Background(), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "consistency check failed: %v\n", err)
+		return 2
+	}
+
+	if *asJson {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		for _, issue := range report.Issues {
+			target := issue.IndexId
+			if issue.KBFileId != "" {
+				target += "/" + issue.KBFileId
+			}
+			if issue.Cache != "" {
+				target += " (" + issue.Cache + ")"
+			}
+			if issue.Path != "" {
+				target += " " + issue.Path
+			}
+			status := ""
+			if issue.Repaired {
+				status = " [repaired]"
+			} else if issue.Error != "" {
+				status = " [failed: " + issue.Error + "]"
+			}
+			fmt.Printf("%-24s %s  %s -> %s%s\n", issue.Kind, target, issue.Detail, issue.Repair, status)
+		}
+		fmt.Printf("issues: %d, repaired: %d, failed: %d\n", len(report.Issues), report.Repaired, report.Failed)
+	}
+	if len(report.Issues) > report.Repaired {
+		return 1
+	}
+	return 0
+}
@@ -6,11 +6,16 @@ import (
 	"gcnote/server/router"
 	"go.uber.org/zap"
 	"net/http"
+	"os"
 	"strconv"
 	"time"
 )
 
 func main() {
+	// 一致性检查命令，不启动服务
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(runCheck(os.Args[2:]))
+	}
 	server.InitConfig()
 	server.InitLogger()
 	server.InitMysql()
@@ -23,6 +28,7 @@ func main() {
 	zap.S().Infof("New Connection")
 	zap.S().Infof("Time: %v", time.Now().String())
 	route := router.InitRouter()
+	server.InitJobQueue()
 	app := &http.Server{
 		Addr:           "0.0.0.0:" + strconv.Itoa(config.ServerCfg.Port),
 		Handler:        route,
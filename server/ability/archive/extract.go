@@ -0,0 +1,209 @@
+// -------------------------------------------------
+// Package archive
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported archive format")
+	ErrTooLarge    = errors.New("archive too large")
+	ErrTooMany     = errors.New("too many files in archive")
+	ErrUnsafePath  = errors.New("unsafe path in archive")
+)
+
+// Limits 解压限制，防止zip炸弹
+type Limits struct {
+	MaxFiles int   // 最多解压的文件数，<=0时使用默认值1000
+	MaxSize  int64 // 解压后的总字节数，<=0时使用默认值512MB
+}
+
+const (
+	defaultMaxFiles = 1000
+	defaultMaxSize  = 512 << 20
+)
+
+// Entry 解压出来的一个文件
+type Entry struct {
+	Name      string // 压缩包内的相对路径，用/分隔
+	LocalPath string // 解压到本地的路径
+}
+
+// Dir 文件在压缩包内所在的目录，根目录为空字符串
+func (e Entry) Dir() string {
+	dir := path.Dir(e.Name)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// IsArchive 是否为支持的压缩包格式
+func IsArchive(name string) bool {
+	return format(name) != ""
+}
+
+func format(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	}
+	return ""
+}
+
+// Extract 把zip或tar.gz解压到dstDir，只解压普通文件，跳过目录、链接以及__MACOSX和.开头的隐藏文件
+// 返回的文件按压缩包内路径排序
+func Extract(archivePath string, dstDir string, limits Limits) ([]Entry, error) {
+	if limits.MaxFiles <= 0 {
+		limits.MaxFiles = defaultMaxFiles
+	}
+	if limits.MaxSize <= 0 {
+		limits.MaxSize = defaultMaxSize
+	}
+	x := &extractor{dstDir: dstDir, limits: limits}
+	var err error
+	switch format(archivePath) {
+	case "zip":
+		err = x.extractZip(archivePath)
+	case "tar.gz":
+		err = x.extractTarGz(archivePath)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupported, filepath.Base(archivePath))
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(x.entries, func(i, j int) bool { return x.entries[i].Name < x.entries[j].Name })
+	return x.entries, nil
+}
+
+type extractor struct {
+	dstDir  string
+	limits  Limits
+	total   int64
+	entries []Entry
+}
+
+func (x *extractor) extractZip(archivePath string) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func(r *zip.ReadCloser) {
+		_ = r.Close()
+	}(r)
+	for _, f := range r.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = x.write(f.Name, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) extractTarGz(archivePath string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer func(gz *gzip.Reader) {
+		_ = gz.Close()
+	}(gz)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err = x.write(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// write 检查路径和大小限制后写入文件，不允许 ../ 和绝对路径（zip slip）
+func (x *extractor) write(name string, r io.Reader) error {
+	name = strings.ReplaceAll(name, "\\", "/")
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || filepath.VolumeName(clean) != "" {
+		return fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	if IsHidden(clean) {
+		return nil
+	}
+	if len(x.entries) >= x.limits.MaxFiles {
+		return ErrTooMany
+	}
+
+	localPath := filepath.Join(x.dstDir, filepath.FromSlash(clean))
+	if err := os.MkdirAll(filepath.Dir(localPath), os.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, io.LimitReader(r, x.limits.MaxSize-x.total+1))
+	closeErr := out.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	x.total += n
+	if x.total > x.limits.MaxSize {
+		return ErrTooLarge
+	}
+	x.entries = append(x.entries, Entry{Name: clean, LocalPath: localPath})
+	return nil
+}
+
+// IsHidden macOS压缩时带的__MACOSX目录以及.开头的隐藏文件
+func IsHidden(name string) bool {
+	for _, part := range strings.Split(path.Clean(name), "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+// -------------------------------------------------
+// Package archive
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package archive
+
+import (
+	"errors"
+	"gcnote/server/ability/convert/convert_fixture"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var sampleFiles = map[string]string{
+	"docs/a.md":            "# a",
+	"docs/sub/b.txt":       "b",
+	"readme.html":          "<p>r</p>",
+	"__MACOSX/docs/._a.md": "fork",
+	"docs/.DS_Store":       "ds",
+}
+
+func TestExtract(t *testing.T) {
+	for _, name := range []string{"bundle.zip", "bundle.tar.gz", "bundle.tgz"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, name)
+			if filepath.Ext(name) == ".zip" {
+				convert_fixture.WriteZip(t, archivePath, sampleFiles)
+			} else {
+				convert_fixture.WriteTarGz(t, archivePath, sampleFiles)
+			}
+			dstDir := filepath.Join(dir, "out")
+			entries, err := Extract(archivePath, dstDir, Limits{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names, dirs []string
+			for _, e := range entries {
+				names = append(names, e.Name)
+				dirs = append(dirs, e.Dir())
+			}
+			if want := []string{"docs/a.md", "docs/sub/b.txt", "readme.html"}; !reflect.DeepEqual(names, want) {
+				t.Errorf("got %v, want %v", names, want)
+			}
+			if want := []string{"docs", "docs/sub", ""}; !reflect.DeepEqual(dirs, want) {
+				t.Errorf("got dirs %v, want %v", dirs, want)
+			}
+			data, err := os.ReadFile(filepath.Join(dstDir, "docs", "sub", "b.txt"))
+			if err != nil || string(data) != "b" {
+				t.Errorf("file not extracted: %q %v", data, err)
+			}
+		})
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name   string
+		files  map[string]string
+		limits Limits
+		want   error
+	}{
+		{"slip.zip", map[string]string{"../../evil.sh": "x"}, Limits{}, ErrUnsafePath},
+		{"abs.tar.gz", map[string]string{"/etc/cron.d/evil": "x"}, Limits{}, ErrUnsafePath},
+		{"many.zip", map[string]string{"a.md": "a", "b.md": "b", "c.md": "c"}, Limits{MaxFiles: 2}, ErrTooMany},
+		{"big.zip", map[string]string{"a.md": "aaaaaa", "b.md": "bbbbbb"}, Limits{MaxSize: 10}, ErrTooLarge},
+		{"notes.rar", nil, Limits{}, ErrUnsupported},
+	}
+	for _, c := range cases {
+		archivePath := filepath.Join(dir, c.name)
+		switch filepath.Ext(c.name) {
+		case ".zip":
+			convert_fixture.WriteZip(t, archivePath, c.files)
+		case ".gz":
+			convert_fixture.WriteTarGz(t, archivePath, c.files)
+		}
+		_, err := Extract(archivePath, filepath.Join(dir, "out-"+c.name), c.limits)
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.sh")); err == nil {
+		t.Error("zip slip wrote outside of the destination")
+	}
+}
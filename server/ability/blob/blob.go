@@ -0,0 +1,77 @@
+// -------------------------------------------------
+// Package blob
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// 按内容寻址的文件存储：内容的SHA-256作为名称，保存在root/<前两位>/<hash>，相同的内容只保存一份
+
+var ErrNotFound = errors.New("blob not found")
+
+// Hash 内容的SHA-256
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Path blob在root下的路径
+func Path(root string, hash string) string {
+	return filepath.Join(root, hash[:2], hash)
+}
+
+// Put 保存内容，返回hash；已经存在时不重复写入。先写临时文件再改名，不会留下写了一半的blob
+func Put(root string, content []byte) (string, error) {
+	hash := Hash(content)
+	path := Path(root, hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// Get 读取内容并校验hash
+func Get(root string, hash string) ([]byte, error) {
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid blob hash %q", hash)
+	}
+	content, err := os.ReadFile(Path(root, hash))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if Hash(content) != hash {
+		return nil, fmt.Errorf("blob %s is corrupted", hash)
+	}
+	return content, nil
+}
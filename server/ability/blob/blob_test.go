@@ -0,0 +1,49 @@
+// -------------------------------------------------
+// Package blob
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package blob
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPutGet(t *testing.T) {
+	root := t.TempDir()
+	hash, err := Put(root, []byte("# 笔记\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 相同的内容只保存一份
+	again, err := Put(root, []byte("# 笔记\n"))
+	if err != nil || again != hash {
+		t.Fatalf("put again: %s %v", again, err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(root, hash[:2]))
+	if len(entries) != 1 {
+		t.Errorf("unexpected entries %v", entries)
+	}
+	content, err := Get(root, hash)
+	if err != nil || string(content) != "# 笔记\n" {
+		t.Fatalf("get: %q %v", content, err)
+	}
+
+	if _, err = Get(root, Hash([]byte("other"))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing blob: %v", err)
+	}
+	if _, err = Get(root, "../../etc/passwd"); err == nil {
+		t.Error("invalid hash should fail")
+	}
+	// 内容被改动过
+	if err = os.WriteFile(Path(root, hash), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Get(root, hash); err == nil {
+		t.Error("corrupted blob should fail")
+	}
+}
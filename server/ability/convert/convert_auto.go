@@ -8,29 +8,50 @@ package convert
 
 import (
 	"errors"
+	"gcnote/server/ability/convert/convert_base"
+	"gcnote/server/ability/convert/convert_code"
+	"gcnote/server/ability/convert/convert_csv"
 	"gcnote/server/ability/convert/convert_docx"
+	"gcnote/server/ability/convert/convert_epub"
 	"gcnote/server/ability/convert/convert_html"
+	"gcnote/server/ability/convert/convert_ipynb"
 	"gcnote/server/ability/convert/convert_md"
 	"gcnote/server/ability/convert/convert_pdf"
+	"gcnote/server/ability/convert/convert_pptx"
 	"gcnote/server/ability/convert/convert_txt"
+	"gcnote/server/ability/convert/convert_xlsx"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// suffixList 支持导入的文件格式，源代码和json/yaml/toml的后缀见convert_code
+var suffixList = append([]string{".docx", ".html", ".txt", ".md", ".pdf", ".pptx", ".xlsx", ".csv", ".epub", ".zip", ".ipynb"},
+	convert_code.Suffixes()...)
+
+// IsSupported 是否支持导入该后缀的文件
+func IsSupported(suffix string) bool {
+	for _, suf := range suffixList {
+		if suf == suffix {
+			return true
+		}
+	}
+	return false
+}
+
 func AutoConvert(documentPath string, outputDir string, suffix string) (string, string, error) {
 	/*
 		返回值：mdPath, mdString, err
 	*/
 	// 首先根据输入的suffix来处理
-	var suffixList = []string{".docx", ".html", ".txt", ".md", ".pdf"}
 	// 如果suffix不为空，直接匹配是否在suffixList里，否则需要判断
 	if suffix == "" {
 		//suffix :=
 		suffix = filepath.Ext(documentPath)
 	}
 	// 查看是否允许这个suffix
-	err := errors.New("不支持格式的文件格式：" + suffix +
-		"，目前仅支持'.md', '.docx', '.html'和'.txt'类型的文件")
+	err := convert_base.NewError(convert_base.ErrUnsupported, documentPath,
+		errors.New("不支持格式的文件格式："+suffix+"，目前仅支持"+strings.Join(suffixList, ", ")+"类型的文件"))
 	for _, suf := range suffixList {
 
 		if suf == suffix {
@@ -45,6 +66,19 @@ func AutoConvert(documentPath string, outputDir string, suffix string) (string,
 		return convert_docx.DocxConvert(documentPath, outputDir)
 	} else if suffix == ".pdf" {
 		return convert_pdf.PdfConvert(documentPath, outputDir)
+	} else if suffix == ".pptx" {
+		return convert_pptx.PptxConvert(documentPath, outputDir)
+	} else if suffix == ".xlsx" {
+		return convert_xlsx.XlsxConvert(documentPath, outputDir)
+	} else if suffix == ".csv" {
+		return convert_csv.CsvConvert(documentPath, outputDir)
+	} else if suffix == ".epub" {
+		return convert_epub.EpubConvert(documentPath, outputDir)
+	} else if suffix == ".ipynb" {
+		return convert_ipynb.IpynbConvert(documentPath, outputDir)
+	} else if suffix == ".zip" {
+		// markdown文件和它引用的图片、附件打包的zip
+		return convert_md.MdZipConvert(documentPath, outputDir)
 	} else if suffix == ".html" {
 		// 在outputDir里创建一个名为images的文件夹
 		err = os.Mkdir(filepath.Join(outputDir, "images"), os.ModePerm)
@@ -58,6 +92,12 @@ func AutoConvert(documentPath string, outputDir string, suffix string) (string,
 			return "", "", err
 		}
 		return convert_txt.TxtConvert(documentPath, outputDir)
+	} else if _, ok := convert_code.Language(suffix); ok {
+		err = os.Mkdir(filepath.Join(outputDir, "images"), os.ModePerm)
+		if err != nil {
+			return "", "", err
+		}
+		return convert_code.CodeConvert(documentPath, outputDir)
 	} else if suffix == ".md" {
 		err = os.Mkdir(filepath.Join(outputDir, "images"), os.ModePerm)
 		if err != nil {
@@ -65,7 +105,33 @@ func AutoConvert(documentPath string, outputDir string, suffix string) (string,
 		}
 		return convert_md.MdConvert(documentPath, outputDir)
 	} else {
-		return "", "", errors.New("未知的文件格式：" + suffix)
+		return "", "", convert_base.NewError(convert_base.ErrUnsupported, documentPath,
+			errors.New("未知的文件格式："+suffix))
 	}
 
 }
+
+// AutoConvertWithProgress 同AutoConvert，支持的格式按页上报转换进度，目前只有pdf；
+// 另外返回文本类文件转换时识别出的源编码，用于在导入任务结果中展示，其他格式为空字符串
+func AutoConvertWithProgress(documentPath string, outputDir string, suffix string,
+	progress convert_base.Progress) (mdPath string, mdString string, encoding string, err error) {
+	if suffix == "" {
+		suffix = filepath.Ext(documentPath)
+	}
+	_, isCode := convert_code.Language(suffix)
+	switch {
+	case suffix == ".pdf":
+		mdPath, mdString, err = convert_pdf.PdfConvertWithProgress(documentPath, outputDir, progress)
+		return mdPath, mdString, "", err
+	case suffix == ".txt" || isCode:
+		if err = os.Mkdir(filepath.Join(outputDir, convert_base.ImagesDirName), os.ModePerm); err != nil {
+			return "", "", "", err
+		}
+		if suffix == ".txt" {
+			return convert_txt.TxtConvertWithEncoding(documentPath, outputDir)
+		}
+		return convert_code.CodeConvertWithEncoding(documentPath, outputDir)
+	}
+	mdPath, mdString, err = AutoConvert(documentPath, outputDir, suffix)
+	return mdPath, mdString, "", err
+}
@@ -0,0 +1,118 @@
+// -------------------------------------------------
+// Package convert_base
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_base
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 图片服务按 名称.后缀 解析文件名，markdown链接里也不能有空格和括号
+var unsafeAssetChars = regexp.MustCompile(`[\s.()\[\]<>#?%"'\\/]+`)
+
+// ImagesDirName 转换出的图片和附件保存在md文件旁边的这个目录
+const ImagesDirName = "images"
+
+// ImageLink images目录下的文件在markdown里的链接：和docx2md保持一致的相对路径，ChunkRead会转为图片服务的url
+func ImageLink(name string) string {
+	return filepath.Join(ImagesDirName, name)
+}
+
+// SaveImage 把图片写入imagesDir，返回markdown里的链接
+func SaveImage(imagesDir string, name string, data []byte) (string, error) {
+	if err := os.WriteFile(filepath.Join(imagesDir, name), data, 0644); err != nil {
+		return "", err
+	}
+	return ImageLink(name), nil
+}
+
+// AssetWriter 把zip里(或已解压目录里)的资源文件(图片、附件)复制到images目录，同一个文件只复制一次，重名时加序号
+type AssetWriter struct {
+	exists  func(part string) bool
+	extract func(part string, dstPath string) error
+	dir     string
+	names   map[string]string // zip内路径 => images下的文件名
+	used    map[string]bool
+}
+
+func NewAssetWriter(r *zip.Reader, imagesDir string) *AssetWriter {
+	return &AssetWriter{
+		exists: func(part string) bool {
+			return FindZipFile(r, part) != nil
+		},
+		extract: func(part string, dstPath string) error {
+			return ExtractZipFile(r, part, dstPath)
+		},
+		dir:   imagesDir,
+		names: map[string]string{},
+		used:  map[string]bool{},
+	}
+}
+
+// NewFSAssetWriter 资源文件来自已经解压的目录(os.DirFS)，part为目录内用/分隔的相对路径
+func NewFSAssetWriter(fsys fs.FS, imagesDir string) *AssetWriter {
+	return &AssetWriter{
+		exists: func(part string) bool {
+			info, err := fs.Stat(fsys, part)
+			return err == nil && info.Mode().IsRegular()
+		},
+		extract: func(part string, dstPath string) error {
+			data, err := fs.ReadFile(fsys, part)
+			if err != nil {
+				return err
+			}
+			if err = os.MkdirAll(filepath.Dir(dstPath), os.ModePerm); err != nil {
+				return err
+			}
+			return os.WriteFile(dstPath, data, 0644)
+		},
+		dir:   imagesDir,
+		names: map[string]string{},
+		used:  map[string]bool{},
+	}
+}
+
+// Copy 复制part，返回images下的文件名；part不存在时返回空字符串
+func (w *AssetWriter) Copy(part string) (string, error) {
+	part = path.Clean(part)
+	if name, ok := w.names[part]; ok {
+		return name, nil
+	}
+	if !fs.ValidPath(part) || !w.exists(part) {
+		return "", nil
+	}
+	ext := path.Ext(part)
+	stem := unsafeAssetChars.ReplaceAllString(strings.TrimSuffix(path.Base(part), ext), "_")
+	if stem == "" {
+		stem = "asset"
+	}
+	name := stem + ext
+	for i := 1; w.used[name]; i++ {
+		name = fmt.Sprintf("%s_%d%s", stem, i, ext)
+	}
+	if err := w.extract(part, filepath.Join(w.dir, name)); err != nil {
+		return "", err
+	}
+	w.names[part] = name
+	w.used[name] = true
+	return name, nil
+}
+
+// CopyLink 复制part，返回markdown里的链接；part不存在时返回空字符串
+func (w *AssetWriter) CopyLink(part string) (string, error) {
+	name, err := w.Copy(part)
+	if err != nil || name == "" {
+		return "", err
+	}
+	return ImageLink(name), nil
+}
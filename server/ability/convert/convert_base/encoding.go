@@ -0,0 +1,165 @@
+// -------------------------------------------------
+// Package convert_base
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_base
+
+import (
+	"bytes"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"unicode/utf8"
+)
+
+// 识别出的源文件编码
+const (
+	EncodingUTF8    = "UTF-8"
+	EncodingUTF16LE = "UTF-16LE"
+	EncodingUTF16BE = "UTF-16BE"
+	EncodingGBK     = "GBK"
+	EncodingGB18030 = "GB18030"
+	EncodingBig5    = "Big5"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// DetectEncoding 识别纯文本的编码：先看BOM，再看是否为合法UTF-8，最后在GBK(GB18030)和Big5之间选择
+// 都不符合时返回空字符串
+func DetectEncoding(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return EncodingUTF8
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return EncodingUTF16BE
+	case utf8.Valid(data):
+		return EncodingUTF8
+	}
+
+	fourByte, gbOk := scanGB18030(data)
+	big5Pairs, lowTrails, big5Ok := scanBig5(data)
+	switch {
+	case gbOk && big5Ok:
+		// 两种编码都能解码时看双字节的第二个字节：GB2312区的常用字和标点都在0xA1以上，
+		// Big5里大约一半的字（包括全角逗号、句号）落在0x40-0x7E
+		if big5Pairs > 0 && lowTrails*10 >= big5Pairs {
+			return EncodingBig5
+		}
+		return gbName(fourByte)
+	case gbOk:
+		return gbName(fourByte)
+	case big5Ok:
+		return EncodingBig5
+	}
+	return ""
+}
+
+// gbName 出现四字节序列时说明用到了GBK以外的字符
+func gbName(fourByte int) string {
+	if fourByte > 0 {
+		return EncodingGB18030
+	}
+	return EncodingGBK
+}
+
+// DecodeText 将文本转为UTF-8（去掉BOM），返回转换后的文本和识别出的源编码
+// 无法识别编码时返回ErrEncoding，转码后出现NUL字节视为二进制文件(ErrCorrupt)
+func DecodeText(documentPath string, data []byte) (string, string, error) {
+	name := DetectEncoding(data)
+	var enc encoding.Encoding
+	switch name {
+	case "":
+		return "", "", NewError(ErrEncoding, documentPath, nil)
+	case EncodingUTF8:
+		data = bytes.TrimPrefix(data, bomUTF8)
+	case EncodingUTF16LE:
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case EncodingUTF16BE:
+		enc = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case EncodingGBK, EncodingGB18030:
+		enc = simplifiedchinese.GB18030
+	case EncodingBig5:
+		enc = traditionalchinese.Big5
+	}
+	if enc != nil {
+		decoded, err := enc.NewDecoder().Bytes(data)
+		if err != nil {
+			return "", "", NewError(ErrEncoding, documentPath, err)
+		}
+		data = decoded
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", "", NewError(ErrCorrupt, documentPath, nil)
+	}
+	return string(data), name, nil
+}
+
+// scanGB18030 检查是否为合法的GB18030字节序列，返回四字节字符数
+// 文件末尾被截断的半个字符不算错误
+func scanGB18030(data []byte) (fourByte int, ok bool) {
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b < 0x80:
+			i++
+			continue
+		case b == 0x80 || b == 0xff:
+			return fourByte, false
+		case i+1 >= len(data):
+			return fourByte, true
+		}
+		b2 := data[i+1]
+		switch {
+		case b2 >= 0x40 && b2 <= 0xfe && b2 != 0x7f:
+			i += 2
+		case b2 >= 0x30 && b2 <= 0x39:
+			if i+3 >= len(data) {
+				return fourByte, true
+			}
+			if data[i+2] < 0x81 || data[i+2] > 0xfe || data[i+3] < 0x30 || data[i+3] > 0x39 {
+				return fourByte, false
+			}
+			fourByte++
+			i += 4
+		default:
+			return fourByte, false
+		}
+	}
+	return fourByte, true
+}
+
+// scanBig5 检查是否为合法的Big5字节序列，返回双字节字符数以及第二个字节在0x40-0x7E的字符数
+func scanBig5(data []byte) (pairs int, lowTrails int, ok bool) {
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b < 0x80:
+			i++
+			continue
+		case b < 0x81 || b == 0xff:
+			return pairs, lowTrails, false
+		case i+1 >= len(data):
+			return pairs, lowTrails, true
+		}
+		b2 := data[i+1]
+		switch {
+		case b2 >= 0x40 && b2 <= 0x7e:
+			lowTrails++
+		case b2 >= 0xa1 && b2 <= 0xfe:
+		default:
+			return pairs, lowTrails, false
+		}
+		pairs++
+		i += 2
+	}
+	return pairs, lowTrails, true
+}
@@ -0,0 +1,59 @@
+// -------------------------------------------------
+// Package convert_base
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_base
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// 转换失败的几种类别，用 errors.Is 判断
+var (
+	ErrUnsupported = errors.New("unsupported format")
+	ErrCorrupt     = errors.New("corrupt file")
+	ErrTooLarge    = errors.New("file too large")
+	ErrEncoding    = errors.New("invalid encoding")
+)
+
+// ConvertError 转换过程中的错误，Kind为上面几种类别之一，Err为底层错误（可以为nil）
+type ConvertError struct {
+	Kind error
+	Path string
+	Err  error
+}
+
+func (e *ConvertError) Error() string {
+	msg := e.Kind.Error()
+	if e.Path != "" {
+		msg += ": " + filepath.Base(e.Path)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *ConvertError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// NewError 构造一个ConvertError
+func NewError(kind error, path string, err error) error {
+	return &ConvertError{Kind: kind, Path: path, Err: err}
+}
+
+// Reason 将转换错误转为导入任务的失败原因
+func Reason(err error) string {
+	var convErr *ConvertError
+	if errors.As(err, &convErr) {
+		return "convert file error: " + convErr.Error()
+	}
+	return "convert file error."
+}
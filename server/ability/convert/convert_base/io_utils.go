@@ -0,0 +1,44 @@
+// -------------------------------------------------
+// Package convert_base
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_base
+
+import (
+	"bytes"
+	"os"
+	"unicode/utf8"
+)
+
+// MaxSourceSize 允许转换的单个文件的最大字节数
+var MaxSourceSize int64 = 64 << 20
+
+// ReadSource 读取待转换的文件，超过MaxSourceSize时返回ErrTooLarge
+func ReadSource(documentPath string) ([]byte, error) {
+	info, err := os.Stat(documentPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > MaxSourceSize {
+		return nil, NewError(ErrTooLarge, documentPath, nil)
+	}
+	return os.ReadFile(documentPath)
+}
+
+// CheckText 检查文本类文件的内容：出现NUL字节视为二进制文件(ErrCorrupt)，非UTF-8视为ErrEncoding
+func CheckText(documentPath string, data []byte) error {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return NewError(ErrCorrupt, documentPath, nil)
+	}
+	if !utf8.Valid(data) {
+		return NewError(ErrEncoding, documentPath, nil)
+	}
+	return nil
+}
+
+// SaveFile 保存markdown文件，替代docx_parser.SaveFile（那个出错会直接log.Fatalf）
+func SaveFile(filePath string, mdStr string) error {
+	return os.WriteFile(filePath, []byte(mdStr), 0644)
+}
@@ -0,0 +1,125 @@
+// -------------------------------------------------
+// Package convert_base
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_base
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ![alt](target "title") 和 [text](target)，target可以用<>包起来（可以含空格）
+	mdLinkRe = regexp.MustCompile(`(!?)\[((?:[^\[\]]|\[[^\[\]]*\])*)\]\(\s*(<[^>]*>|[^)\s]+)(\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+	// [id]: target "title"
+	mdRefRe = regexp.MustCompile(`(?m)^( {0,3}\[[^\]]+\]:\s*)(<[^>]*>|\S+)`)
+	// <img src="target">
+	htmlImgRe = regexp.MustCompile(`(?i)(<img\b[^>]*?\bsrc\s*=\s*)("[^"]*"|'[^']*')`)
+	fenceRe   = regexp.MustCompile("(?m)^ {0,3}(```|~~~)")
+)
+
+// LinkRewriter 返回新的链接地址，ok为false时保持原样
+type LinkRewriter func(target string, isImage bool) (newTarget string, ok bool)
+
+// RewriteLinks 改写markdown中的链接和图片地址（包括引用式链接和html的img），代码块里的内容不处理
+func RewriteLinks(markdown string, rewrite LinkRewriter) string {
+	var builder strings.Builder
+	inFence := false
+	for _, segment := range SplitFences(markdown) {
+		if inFence {
+			builder.WriteString(segment)
+		} else {
+			builder.WriteString(rewriteSegment(segment, rewrite))
+		}
+		inFence = !inFence
+	}
+	return builder.String()
+}
+
+// SplitFences 按代码块切分，奇数位置是代码块（包括围栏行），未闭合的代码块延续到结尾
+func SplitFences(markdown string) []string {
+	var segments []string
+	rest := markdown
+	for {
+		loc := fenceRe.FindStringIndex(rest)
+		if loc == nil {
+			return append(segments, rest)
+		}
+		fence := strings.TrimSpace(rest[loc[0]:loc[1]])
+		segments = append(segments, rest[:loc[0]])
+		rest = rest[loc[0]:]
+		// 找到结束的围栏行
+		afterOpen := strings.IndexByte(rest, '\n')
+		if afterOpen < 0 {
+			return append(segments, rest)
+		}
+		closeRe := regexp.MustCompile("(?m)^ {0,3}" + regexp.QuoteMeta(fence) + `\s*$`)
+		end := closeRe.FindStringIndex(rest[afterOpen+1:])
+		if end == nil {
+			return append(segments, rest)
+		}
+		blockEnd := afterOpen + 1 + end[1]
+		segments = append(segments, rest[:blockEnd])
+		rest = rest[blockEnd:]
+	}
+}
+
+func rewriteSegment(segment string, rewrite LinkRewriter) string {
+	segment = mdLinkRe.ReplaceAllStringFunc(segment, func(m string) string {
+		parts := mdLinkRe.FindStringSubmatch(m)
+		target := strings.TrimSuffix(strings.TrimPrefix(parts[3], "<"), ">")
+		newTarget, ok := rewrite(target, parts[1] == "!")
+		if !ok {
+			return m
+		}
+		return parts[1] + "[" + parts[2] + "](" + newTarget + parts[4] + ")"
+	})
+	segment = mdRefRe.ReplaceAllStringFunc(segment, func(m string) string {
+		parts := mdRefRe.FindStringSubmatch(m)
+		target := strings.TrimSuffix(strings.TrimPrefix(parts[2], "<"), ">")
+		newTarget, ok := rewrite(target, IsImagePath(target))
+		if !ok {
+			return m
+		}
+		return parts[1] + newTarget
+	})
+	return htmlImgRe.ReplaceAllStringFunc(segment, func(m string) string {
+		parts := htmlImgRe.FindStringSubmatch(m)
+		target := parts[2][1 : len(parts[2])-1]
+		newTarget, ok := rewrite(target, true)
+		if !ok {
+			return m
+		}
+		return parts[1] + `"` + newTarget + `"`
+	})
+}
+
+// LocalTarget 判断链接是否指向本地的相对路径，是的话返回解码后、去掉锚点和查询参数的路径
+func LocalTarget(target string) (string, bool) {
+	if target == "" || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/") {
+		return "", false
+	}
+	// windows下导出的文件可能用反斜杠
+	u, err := url.Parse(strings.ReplaceAll(target, "\\", "/"))
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "", false
+	}
+	if u.Path == "" {
+		return "", false
+	}
+	return u.Path, true
+}
+
+var imageExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".webp": true, ".svg": true,
+}
+
+// IsImagePath 按后缀判断是否为图片
+func IsImagePath(p string) bool {
+	return imageExts[strings.ToLower(path.Ext(p))]
+}
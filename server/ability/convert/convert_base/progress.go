@@ -0,0 +1,17 @@
+// -------------------------------------------------
+// Package convert_base
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_base
+
+// Progress 转换进度回调，如pdf每转换完一页调用一次，done从1开始；可以为nil
+type Progress func(done int, total int)
+
+// Report progress为nil时什么都不做
+func (p Progress) Report(done int, total int) {
+	if p != nil {
+		p(done, total)
+	}
+}
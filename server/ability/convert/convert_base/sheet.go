@@ -0,0 +1,114 @@
+// -------------------------------------------------
+// Package convert_base
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_base
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 表头识别方式
+const (
+	SheetHeaderAuto     = "auto"
+	SheetHeaderFirstRow = "first_row"
+	SheetHeaderNone     = "none"
+)
+
+const defaultSheetMaxRows = 1000
+
+// SheetOptions 表格类文件(xlsx、csv)的转换选项
+type SheetOptions struct {
+	MaxRows int    // 最多输出的数据行数，<=0时使用默认值
+	Header  string // 表头识别方式
+}
+
+// SheetMarkdown 将一个sheet渲染为 一级标题 + markdown表格，超出行数限制的部分截断并注明
+func SheetMarkdown(name string, rows [][]string, opts SheetOptions) string {
+	rows = trimSheet(rows)
+	heading := "# " + name
+	if len(rows) == 0 {
+		return heading
+	}
+
+	var header []string
+	switch opts.Header {
+	case SheetHeaderFirstRow:
+		header, rows = rows[0], rows[1:]
+	case SheetHeaderNone:
+	default:
+		if isHeaderRow(rows) {
+			header, rows = rows[0], rows[1:]
+		}
+	}
+	width := 0
+	for _, row := range append([][]string{header}, rows...) {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	if header == nil {
+		// 没有表头时生成 列1、列2...
+		header = make([]string, width)
+		for i := range header {
+			header[i] = fmt.Sprintf("列%d", i+1)
+		}
+	}
+
+	maxRows := opts.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultSheetMaxRows
+	}
+	total := len(rows)
+	if total > maxRows {
+		rows = rows[:maxRows]
+	}
+	table := MarkdownTable(append([][]string{header}, rows...))
+	result := heading + "\n\n" + strings.TrimSuffix(table, "\n")
+	if total > maxRows {
+		result += fmt.Sprintf("\n\n（仅导入了前%d行，共%d行）", maxRows, total)
+	}
+	return result
+}
+
+// isHeaderRow 第一行全部非空、都不是数字且互不重复时视为表头；只有一行的表不认为有表头
+func isHeaderRow(rows [][]string) bool {
+	if len(rows) < 2 || len(rows[0]) == 0 {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, cell := range rows[0] {
+		cell = strings.TrimSpace(cell)
+		if cell == "" || isNumeric(cell) || seen[cell] {
+			return false
+		}
+		seen[cell] = true
+	}
+	return true
+}
+
+func isNumeric(s string) bool {
+	s = strings.TrimSuffix(strings.ReplaceAll(s, ",", ""), "%")
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// trimSheet 去掉尾部的空行和每行末尾的空单元格
+func trimSheet(rows [][]string) [][]string {
+	result := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		end := len(row)
+		for end > 0 && strings.TrimSpace(row[end-1]) == "" {
+			end--
+		}
+		result = append(result, row[:end])
+	}
+	for len(result) > 0 && len(result[len(result)-1]) == 0 {
+		result = result[:len(result)-1]
+	}
+	return result
+}
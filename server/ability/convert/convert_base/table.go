@@ -0,0 +1,53 @@
+// -------------------------------------------------
+// Package convert_base
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_base
+
+import (
+	"strings"
+)
+
+// MarkdownTable 将二维表格转为markdown表格，第一行作为表头
+func MarkdownTable(rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	if width == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	for i, row := range rows {
+		builder.WriteString("|")
+		for j := 0; j < width; j++ {
+			cell := ""
+			if j < len(row) {
+				cell = escapeCell(row[j])
+			}
+			builder.WriteString(" " + cell + " |")
+		}
+		builder.WriteString("\n")
+		if i == 0 {
+			builder.WriteString("|")
+			builder.WriteString(strings.Repeat(" --- |", width))
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String()
+}
+
+// escapeCell 单元格内不能出现换行和未转义的竖线
+func escapeCell(cell string) string {
+	cell = strings.TrimSpace(cell)
+	cell = strings.ReplaceAll(cell, "\r\n", "<br>")
+	cell = strings.ReplaceAll(cell, "\n", "<br>")
+	return strings.ReplaceAll(cell, "|", "\\|")
+}
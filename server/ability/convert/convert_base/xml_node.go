@@ -0,0 +1,119 @@
+// -------------------------------------------------
+// Package convert_base
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_base
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// XmlNode 通用的xml节点，保留子节点顺序，用于解析OOXML这类结构复杂的文档
+type XmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []XmlNode  `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+// ParseXml 解析xml内容为节点树
+func ParseXml(data []byte) (*XmlNode, error) {
+	var root XmlNode
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// Name 节点的本地名称(不含命名空间前缀)
+func (n *XmlNode) Name() string {
+	return n.XMLName.Local
+}
+
+// Attr 获取属性值，按本地名称匹配
+func (n *XmlNode) Attr(name string) string {
+	for _, attr := range n.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// AttrNS 获取属性值，按命名空间和本地名称匹配，如关系属性r:id
+func (n *XmlNode) AttrNS(space string, name string) string {
+	for _, attr := range n.Attrs {
+		if attr.Name.Space == space && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// Child 第一个名为name的直接子节点
+func (n *XmlNode) Child(name string) *XmlNode {
+	for i := range n.Nodes {
+		if n.Nodes[i].Name() == name {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// Children 所有名为name的直接子节点
+func (n *XmlNode) Children(name string) []*XmlNode {
+	var result []*XmlNode
+	for i := range n.Nodes {
+		if n.Nodes[i].Name() == name {
+			result = append(result, &n.Nodes[i])
+		}
+	}
+	return result
+}
+
+// Path 按路径逐级查找第一个匹配的子节点，如 Path("nvSpPr", "nvPr", "ph")
+func (n *XmlNode) Path(names ...string) *XmlNode {
+	current := n
+	for _, name := range names {
+		if current = current.Child(name); current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+// FindAll 深度优先查找所有名为name的后代节点
+func (n *XmlNode) FindAll(name string) []*XmlNode {
+	var result []*XmlNode
+	for i := range n.Nodes {
+		child := &n.Nodes[i]
+		if child.Name() == name {
+			result = append(result, child)
+		}
+		result = append(result, child.FindAll(name)...)
+	}
+	return result
+}
+
+// InnerText 拼接所有后代节点的文本
+func (n *XmlNode) InnerText() string {
+	var builder strings.Builder
+	n.writeText(&builder)
+	return builder.String()
+}
+
+func (n *XmlNode) writeText(builder *strings.Builder) {
+	if len(n.Nodes) == 0 {
+		builder.WriteString(n.Text)
+		return
+	}
+	for i := range n.Nodes {
+		n.Nodes[i].writeText(builder)
+	}
+}
@@ -0,0 +1,126 @@
+// -------------------------------------------------
+// Package convert_base
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_base
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// OpenZip 打开zip格式的文档(docx、pptx、xlsx、epub等)，格式错误时返回ErrCorrupt
+func OpenZip(documentPath string) (*zip.ReadCloser, error) {
+	info, err := os.Stat(documentPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > MaxSourceSize {
+		return nil, NewError(ErrTooLarge, documentPath, nil)
+	}
+	r, err := zip.OpenReader(documentPath)
+	if err != nil {
+		return nil, NewError(ErrCorrupt, documentPath, err)
+	}
+	return r, nil
+}
+
+// FindZipFile 按名称查找zip里的文件，找不到返回nil
+func FindZipFile(r *zip.Reader, name string) *zip.File {
+	name = path.Clean(name)
+	for _, f := range r.File {
+		if path.Clean(f.Name) == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// ReadZipFile 读取zip里的文件，解压后超过MaxSourceSize视为ErrTooLarge（防止zip炸弹）
+func ReadZipFile(r *zip.Reader, name string) ([]byte, error) {
+	f := FindZipFile(r, name)
+	if f == nil {
+		return nil, fmt.Errorf("%s not found in archive", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func(rc io.ReadCloser) {
+		_ = rc.Close()
+	}(rc)
+	data, err := io.ReadAll(io.LimitReader(rc, MaxSourceSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > MaxSourceSize {
+		return nil, NewError(ErrTooLarge, name, nil)
+	}
+	return data, nil
+}
+
+// ExtractZipFile 将zip里的文件解压到dstPath
+func ExtractZipFile(r *zip.Reader, name string, dstPath string) error {
+	data, err := ReadZipFile(r, name)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(dstPath), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(dstPath, data, 0644)
+}
+
+// ResolvePart 解析OOXML关系里的相对路径，如 ppt/slides/slide1.xml + ../media/image1.png => ppt/media/image1.png
+func ResolvePart(basePart string, target string) string {
+	if path.IsAbs(target) {
+		return path.Clean(target[1:])
+	}
+	return path.Join(path.Dir(basePart), target)
+}
+
+// RelationshipNS OOXML中r:id、r:embed等关系属性的命名空间
+const RelationshipNS = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+
+// Relationship OOXML的.rels文件中的一条关系
+type Relationship struct {
+	Id     string
+	Type   string
+	Target string
+}
+
+// ReadRelationships 读取part对应的.rels文件，如 ppt/slides/slide1.xml => ppt/slides/_rels/slide1.xml.rels
+// 返回的Target已经解析为zip内的完整路径，不存在rels文件时返回空map
+func ReadRelationships(r *zip.Reader, part string) (map[string]Relationship, error) {
+	relsPath := path.Join(path.Dir(part), "_rels", path.Base(part)+".rels")
+	rels := map[string]Relationship{}
+	if FindZipFile(r, relsPath) == nil {
+		return rels, nil
+	}
+	data, err := ReadZipFile(r, relsPath)
+	if err != nil {
+		return nil, err
+	}
+	root, err := ParseXml(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range root.Children("Relationship") {
+		target := rel.Attr("Target")
+		if rel.Attr("TargetMode") != "External" {
+			target = ResolvePart(part, target)
+		}
+		rels[rel.Attr("Id")] = Relationship{
+			Id:     rel.Attr("Id"),
+			Type:   rel.Attr("Type"),
+			Target: target,
+		}
+	}
+	return rels, nil
+}
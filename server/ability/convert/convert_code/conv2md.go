@@ -0,0 +1,106 @@
+// -------------------------------------------------
+// Package convert_code
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_code
+
+import (
+	"gcnote/server/ability/convert/convert_base"
+	"github.com/zakahan/docx2md/docx_parser"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// languages 源代码和结构化数据的后缀 => 代码块的语言，和splitter.NormalizeLanguage的写法保持一致
+var languages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  "java",
+	".kt":    "kotlin",
+	".scala": "scala",
+	".swift": "swift",
+	".c":     "c",
+	".h":     "c",
+	".cpp":   "cpp",
+	".cc":    "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".rs":    "rust",
+	".rb":    "ruby",
+	".php":   "php",
+	".lua":   "lua",
+	".sh":    "bash",
+	".sql":   "sql",
+	".json":  "json",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".toml":  "toml",
+}
+
+// Language 后缀对应的代码块语言
+func Language(suffix string) (string, bool) {
+	language, ok := languages[strings.ToLower(suffix)]
+	return language, ok
+}
+
+// Suffixes 支持的所有后缀，按字母排序
+func Suffixes() []string {
+	suffixes := make([]string, 0, len(languages))
+	for suffix := range languages {
+		suffixes = append(suffixes, suffix)
+	}
+	sort.Strings(suffixes)
+	return suffixes
+}
+
+// CodeConvert 将源代码或json/yaml/toml文件转为 文件名标题 + 带语言的代码块，切分时按函数、顶层key等边界切分
+func CodeConvert(documentPath string, outputDir string) (string, string, error) {
+	mdPath, mdString, _, err := CodeConvertWithEncoding(documentPath, outputDir)
+	return mdPath, mdString, err
+}
+
+// CodeConvertWithEncoding 同CodeConvert，同时返回识别出的源编码
+func CodeConvertWithEncoding(documentPath string, outputDir string) (string, string, string, error) {
+	suffix := filepath.Ext(documentPath)
+	language, ok := Language(suffix)
+	if !ok {
+		return "", "", "", convert_base.NewError(convert_base.ErrUnsupported, documentPath, nil)
+	}
+	mdPath, _, err := docx_parser.CreateMdDir(documentPath, outputDir, suffix)
+	if err != nil {
+		return "", "", "", err
+	}
+	data, err := convert_base.ReadSource(documentPath)
+	if err != nil {
+		return "", "", "", err
+	}
+	// 源文件也可能是GBK等编码
+	code, encoding, err := convert_base.DecodeText(documentPath, data)
+	if err != nil {
+		return "", "", "", err
+	}
+	code = strings.Trim(strings.ReplaceAll(code, "\r\n", "\n"), "\n")
+
+	mdString := "# " + filepath.Base(documentPath) + "\n\n" + fenced(language, code) + "\n"
+	err = convert_base.SaveFile(mdPath, mdString)
+	if err != nil {
+		return "", "", "", err
+	}
+	return mdPath, mdString, encoding, nil
+}
+
+// fenced 生成代码块，内容里有```时用更长的围栏
+func fenced(language string, code string) string {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
@@ -0,0 +1,52 @@
+// -------------------------------------------------
+// Package convert_code
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_code
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCodeConvert(t *testing.T) {
+	cases := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"main.go", "package main\r\n\r\nfunc main() {}\r\n", "# main.go\n\n```go\npackage main\n\nfunc main() {}\n```\n"},
+		{"config.yml", "server:\n  port: 8080\n", "# config.yml\n\n```yaml\nserver:\n  port: 8080\n```\n"},
+		// 内容里有```时用更长的围栏
+		{"gen.py", "DOC = \"\"\"\n```\n\"\"\"\n", "# gen.py\n\n````python\nDOC = \"\"\"\n```\n\"\"\"\n````\n"},
+	}
+	for _, c := range cases {
+		dir := t.TempDir()
+		documentPath := filepath.Join(dir, c.name)
+		if err := os.WriteFile(documentPath, []byte(c.code), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mdPath, mdString, err := CodeConvert(documentPath, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mdString != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, mdString, c.want)
+		}
+		if saved, _ := os.ReadFile(mdPath); string(saved) != c.want {
+			t.Errorf("%s: saved markdown %q", c.name, saved)
+		}
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	if language, ok := Language(".TSX"); !ok || language != "typescript" {
+		t.Errorf("got %q %v", language, ok)
+	}
+	if _, ok := Language(".exe"); ok {
+		t.Error(".exe should not be a code suffix")
+	}
+}
@@ -0,0 +1,75 @@
+// -------------------------------------------------
+// Package convert_csv
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"gcnote/server/ability/convert/convert_base"
+	"gcnote/server/config"
+	"github.com/zakahan/docx2md/docx_parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CsvConvert 将csv转为 一级标题(文件名) + markdown表格
+func CsvConvert(documentPath string, outputDir string) (string, string, error) {
+	mdPath, mdDirPath, err := docx_parser.CreateMdDir(documentPath, outputDir, ".csv")
+	if err != nil {
+		return "", "", err
+	}
+	err = os.MkdirAll(filepath.Join(mdDirPath, convert_base.ImagesDirName), os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := convert_base.ReadSource(documentPath)
+	if err != nil {
+		return "", "", err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	err = convert_base.CheckText(documentPath, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = sniffDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(documentPath), filepath.Ext(documentPath))
+	opts := convert_base.SheetOptions{
+		MaxRows: config.ServerCfg.ConvertConf.SheetMaxRows,
+		Header:  config.ServerCfg.ConvertConf.SheetHeader,
+	}
+	mdString := convert_base.SheetMarkdown(name, rows, opts) + "\n"
+	err = convert_base.SaveFile(mdPath, mdString)
+	if err != nil {
+		return "", "", err
+	}
+	return mdPath, mdString, nil
+}
+
+// sniffDelimiter 根据第一行里出现最多的分隔符判断是逗号、分号还是制表符
+func sniffDelimiter(data []byte) rune {
+	firstLine := string(data)
+	if i := strings.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+	delimiter, maxCount := ',', strings.Count(firstLine, ",")
+	for _, candidate := range []rune{';', '\t'} {
+		if count := strings.Count(firstLine, string(candidate)); count > maxCount {
+			delimiter, maxCount = candidate, count
+		}
+	}
+	return delimiter
+}
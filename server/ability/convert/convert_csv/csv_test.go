@@ -0,0 +1,66 @@
+// -------------------------------------------------
+// Package convert_csv
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_csv
+
+import (
+	"gcnote/server/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCsvConvert(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		header  string
+		maxRows int
+		want    string
+	}{
+		{
+			name:    "comma",
+			content: "\xef\xbb\xbf姓名,年龄\n张三,18\n\"李,四\",20\n",
+			header:  "auto",
+			want:    "# comma\n\n| 姓名 | 年龄 |\n| --- | --- |\n| 张三 | 18 |\n| 李,四 | 20 |\n",
+		},
+		{
+			name:    "semicolon",
+			content: "1;2;3\n4;5;6\n",
+			header:  "auto",
+			want:    "# semicolon\n\n| 列1 | 列2 | 列3 |\n| --- | --- | --- |\n| 1 | 2 | 3 |\n| 4 | 5 | 6 |\n",
+		},
+		{
+			name:    "tab",
+			content: "a\tb\nc\td\ne\tf\n",
+			header:  "none",
+			maxRows: 2,
+			want:    "# tab\n\n| 列1 | 列2 |\n| --- | --- |\n| a | b |\n| c | d |\n\n（仅导入了前2行，共3行）\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config.ServerCfg.ConvertConf.SheetHeader = c.header
+			config.ServerCfg.ConvertConf.SheetMaxRows = c.maxRows
+			dir := t.TempDir()
+			documentPath := filepath.Join(dir, c.name+".csv")
+			if err := os.WriteFile(documentPath, []byte(c.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			outputDir := filepath.Join(dir, "out")
+			if err := os.Mkdir(outputDir, os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			_, mdString, err := CsvConvert(documentPath, outputDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mdString != c.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", mdString, c.want)
+			}
+		})
+	}
+}
@@ -6,8 +6,28 @@
 
 package convert_docx
 
-import "github.com/zakahan/docx2md"
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"gcnote/server/ability/convert/convert_base"
+	"github.com/zakahan/docx2md"
+	"os"
+)
 
 func DocxConvert(documentPath string, outputDir string) (string, string, error) {
-	return docx2md.DocxConvert(documentPath, outputDir)
+	info, err := os.Stat(documentPath)
+	if err != nil {
+		return "", "", err
+	}
+	if info.Size() > convert_base.MaxSourceSize {
+		return "", "", convert_base.NewError(convert_base.ErrTooLarge, documentPath, nil)
+	}
+	mdPath, mdString, err := docx2md.DocxConvert(documentPath, outputDir)
+	// zip或者xml解析失败，说明文件本身有问题
+	var syntaxErr *xml.SyntaxError
+	if errors.Is(err, zip.ErrFormat) || errors.As(err, &syntaxErr) {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+	}
+	return mdPath, mdString, err
 }
@@ -0,0 +1,285 @@
+// -------------------------------------------------
+// Package convert_enex
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_enex
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"gcnote/server/ability/convert/convert_base"
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/zakahan/docx2md/docx_parser"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// enex里时间的格式，如 20230115T083000Z
+const enexTimeLayout = "20060102T150405Z"
+
+// Note Evernote导出的一篇笔记
+type Note struct {
+	Title     string
+	Content   string // ENML，<en-note>...</en-note>
+	Created   time.Time
+	Updated   time.Time
+	Tags      []string
+	Resources []Resource
+}
+
+// Resource 笔记里的附件，ENML中用 <en-media hash="md5"> 引用
+type Resource struct {
+	Data     []byte
+	Mime     string
+	FileName string
+}
+
+type xmlNote struct {
+	Title     string        `xml:"title"`
+	Content   string        `xml:"content"`
+	Created   string        `xml:"created"`
+	Updated   string        `xml:"updated"`
+	Tags      []string      `xml:"tag"`
+	Resources []xmlResource `xml:"resource"`
+}
+
+type xmlResource struct {
+	Data     string `xml:"data"`
+	Mime     string `xml:"mime"`
+	FileName string `xml:"resource-attributes>file-name"`
+}
+
+// ReadNotes 逐篇读取.enex里的笔记，一个文件里可能有上千篇，不一次性全部读到内存里
+func ReadNotes(r io.Reader, fn func(note *Note) error) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+		var raw xmlNote
+		if err = decoder.DecodeElement(&raw, &start); err != nil {
+			return err
+		}
+		note, err := raw.toNote()
+		if err != nil {
+			return err
+		}
+		if err = fn(note); err != nil {
+			return err
+		}
+	}
+}
+
+func (raw *xmlNote) toNote() (*Note, error) {
+	note := &Note{
+		Title:   strings.TrimSpace(raw.Title),
+		Content: raw.Content,
+	}
+	// 时间格式不对时保持零值，由数据库填当前时间
+	note.Created, _ = time.Parse(enexTimeLayout, strings.TrimSpace(raw.Created))
+	note.Updated, _ = time.Parse(enexTimeLayout, strings.TrimSpace(raw.Updated))
+	for _, tag := range raw.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			note.Tags = append(note.Tags, tag)
+		}
+	}
+	for _, res := range raw.Resources {
+		// base64内容按76个字符换行
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(res.Data), ""))
+		if err != nil {
+			return nil, fmt.Errorf("decode resource of note %q: %w", note.Title, err)
+		}
+		note.Resources = append(note.Resources, Resource{
+			Data:     data,
+			Mime:     strings.TrimSpace(res.Mime),
+			FileName: strings.TrimSpace(res.FileName),
+		})
+	}
+	return note, nil
+}
+
+// Convert 将笔记转为markdown：标题作为一级标题，附件保存到images目录，
+// 图片类的<en-media>转为图片，其他附件转为链接，<en-todo>转为 [ ] / [x]
+func (note *Note) Convert(outputDir string) (string, string, error) {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, note.Title)
+	mdPath, mdDirPath, err := docx_parser.CreateMdDir(name+".enex", outputDir, ".enex")
+	if err != nil {
+		return "", "", err
+	}
+	imagesDir := filepath.Join(mdDirPath, convert_base.ImagesDirName)
+	err = os.MkdirAll(imagesDir, os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	links, err := saveResources(note.Resources, imagesDir)
+	if err != nil {
+		return "", "", err
+	}
+	doc, err := html.Parse(strings.NewReader(note.Content))
+	if err != nil {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, note.Title, err)
+	}
+	replaceENML(doc, links)
+	markdown, err := htmltomarkdown.ConvertNode(doc)
+	if err != nil {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, note.Title, err)
+	}
+
+	title := note.Title
+	if title == "" {
+		title = "Untitled"
+	}
+	mdString := "# " + title + "\n\n" + strings.TrimSpace(string(markdown)) + "\n"
+	err = convert_base.SaveFile(mdPath, mdString)
+	if err != nil {
+		return "", "", err
+	}
+	return mdPath, mdString, nil
+}
+
+// mediaLink 保存后的附件
+type mediaLink struct {
+	name    string // images下的文件名
+	label   string // 原始文件名，用作链接文字
+	isImage bool
+}
+
+// saveResources 保存附件，命名和docx2md一样为image1.png...，非图片附件为attachment1.pdf...
+// 返回 md5 => 附件，ENML按内容的md5引用附件
+func saveResources(resources []Resource, imagesDir string) (map[string]mediaLink, error) {
+	links := map[string]mediaLink{}
+	imageCount, attachmentCount := 0, 0
+	for _, res := range resources {
+		sum := md5.Sum(res.Data)
+		hash := hex.EncodeToString(sum[:])
+		if _, ok := links[hash]; ok {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(res.FileName))
+		if ext == "" {
+			ext = mimeExt(res.Mime)
+		}
+		link := mediaLink{label: res.FileName, isImage: strings.HasPrefix(res.Mime, "image/")}
+		if link.isImage {
+			imageCount++
+			link.name = fmt.Sprintf("image%d%s", imageCount, ext)
+		} else {
+			attachmentCount++
+			link.name = fmt.Sprintf("attachment%d%s", attachmentCount, ext)
+		}
+		if link.label == "" {
+			link.label = link.name
+		}
+		if _, err := convert_base.SaveImage(imagesDir, link.name, res.Data); err != nil {
+			return nil, err
+		}
+		links[hash] = link
+	}
+	return links, nil
+}
+
+var mimeExts = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/bmp":       ".bmp",
+	"image/webp":      ".webp",
+	"image/svg+xml":   ".svg",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+func mimeExt(mime string) string {
+	if ext, ok := mimeExts[strings.ToLower(mime)]; ok {
+		return ext
+	}
+	return ".bin"
+}
+
+// replaceENML 把ENML特有的标签替换为普通html，加密内容(en-crypt)无法解密，直接删掉
+func replaceENML(n *html.Node, links map[string]mediaLink) {
+	for c := n.FirstChild; c != nil; {
+		if c.Type != html.ElementNode {
+			c = c.NextSibling
+			continue
+		}
+		switch c.Data {
+		case "en-media", "en-todo":
+			// html解析器不认自闭合的<en-media/>，后面的内容会变成它的子节点，先移出来
+			hoistChildren(c)
+			if c.Data == "en-media" {
+				if link, ok := links[strings.ToLower(attr(c, "hash"))]; ok {
+					n.InsertBefore(mediaNode(link), c)
+				}
+			} else {
+				text := "[ ] "
+				if attr(c, "checked") == "true" {
+					text = "[x] "
+				}
+				n.InsertBefore(&html.Node{Type: html.TextNode, Data: text}, c)
+			}
+		case "en-crypt":
+		default:
+			replaceENML(c, links)
+			c = c.NextSibling
+			continue
+		}
+		next := c.NextSibling
+		n.RemoveChild(c)
+		c = next
+	}
+}
+
+// hoistChildren 把n的子节点移到n的后面
+func hoistChildren(n *html.Node) {
+	for child := n.LastChild; child != nil; child = n.LastChild {
+		n.RemoveChild(child)
+		n.Parent.InsertBefore(child, n.NextSibling)
+	}
+}
+
+// mediaNode 引用images目录下的附件，图片为img，其他为链接
+func mediaNode(link mediaLink) *html.Node {
+	src := convert_base.ImageLink(link.name)
+	if link.isImage {
+		return &html.Node{Type: html.ElementNode, DataAtom: atom.Img, Data: "img",
+			Attr: []html.Attribute{{Key: "src", Val: src}, {Key: "alt", Val: link.label}}}
+	}
+	a := &html.Node{Type: html.ElementNode, DataAtom: atom.A, Data: "a",
+		Attr: []html.Attribute{{Key: "href", Val: src}}}
+	a.AppendChild(&html.Node{Type: html.TextNode, Data: link.label})
+	return a
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
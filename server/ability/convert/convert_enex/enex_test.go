@@ -0,0 +1,87 @@
+// -------------------------------------------------
+// Package convert_enex
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_enex
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadNotesAndConvert(t *testing.T) {
+	image := []byte("\x89PNG evernote image")
+	sum := md5.Sum(image)
+	hash := hex.EncodeToString(sum[:])
+	enex := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-export SYSTEM "http://xml.evernote.com/pub/evernote-export3.dtd">
+<en-export export-date="20240101T000000Z" application="Evernote">
+<note>
+<title>读书笔记</title>
+<content><![CDATA[<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<!DOCTYPE en-note SYSTEM "http://xml.evernote.com/pub/enml2.dtd">
+<en-note><div><b>重点</b>内容</div><div><en-todo checked="true"/>已读</div><div><en-media hash="` + hash + `" type="image/png"/></div><en-crypt>c2VjcmV0</en-crypt></en-note>]]></content>
+<created>20230115T083000Z</created>
+<updated>20230201T100000Z</updated>
+<tag>阅读</tag>
+<tag>2023</tag>
+<resource>
+<data encoding="base64">` + base64.StdEncoding.EncodeToString(image) + `</data>
+<mime>image/png</mime>
+<resource-attributes><file-name>cover.png</file-name></resource-attributes>
+</resource>
+</note>
+<note><title>第二篇</title><content><![CDATA[<en-note>hello</en-note>]]></content></note>
+</en-export>`
+
+	var notes []*Note
+	err := ReadNotes(strings.NewReader(enex), func(note *Note) error {
+		notes = append(notes, note)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("expected 2 notes, got %d", len(notes))
+	}
+	note := notes[0]
+	if !reflect.DeepEqual(note.Tags, []string{"阅读", "2023"}) {
+		t.Errorf("unexpected tags %v", note.Tags)
+	}
+	if !note.Created.Equal(time.Date(2023, 1, 15, 8, 30, 0, 0, time.UTC)) ||
+		!note.Updated.Equal(time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected dates %v %v", note.Created, note.Updated)
+	}
+	if !notes[1].Created.IsZero() {
+		t.Errorf("missing created date should be zero, got %v", notes[1].Created)
+	}
+
+	outputDir := t.TempDir()
+	_, mdString, err := note.Convert(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# 读书笔记", "**重点**内容", "[x] 已读",
+		"![cover.png](" + filepath.Join("images", "image1.png") + ")"} {
+		if !strings.Contains(mdString, want) {
+			t.Errorf("markdown missing %q:\n%s", want, mdString)
+		}
+	}
+	if strings.Contains(mdString, "c2VjcmV0") {
+		t.Errorf("encrypted content should be dropped:\n%s", mdString)
+	}
+	data, err := os.ReadFile(filepath.Join(outputDir, "images", "image1.png"))
+	if err != nil || string(data) != string(image) {
+		t.Errorf("resource not saved: %v", err)
+	}
+}
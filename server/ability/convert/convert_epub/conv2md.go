@@ -0,0 +1,349 @@
+// -------------------------------------------------
+// Package convert_epub
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"gcnote/server/ability/convert/convert_base"
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/zakahan/docx2md/docx_parser"
+	"golang.org/x/net/html"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const containerPart = "META-INF/container.xml"
+
+// manifestItem opf里manifest下的一项
+type manifestItem struct {
+	href      string // zip内的完整路径
+	mediaType string
+}
+
+// book 解析opf得到的阅读顺序和目录标题
+type book struct {
+	spine  []manifestItem
+	titles map[string]string // 章节路径 => 目录里的标题
+}
+
+// EpubConvert 按opf的spine顺序把每个章节的xhtml转为markdown，合并为一个文档
+// 每章以一级标题开头，图片复制到images目录并改写链接
+func EpubConvert(documentPath string, outputDir string) (string, string, error) {
+	mdPath, mdDirPath, err := docx_parser.CreateMdDir(documentPath, outputDir, ".epub")
+	if err != nil {
+		return "", "", err
+	}
+	imagesDir := filepath.Join(mdDirPath, convert_base.ImagesDirName)
+	err = os.MkdirAll(imagesDir, os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	r, err := convert_base.OpenZip(documentPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer func(r *zip.ReadCloser) {
+		_ = r.Close()
+	}(r)
+
+	b, err := readBook(&r.Reader)
+	if err != nil {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+	}
+	if len(b.spine) == 0 {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, errors.New("empty spine"))
+	}
+
+	images := convert_base.NewAssetWriter(&r.Reader, imagesDir)
+	var chapters []string
+	for i, item := range b.spine {
+		chapter, err := convertChapter(&r.Reader, item.href, images)
+		if err != nil {
+			return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+		}
+		if chapter.markdown == "" && len(b.spine) > 1 {
+			continue
+		}
+		if !strings.HasPrefix(chapter.markdown, "# ") {
+			title := b.titles[item.href]
+			if title == "" {
+				title = chapter.title
+			}
+			if title == "" {
+				title = fmt.Sprintf("第%d章", i+1)
+			}
+			chapter.markdown = strings.TrimSpace("# " + title + "\n\n" + chapter.markdown)
+		}
+		chapters = append(chapters, chapter.markdown)
+	}
+
+	mdString := strings.Join(chapters, "\n\n") + "\n"
+	err = convert_base.SaveFile(mdPath, mdString)
+	if err != nil {
+		return "", "", err
+	}
+	return mdPath, mdString, nil
+}
+
+// readBook container.xml => opf => manifest、spine，目录标题优先用epub3的nav，其次是epub2的ncx
+func readBook(r *zip.Reader) (*book, error) {
+	data, err := convert_base.ReadZipFile(r, containerPart)
+	if err != nil {
+		return nil, err
+	}
+	container, err := convert_base.ParseXml(data)
+	if err != nil {
+		return nil, err
+	}
+	rootfiles := container.FindAll("rootfile")
+	if len(rootfiles) == 0 {
+		return nil, errors.New("rootfile not found in container.xml")
+	}
+	opfPath := rootfiles[0].Attr("full-path")
+	data, err = convert_base.ReadZipFile(r, opfPath)
+	if err != nil {
+		return nil, err
+	}
+	opf, err := convert_base.ParseXml(data)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := map[string]manifestItem{}
+	navHref := ""
+	if node := opf.Child("manifest"); node != nil {
+		for _, item := range node.Children("item") {
+			href := resolveHref(opfPath, item.Attr("href"))
+			manifest[item.Attr("id")] = manifestItem{href: href, mediaType: item.Attr("media-type")}
+			if strings.Contains(" "+item.Attr("properties")+" ", " nav ") {
+				navHref = href
+			}
+		}
+	}
+
+	b := &book{titles: map[string]string{}}
+	spine := opf.Child("spine")
+	if spine == nil {
+		return b, nil
+	}
+	for _, itemref := range spine.Children("itemref") {
+		item, ok := manifest[itemref.Attr("idref")]
+		if !ok {
+			return nil, fmt.Errorf("spine item %s not found in manifest", itemref.Attr("idref"))
+		}
+		if item.mediaType == "application/xhtml+xml" || item.mediaType == "text/html" {
+			b.spine = append(b.spine, item)
+		}
+	}
+
+	if navHref != "" {
+		err = readNavTitles(r, navHref, b.titles)
+	} else if ncx, ok := manifest[spine.Attr("toc")]; ok {
+		err = readNcxTitles(r, ncx.href, b.titles)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// readNcxTitles epub2目录，navPoint的navLabel和content src
+func readNcxTitles(r *zip.Reader, ncxPath string, titles map[string]string) error {
+	data, err := convert_base.ReadZipFile(r, ncxPath)
+	if err != nil {
+		return err
+	}
+	ncx, err := convert_base.ParseXml(data)
+	if err != nil {
+		return err
+	}
+	for _, navPoint := range ncx.FindAll("navPoint") {
+		content := navPoint.Child("content")
+		label := navPoint.Path("navLabel", "text")
+		if content == nil || label == nil {
+			continue
+		}
+		addTitle(titles, resolveHref(ncxPath, content.Attr("src")), label.InnerText())
+	}
+	return nil
+}
+
+// readNavTitles epub3目录，nav.xhtml里的 <nav epub:type="toc"> 链接
+func readNavTitles(r *zip.Reader, navPath string, titles map[string]string) error {
+	data, err := convert_base.ReadZipFile(r, navPath)
+	if err != nil {
+		return err
+	}
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			if href := attr(n, "href"); href != "" {
+				addTitle(titles, resolveHref(navPath, href), textContent(n))
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return nil
+}
+
+// addTitle 同一个文件可能对应多个目录项(锚点)，只取第一个
+func addTitle(titles map[string]string, href string, title string) {
+	title = strings.Join(strings.Fields(title), " ")
+	if _, ok := titles[href]; !ok && title != "" {
+		titles[href] = title
+	}
+}
+
+type chapter struct {
+	title    string
+	markdown string
+}
+
+func convertChapter(r *zip.Reader, chapterPath string, images *convert_base.AssetWriter) (chapter, error) {
+	data, err := convert_base.ReadZipFile(r, chapterPath)
+	if err != nil {
+		return chapter{}, err
+	}
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return chapter{}, err
+	}
+	var title string
+	var walk func(n *html.Node) error
+	walk = func(n *html.Node) error {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "title":
+				title = textContent(n)
+			case "img":
+				if err := rewriteImage(images, n, "src", chapterPath); err != nil {
+					return err
+				}
+			case "svg":
+				// 封面常用 <svg><image xlink:href="..."/></svg>，换成img交给html-to-markdown处理
+				for _, image := range findElements(n, "image") {
+					// xlink:href解析后Key为href、Namespace为xlink
+					if src := attr(image, "href"); src != "" {
+						img := &html.Node{Type: html.ElementNode, Data: "img", Attr: []html.Attribute{{Key: "src", Val: src}}}
+						n.Parent.InsertBefore(img, n)
+						if err := rewriteImage(images, img, "src", chapterPath); err != nil {
+							return err
+						}
+					}
+				}
+				n.Parent.RemoveChild(n)
+				return nil
+			}
+		}
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if err := walk(c); err != nil {
+				return err
+			}
+			c = next
+		}
+		return nil
+	}
+	if err = walk(doc); err != nil {
+		return chapter{}, err
+	}
+	markdown, err := htmltomarkdown.ConvertNode(doc)
+	if err != nil {
+		return chapter{}, err
+	}
+	return chapter{title: strings.TrimSpace(title), markdown: strings.TrimSpace(string(markdown))}, nil
+}
+
+// rewriteImage 把章节里引用的图片复制到images目录并改写链接
+func rewriteImage(images *convert_base.AssetWriter, n *html.Node, key string, chapterPath string) error {
+	src := attr(n, key)
+	if src == "" || strings.HasPrefix(src, "data:") || strings.Contains(src, "://") {
+		return nil
+	}
+	name, err := images.Copy(resolveHref(chapterPath, src))
+	if err != nil || name == "" {
+		return err
+	}
+	setAttr(n, key, convert_base.ImageLink(name))
+	if attr(n, "alt") == "" {
+		setAttr(n, "alt", name)
+	}
+	return nil
+}
+
+// resolveHref 解析相对于base文件的链接，去掉锚点并做url解码，返回zip内路径
+func resolveHref(base string, href string) string {
+	if i := strings.IndexByte(href, '#'); i >= 0 {
+		href = href[:i]
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	if href == "" {
+		return path.Clean(base)
+	}
+	return convert_base.ResolvePart(base, href)
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key string, val string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func findElements(n *html.Node, name string) []*html.Node {
+	var result []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == name {
+			result = append(result, c)
+		}
+		result = append(result, findElements(c, name)...)
+	}
+	return result
+}
+
+func textContent(n *html.Node) string {
+	var builder strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			builder.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
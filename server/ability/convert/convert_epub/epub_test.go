@@ -0,0 +1,79 @@
+// -------------------------------------------------
+// Package convert_epub
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_epub
+
+import (
+	"gcnote/server/ability/convert/convert_fixture"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var epubParts = map[string]string{
+	"mimetype": "application/epub+zip",
+	"META-INF/container.xml": `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`,
+	"OEBPS/content.opf": `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+<manifest>
+<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+<item id="cover" href="text/cover.xhtml" media-type="application/xhtml+xml"/>
+<item id="c1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>
+<item id="c2" href="text/chapter2.xhtml" media-type="application/xhtml+xml"/>
+<item id="img1" href="images/cover.jpg" media-type="image/jpeg"/>
+<item id="img2" href="text/images/cover.jpg" media-type="image/jpeg"/>
+</manifest>
+<spine toc="ncx"><itemref idref="cover"/><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`,
+	"OEBPS/toc.ncx": `<?xml version="1.0"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/"><navMap>
+<navPoint id="n1"><navLabel><text>第一章 起源</text></navLabel><content src="text/chapter%201.xhtml#start"/></navPoint>
+<navPoint id="n2"><navLabel><text>第二章</text></navLabel><content src="text/chapter2.xhtml"/></navPoint>
+</navMap></ncx>`,
+	"OEBPS/text/cover.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>封面</title></head><body>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><image xlink:href="../images/cover.jpg"/></svg>
+</body></html>`,
+	"OEBPS/text/chapter 1.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>ch1</title></head><body>
+<p id="start">很久以前。</p><p><img src="images/cover.jpg" alt="插图"/></p>
+</body></html>`,
+	"OEBPS/text/chapter2.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>ch2</title></head><body>
+<h1>结局</h1><p>完。</p>
+</body></html>`,
+	"OEBPS/images/cover.jpg":      "fake cover",
+	"OEBPS/text/images/cover.jpg": "fake illustration",
+}
+
+func TestEpubConvert(t *testing.T) {
+	dir := t.TempDir()
+	documentPath := filepath.Join(dir, "novel.epub")
+	convert_fixture.WriteZip(t, documentPath, epubParts)
+	outputDir := filepath.Join(dir, "out")
+	if err := os.Mkdir(outputDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	mdPath, mdString, err := EpubConvert(documentPath, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# 封面\n\n![cover.jpg](" + filepath.Join("images", "cover.jpg") + ")\n\n" +
+		"# 第一章 起源\n\n很久以前。\n\n![插图](" + filepath.Join("images", "cover_1.jpg") + ")\n\n" +
+		"# 结局\n\n完。\n"
+	if mdString != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", mdString, want)
+	}
+	if filepath.Base(mdPath) != "novel.md" {
+		t.Errorf("unexpected md path %s", mdPath)
+	}
+	for name, content := range map[string]string{"cover.jpg": "fake cover", "cover_1.jpg": "fake illustration"} {
+		data, err := os.ReadFile(filepath.Join(outputDir, "images", name))
+		if err != nil || string(data) != content {
+			t.Errorf("image %s not copied correctly: %q %v", name, data, err)
+		}
+	}
+}
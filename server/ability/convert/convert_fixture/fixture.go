@@ -0,0 +1,67 @@
+// -------------------------------------------------
+// Package convert_fixture
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+// 测试用的压缩包：pptx、xlsx、epub等都是zip，只在测试中引用
+
+package convert_fixture
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"testing"
+)
+
+// WriteZip 生成zip文件，parts为 包内路径 => 内容
+func WriteZip(t testing.TB, zipPath string, parts map[string]string) {
+	t.Helper()
+	file, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	w := zip.NewWriter(file)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// WriteTarGz 生成tar.gz文件，parts为 包内路径 => 内容
+func WriteTarGz(t testing.TB, tarPath string, parts map[string]string) {
+	t.Helper()
+	file, err := os.Create(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for name, content := range parts {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err = tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,47 +7,69 @@
 package convert_html
 
 import (
-	"fmt"
+	"bytes"
+	"gcnote/server/ability/convert/convert_base"
+	"gcnote/server/config"
 	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
 	"github.com/zakahan/docx2md/docx_parser"
-	"go.uber.org/zap"
-	"io"
-	"log"
+	"golang.org/x/net/html"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
+// Options html转换选项
+type Options struct {
+	BaseURL     string // 页面的原始地址，用于解析相对路径的图片；为空时只处理绝对地址和data URI
+	Readability bool   // 只提取正文，去掉导航、页脚、脚本等
+}
+
 func HtmlConvert(documentPath string, outputDir string) (string, string, error) {
-	mdPath, _, err := docx_parser.CreateMdDir(documentPath, outputDir, ".html")
+	return HtmlConvertWithOptions(documentPath, outputDir, Options{
+		Readability: config.ServerCfg.ConvertConf.HtmlReadability,
+	})
+}
+
+// HtmlConvertWithOptions html转markdown，图片下载(或解码data URI)到images目录并改写为本地链接
+func HtmlConvertWithOptions(documentPath string, outputDir string, opts Options) (string, string, error) {
+	mdPath, mdDirPath, err := docx_parser.CreateMdDir(documentPath, outputDir, ".html")
 	if err != nil {
-		fmt.Println("Error:", err)
 		return "", "", err
 	}
-	// 打开文件
-	file, err := os.Open(documentPath)
+	imagesDir := filepath.Join(mdDirPath, convert_base.ImagesDirName)
+	err = os.MkdirAll(imagesDir, os.ModePerm)
 	if err != nil {
-		log.Fatalf("无法打开文件：%v", err)
 		return "", "", err
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			zap.S().Errorf("无法关闭文件：%v", err)
-		}
-	}(file)
 	// 读取文件
-	data, err := io.ReadAll(file)
+	data, err := convert_base.ReadSource(documentPath)
 	if err != nil {
-		log.Fatalf("读取文件时发生错误")
+		return "", "", err
+	}
+	err = convert_base.CheckText(documentPath, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+	}
+	root := doc
+	if opts.Readability {
+		root = ExtractMainContent(doc)
+	}
+	err = newImageSaver(imagesDir, opts.BaseURL).saveAll(root)
+	if err != nil {
+		return "", "", err
 	}
 
-	// 将数据转为字符串
-	inputString := string(data)
-	// 改一下，添加表格处理的功能
-	markdownStr, err := htmltomarkdown.ConvertString(inputString)
+	markdown, err := htmltomarkdown.ConvertNode(root)
 	if err != nil {
-		log.Fatal(err)
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
 	}
+	markdownStr := strings.TrimSpace(string(markdown)) + "\n"
 
-	err = docx_parser.SaveFile(mdPath, markdownStr)
+	err = convert_base.SaveFile(mdPath, markdownStr)
 	return mdPath, markdownStr, err
 }
@@ -0,0 +1,113 @@
+// -------------------------------------------------
+// Package convert_html
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_html
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var pngData = []byte("\x89PNG\r\n\x1a\n fake png")
+
+func convertPage(t *testing.T, page string, opts Options) (string, string) {
+	dir := t.TempDir()
+	documentPath := filepath.Join(dir, "page.html")
+	if err := os.WriteFile(documentPath, []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(dir, "out")
+	if err := os.Mkdir(outputDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	_, mdString, err := HtmlConvertWithOptions(documentPath, outputDir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mdString, filepath.Join(outputDir, "images")
+}
+
+func TestHtmlConvertImages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/img/a.png":
+			_, _ = w.Write(pngData)
+		case "/moved.png":
+			http.Redirect(w, r, "/img/a.png", http.StatusFound)
+		case "/script.png":
+			_, _ = w.Write([]byte("<script>alert(1)</script>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	page := `<html><body><p>正文</p>
+<img src="../img/a.png" alt="相对路径">
+<img src="` + server.URL + `/img/a.png">
+<img data-src="/moved.png" src="placeholder.gif">
+<img src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(pngData) + `" alt="内嵌">
+<img src="/script.png" alt="伪装">
+<img src="/missing.png" alt="丢失">
+<img src="data:text/plain;base64,aGVsbG8=" alt="文本">
+</body></html>`
+	mdString, imagesDir := convertPage(t, page, Options{BaseURL: server.URL + "/posts/1.html"})
+
+	local := func(name string) string { return "(" + filepath.Join("images", name) + ")" }
+	// 同一个地址只下载一次
+	if strings.Count(mdString, local("image1.png")) != 2 || !strings.Contains(mdString, "![相对路径]"+local("image1.png")) {
+		t.Errorf("relative image not saved: %s", mdString)
+	}
+	for _, want := range []string{
+		"![image2.png]" + local("image2.png"),
+		"![内嵌]" + local("image3.png"),
+		"![伪装](" + server.URL + "/script.png)",
+		"![丢失](" + server.URL + "/missing.png)",
+	} {
+		if !strings.Contains(mdString, want) {
+			t.Errorf("missing %q in:\n%s", want, mdString)
+		}
+	}
+	if strings.Contains(mdString, "文本") || strings.Contains(mdString, "data:") {
+		t.Errorf("data uri with disallowed type should be dropped:\n%s", mdString)
+	}
+	entries, err := os.ReadDir(imagesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("expected 3 saved images, got %d", len(entries))
+	}
+}
+
+func TestHtmlConvertReadability(t *testing.T) {
+	page := `<html><head><title>发布说明</title><script>var x = 1;</script></head><body>
+<nav><a href="/">首页</a><a href="/docs">文档</a></nav>
+<div class="sidebar-menu"><ul><li>推荐阅读</li></ul></div>
+<div id="content">
+<p>本次版本修复了导入时的编码问题，同时改进了表格的切分方式，让检索结果更加完整。</p>
+<p>升级前请备份数据库，升级后需要重新构建索引，否则旧的文档无法被检索到。</p>
+</div>
+<div class="comments"><p>这是一条很长很长很长很长很长很长很长很长的评论，不应该出现在正文中。</p></div>
+<footer>版权所有</footer>
+</body></html>`
+	mdString, _ := convertPage(t, page, Options{Readability: true})
+	want := "# 发布说明\n\n本次版本修复了导入时的编码问题，同时改进了表格的切分方式，让检索结果更加完整。\n\n" +
+		"升级前请备份数据库，升级后需要重新构建索引，否则旧的文档无法被检索到。\n"
+	if mdString != want {
+		t.Errorf("got:\n%s\nwant:\n%s", mdString, want)
+	}
+
+	mdString, _ = convertPage(t, page, Options{})
+	if !strings.Contains(mdString, "首页") || !strings.Contains(mdString, "版权所有") {
+		t.Errorf("full page conversion should keep everything:\n%s", mdString)
+	}
+}
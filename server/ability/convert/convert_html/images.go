@@ -0,0 +1,234 @@
+// -------------------------------------------------
+// Package convert_html
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_html
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gcnote/server/ability/convert/convert_base"
+	"gcnote/server/ability/web_fetch"
+	"gcnote/server/config"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultImageMaxSize = 5 << 20
+	imageMaxRedirects   = 3
+)
+
+var defaultImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+var imageExts = map[string]string{
+	"image/png":     ".png",
+	"image/jpeg":    ".jpg",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/bmp":     ".bmp",
+	"image/x-icon":  ".ico",
+	"image/svg+xml": ".svg",
+}
+
+// imageSaver 把html里的图片保存到images目录，命名和docx2md一样为image1.png、image2.jpg...
+type imageSaver struct {
+	imagesDir string
+	baseURL   *url.URL
+	fetchOpts web_fetch.Options
+	maxSize   int64
+	types     []string
+	saved     map[string]string // 原始地址 => images下的文件名
+}
+
+func newImageSaver(imagesDir string, baseURL string) *imageSaver {
+	conf := config.ServerCfg.ConvertConf
+	s := &imageSaver{
+		imagesDir: imagesDir,
+		maxSize:   conf.HtmlImageMaxSize,
+		types:     conf.HtmlImageTypes,
+		saved:     map[string]string{},
+	}
+	if s.maxSize <= 0 {
+		s.maxSize = defaultImageMaxSize
+	}
+	if len(s.types) == 0 {
+		s.types = defaultImageTypes
+	}
+	s.fetchOpts = web_fetch.Options{
+		Timeout:      time.Duration(conf.HtmlImageTimeout) * time.Second,
+		MaxSize:      s.maxSize,
+		MaxRedirects: imageMaxRedirects,
+		AllowHosts:   conf.HtmlImageHosts,
+		BlockPrivate: conf.HtmlImageBlockPrivate,
+	}
+	if baseURL != "" {
+		s.baseURL, _ = url.Parse(baseURL)
+	}
+	return s
+}
+
+// saveAll 处理所有img：保存成功的改写为本地链接；远程图片下载失败时保留原地址，data URI失败时直接删掉
+func (s *imageSaver) saveAll(root *html.Node) error {
+	var images []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "img" {
+			images = append(images, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	for _, img := range images {
+		src := s.resolve(imageSource(img))
+		if src == "" {
+			continue
+		}
+		name, err := s.save(src)
+		if err != nil {
+			// 只有写文件失败才中断转换
+			var pathErr *os.PathError
+			if errors.As(err, &pathErr) {
+				return err
+			}
+			if strings.HasPrefix(src, "data:") {
+				if img.Parent != nil {
+					img.Parent.RemoveChild(img)
+				}
+			} else {
+				setAttr(img, "src", src)
+			}
+			continue
+		}
+		setAttr(img, "src", convert_base.ImageLink(name))
+		removeAttr(img, "srcset")
+		if attr(img, "alt") == "" {
+			setAttr(img, "alt", name)
+		}
+	}
+	return nil
+}
+
+// resolve 返回data URI或者绝对地址；相对路径且没有BaseURL时无法处理，返回空字符串
+func (s *imageSaver) resolve(src string) string {
+	if src == "" || strings.HasPrefix(src, "data:") {
+		return src
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return ""
+	}
+	if s.baseURL != nil {
+		u = s.baseURL.ResolveReference(u)
+	}
+	if !u.IsAbs() {
+		return ""
+	}
+	return u.String()
+}
+
+// save 下载或解码图片并保存，返回images下的文件名
+func (s *imageSaver) save(src string) (string, error) {
+	if name, ok := s.saved[src]; ok {
+		return name, nil
+	}
+	var data []byte
+	var err error
+	if strings.HasPrefix(src, "data:") {
+		data, err = decodeDataURI(src)
+	} else {
+		var resp *web_fetch.Response
+		resp, err = web_fetch.Get(context.Background(), src, s.fetchOpts)
+		if resp != nil {
+			data = resp.Data
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > s.maxSize {
+		return "", web_fetch.ErrTooLarge
+	}
+
+	// 不信任扩展名和Content-Type，按内容判断类型
+	contentType := http.DetectContentType(data)
+	if !s.allowed(contentType) {
+		return "", fmt.Errorf("image type %s not allowed", contentType)
+	}
+	name := fmt.Sprintf("image%d%s", len(s.saved)+1, imageExts[contentType])
+	_, err = convert_base.SaveImage(s.imagesDir, name, data)
+	if err != nil {
+		return "", err
+	}
+	s.saved[src] = name
+	return name, nil
+}
+
+func (s *imageSaver) allowed(contentType string) bool {
+	for _, t := range s.types {
+		if strings.EqualFold(t, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeDataURI 只支持base64编码的 data:image/png;base64,xxxx
+func decodeDataURI(uri string) ([]byte, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return nil, errors.New("unsupported data uri")
+	}
+	payload = strings.Join(strings.Fields(payload), "")
+	return base64.StdEncoding.DecodeString(payload)
+}
+
+// imageSource 懒加载的图片真实地址一般在data-src里
+func imageSource(img *html.Node) string {
+	for _, key := range []string{"data-src", "data-original", "src"} {
+		if v := strings.TrimSpace(attr(img, key)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key string, val string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func removeAttr(n *html.Node, key string) {
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		if a.Key != key {
+			attrs = append(attrs, a)
+		}
+	}
+	n.Attr = attrs
+}
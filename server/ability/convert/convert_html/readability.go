@@ -0,0 +1,160 @@
+// -------------------------------------------------
+// Package convert_html
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_html
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 一定不是正文的标签，直接删除
+var boilerplateTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Nav: true, atom.Footer: true,
+	atom.Header: true, atom.Aside: true, atom.Form: true, atom.Iframe: true, atom.Button: true,
+	atom.Input: true, atom.Select: true, atom.Textarea: true, atom.Template: true,
+}
+
+var (
+	unlikelyPattern = regexp.MustCompile(`(?i)comment|footer|header|nav|sidebar|menu|breadcrumb|share|social|advert|\bads?\b|banner|popup|cookie|related|recommend`)
+	maybePattern    = regexp.MustCompile(`(?i)article|content|main|post|body|entry|text`)
+)
+
+// ExtractMainContent 类似readability的正文提取：先删掉脚本、导航、页脚等，
+// 再依次取 <article>、<main>、role=main，都没有时按段落文字量给容器打分取最高的
+// 正文里没有一级标题时用页面的<title>补上
+func ExtractMainContent(doc *html.Node) *html.Node {
+	title := ""
+	if n := findFirst(doc, func(n *html.Node) bool { return n.DataAtom == atom.Title }); n != nil {
+		title = strings.TrimSpace(textContent(n))
+	}
+	removeBoilerplate(doc)
+
+	content := findFirst(doc, func(n *html.Node) bool {
+		return n.DataAtom == atom.Article || n.DataAtom == atom.Main || attr(n, "role") == "main"
+	})
+	if content == nil {
+		content = bestCandidate(doc)
+	}
+	if content == nil {
+		content = findFirst(doc, func(n *html.Node) bool { return n.DataAtom == atom.Body })
+	}
+	if content == nil {
+		content = doc
+	}
+
+	// 把正文挂到新的容器下，和原文档断开
+	container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	if title != "" && findFirst(content, func(n *html.Node) bool { return n.DataAtom == atom.H1 }) == nil {
+		h1 := &html.Node{Type: html.ElementNode, Data: "h1", DataAtom: atom.H1}
+		h1.AppendChild(&html.Node{Type: html.TextNode, Data: title})
+		container.AppendChild(h1)
+	}
+	if content.Parent != nil {
+		content.Parent.RemoveChild(content)
+	}
+	container.AppendChild(content)
+	return container
+}
+
+func removeBoilerplate(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || c.Type == html.ElementNode && isBoilerplate(c) {
+			n.RemoveChild(c)
+		} else {
+			removeBoilerplate(c)
+		}
+		c = next
+	}
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateTags[n.DataAtom] {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Html, atom.Body, atom.Article, atom.Main:
+		return false
+	}
+	if attr(n, "hidden") != "" || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	match := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyPattern.MatchString(match) && !maybePattern.MatchString(match)
+}
+
+// bestCandidate 每个足够长的段落给父节点加分、祖父节点加一半，返回得分最高的节点
+func bestCandidate(doc *html.Node) *html.Node {
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node // 按文档顺序，同分时取靠前的
+	addScore := func(n *html.Node, score float64) {
+		if _, ok := scores[n]; !ok {
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+				text := textContent(n)
+				length := utf8.RuneCountInString(text)
+				if length >= 25 && n.Parent != nil {
+					score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")+strings.Count(text, "。"))
+					score += min(float64(length)/100, 3)
+					addScore(n.Parent, score)
+					if n.Parent.Parent != nil {
+						addScore(n.Parent.Parent, score/2)
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var best *html.Node
+	for _, n := range candidates {
+		if best == nil || scores[n] > scores[best] {
+			best = n
+		}
+	}
+	return best
+}
+
+func findFirst(n *html.Node, match func(n *html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	var builder strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			builder.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
@@ -0,0 +1,245 @@
+// -------------------------------------------------
+// Package convert_ipynb
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_ipynb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gcnote/server/ability/convert/convert_base"
+	"github.com/zakahan/docx2md/docx_parser"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 代码块里的ANSI颜色控制符（异常的traceback里很常见）
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// source nbformat里的多行文本，可能是字符串也可能是按行拆开的字符串数组
+type source string
+
+func (s *source) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*s = source(strings.Join(lines, ""))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*s = source(text)
+	return nil
+}
+
+type notebook struct {
+	NbFormat int    `json:"nbformat"`
+	Cells    []cell `json:"cells"`
+	Metadata struct {
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+type cell struct {
+	CellType    string                       `json:"cell_type"`
+	Source      source                       `json:"source"`
+	Outputs     []output                     `json:"outputs"`
+	Attachments map[string]map[string]source `json:"attachments"`
+}
+
+type output struct {
+	OutputType string            `json:"output_type"`
+	Text       source            `json:"text"`
+	Data       map[string]source `json:"data"`
+	EName      string            `json:"ename"`
+	EValue     string            `json:"evalue"`
+}
+
+// IpynbConvert 将Jupyter notebook转为markdown：markdown单元格原样保留，代码单元格转为带内核语言的代码块，
+// 文本输出转为output代码块，图片输出和单元格附件保存到images目录
+func IpynbConvert(documentPath string, outputDir string) (string, string, error) {
+	mdPath, mdDirPath, err := docx_parser.CreateMdDir(documentPath, outputDir, ".ipynb")
+	if err != nil {
+		return "", "", err
+	}
+	imagesDir := filepath.Join(mdDirPath, convert_base.ImagesDirName)
+	err = os.MkdirAll(imagesDir, os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := convert_base.ReadSource(documentPath)
+	if err != nil {
+		return "", "", err
+	}
+	var nb notebook
+	if err = json.Unmarshal(data, &nb); err != nil {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+	}
+	if nb.NbFormat < 4 {
+		return "", "", convert_base.NewError(convert_base.ErrUnsupported, documentPath,
+			fmt.Errorf("nbformat %d is not supported, please upgrade the notebook to nbformat 4", nb.NbFormat))
+	}
+	language := nb.Metadata.KernelSpec.Language
+	if language == "" {
+		language = nb.Metadata.LanguageInfo.Name
+	}
+
+	w := &notebookWriter{imagesDir: imagesDir, language: language}
+	for _, c := range nb.Cells {
+		if err = w.writeCell(c); err != nil {
+			return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+		}
+	}
+	if len(w.blocks) == 0 {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, errors.New("empty notebook"))
+	}
+
+	mdString := strings.Join(w.blocks, "\n\n") + "\n"
+	err = convert_base.SaveFile(mdPath, mdString)
+	if err != nil {
+		return "", "", err
+	}
+	return mdPath, mdString, nil
+}
+
+// notebookWriter 收集各个单元格转换后的markdown块
+type notebookWriter struct {
+	imagesDir  string
+	language   string
+	imageCount int
+	blocks     []string
+}
+
+func (w *notebookWriter) writeCell(c cell) error {
+	text := strings.TrimRight(string(c.Source), "\n")
+	switch c.CellType {
+	case "markdown":
+		md, err := w.rewriteAttachments(text, c.Attachments)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(md) != "" {
+			w.blocks = append(w.blocks, md)
+		}
+	case "code":
+		if strings.TrimSpace(text) != "" {
+			w.blocks = append(w.blocks, fenced(w.language, text))
+		}
+		for _, out := range c.Outputs {
+			if err := w.writeOutput(out); err != nil {
+				return err
+			}
+		}
+	case "raw":
+		if strings.TrimSpace(text) != "" {
+			w.blocks = append(w.blocks, fenced("", text))
+		}
+	}
+	return nil
+}
+
+// writeOutput 图片优先，其次是markdown，最后是纯文本；html输出一般是表格的另一种展示，有text/plain时不重复导入
+func (w *notebookWriter) writeOutput(out output) error {
+	switch out.OutputType {
+	case "stream":
+		if text := strings.TrimRight(string(out.Text), "\n"); strings.TrimSpace(text) != "" {
+			w.blocks = append(w.blocks, fenced("output", text))
+		}
+	case "error":
+		w.blocks = append(w.blocks, fenced("output", ansiRe.ReplaceAllString(out.EName+": "+out.EValue, "")))
+	case "execute_result", "display_data":
+		for _, mimeType := range []string{"image/png", "image/jpeg", "image/gif"} {
+			if encoded, ok := out.Data[mimeType]; ok {
+				return w.writeImage(string(encoded), mimeType)
+			}
+		}
+		if md, ok := out.Data["text/markdown"]; ok && strings.TrimSpace(string(md)) != "" {
+			w.blocks = append(w.blocks, strings.TrimRight(string(md), "\n"))
+			return nil
+		}
+		if text, ok := out.Data["text/plain"]; ok && strings.TrimSpace(string(text)) != "" {
+			w.blocks = append(w.blocks, fenced("output", strings.TrimRight(string(text), "\n")))
+		}
+	}
+	return nil
+}
+
+// writeImage 命名和docx2md一样为image1.png、image2.jpg...
+func (w *notebookWriter) writeImage(encoded string, mimeType string) error {
+	name, err := w.saveImage(encoded, mimeType)
+	if err != nil {
+		return err
+	}
+	w.blocks = append(w.blocks, "!["+name+"]("+convert_base.ImageLink(name)+")")
+	return nil
+}
+
+func (w *notebookWriter) saveImage(encoded string, mimeType string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return "", err
+	}
+	ext := "." + strings.TrimPrefix(mimeType, "image/")
+	if ext == ".jpeg" {
+		ext = ".jpg"
+	}
+	w.imageCount++
+	name := fmt.Sprintf("image%d%s", w.imageCount, ext)
+	if _, err = convert_base.SaveImage(w.imagesDir, name, data); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// rewriteAttachments markdown单元格里粘贴的图片以 attachment:名称 引用，保存到images目录
+func (w *notebookWriter) rewriteAttachments(markdown string, attachments map[string]map[string]source) (string, error) {
+	if len(attachments) == 0 {
+		return markdown, nil
+	}
+	var saveErr error
+	saved := map[string]string{}
+	result := convert_base.RewriteLinks(markdown, func(target string, isImage bool) (string, bool) {
+		name, ok := strings.CutPrefix(target, "attachment:")
+		if !ok || saveErr != nil {
+			return "", false
+		}
+		if file, ok := saved[name]; ok {
+			return convert_base.ImageLink(file), true
+		}
+		for mimeType, encoded := range attachments[name] {
+			if !strings.HasPrefix(mimeType, "image/") {
+				continue
+			}
+			file, err := w.saveImage(string(encoded), mimeType)
+			if err != nil {
+				saveErr = err
+				return "", false
+			}
+			saved[name] = file
+			return convert_base.ImageLink(file), true
+		}
+		return "", false
+	})
+	return result, saveErr
+}
+
+// fenced 生成代码块，内容里有```时用更长的围栏
+func fenced(language string, text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + text + "\n" + fence
+}
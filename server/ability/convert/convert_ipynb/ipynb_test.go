@@ -0,0 +1,78 @@
+// -------------------------------------------------
+// Package convert_ipynb
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_ipynb
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIpynbConvert(t *testing.T) {
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG plot"))
+	notebook := `{
+ "nbformat": 4,
+ "nbformat_minor": 5,
+ "metadata": {"kernelspec": {"language": "python", "name": "python3"}},
+ "cells": [
+  {"cell_type": "markdown", "source": ["# 销量分析\n", "\n", "![截图](attachment:shot.png)"],
+   "attachments": {"shot.png": {"image/png": "` + png + `"}}},
+  {"cell_type": "code", "source": "import pandas as pd\nprint(len(df))", "outputs": [
+   {"output_type": "stream", "name": "stdout", "text": ["42\n"]},
+   {"output_type": "display_data", "data": {"image/png": "` + png + `", "text/plain": ["<Figure>"]}}
+  ]},
+  {"cell_type": "code", "source": ["df.head(1)"], "outputs": [
+   {"output_type": "execute_result", "data": {"text/plain": ["   a\n", "0  1"], "text/html": ["<table></table>"]}}
+  ]},
+  {"cell_type": "code", "source": "1/0", "outputs": [
+   {"output_type": "error", "ename": "ZeroDivisionError", "evalue": "division by zero", "traceback": ["\u001b[0;31m..."]}
+  ]}
+ ]
+}`
+	dir := t.TempDir()
+	documentPath := filepath.Join(dir, "analysis.ipynb")
+	if err := os.WriteFile(documentPath, []byte(notebook), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(dir, "out")
+	if err := os.Mkdir(outputDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	mdPath, mdString, err := IpynbConvert(documentPath, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# 销量分析\n\n![截图](" + filepath.Join("images", "image1.png") + ")\n\n" +
+		"```python\nimport pandas as pd\nprint(len(df))\n```\n\n" +
+		"```output\n42\n```\n\n" +
+		"![image2.png](" + filepath.Join("images", "image2.png") + ")\n\n" +
+		"```python\ndf.head(1)\n```\n\n" +
+		"```output\n   a\n0  1\n```\n\n" +
+		"```python\n1/0\n```\n\n" +
+		"```output\nZeroDivisionError: division by zero\n```\n"
+	if mdString != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", mdString, want)
+	}
+	if filepath.Base(mdPath) != "analysis.md" {
+		t.Errorf("unexpected md path %s", mdPath)
+	}
+	for _, name := range []string{"image1.png", "image2.png"} {
+		if _, err = os.Stat(filepath.Join(outputDir, "images", name)); err != nil {
+			t.Errorf("%s not saved: %v", name, err)
+		}
+	}
+}
+
+func TestFenced(t *testing.T) {
+	got := fenced("markdown", "```go\nx\n```")
+	want := "````markdown\n```go\nx\n```\n````"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
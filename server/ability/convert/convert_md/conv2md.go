@@ -7,39 +7,26 @@
 package convert_md
 
 import (
-	"fmt"
+	"gcnote/server/ability/convert/convert_base"
 	"github.com/zakahan/docx2md/docx_parser"
-	"io"
-	"log"
-	"os"
 )
 
 func MdConvert(documentPath string, outputDir string) (string, string, error) {
 	mdPath, _, err := docx_parser.CreateMdDir(documentPath, outputDir, ".md")
 	if err != nil {
-		fmt.Println("Error:", err)
 		return "", "", err
 	}
-	// 打开文件
-	file, err := os.Open(documentPath)
+	// 读取文件
+	data, err := convert_base.ReadSource(documentPath)
 	if err != nil {
-		log.Fatalf("无法打开文件：%v", err)
 		return "", "", err
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-
-		}
-	}(file)
-	// 读取文件
-	data, err := io.ReadAll(file)
+	err = convert_base.CheckText(documentPath, data)
 	if err != nil {
-		log.Fatalf("读取文件时发生错误")
 		return "", "", err
 	}
 	inputString := string(data)
 	// 保存
-	err = docx_parser.SaveFile(mdPath, inputString)
+	err = convert_base.SaveFile(mdPath, inputString)
 	return mdPath, inputString, err
 }
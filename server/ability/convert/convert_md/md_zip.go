@@ -0,0 +1,99 @@
+// -------------------------------------------------
+// Package convert_md
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_md
+
+import (
+	"archive/zip"
+	"errors"
+	"gcnote/server/ability/convert/convert_base"
+	"github.com/zakahan/docx2md/docx_parser"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MdZipConvert 导入 markdown文件 + 图片等资源 的zip包，zip里只能有一个.md文件
+// 相对路径引用的图片和附件复制到images目录，链接改写为 images/xxx
+func MdZipConvert(documentPath string, outputDir string) (string, string, error) {
+	mdPath, mdDirPath, err := docx_parser.CreateMdDir(documentPath, outputDir, ".zip")
+	if err != nil {
+		return "", "", err
+	}
+	imagesDir := filepath.Join(mdDirPath, convert_base.ImagesDirName)
+	err = os.MkdirAll(imagesDir, os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	r, err := convert_base.OpenZip(documentPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer func(r *zip.ReadCloser) {
+		_ = r.Close()
+	}(r)
+
+	var mdParts []string
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() && strings.EqualFold(path.Ext(f.Name), ".md") && !IsHiddenPart(f.Name) {
+			mdParts = append(mdParts, f.Name)
+		}
+	}
+	if len(mdParts) != 1 {
+		return "", "", convert_base.NewError(convert_base.ErrUnsupported, documentPath,
+			errors.New("zip中需要有且只有一个.md文件，多个文档请使用批量导入"))
+	}
+	data, err := convert_base.ReadZipFile(&r.Reader, mdParts[0])
+	if err != nil {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+	}
+	err = convert_base.CheckText(documentPath, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	assets := convert_base.NewAssetWriter(&r.Reader, imagesDir)
+	mdString, err := RewriteAssets(string(data), mdParts[0], assets)
+	if err != nil {
+		return "", "", err
+	}
+	err = convert_base.SaveFile(mdPath, mdString)
+	if err != nil {
+		return "", "", err
+	}
+	return mdPath, mdString, nil
+}
+
+// RewriteAssets 把markdown里相对于mdPart的本地图片和附件复制到images目录，并改写链接
+// 指向其他.md文档的链接和zip里不存在的文件保持原样
+func RewriteAssets(markdown string, mdPart string, assets *convert_base.AssetWriter) (string, error) {
+	var copyErr error
+	result := convert_base.RewriteLinks(markdown, func(target string, isImage bool) (string, bool) {
+		local, ok := convert_base.LocalTarget(target)
+		if !ok || copyErr != nil || strings.EqualFold(path.Ext(local), ".md") {
+			return "", false
+		}
+		link, err := assets.CopyLink(path.Join(path.Dir(mdPart), local))
+		if err != nil {
+			copyErr = err
+			return "", false
+		}
+		return link, link != ""
+	})
+	return result, copyErr
+}
+
+// IsHiddenPart macOS压缩时带的__MACOSX目录以及.开头的隐藏文件
+func IsHiddenPart(name string) bool {
+	for _, part := range strings.Split(path.Clean(name), "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+// -------------------------------------------------
+// Package convert_md
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_md
+
+import (
+	"errors"
+	"gcnote/server/ability/convert/convert_base"
+	"gcnote/server/ability/convert/convert_fixture"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMdZipConvert(t *testing.T) {
+	note := "# 笔记\n\n" +
+		"![架构图](./img/arch.png \"总体架构\")\n\n" +
+		"见[附件](<files/设计 文档.pdf>)和[另一篇](other.md#top)，[官网](https://example.com/a.png)。\n\n" +
+		"<img src=\"img/arch.png\" width=\"300\">\n\n" +
+		"![丢失](img/missing.png)\n\n" +
+		"```markdown\n![代码块里的](img/arch.png)\n```\n\n" +
+		"[logo]: ../shared/logo.png\n"
+	dir := t.TempDir()
+	documentPath := filepath.Join(dir, "notes.zip")
+	convert_fixture.WriteZip(t, documentPath, map[string]string{
+		"notes/note.md":            note,
+		"notes/img/arch.png":       "arch",
+		"notes/files/设计 文档.pdf":    "pdf",
+		"shared/logo.png":          "logo",
+		"__MACOSX/notes/._note.md": "resource fork",
+	})
+	outputDir := filepath.Join(dir, "out")
+	if err := os.Mkdir(outputDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	mdPath, mdString, err := MdZipConvert(documentPath, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := func(name string) string { return filepath.Join("images", name) }
+	want := "# 笔记\n\n" +
+		"![架构图](" + img("arch.png") + " \"总体架构\")\n\n" +
+		"见[附件](" + img("设计_文档.pdf") + ")和[另一篇](other.md#top)，[官网](https://example.com/a.png)。\n\n" +
+		"<img src=\"" + img("arch.png") + "\" width=\"300\">\n\n" +
+		"![丢失](img/missing.png)\n\n" +
+		"```markdown\n![代码块里的](img/arch.png)\n```\n\n" +
+		"[logo]: " + img("logo.png") + "\n"
+	if mdString != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", mdString, want)
+	}
+	if filepath.Base(mdPath) != "notes.md" {
+		t.Errorf("unexpected md path %s", mdPath)
+	}
+	for _, name := range []string{"arch.png", "设计_文档.pdf", "logo.png"} {
+		if _, err = os.Stat(filepath.Join(outputDir, "images", name)); err != nil {
+			t.Errorf("asset %s not copied: %v", name, err)
+		}
+	}
+}
+
+func TestMdZipConvertMultipleDocs(t *testing.T) {
+	dir := t.TempDir()
+	documentPath := filepath.Join(dir, "many.zip")
+	convert_fixture.WriteZip(t, documentPath, map[string]string{"a.md": "a", "b.md": "b"})
+	_, _, err := MdZipConvert(documentPath, dir)
+	if !errors.Is(err, convert_base.ErrUnsupported) {
+		t.Errorf("got %v, want %v", err, convert_base.ErrUnsupported)
+	}
+}
@@ -0,0 +1,210 @@
+// -------------------------------------------------
+// Package convert_notion
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_notion
+
+import (
+	"bytes"
+	"encoding/csv"
+	"gcnote/server/ability/convert/convert_base"
+	"gcnote/server/config"
+	"github.com/zakahan/docx2md/docx_parser"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Notion导出的文件名和目录名后面带一个32位的页面id，数据库的完整视图还会再带_all
+var pageIdRe = regexp.MustCompile(`\s+[0-9a-fA-F]{32}(_all)?$`)
+
+// Page Notion导出的一个页面(.md)或数据库(.csv)
+type Page struct {
+	Path     string // 导出包内的路径，用/分隔
+	Title    string // 去掉页面id后的标题
+	Database bool   // 是否为数据库
+	Parent   *Page  // 上级页面，顶层页面为nil
+	Link     string // 导入后文档的链接地址，由调用方在转换前填好
+}
+
+// Export 解压后的Notion导出包（Markdown & CSV格式）
+type Export struct {
+	fsys   fs.FS
+	pages  []*Page
+	byPath map[string]*Page // 小写路径 => 页面，数据库的两个csv都指向同一个Page
+	byDir  map[string]*Page // 小写的子页面目录 => 页面
+}
+
+// NewExport files为导出包内所有文件用/分隔的相对路径
+func NewExport(fsys fs.FS, files []string) *Export {
+	e := &Export{fsys: fsys, byPath: map[string]*Page{}, byDir: map[string]*Page{}}
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+
+	fileSet := map[string]bool{}
+	for _, file := range sorted {
+		fileSet[strings.ToLower(file)] = true
+	}
+	for _, file := range sorted {
+		ext := strings.ToLower(path.Ext(file))
+		if ext != ".md" && ext != ".csv" {
+			continue
+		}
+		stem := strings.TrimSuffix(file, path.Ext(file))
+		dir := strings.TrimSuffix(stem, "_all")
+		if ext == ".csv" && !strings.HasSuffix(stem, "_all") && fileSet[strings.ToLower(stem+"_all.csv")] {
+			// 同时有 xxx.csv 和 xxx_all.csv 时只用包含全部行的_all
+			continue
+		}
+		page := &Page{Path: file, Title: PageTitle(path.Base(file)), Database: ext == ".csv"}
+		e.pages = append(e.pages, page)
+		e.byPath[strings.ToLower(file)] = page
+		e.byDir[strings.ToLower(dir)] = page
+		if page.Database && dir != stem {
+			e.byPath[strings.ToLower(dir+".csv")] = page
+		}
+	}
+	// 子页面放在和上级页面同名(不带后缀)的目录里
+	for _, page := range e.pages {
+		for dir := path.Dir(page.Path); dir != "."; dir = path.Dir(dir) {
+			if parent, ok := e.byDir[strings.ToLower(dir)]; ok && parent != page {
+				page.Parent = parent
+				break
+			}
+		}
+	}
+	// 上级页面排在前面，导入时可以先创建上级
+	sort.SliceStable(e.pages, func(i, j int) bool {
+		return e.depth(e.pages[i]) < e.depth(e.pages[j])
+	})
+	return e
+}
+
+// PageTitle 去掉后缀和页面id，如 "会议记录 0123456789abcdef0123456789abcdef.md" => "会议记录"
+func PageTitle(name string) string {
+	title := strings.TrimSuffix(name, path.Ext(name))
+	title = strings.TrimSpace(pageIdRe.ReplaceAllString(title, ""))
+	if title == "" {
+		return "Untitled"
+	}
+	return title
+}
+
+// Pages 所有页面和数据库，上级页面在前
+func (e *Export) Pages() []*Page {
+	return e.pages
+}
+
+// TitlePath 上级页面的标题路径，如 "项目/2024"，顶层页面为空字符串
+func (e *Export) TitlePath(page *Page) string {
+	var titles []string
+	for p := page.Parent; p != nil; p = p.Parent {
+		titles = append([]string{p.Title}, titles...)
+	}
+	return strings.Join(titles, "/")
+}
+
+func (e *Export) depth(page *Page) int {
+	depth := 0
+	for p := page.Parent; p != nil; p = p.Parent {
+		depth++
+	}
+	return depth
+}
+
+// Convert 转换一个页面：页面之间的链接改为Page.Link，图片和附件复制到images目录；数据库转为表格
+func (e *Export) Convert(page *Page, outputDir string) (string, string, error) {
+	mdPath, mdDirPath, err := docx_parser.CreateMdDir(page.Path, outputDir, path.Ext(page.Path))
+	if err != nil {
+		return "", "", err
+	}
+	imagesDir := filepath.Join(mdDirPath, convert_base.ImagesDirName)
+	err = os.MkdirAll(imagesDir, os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := fs.ReadFile(e.fsys, page.Path)
+	if err != nil {
+		return "", "", err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	err = convert_base.CheckText(page.Path, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	var mdString string
+	if page.Database {
+		mdString, err = e.databaseMarkdown(page, data)
+	} else {
+		mdString, err = e.pageMarkdown(page, string(data), convert_base.NewFSAssetWriter(e.fsys, imagesDir))
+	}
+	if err != nil {
+		return "", "", err
+	}
+	err = convert_base.SaveFile(mdPath, mdString)
+	if err != nil {
+		return "", "", err
+	}
+	return mdPath, mdString, nil
+}
+
+func (e *Export) pageMarkdown(page *Page, markdown string, assets *convert_base.AssetWriter) (string, error) {
+	var copyErr error
+	result := convert_base.RewriteLinks(markdown, func(target string, isImage bool) (string, bool) {
+		local, ok := convert_base.LocalTarget(target)
+		if !ok || copyErr != nil {
+			return "", false
+		}
+		part := path.Join(path.Dir(page.Path), local)
+		if linked, ok := e.byPath[strings.ToLower(part)]; ok {
+			if linked.Link == "" {
+				return "", false
+			}
+			return linked.Link, true
+		}
+		link, err := assets.CopyLink(part)
+		if err != nil {
+			copyErr = err
+			return "", false
+		}
+		return link, link != ""
+	})
+	return result, copyErr
+}
+
+// databaseMarkdown 数据库转为 一级标题 + 表格，第一列(标题列)链接到对应的行页面
+func (e *Export) databaseMarkdown(page *Page, data []byte) (string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return "", convert_base.NewError(convert_base.ErrCorrupt, page.Path, err)
+	}
+	rowPages := map[string]*Page{}
+	for _, child := range e.pages {
+		if child.Parent == page && !child.Database {
+			rowPages[child.Title] = child
+		}
+	}
+	for i := 1; i < len(rows); i++ {
+		if len(rows[i]) == 0 {
+			continue
+		}
+		if child, ok := rowPages[strings.TrimSpace(rows[i][0])]; ok && child.Link != "" {
+			rows[i][0] = "[" + strings.TrimSpace(rows[i][0]) + "](" + child.Link + ")"
+		}
+	}
+	opts := convert_base.SheetOptions{
+		MaxRows: config.ServerCfg.ConvertConf.SheetMaxRows,
+		Header:  convert_base.SheetHeaderFirstRow,
+	}
+	return convert_base.SheetMarkdown(page.Title, rows, opts) + "\n", nil
+}
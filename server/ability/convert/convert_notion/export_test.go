@@ -0,0 +1,116 @@
+// -------------------------------------------------
+// Package convert_notion
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_notion
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const (
+	rootId  = "0123456789abcdef0123456789abcdef"
+	childId = "11111111111111111111111111111111"
+	dbId    = "22222222222222222222222222222222"
+	rowId   = "33333333333333333333333333333333"
+)
+
+var exportFiles = fstest.MapFS{
+	"Export/项目 " + rootId + ".md": {Data: []byte("# 项目\n\n" +
+		"[计划](%E9%A1%B9%E7%9B%AE%20" + rootId + "/%E8%AE%A1%E5%88%92%20" + childId + ".md)\n\n" +
+		"[任务](%E9%A1%B9%E7%9B%AE%20" + rootId + "/%E4%BB%BB%E5%8A%A1%20" + dbId + ".csv)\n\n" +
+		"![](%E9%A1%B9%E7%9B%AE%20" + rootId + "/diagram.png)\n")},
+	"Export/项目 " + rootId + "/计划 " + childId + ".md":                {Data: []byte("# 计划\n")},
+	"Export/项目 " + rootId + "/diagram.png":                          {Data: []byte("\x89PNG")},
+	"Export/项目 " + rootId + "/任务 " + dbId + ".csv":                  {Data: []byte("Name,Status\n写文档,Done\n")},
+	"Export/项目 " + rootId + "/任务 " + dbId + "_all.csv":              {Data: []byte("\xef\xbb\xbfName,Status\n写文档,Done\n评审,Todo\n")},
+	"Export/项目 " + rootId + "/任务 " + dbId + "/写文档 " + rowId + ".md": {Data: []byte("# 写文档\n\nStatus: Done\n")},
+}
+
+func newTestExport() *Export {
+	var files []string
+	for name := range exportFiles {
+		files = append(files, name)
+	}
+	e := NewExport(exportFiles, files)
+	for _, page := range e.Pages() {
+		page.Link = "kbfile://idx/" + page.Title
+	}
+	return e
+}
+
+func findPage(e *Export, title string) *Page {
+	for _, page := range e.Pages() {
+		if page.Title == title {
+			return page
+		}
+	}
+	return nil
+}
+
+func TestExportHierarchy(t *testing.T) {
+	e := newTestExport()
+	if len(e.Pages()) != 4 {
+		t.Fatalf("expected 4 pages (the filtered csv is skipped), got %d", len(e.Pages()))
+	}
+	want := map[string]string{"项目": "", "计划": "项目", "任务": "项目", "写文档": "项目/任务"}
+	for title, parentPath := range want {
+		page := findPage(e, title)
+		if page == nil {
+			t.Fatalf("page %s not found", title)
+		}
+		if got := e.TitlePath(page); got != parentPath {
+			t.Errorf("%s: got parent path %q, want %q", title, got, parentPath)
+		}
+	}
+	if e.Pages()[0].Title != "项目" {
+		t.Errorf("parent pages should come first, got %s", e.Pages()[0].Title)
+	}
+}
+
+func TestExportConvert(t *testing.T) {
+	e := newTestExport()
+	outputDir := t.TempDir()
+	_, mdString, err := e.Convert(findPage(e, "项目"), outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# 项目\n\n[计划](kbfile://idx/计划)\n\n[任务](kbfile://idx/任务)\n\n" +
+		"![](" + filepath.Join("images", "diagram.png") + ")\n"
+	if mdString != want {
+		t.Errorf("got:\n%s\nwant:\n%s", mdString, want)
+	}
+	if _, err = os.Stat(filepath.Join(outputDir, "images", "diagram.png")); err != nil {
+		t.Errorf("image not copied: %v", err)
+	}
+
+	_, mdString, err = e.Convert(findPage(e, "任务"), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(mdString, "# 任务\n\n| Name | Status |") ||
+		!strings.Contains(mdString, "| [写文档](kbfile://idx/写文档) | Done |") ||
+		!strings.Contains(mdString, "| 评审 | Todo |") {
+		t.Errorf("unexpected database markdown:\n%s", mdString)
+	}
+}
+
+func TestPageTitle(t *testing.T) {
+	cases := map[string]string{
+		"会议记录 " + rootId + ".md":  "会议记录",
+		"任务 " + dbId + "_all.csv": "任务",
+		"no id.md":                "no id",
+		rootId + ".md":            rootId,
+	}
+	for name, want := range cases {
+		if got := PageTitle(name); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// -------------------------------------------------
+// Package convert_obsidian
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_obsidian
+
+import (
+	"gcnote/server/ability/convert/convert_base"
+	"regexp"
+	"strings"
+)
+
+var (
+	// 正文里的#tag：前面是行首或空白，标签里至少有一个非数字字符（#123不是标签），可以用/分层级
+	inlineTagRe = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/\-]*[\p{L}_/\-][\p{L}\p{N}_/\-]*)`)
+	// front matter里的 key: value
+	yamlKeyRe = regexp.MustCompile(`^([A-Za-z_][\w\-]*)\s*:\s*(.*)$`)
+)
+
+// SplitFrontMatter 拆出开头 --- 和 --- 之间的YAML front matter，没有时frontMatter为空
+func SplitFrontMatter(markdown string) (frontMatter string, body string) {
+	text := strings.TrimPrefix(markdown, "\uFEFF")
+	if !strings.HasPrefix(text, "---\n") && !strings.HasPrefix(text, "---\r\n") {
+		return "", markdown
+	}
+	lines := strings.SplitAfter(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "---" || line == "..." {
+			frontMatter = strings.Join(lines[1:i], "")
+			body = strings.TrimLeft(strings.Join(lines[i+1:], ""), "\r\n")
+			return frontMatter, body
+		}
+	}
+	// 没有结束的分隔线，不是front matter
+	return "", markdown
+}
+
+// FrontMatterTags 读取front matter中的tags（或tag），支持 [a, b]、a, b 和多行列表三种写法
+func FrontMatterTags(frontMatter string) []string {
+	var tags []string
+	inList := false
+	for _, line := range strings.Split(frontMatter, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if inList {
+			item := strings.TrimSpace(line)
+			if strings.HasPrefix(item, "- ") || item == "-" {
+				tags = appendTag(tags, strings.TrimPrefix(item, "-"))
+				continue
+			}
+			if item == "" || strings.HasPrefix(item, "#") {
+				continue
+			}
+			inList = false
+		}
+		m := yamlKeyRe.FindStringSubmatch(line)
+		if m == nil || (m[1] != "tags" && m[1] != "tag") {
+			continue
+		}
+		value := strings.TrimSpace(m[2])
+		switch {
+		case value == "":
+			inList = true
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				tags = appendTag(tags, item)
+			}
+		default:
+			for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+				tags = appendTag(tags, item)
+			}
+		}
+	}
+	return tags
+}
+
+// appendTag 去掉引号和开头的#，空标签忽略
+func appendTag(tags []string, tag string) []string {
+	tag = strings.Trim(strings.TrimSpace(tag), `"'`)
+	tag = strings.TrimSpace(strings.TrimPrefix(tag, "#"))
+	if tag == "" {
+		return tags
+	}
+	return append(tags, tag)
+}
+
+// InlineTags 正文中的#tag，代码块和行内代码里的不算
+func InlineTags(body string) []string {
+	var tags []string
+	inFence := false
+	for _, segment := range convert_base.SplitFences(body) {
+		if !inFence {
+			outsideInlineCode(segment, func(s string) string {
+				for _, m := range inlineTagRe.FindAllStringSubmatch(s, -1) {
+					tags = appendTag(tags, m[1])
+				}
+				return s
+			})
+		}
+		inFence = !inFence
+	}
+	return tags
+}
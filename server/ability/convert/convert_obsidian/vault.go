@@ -0,0 +1,283 @@
+// -------------------------------------------------
+// Package convert_obsidian
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_obsidian
+
+import (
+	"gcnote/server/ability/convert/convert_base"
+	"github.com/zakahan/docx2md/docx_parser"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// [[target#heading|alias]] 和 ![[embed]]
+	wikiLinkRe = regexp.MustCompile(`(!?)\[\[([^\[\]\n]+)\]\]`)
+	// 行内代码，里面的wikilink和#tag不处理
+	inlineCodeRe = regexp.MustCompile("`[^`\n]*`")
+	// ![[image.png|300]] 和 ![[image.png|300x200]] 里的尺寸
+	imageSizeRe = regexp.MustCompile(`^\d+(x\d+)?$`)
+)
+
+// Note 仓库中的一篇笔记
+type Note struct {
+	Path string // 仓库内的路径，用/分隔
+	Name string // 不带.md后缀的文件名，也是[[wikilink]]使用的名称
+	Link string // 导入后文档的链接地址，由调用方在转换前填好
+}
+
+// Vault 解压后的Obsidian仓库，.md是笔记，其他文件都当作附件
+type Vault struct {
+	fsys   fs.FS
+	notes  []*Note
+	byName map[string][]*Note  // 小写名称 => 笔记
+	files  map[string][]string // 小写文件名 => 附件路径
+}
+
+// NewVault files为仓库内所有文件用/分隔的相对路径（隐藏文件和.obsidian配置目录应已去掉）
+func NewVault(fsys fs.FS, files []string) *Vault {
+	v := &Vault{fsys: fsys, byName: map[string][]*Note{}, files: map[string][]string{}}
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+	for _, file := range sorted {
+		base := path.Base(file)
+		if strings.EqualFold(path.Ext(file), ".md") {
+			note := &Note{Path: file, Name: strings.TrimSuffix(base, path.Ext(base))}
+			v.notes = append(v.notes, note)
+			key := strings.ToLower(note.Name)
+			v.byName[key] = append(v.byName[key], note)
+		} else {
+			key := strings.ToLower(base)
+			v.files[key] = append(v.files[key], file)
+		}
+	}
+	return v
+}
+
+// Notes 按路径排序的所有笔记
+func (v *Vault) Notes() []*Note {
+	return v.notes
+}
+
+// ResolveNote 按Obsidian的规则解析链接指向的笔记：只写名称时在整个仓库里按名称查找，
+// 带目录时按路径后缀匹配；有多个候选时优先同目录，其次路径最短的。找不到返回nil
+func (v *Vault) ResolveNote(target string, from string) *Note {
+	target = strings.TrimSuffix(path.Clean(strings.ReplaceAll(target, "\\", "/")), ".md")
+	var paths []string
+	notes := map[string]*Note{}
+	for _, note := range v.byName[strings.ToLower(path.Base(target))] {
+		paths = append(paths, note.Path)
+		notes[note.Path] = note
+	}
+	best := pickPath(paths, strings.TrimPrefix(target, "/")+".md", from)
+	if best == "" {
+		return nil
+	}
+	return notes[best]
+}
+
+// ResolveFile 解析链接指向的附件，规则同ResolveNote，返回附件在仓库内的路径，找不到返回空字符串
+func (v *Vault) ResolveFile(target string, from string) string {
+	target = path.Clean(strings.ReplaceAll(target, "\\", "/"))
+	return pickPath(v.files[strings.ToLower(path.Base(target))], strings.TrimPrefix(target, "/"), from)
+}
+
+// pickPath 在同名的候选路径中选出链接指向的那个
+func pickPath(candidates []string, target string, from string) string {
+	lowerTarget := strings.ToLower(target)
+	fromDir := path.Dir(from)
+	var matched []string
+	for _, candidate := range candidates {
+		lower := strings.ToLower(candidate)
+		// 相对于当前笔记的路径，或者仓库内的路径（仓库外面可能还包了一层目录）
+		if strings.ToLower(path.Join(fromDir, target)) == lower ||
+			lower == lowerTarget || strings.HasSuffix(lower, "/"+lowerTarget) {
+			matched = append(matched, candidate)
+		}
+	}
+	if len(matched) == 0 {
+		return ""
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		si, sj := path.Dir(matched[i]) == fromDir, path.Dir(matched[j]) == fromDir
+		if si != sj {
+			return si
+		}
+		return len(matched[i]) < len(matched[j])
+	})
+	return matched[0]
+}
+
+// Tags 笔记的标签，包括front matter里的tags和正文里的#tag，去掉#，不区分大小写去重
+func (v *Vault) Tags(note *Note) ([]string, error) {
+	data, err := fs.ReadFile(v.fsys, note.Path)
+	if err != nil {
+		return nil, err
+	}
+	frontMatter, body := SplitFrontMatter(string(data))
+	tags := append(FrontMatterTags(frontMatter), InlineTags(body)...)
+	seen := map[string]bool{}
+	var result []string
+	for _, tag := range tags {
+		if key := strings.ToLower(tag); !seen[key] {
+			seen[key] = true
+			result = append(result, tag)
+		}
+	}
+	return result, nil
+}
+
+// Convert 转换一篇笔记：去掉front matter，wikilink改写为普通markdown链接，
+// 指向笔记的链接使用Note.Link，嵌入和引用的附件复制到images目录
+func (v *Vault) Convert(note *Note, outputDir string) (string, string, error) {
+	mdPath, mdDirPath, err := docx_parser.CreateMdDir(note.Path, outputDir, path.Ext(note.Path))
+	if err != nil {
+		return "", "", err
+	}
+	imagesDir := filepath.Join(mdDirPath, convert_base.ImagesDirName)
+	err = os.MkdirAll(imagesDir, os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := fs.ReadFile(v.fsys, note.Path)
+	if err != nil {
+		return "", "", err
+	}
+	err = convert_base.CheckText(note.Path, data)
+	if err != nil {
+		return "", "", err
+	}
+	_, body := SplitFrontMatter(string(data))
+
+	c := &noteConverter{vault: v, note: note, assets: convert_base.NewFSAssetWriter(v.fsys, imagesDir)}
+	// 先处理普通的markdown链接，wikilink转换出来的链接不需要再处理
+	body = convert_base.RewriteLinks(body, c.rewriteLink)
+	var builder strings.Builder
+	inFence := false
+	for _, segment := range convert_base.SplitFences(body) {
+		if inFence {
+			builder.WriteString(segment)
+		} else {
+			builder.WriteString(outsideInlineCode(segment, func(s string) string {
+				return wikiLinkRe.ReplaceAllStringFunc(s, c.replaceWikiLink)
+			}))
+		}
+		inFence = !inFence
+	}
+	if c.err != nil {
+		return "", "", c.err
+	}
+
+	mdString := builder.String()
+	err = convert_base.SaveFile(mdPath, mdString)
+	if err != nil {
+		return "", "", err
+	}
+	return mdPath, mdString, nil
+}
+
+// noteConverter 转换一篇笔记时的状态，复制附件出错时记录第一个错误
+type noteConverter struct {
+	vault  *Vault
+	note   *Note
+	assets *convert_base.AssetWriter
+	err    error
+}
+
+// rewriteLink 普通的markdown链接：指向笔记的改为Note.Link，指向附件的复制到images目录
+func (c *noteConverter) rewriteLink(target string, isImage bool) (string, bool) {
+	local, ok := convert_base.LocalTarget(target)
+	if !ok || c.err != nil {
+		return "", false
+	}
+	if strings.EqualFold(path.Ext(local), ".md") {
+		if linked := c.vault.ResolveNote(local, c.note.Path); linked != nil && linked.Link != "" {
+			return linked.Link, true
+		}
+		return "", false
+	}
+	return c.copyFile(local)
+}
+
+// replaceWikiLink 转换一个[[wikilink]]或![[embed]]，找不到目标时只保留显示的文字
+func (c *noteConverter) replaceWikiLink(m string) string {
+	parts := wikiLinkRe.FindStringSubmatch(m)
+	embed := parts[1] == "!"
+	target, alias, _ := strings.Cut(parts[2], "|")
+	target, heading, _ := strings.Cut(target, "#")
+	target, alias = strings.TrimSpace(target), strings.TrimSpace(alias)
+	heading = strings.TrimPrefix(strings.TrimSpace(heading), "^")
+
+	display := alias
+	if display == "" || embed && imageSizeRe.MatchString(display) {
+		switch {
+		case target == "":
+			display = heading
+		case heading != "":
+			display = target + " > " + heading
+		default:
+			display = target
+		}
+	}
+	if target == "" {
+		// [[#标题]] 指向笔记自身
+		return display
+	}
+
+	// 没有后缀或者后缀是.md的先按笔记解析，笔记名里可能有点号，找不到再按附件解析
+	ext := strings.ToLower(path.Ext(target))
+	if ext == "" || ext == ".md" || len(c.vault.byName[strings.ToLower(path.Base(target))]) > 0 {
+		if linked := c.vault.ResolveNote(target, c.note.Path); linked != nil {
+			if linked.Link == "" {
+				return display
+			}
+			return "[" + display + "](" + linked.Link + ")"
+		}
+	}
+	link, ok := c.copyFile(target)
+	if !ok {
+		return display
+	}
+	if embed && convert_base.IsImagePath(target) {
+		if alias == "" || imageSizeRe.MatchString(alias) {
+			display = path.Base(target)
+		}
+		return "![" + display + "](" + link + ")"
+	}
+	return "[" + display + "](" + link + ")"
+}
+
+// copyFile 把链接指向的附件复制到images目录，返回新的链接地址
+func (c *noteConverter) copyFile(target string) (string, bool) {
+	file := c.vault.ResolveFile(target, c.note.Path)
+	if file == "" || c.err != nil {
+		return "", false
+	}
+	link, err := c.assets.CopyLink(file)
+	if err != nil {
+		c.err = err
+		return "", false
+	}
+	return link, link != ""
+}
+
+// outsideInlineCode 只对行内代码以外的部分执行replace
+func outsideInlineCode(s string, replace func(string) string) string {
+	var builder strings.Builder
+	last := 0
+	for _, loc := range inlineCodeRe.FindAllStringIndex(s, -1) {
+		builder.WriteString(replace(s[last:loc[0]]))
+		builder.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	builder.WriteString(replace(s[last:]))
+	return builder.String()
+}
@@ -0,0 +1,126 @@
+// -------------------------------------------------
+// Package convert_obsidian
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_obsidian
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+var vaultFiles = fstest.MapFS{
+	"MyVault/Home.md": {Data: []byte(`---
+title: 首页
+tags: [project, "#Work"]
+---
+# 首页
+
+见 [[Plan]] 和 [[sub/Plan|子计划]]，以及 [[Plan#目标]]、[[Missing note]]。
+
+![[diagram.png|300]]
+![[report.pdf]]
+[旧链接](sub/Plan.md) ![截图](attachments/shot%201.png)
+
+正文标签 #idea 和 #work，#123 不是标签。
+
+` + "```" + `
+[[Plan]] #code
+` + "```" + `
+行内 ` + "`[[Plan]]`" + `
+`)},
+	"MyVault/Plan.md":                 {Data: []byte("计划\n")},
+	"MyVault/sub/Plan.md":             {Data: []byte("子计划\n")},
+	"MyVault/attachments/diagram.png": {Data: []byte("\x89PNG diagram")},
+	"MyVault/attachments/shot 1.png":  {Data: []byte("\x89PNG shot")},
+	"MyVault/files/report.pdf":        {Data: []byte("%PDF report")},
+}
+
+func newTestVault() *Vault {
+	var files []string
+	for name := range vaultFiles {
+		files = append(files, name)
+	}
+	v := NewVault(vaultFiles, files)
+	for _, note := range v.Notes() {
+		note.Link = "kbfile://idx/" + note.Name
+		if note.Path == "MyVault/sub/Plan.md" {
+			note.Link = "kbfile://idx/sub-plan"
+		}
+	}
+	return v
+}
+
+func TestVaultConvert(t *testing.T) {
+	v := newTestVault()
+	home := v.ResolveNote("Home", "")
+	if home == nil {
+		t.Fatal("Home note not found")
+	}
+	outputDir := t.TempDir()
+	_, mdString, err := v.Convert(home, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images := func(name string) string {
+		return filepath.Join("images", name)
+	}
+	want := "# 首页\n\n" +
+		"见 [Plan](kbfile://idx/Plan) 和 [子计划](kbfile://idx/sub-plan)，以及 [Plan > 目标](kbfile://idx/Plan)、Missing note。\n\n" +
+		"![diagram.png](" + images("diagram.png") + ")\n" +
+		"[report.pdf](" + images("report.pdf") + ")\n" +
+		"[旧链接](kbfile://idx/sub-plan) ![截图](" + images("shot_1.png") + ")\n\n" +
+		"正文标签 #idea 和 #work，#123 不是标签。\n\n" +
+		"```\n[[Plan]] #code\n```\n" +
+		"行内 `[[Plan]]`\n"
+	if mdString != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", mdString, want)
+	}
+	for _, name := range []string{"diagram.png", "report.pdf", "shot_1.png"} {
+		if _, err = os.Stat(filepath.Join(outputDir, "images", name)); err != nil {
+			t.Errorf("attachment %s not copied: %v", name, err)
+		}
+	}
+}
+
+func TestVaultTags(t *testing.T) {
+	v := newTestVault()
+	tags, err := v.Tags(v.ResolveNote("Home", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"project", "Work", "idea"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("got %v, want %v", tags, want)
+	}
+}
+
+func TestFrontMatterTags(t *testing.T) {
+	cases := map[string][]string{
+		"tags: a, b":                         {"a", "b"},
+		"tags: a b":                          {"a", "b"},
+		"tag: [x]":                           {"x"},
+		"tags:\n  - one\n  - \"#two\"\nk: v": {"one", "two"},
+		"title: no tags":                     nil,
+	}
+	for frontMatter, want := range cases {
+		if got := FrontMatterTags(frontMatter); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %v, want %v", frontMatter, got, want)
+		}
+	}
+}
+
+func TestResolveNotePrefersSameFolder(t *testing.T) {
+	v := newTestVault()
+	if note := v.ResolveNote("Plan", "MyVault/sub/Other.md"); note == nil || note.Path != "MyVault/sub/Plan.md" {
+		t.Errorf("expected sub/Plan.md, got %+v", note)
+	}
+	if note := v.ResolveNote("Plan", "MyVault/Home.md"); note == nil || note.Path != "MyVault/Plan.md" {
+		t.Errorf("expected Plan.md, got %+v", note)
+	}
+}
@@ -7,48 +7,27 @@
 package convert_pdf
 
 import (
-	"encoding/json"
-	"fmt"
+	"gcnote/server/ability/convert/convert_base"
 	"gcnote/server/config"
 	"os"
-	"os/exec"
-	"path/filepath"
 )
 
-type PythonOutput struct {
-	Success bool   `json:"success"`
-	MdPath  string `json:"md_path"`
-	MdDir   string `json:"md_dir"`
-	Error   string `json:"error,omitempty"`
-}
-
+// PdfConvert 默认使用纯Go的文本提取，配置 convert.pdf_backend = python 时使用python的高精度转换(支持图片和表格)
 func PdfConvert(documentPath string, outputDir string) (string, string, error) {
-	// Python 脚本路径
-	pythonScript := filepath.Join(config.PathCfg.BaseProjectPath, "component", "pdf_convert_ability", "main.py")
-
-	// 构建命令 与python环境参数
-	pythonExe := os.Getenv("GC_NOTE_PYTHON_EXE")
-	cmd := exec.Command(pythonExe, pythonScript, "--pdf_path", documentPath, "--output_dir", outputDir)
+	return PdfConvertWithProgress(documentPath, outputDir, nil)
+}
 
-	// 获取标准输出
-	output, err := cmd.Output()
+// PdfConvertWithProgress 同PdfConvert，每转换完一页调用一次progress；python后端不上报进度
+func PdfConvertWithProgress(documentPath string, outputDir string, progress convert_base.Progress) (string, string, error) {
+	info, err := os.Stat(documentPath)
 	if err != nil {
-		fmt.Printf("Error executing script: %s\n", err)
 		return "", "", err
 	}
-
-	// 解析 JSON 输出
-	var result PythonOutput
-	err = json.Unmarshal(output, &result)
-	if err != nil {
-		fmt.Printf("Error parsing JSON: %s\n", err)
-		return "", "", err
+	if info.Size() > convert_base.MaxSourceSize {
+		return "", "", convert_base.NewError(convert_base.ErrTooLarge, documentPath, nil)
 	}
-
-	// 判断执行结果
-	if result.Success {
-		return result.MdPath, result.MdDir, nil
-	} else {
-		return "", "", fmt.Errorf(result.Error)
+	if config.ServerCfg.ConvertConf.PdfBackend == "python" {
+		return PythonConvert(documentPath, outputDir)
 	}
+	return nativeConvert(documentPath, outputDir, progress)
 }
@@ -0,0 +1,216 @@
+// -------------------------------------------------
+// Package convert_pdf
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_pdf
+
+import (
+	"errors"
+	"fmt"
+	"gcnote/server/ability/convert/convert_base"
+	"github.com/ledongthuc/pdf"
+	"github.com/zakahan/docx2md/docx_parser"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// pdfLine 同一行的文字，fontSize取该行最小的字号（和docx2md一样按最小的来）
+type pdfLine struct {
+	text     string
+	fontSize float64
+	y        float64
+}
+
+// NativeConvert 纯Go实现的pdf转换，只提取文本层，按字号识别标题
+func NativeConvert(documentPath string, outputDir string) (string, string, error) {
+	return nativeConvert(documentPath, outputDir, nil)
+}
+
+func nativeConvert(documentPath string, outputDir string, progress convert_base.Progress) (mdPath string, mdString string, err error) {
+	mdPath, mdDirPath, err := docx_parser.CreateMdDir(documentPath, outputDir, ".pdf")
+	if err != nil {
+		return "", "", err
+	}
+	err = os.MkdirAll(filepath.Join(mdDirPath, convert_base.ImagesDirName), os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	file, err := os.Open(documentPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	info, err := file.Stat()
+	if err != nil {
+		return "", "", err
+	}
+
+	// pdf库遇到格式错误的文件会直接panic
+	defer func() {
+		if r := recover(); r != nil {
+			mdPath, mdString = "", ""
+			err = convert_base.NewError(convert_base.ErrCorrupt, documentPath, fmt.Errorf("%v", r))
+		}
+	}()
+
+	reader, err := pdf.NewReader(file, info.Size())
+	if errors.Is(err, pdf.ErrInvalidPassword) {
+		return "", "", convert_base.NewError(convert_base.ErrUnsupported, documentPath, errors.New("encrypted pdf"))
+	} else if err != nil {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+	}
+
+	var pages []string
+	numPage := reader.NumPage()
+	for i := 1; i <= numPage; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			progress.Report(i, numPage)
+			continue
+		}
+		lines := groupLines(page.Content().Text)
+		if pageStr := linesToMarkdown(lines); pageStr != "" {
+			pages = append(pages, pageStr)
+		}
+		progress.Report(i, numPage)
+	}
+	if len(pages) == 0 {
+		// 扫描件之类没有文本层的pdf，需要python后端
+		return "", "", convert_base.NewError(convert_base.ErrUnsupported, documentPath,
+			errors.New("no text layer found, try the python pdf backend"))
+	}
+
+	mdString = strings.Join(pages, "\n\n") + "\n"
+	err = convert_base.SaveFile(mdPath, mdString)
+	if err != nil {
+		return "", "", err
+	}
+	return mdPath, mdString, nil
+}
+
+// groupLines 按内容流的顺序把单个字符合并为行，y坐标变化超过半个字号视为换行
+func groupLines(texts []pdf.Text) []pdfLine {
+	var lines []pdfLine
+	var builder strings.Builder
+	var current pdfLine
+	var lastEnd float64
+	flush := func() {
+		current.text = strings.TrimSpace(builder.String())
+		if current.text != "" {
+			lines = append(lines, current)
+		}
+		builder.Reset()
+	}
+	for i, t := range texts {
+		if t.S == "" || t.FontSize <= 0 {
+			continue
+		}
+		if i == 0 || builder.Len() == 0 {
+			current = pdfLine{fontSize: t.FontSize, y: t.Y}
+		} else if math.Abs(t.Y-current.y) > math.Max(t.FontSize, current.fontSize)/2 {
+			flush()
+			current = pdfLine{fontSize: t.FontSize, y: t.Y}
+		} else {
+			// 同一行里x方向出现明显的空隙，补一个空格
+			if t.X-lastEnd > t.FontSize*0.3 && !strings.HasSuffix(builder.String(), " ") && t.S != " " {
+				builder.WriteString(" ")
+			}
+			current.fontSize = math.Min(current.fontSize, t.FontSize)
+		}
+		builder.WriteString(t.S)
+		lastEnd = t.X + t.W
+	}
+	flush()
+	return lines
+}
+
+// linesToMarkdown 相邻且字号相同、行距正常的行合并为段落，再按字号转为标题
+func linesToMarkdown(lines []pdfLine) string {
+	var paragraphs []string
+	var builder strings.Builder
+	var fontSize float64
+	flush := func() {
+		if builder.Len() > 0 {
+			paragraphs = append(paragraphs, word2Heading(builder.String(), fontSize))
+			builder.Reset()
+		}
+	}
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			gap := math.Abs(prev.y - line.y)
+			if math.Abs(prev.fontSize-line.fontSize) > 0.5 || gap > line.fontSize*1.8 {
+				flush()
+			}
+		}
+		if builder.Len() == 0 {
+			fontSize = line.fontSize
+		} else {
+			builder.WriteString(lineJoiner(builder.String(), line.text))
+		}
+		builder.WriteString(line.text)
+	}
+	flush()
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// lineJoiner 中文换行直接拼接，英文之间补空格
+func lineJoiner(prev, next string) string {
+	last, _ := lastRune(prev)
+	first := []rune(next)[0]
+	if isCJK(last) || isCJK(first) {
+		return ""
+	}
+	return " "
+}
+
+func lastRune(s string) (rune, bool) {
+	r := []rune(s)
+	if len(r) == 0 {
+		return 0, false
+	}
+	return r[len(r)-1], true
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) || unicode.IsPunct(r) && r > 0x2e80
+}
+
+// word2Heading 与python版本(pdf_parser/utils.py)的规则保持一致，pdf字号单位是pt，换算成docx的半磅
+func word2Heading(text string, fontSize float64) string {
+	size := fontSize * 2
+	maxHeadingLength := 15
+	h1, h2, h3, h4 := 48.0, 36.0, 28.0, 24.0
+	length := len([]rune(text))
+	if docx_parser.CheckString(text) {
+		switch {
+		case h1 <= size:
+			return "# " + text
+		case h2 <= size:
+			return "## " + text
+		case h3 <= size:
+			return "### " + text
+		case h4 <= size && length < maxHeadingLength:
+			return "#### " + text
+		}
+	} else {
+		switch {
+		case h1 <= size:
+			return "# " + text
+		case h2 <= size:
+			return "## " + text
+		case h3 <= size && length < maxHeadingLength:
+			return "### " + text
+		}
+	}
+	return text
+}
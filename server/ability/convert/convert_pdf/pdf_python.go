@@ -0,0 +1,67 @@
+// -------------------------------------------------
+// Package convert_pdf
+// Author: hanzhi
+// Date: 2025/1/8
+// -------------------------------------------------
+
+package convert_pdf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gcnote/server/ability/convert/convert_base"
+	"gcnote/server/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+type PythonOutput struct {
+	Success bool   `json:"success"`
+	MdPath  string `json:"md_path"`
+	MdDir   string `json:"md_dir"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PythonConvert 调用component/pdf_convert_ability里的python脚本转换，返回值与其他转换器一致：mdPath, mdString, err
+func PythonConvert(documentPath string, outputDir string) (string, string, error) {
+	// Python 脚本路径
+	pythonScript := filepath.Join(config.PathCfg.BaseProjectPath, "component", "pdf_convert_ability", "main.py")
+
+	// 构建命令 与python环境参数
+	pythonExe := config.ServerCfg.ConvertConf.PythonExe
+	if pythonExe == "" {
+		pythonExe = os.Getenv("GC_NOTE_PYTHON_EXE")
+	}
+	if pythonExe == "" {
+		return "", "", errors.New("python executable is not configured, set convert.python_exe or GC_NOTE_PYTHON_EXE")
+	}
+	cmd := exec.Command(pythonExe, pythonScript, "--pdf_path", documentPath, "--output_dir", outputDir)
+
+	// 获取标准输出，脚本失败时也会在标准输出里写入json，所以先不处理退出码
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return "", "", fmt.Errorf("execute pdf convert script: %w", err)
+	}
+
+	// 解析 JSON 输出
+	var result PythonOutput
+	if jsonErr := json.Unmarshal(output, &result); jsonErr != nil {
+		if err != nil {
+			return "", "", fmt.Errorf("execute pdf convert script: %w", err)
+		}
+		return "", "", fmt.Errorf("parse pdf convert output: %w", jsonErr)
+	}
+
+	// 判断执行结果
+	if !result.Success {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, errors.New(result.Error))
+	}
+	content, err := os.ReadFile(result.MdPath)
+	if err != nil {
+		return "", "", err
+	}
+	return result.MdPath, string(content), nil
+}
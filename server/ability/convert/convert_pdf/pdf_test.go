@@ -0,0 +1,94 @@
+// -------------------------------------------------
+// Package convert_pdf
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gcnote/server/ability/convert/convert_base"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildPdf 生成一个只有一页、使用Helvetica字体的最小pdf
+func buildPdf(content string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestNativeConvert(t *testing.T) {
+	dir := t.TempDir()
+	documentPath := filepath.Join(dir, "report.pdf")
+	content := "BT /F1 24 Tf 72 720 Td (Introduction) Tj ET\n" +
+		"BT /F1 11 Tf 72 690 Td (Hello world, this is) Tj 0 -13 Td (a paragraph.) Tj ET"
+	if err := os.WriteFile(documentPath, buildPdf(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mdPath, mdString, err := NativeConvert(documentPath, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Introduction\n\nHello world, this is a paragraph.\n"
+	if mdString != want {
+		t.Fatalf("got %q, want %q", mdString, want)
+	}
+	saved, err := os.ReadFile(mdPath)
+	if err != nil || string(saved) != mdString {
+		t.Fatalf("saved markdown mismatch: %q, %v", saved, err)
+	}
+}
+
+func TestNativeConvertCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	documentPath := filepath.Join(dir, "broken.pdf")
+	if err := os.WriteFile(documentPath, []byte("%PDF-1.4\ngarbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := NativeConvert(documentPath, dir)
+	if !errors.Is(err, convert_base.ErrCorrupt) {
+		t.Fatalf("got err %v, want %v", err, convert_base.ErrCorrupt)
+	}
+}
+
+func TestPdfConvertProgress(t *testing.T) {
+	dir := t.TempDir()
+	documentPath := filepath.Join(dir, "progress.pdf")
+	if err := os.WriteFile(documentPath, buildPdf("BT /F1 11 Tf 72 720 Td (One page) Tj ET"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var reports [][2]int
+	_, _, err := PdfConvertWithProgress(documentPath, dir, func(done int, total int) {
+		reports = append(reports, [2]int{done, total})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0] != [2]int{1, 1} {
+		t.Errorf("unexpected progress %v", reports)
+	}
+}
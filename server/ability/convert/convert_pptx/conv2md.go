@@ -0,0 +1,306 @@
+// -------------------------------------------------
+// Package convert_pptx
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_pptx
+
+import (
+	"archive/zip"
+	"fmt"
+	"gcnote/server/ability/convert/convert_base"
+	"github.com/zakahan/docx2md/docx_parser"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const presentationPart = "ppt/presentation.xml"
+
+// PptxConvert 将pptx转为markdown，每页幻灯片一节：标题作为一级标题，正文列表、表格、图片依次输出，演讲者备注用引用块
+func PptxConvert(documentPath string, outputDir string) (string, string, error) {
+	mdPath, mdDirPath, err := docx_parser.CreateMdDir(documentPath, outputDir, ".pptx")
+	if err != nil {
+		return "", "", err
+	}
+	imagesDir := filepath.Join(mdDirPath, convert_base.ImagesDirName)
+	err = os.MkdirAll(imagesDir, os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	r, err := convert_base.OpenZip(documentPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer func(r *zip.ReadCloser) {
+		_ = r.Close()
+	}(r)
+
+	slideParts, err := readSlideParts(&r.Reader)
+	if err != nil {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+	}
+
+	var sections []string
+	for i, slidePart := range slideParts {
+		section, err := convertSlide(&r.Reader, slidePart, i+1, imagesDir)
+		if err != nil {
+			return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+		}
+		sections = append(sections, section)
+	}
+
+	mdString := strings.Join(sections, "\n\n") + "\n"
+	err = convert_base.SaveFile(mdPath, mdString)
+	if err != nil {
+		return "", "", err
+	}
+	return mdPath, mdString, nil
+}
+
+// readSlideParts 按presentation.xml中sldIdLst的顺序返回幻灯片在zip中的路径
+func readSlideParts(r *zip.Reader) ([]string, error) {
+	data, err := convert_base.ReadZipFile(r, presentationPart)
+	if err != nil {
+		return nil, err
+	}
+	root, err := convert_base.ParseXml(data)
+	if err != nil {
+		return nil, err
+	}
+	rels, err := convert_base.ReadRelationships(r, presentationPart)
+	if err != nil {
+		return nil, err
+	}
+	var parts []string
+	sldIdLst := root.Child("sldIdLst")
+	if sldIdLst == nil {
+		return parts, nil
+	}
+	for _, sldId := range sldIdLst.Children("sldId") {
+		rel, ok := rels[sldId.AttrNS(convert_base.RelationshipNS, "id")]
+		if !ok {
+			return nil, fmt.Errorf("slide relationship %s not found", sldId.AttrNS(convert_base.RelationshipNS, "id"))
+		}
+		parts = append(parts, rel.Target)
+	}
+	return parts, nil
+}
+
+// slideWriter 收集一页幻灯片的内容
+type slideWriter struct {
+	r         *zip.Reader
+	rels      map[string]convert_base.Relationship
+	imagesDir string
+	title     string
+	blocks    []string
+}
+
+func convertSlide(r *zip.Reader, slidePart string, slideNum int, imagesDir string) (string, error) {
+	data, err := convert_base.ReadZipFile(r, slidePart)
+	if err != nil {
+		return "", err
+	}
+	root, err := convert_base.ParseXml(data)
+	if err != nil {
+		return "", err
+	}
+	rels, err := convert_base.ReadRelationships(r, slidePart)
+	if err != nil {
+		return "", err
+	}
+	w := &slideWriter{r: r, rels: rels, imagesDir: imagesDir}
+	if spTree := root.Path("cSld", "spTree"); spTree != nil {
+		if err = w.walkShapes(spTree); err != nil {
+			return "", err
+		}
+	}
+
+	title := w.title
+	if title == "" {
+		title = fmt.Sprintf("幻灯片 %d", slideNum)
+	}
+	blocks := append([]string{"# " + title}, w.blocks...)
+
+	// 演讲者备注
+	for _, rel := range rels {
+		if strings.HasSuffix(rel.Type, "/notesSlide") {
+			notes, err := readNotes(r, rel.Target)
+			if err != nil {
+				return "", err
+			}
+			if notes != "" {
+				blocks = append(blocks, notes)
+			}
+		}
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// walkShapes 按顺序处理形状树，组合形状递归处理
+func (w *slideWriter) walkShapes(tree *convert_base.XmlNode) error {
+	for i := range tree.Nodes {
+		node := &tree.Nodes[i]
+		switch node.Name() {
+		case "sp":
+			w.writeShape(node)
+		case "pic":
+			if err := w.writePicture(node); err != nil {
+				return err
+			}
+		case "graphicFrame":
+			if tbl := node.FindAll("tbl"); len(tbl) > 0 {
+				w.blocks = append(w.blocks, tableMarkdown(tbl[0]))
+			}
+		case "grpSp":
+			if err := w.walkShapes(node); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *slideWriter) writeShape(sp *convert_base.XmlNode) {
+	txBody := sp.Child("txBody")
+	if txBody == nil {
+		return
+	}
+	ph := sp.Path("nvSpPr", "nvPr", "ph")
+	phType := ""
+	if ph != nil {
+		phType = ph.Attr("type")
+	}
+	if (phType == "title" || phType == "ctrTitle") && w.title == "" {
+		w.title = strings.Join(paragraphTexts(txBody), " ")
+		return
+	}
+	// 正文占位符默认带项目符号，普通文本框只有显式设置了项目符号才当作列表
+	defaultBullet := ph != nil && (phType == "" || phType == "body" || phType == "obj")
+
+	var lines []string
+	number := 0
+	for _, p := range txBody.Children("p") {
+		text := paragraphText(p)
+		if text == "" {
+			continue
+		}
+		pPr := p.Child("pPr")
+		level := 0
+		bullet := defaultBullet
+		numbered := false
+		if pPr != nil {
+			level, _ = strconv.Atoi(pPr.Attr("lvl"))
+			switch {
+			case pPr.Child("buNone") != nil:
+				bullet = false
+			case pPr.Child("buAutoNum") != nil:
+				bullet, numbered = true, true
+			case pPr.Child("buChar") != nil:
+				bullet = true
+			}
+		}
+		indent := strings.Repeat("  ", level)
+		switch {
+		case numbered:
+			number++
+			lines = append(lines, fmt.Sprintf("%s%d. %s", indent, number, text))
+		case bullet:
+			lines = append(lines, indent+"- "+text)
+		default:
+			lines = append(lines, text)
+		}
+	}
+	if len(lines) > 0 {
+		w.blocks = append(w.blocks, strings.Join(lines, "\n"))
+	}
+}
+
+func (w *slideWriter) writePicture(pic *convert_base.XmlNode) error {
+	blips := pic.FindAll("blip")
+	if len(blips) == 0 {
+		return nil
+	}
+	rel, ok := w.rels[blips[0].AttrNS(convert_base.RelationshipNS, "embed")]
+	if !ok || convert_base.FindZipFile(w.r, rel.Target) == nil {
+		return nil
+	}
+	imageName := path.Base(rel.Target)
+	err := convert_base.ExtractZipFile(w.r, rel.Target, filepath.Join(w.imagesDir, imageName))
+	if err != nil {
+		return err
+	}
+	w.blocks = append(w.blocks, "!["+imageName+"]("+convert_base.ImageLink(imageName)+")")
+	return nil
+}
+
+// readNotes 读取备注页正文占位符里的文字，转为引用块
+func readNotes(r *zip.Reader, notesPart string) (string, error) {
+	data, err := convert_base.ReadZipFile(r, notesPart)
+	if err != nil {
+		return "", err
+	}
+	root, err := convert_base.ParseXml(data)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, sp := range root.FindAll("sp") {
+		ph := sp.Path("nvSpPr", "nvPr", "ph")
+		if ph == nil || ph.Attr("type") != "body" || sp.Child("txBody") == nil {
+			continue
+		}
+		for _, text := range paragraphTexts(sp.Child("txBody")) {
+			lines = append(lines, "> "+text)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func tableMarkdown(tbl *convert_base.XmlNode) string {
+	var rows [][]string
+	for _, tr := range tbl.Children("tr") {
+		var row []string
+		for _, tc := range tr.Children("tc") {
+			cell := ""
+			if txBody := tc.Child("txBody"); txBody != nil {
+				cell = strings.Join(paragraphTexts(txBody), " ")
+			}
+			row = append(row, cell)
+		}
+		rows = append(rows, row)
+	}
+	return strings.TrimSuffix(convert_base.MarkdownTable(rows), "\n")
+}
+
+// paragraphTexts 文本框中所有非空段落的文字
+func paragraphTexts(txBody *convert_base.XmlNode) []string {
+	var texts []string
+	for _, p := range txBody.Children("p") {
+		if text := paragraphText(p); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// paragraphText 段落 a:p 的文字，a:r 和 a:fld 里的 a:t，a:br 换为空格
+func paragraphText(p *convert_base.XmlNode) string {
+	var builder strings.Builder
+	for i := range p.Nodes {
+		node := &p.Nodes[i]
+		switch node.Name() {
+		case "r", "fld":
+			if t := node.Child("t"); t != nil {
+				builder.WriteString(t.Text)
+			}
+		case "br":
+			builder.WriteString(" ")
+		}
+	}
+	return strings.TrimSpace(builder.String())
+}
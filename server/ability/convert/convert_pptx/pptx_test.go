@@ -0,0 +1,82 @@
+// -------------------------------------------------
+// Package convert_pptx
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_pptx
+
+import (
+	"gcnote/server/ability/convert/convert_fixture"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	nsP   = `xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"`
+	nsA   = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"`
+	nsR   = `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	nsRel = `xmlns="http://schemas.openxmlformats.org/package/2006/relationships"`
+)
+
+var pptxParts = map[string]string{
+	"ppt/presentation.xml": `<p:presentation ` + nsP + ` ` + nsR + `><p:sldIdLst>
+<p:sldId id="257" r:id="rId3"/><p:sldId id="256" r:id="rId2"/></p:sldIdLst></p:presentation>`,
+	"ppt/_rels/presentation.xml.rels": `<Relationships ` + nsRel + `>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide1.xml"/>
+<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide2.xml"/>
+</Relationships>`,
+	"ppt/slides/slide1.xml": `<p:sld ` + nsP + ` ` + nsA + ` ` + nsR + `><p:cSld><p:spTree>
+<p:sp><p:nvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>季度总结</a:t></a:r></a:p></p:txBody></p:sp>
+<p:sp><p:nvSpPr><p:nvPr><p:ph idx="1"/></p:nvPr></p:nvSpPr><p:txBody>
+<a:p><a:r><a:t>营收增长</a:t></a:r></a:p>
+<a:p><a:pPr lvl="1"/><a:r><a:t>线上 </a:t></a:r><a:r><a:t>渠道</a:t></a:r></a:p>
+</p:txBody></p:sp>
+<p:graphicFrame><a:graphic><a:graphicData><a:tbl>
+<a:tr><a:tc><a:txBody><a:p><a:r><a:t>地区</a:t></a:r></a:p></a:txBody></a:tc><a:tc><a:txBody><a:p><a:r><a:t>金额</a:t></a:r></a:p></a:txBody></a:tc></a:tr>
+<a:tr><a:tc><a:txBody><a:p><a:r><a:t>华东</a:t></a:r></a:p></a:txBody></a:tc><a:tc><a:txBody><a:p><a:r><a:t>120</a:t></a:r></a:p></a:txBody></a:tc></a:tr>
+</a:tbl></a:graphicData></a:graphic></p:graphicFrame>
+<p:pic><p:blipFill><a:blip r:embed="rId2"/></p:blipFill></p:pic>
+</p:spTree></p:cSld></p:sld>`,
+	"ppt/slides/_rels/slide1.xml.rels": `<Relationships ` + nsRel + `>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="../media/image1.png"/>
+<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide" Target="../notesSlides/notesSlide1.xml"/>
+</Relationships>`,
+	"ppt/notesSlides/notesSlide1.xml": `<p:notes ` + nsP + ` ` + nsA + `><p:cSld><p:spTree>
+<p:sp><p:nvSpPr><p:nvPr><p:ph type="body" idx="1"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>强调线上渠道</a:t></a:r></a:p></p:txBody></p:sp>
+</p:spTree></p:cSld></p:notes>`,
+	"ppt/slides/slide2.xml": `<p:sld ` + nsP + ` ` + nsA + `><p:cSld><p:spTree>
+<p:sp><p:nvSpPr><p:nvPr/></p:nvSpPr><p:txBody><a:p><a:r><a:t>封面</a:t></a:r></a:p></p:txBody></p:sp>
+</p:spTree></p:cSld></p:sld>`,
+	"ppt/media/image1.png": "\x89PNG fake image",
+}
+
+func TestPptxConvert(t *testing.T) {
+	dir := t.TempDir()
+	documentPath := filepath.Join(dir, "deck.pptx")
+	convert_fixture.WriteZip(t, documentPath, pptxParts)
+	outputDir := filepath.Join(dir, "out")
+	if err := os.Mkdir(outputDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	mdPath, mdString, err := PptxConvert(documentPath, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# 幻灯片 1\n\n封面\n\n" +
+		"# 季度总结\n\n- 营收增长\n  - 线上 渠道\n\n" +
+		"| 地区 | 金额 |\n| --- | --- |\n| 华东 | 120 |\n\n" +
+		"![image1.png](" + filepath.Join("images", "image1.png") + ")\n\n" +
+		"> 强调线上渠道\n"
+	if mdString != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", mdString, want)
+	}
+	if filepath.Base(mdPath) != "deck.md" {
+		t.Errorf("unexpected md path %s", mdPath)
+	}
+	if _, err = os.Stat(filepath.Join(outputDir, "images", "image1.png")); err != nil {
+		t.Errorf("slide image not extracted: %v", err)
+	}
+}
@@ -7,9 +7,17 @@
 package convert
 
 import (
+	"errors"
 	"fmt"
+	"gcnote/server/ability/convert/convert_base"
 	"gcnote/server/config"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -23,3 +31,104 @@ func TestAutoConvert(t *testing.T) {
 	}
 	fmt.Println(s)
 }
+
+func TestAutoConvertMalformed(t *testing.T) {
+	cases := []struct {
+		name   string
+		suffix string
+		data   []byte
+		want   error
+	}{
+		{"gbk.html", ".html", []byte{0x3c, 0x70, 0x3e, 0xc4, 0xe3, 0xba, 0xc3, 0x3c, 0x2f, 0x70, 0x3e}, convert_base.ErrEncoding},
+		{"binary.md", ".md", []byte{0x89, 'P', 'N', 'G', 0x00, 0x00, 0x1a}, convert_base.ErrCorrupt},
+		{"bad.txt", ".txt", []byte{'a', 0xff, 0xfe, 'b'}, convert_base.ErrEncoding},
+		{"broken.docx", ".docx", []byte("not a zip archive"), convert_base.ErrCorrupt},
+		{"broken.ipynb", ".ipynb", []byte(`{"cells": [`), convert_base.ErrCorrupt},
+		{"binary.go", ".go", []byte{'p', 0x00, 0x01, 0x02}, convert_base.ErrCorrupt},
+		{"image.bmp", ".bmp", []byte("BM"), convert_base.ErrUnsupported},
+	}
+	for _, c := range cases {
+		dir := t.TempDir()
+		documentPath := filepath.Join(dir, c.name)
+		if err := os.WriteFile(documentPath, c.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		outputDir := filepath.Join(dir, "out")
+		if err := os.Mkdir(outputDir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		_, _, err := AutoConvert(documentPath, outputDir, c.suffix)
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: got err %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestAutoConvertTooLarge(t *testing.T) {
+	maxSize := convert_base.MaxSourceSize
+	convert_base.MaxSourceSize = 8
+	defer func() { convert_base.MaxSourceSize = maxSize }()
+
+	dir := t.TempDir()
+	documentPath := filepath.Join(dir, "large.txt")
+	if err := os.WriteFile(documentPath, []byte("more than eight bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := AutoConvert(documentPath, dir, ".txt")
+	if !errors.Is(err, convert_base.ErrTooLarge) {
+		t.Fatalf("got err %v, want %v", err, convert_base.ErrTooLarge)
+	}
+	if reason := convert_base.Reason(err); reason != "convert file error: file too large: large.txt" {
+		t.Errorf("unexpected reason %q", reason)
+	}
+}
+
+func TestAutoConvertEncoding(t *testing.T) {
+	cases := []struct {
+		name     string
+		text     string
+		enc      encoding.Encoding
+		encoding string
+	}{
+		{"utf8.txt", "你好，世界\n", nil, convert_base.EncodingUTF8},
+		{"utf8bom.txt", "\ufeff你好，世界\n", nil, convert_base.EncodingUTF8},
+		{"gbk.txt", "你好，世界\n第二行：数据库连接失败。\n", simplifiedchinese.GBK, convert_base.EncodingGBK},
+		{"gb18030.txt", "生僻字：𪚥\n", simplifiedchinese.GB18030, convert_base.EncodingGB18030},
+		{"big5.txt", "你好，世界\n資料庫連線失敗。\n", traditionalchinese.Big5, convert_base.EncodingBig5},
+		{"utf16.txt", "你好，世界\n", unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), convert_base.EncodingUTF16LE},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data := []byte(c.text)
+			if c.enc != nil {
+				var err error
+				if data, err = c.enc.NewEncoder().Bytes(data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dir := t.TempDir()
+			documentPath := filepath.Join(dir, c.name)
+			if err := os.WriteFile(documentPath, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			outputDir := filepath.Join(dir, "out")
+			if err := os.Mkdir(outputDir, os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			mdPath, mdString, encoding, err := AutoConvertWithProgress(documentPath, outputDir, ".txt", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.TrimPrefix(c.text, "\ufeff")
+			if mdString != want {
+				t.Errorf("got %q, want %q", mdString, want)
+			}
+			if saved, _ := os.ReadFile(mdPath); string(saved) != want {
+				t.Errorf("saved markdown %q, want %q", saved, want)
+			}
+			if encoding != c.encoding {
+				t.Errorf("got encoding %q, want %q", encoding, c.encoding)
+			}
+		})
+	}
+}
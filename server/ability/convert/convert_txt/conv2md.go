@@ -7,40 +7,32 @@
 package convert_txt
 
 import (
-	"fmt"
+	"gcnote/server/ability/convert/convert_base"
 	"github.com/zakahan/docx2md/docx_parser"
-	"go.uber.org/zap"
-	"io"
-	"log"
-	"os"
 )
 
 func TxtConvert(documentPath string, outputDir string) (string, string, error) {
+	mdPath, mdString, _, err := TxtConvertWithEncoding(documentPath, outputDir)
+	return mdPath, mdString, err
+}
+
+// TxtConvertWithEncoding 同TxtConvert，同时返回识别出的源编码
+func TxtConvertWithEncoding(documentPath string, outputDir string) (string, string, string, error) {
 	mdPath, _, err := docx_parser.CreateMdDir(documentPath, outputDir, ".txt")
 	if err != nil {
-		fmt.Println("Error:", err)
-		return "", "", err
+		return "", "", "", err
 	}
-	// 打开文件
-	file, err := os.Open(documentPath)
+	// 读取文件
+	data, err := convert_base.ReadSource(documentPath)
 	if err != nil {
-		log.Fatalf("无法打开文件：%v", err)
-		return "", "", err
+		return "", "", "", err
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			zap.S().Errorf("无法关闭文件：%v", err)
-		}
-	}(file)
-	// 读取文件
-	data, err := io.ReadAll(file)
+	// GBK、Big5等编码的文件先转为UTF-8
+	inputString, encoding, err := convert_base.DecodeText(documentPath, data)
 	if err != nil {
-		log.Fatalf("读取文件时发生错误")
-		return "", "", err
+		return "", "", "", err
 	}
-	inputString := string(data)
 	// 保存
-	err = docx_parser.SaveFile(mdPath, inputString)
-	return mdPath, inputString, err
+	err = convert_base.SaveFile(mdPath, inputString)
+	return mdPath, inputString, encoding, err
 }
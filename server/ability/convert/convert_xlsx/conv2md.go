@@ -0,0 +1,219 @@
+// -------------------------------------------------
+// Package convert_xlsx
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_xlsx
+
+import (
+	"archive/zip"
+	"fmt"
+	"gcnote/server/ability/convert/convert_base"
+	"gcnote/server/config"
+	"github.com/zakahan/docx2md/docx_parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	workbookPart      = "xl/workbook.xml"
+	sharedStringsPart = "xl/sharedStrings.xml"
+)
+
+// XlsxConvert 将xlsx的每个sheet转为 一级标题 + markdown表格
+func XlsxConvert(documentPath string, outputDir string) (string, string, error) {
+	mdPath, mdDirPath, err := docx_parser.CreateMdDir(documentPath, outputDir, ".xlsx")
+	if err != nil {
+		return "", "", err
+	}
+	err = os.MkdirAll(filepath.Join(mdDirPath, convert_base.ImagesDirName), os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	r, err := convert_base.OpenZip(documentPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer func(r *zip.ReadCloser) {
+		_ = r.Close()
+	}(r)
+
+	sheets, err := ReadSheets(&r.Reader)
+	if err != nil {
+		return "", "", convert_base.NewError(convert_base.ErrCorrupt, documentPath, err)
+	}
+	opts := convert_base.SheetOptions{
+		MaxRows: config.ServerCfg.ConvertConf.SheetMaxRows,
+		Header:  config.ServerCfg.ConvertConf.SheetHeader,
+	}
+	var sections []string
+	for _, sheet := range sheets {
+		sections = append(sections, convert_base.SheetMarkdown(sheet.Name, sheet.Rows, opts))
+	}
+
+	mdString := strings.Join(sections, "\n\n") + "\n"
+	err = convert_base.SaveFile(mdPath, mdString)
+	if err != nil {
+		return "", "", err
+	}
+	return mdPath, mdString, nil
+}
+
+// Sheet 一个工作表的名称和单元格文本
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// ReadSheets 按workbook.xml中的顺序读取所有工作表
+func ReadSheets(r *zip.Reader) ([]Sheet, error) {
+	data, err := convert_base.ReadZipFile(r, workbookPart)
+	if err != nil {
+		return nil, err
+	}
+	workbook, err := convert_base.ParseXml(data)
+	if err != nil {
+		return nil, err
+	}
+	rels, err := convert_base.ReadRelationships(r, workbookPart)
+	if err != nil {
+		return nil, err
+	}
+	sharedStrings, err := readSharedStrings(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var sheets []Sheet
+	sheetsNode := workbook.Child("sheets")
+	if sheetsNode == nil {
+		return sheets, nil
+	}
+	for _, node := range sheetsNode.Children("sheet") {
+		relId := node.AttrNS(convert_base.RelationshipNS, "id")
+		rel, ok := rels[relId]
+		if !ok {
+			return nil, fmt.Errorf("sheet relationship %s not found", relId)
+		}
+		rows, err := readWorksheet(r, rel.Target, sharedStrings)
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, Sheet{Name: node.Attr("name"), Rows: rows})
+	}
+	return sheets, nil
+}
+
+// readSharedStrings 共享字符串表，单元格t="s"时v是这里的下标
+func readSharedStrings(r *zip.Reader) ([]string, error) {
+	if convert_base.FindZipFile(r, sharedStringsPart) == nil {
+		return nil, nil
+	}
+	data, err := convert_base.ReadZipFile(r, sharedStringsPart)
+	if err != nil {
+		return nil, err
+	}
+	sst, err := convert_base.ParseXml(data)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, si := range sst.Children("si") {
+		result = append(result, richText(si))
+	}
+	return result, nil
+}
+
+// richText 文本可能是 <t> 也可能是多段 <r><t>，忽略拼音注释 <rPh>
+func richText(node *convert_base.XmlNode) string {
+	if t := node.Child("t"); t != nil {
+		return t.Text
+	}
+	var builder strings.Builder
+	for _, run := range node.Children("r") {
+		if t := run.Child("t"); t != nil {
+			builder.WriteString(t.Text)
+		}
+	}
+	return builder.String()
+}
+
+func readWorksheet(r *zip.Reader, part string, sharedStrings []string) ([][]string, error) {
+	data, err := convert_base.ReadZipFile(r, part)
+	if err != nil {
+		return nil, err
+	}
+	worksheet, err := convert_base.ParseXml(data)
+	if err != nil {
+		return nil, err
+	}
+	sheetData := worksheet.Child("sheetData")
+	if sheetData == nil {
+		return nil, nil
+	}
+
+	var rows [][]string
+	for _, rowNode := range sheetData.Children("row") {
+		// 行号从1开始，中间跳过的空行补齐
+		rowNum := len(rows) + 1
+		fmt.Sscanf(rowNode.Attr("r"), "%d", &rowNum)
+		for len(rows) < rowNum-1 {
+			rows = append(rows, nil)
+		}
+		var row []string
+		for _, c := range rowNode.Children("c") {
+			col := len(row)
+			if ref := c.Attr("r"); ref != "" {
+				col = columnIndex(ref)
+			}
+			for len(row) < col {
+				row = append(row, "")
+			}
+			row = append(row, cellValue(c, sharedStrings))
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func cellValue(c *convert_base.XmlNode, sharedStrings []string) string {
+	v := ""
+	if vNode := c.Child("v"); vNode != nil {
+		v = vNode.Text
+	}
+	switch c.Attr("t") {
+	case "s":
+		var idx int
+		if _, err := fmt.Sscanf(v, "%d", &idx); err == nil && idx >= 0 && idx < len(sharedStrings) {
+			return sharedStrings[idx]
+		}
+		return ""
+	case "inlineStr":
+		if is := c.Child("is"); is != nil {
+			return richText(is)
+		}
+		return ""
+	case "b":
+		if v == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return v
+	}
+}
+
+// columnIndex 单元格引用的列号，从0开始，如 A1 => 0, AB12 => 27
+func columnIndex(ref string) int {
+	col := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+	}
+	return col - 1
+}
@@ -0,0 +1,72 @@
+// -------------------------------------------------
+// Package convert_xlsx
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package convert_xlsx
+
+import (
+	"gcnote/server/ability/convert/convert_fixture"
+	"gcnote/server/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	nsMain = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+	nsR    = `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	nsRel  = `xmlns="http://schemas.openxmlformats.org/package/2006/relationships"`
+)
+
+var xlsxParts = map[string]string{
+	"xl/workbook.xml": `<workbook ` + nsMain + ` ` + nsR + `><sheets>
+<sheet name="销售" sheetId="1" r:id="rId1"/><sheet name="备注" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+	"xl/_rels/workbook.xml.rels": `<Relationships ` + nsRel + `>
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>
+</Relationships>`,
+	"xl/sharedStrings.xml": `<sst ` + nsMain + `><si><t>地区</t></si><si><t>金额</t></si><si><r><t>华</t></r><r><t>东</t></r></si></sst>`,
+	"xl/worksheets/sheet1.xml": `<worksheet ` + nsMain + `><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>达标</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>120.5</v></c><c r="C2" t="b"><v>1</v></c></row>
+<row r="4"><c r="B4"><v>7</v></c></row>
+</sheetData></worksheet>`,
+	"xl/worksheets/sheet2.xml": `<worksheet ` + nsMain + `><sheetData>
+<row r="1"><c r="A1" t="str"><v>数据来自|财务</v></c></row>
+</sheetData></worksheet>`,
+}
+
+func TestXlsxConvert(t *testing.T) {
+	config.ServerCfg.ConvertConf.SheetHeader = "auto"
+	dir := t.TempDir()
+	documentPath := filepath.Join(dir, "report.xlsx")
+	convert_fixture.WriteZip(t, documentPath, xlsxParts)
+	outputDir := filepath.Join(dir, "out")
+	if err := os.Mkdir(outputDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	mdPath, mdString, err := XlsxConvert(documentPath, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# 销售\n\n| 地区 | 金额 | 达标 |\n| --- | --- | --- |\n| 华东 | 120.5 | TRUE |\n|  |  |  |\n|  | 7 |  |\n\n" +
+		"# 备注\n\n| 列1 |\n| --- |\n| 数据来自\\|财务 |\n"
+	if mdString != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", mdString, want)
+	}
+	if filepath.Base(mdPath) != "report.md" {
+		t.Errorf("unexpected md path %s", mdPath)
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	cases := map[string]int{"A1": 0, "C7": 2, "Z3": 25, "AA1": 26, "AB12": 27}
+	for ref, want := range cases {
+		if got := columnIndex(ref); got != want {
+			t.Errorf("columnIndex(%s) = %d, want %d", ref, got, want)
+		}
+	}
+}
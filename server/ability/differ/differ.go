@@ -0,0 +1,407 @@
+// -------------------------------------------------
+// Package differ
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package differ
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Op 一段内容的变化
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Segment 行内按词对比的一段
+type Segment struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Line hunk中的一行
+type Line struct {
+	Op    Op        `json:"op"`
+	Text  string    `json:"text"`             // 不含换行符
+	OldNo int       `json:"old_no,omitempty"` // 在旧内容中的行号，从1开始，新增的行没有
+	NewNo int       `json:"new_no,omitempty"` // 在新内容中的行号，从1开始，删除的行没有
+	Words []Segment `json:"words,omitempty"`  // 修改过的行按词对比的结果，删除的行不含Insert，新增的行不含Delete
+}
+
+// Hunk 一处修改和它前后的上下文，行号从1开始，和unified diff一致
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Lines    []Line `json:"lines"`
+}
+
+// Result 两段文本的对比结果
+type Result struct {
+	Hunks   []Hunk `json:"hunks"`
+	Added   int    `json:"added"`   // 新增的行数
+	Deleted int    `json:"deleted"` // 删除的行数
+}
+
+// maxEditDistance 编辑距离超过这个值时不再找最短的编辑序列，剩下的部分整体按删除+新增处理，
+// 避免两个完全不同的大文件占用太多内存
+const maxEditDistance = 2000
+
+// Diff 按行对比两段文本，context为每处修改前后保留的相同行数；修改过的行再按词对比
+func Diff(old string, new string, context int) *Result {
+	if context < 0 {
+		context = 0
+	}
+	oldLines, newLines := splitLines(old), splitLines(new)
+	ops := diffStrings(oldLines, newLines)
+
+	// 按编辑序列生成所有行，再截取修改附近的部分
+	lines := make([]Line, 0, len(ops))
+	result := &Result{}
+	i, j := 0, 0
+	for _, op := range ops {
+		switch op {
+		case Equal:
+			lines = append(lines, Line{Op: Equal, Text: oldLines[i], OldNo: i + 1, NewNo: j + 1})
+			i++
+			j++
+		case Delete:
+			lines = append(lines, Line{Op: Delete, Text: oldLines[i], OldNo: i + 1})
+			result.Deleted++
+			i++
+		case Insert:
+			lines = append(lines, Line{Op: Insert, Text: newLines[j], NewNo: j + 1})
+			result.Added++
+			j++
+		}
+	}
+	result.Hunks = buildHunks(lines, context)
+	for h := range result.Hunks {
+		diffWords(result.Hunks[h].Lines)
+	}
+	return result
+}
+
+// splitLines 按换行符分割，结尾的换行符不产生空行
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// buildHunks 把相隔不超过2*context行的修改合并为一个hunk
+func buildHunks(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	start, end := -1, -1 // 当前hunk在lines中的范围[start, end)
+	flush := func() {
+		if start < 0 {
+			return
+		}
+		hunk := Hunk{Lines: lines[start:end]}
+		for _, line := range hunk.Lines {
+			if line.Op != Insert {
+				hunk.OldLines++
+				if hunk.OldStart == 0 {
+					hunk.OldStart = line.OldNo
+				}
+			}
+			if line.Op != Delete {
+				hunk.NewLines++
+				if hunk.NewStart == 0 {
+					hunk.NewStart = line.NewNo
+				}
+			}
+		}
+		// 没有行时，起始行号为前一行的行号，和unified diff一致
+		if hunk.OldLines == 0 {
+			hunk.OldStart = lineNoBefore(lines, start, func(l Line) int { return l.OldNo })
+		}
+		if hunk.NewLines == 0 {
+			hunk.NewStart = lineNoBefore(lines, start, func(l Line) int { return l.NewNo })
+		}
+		hunks = append(hunks, hunk)
+		start, end = -1, -1
+	}
+	for i, line := range lines {
+		if line.Op == Equal {
+			continue
+		}
+		from := max(i-context, 0)
+		if start >= 0 && from > end {
+			flush()
+		}
+		if start < 0 {
+			start = from
+		}
+		end = min(i+context+1, len(lines))
+	}
+	flush()
+	return hunks
+}
+
+// lineNoBefore lines[i]之前最近一行的行号，没有时为0
+func lineNoBefore(lines []Line, i int, no func(Line) int) int {
+	for i--; i >= 0; i-- {
+		if n := no(lines[i]); n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// diffWords 连续删除的行后面紧跟着连续新增的行时，按顺序两两配对，按词对比
+func diffWords(lines []Line) {
+	for i := 0; i < len(lines); {
+		if lines[i].Op != Delete {
+			i++
+			continue
+		}
+		delStart := i
+		for i < len(lines) && lines[i].Op == Delete {
+			i++
+		}
+		insStart := i
+		for i < len(lines) && lines[i].Op == Insert {
+			i++
+		}
+		pairs := min(insStart-delStart, i-insStart)
+		for p := 0; p < pairs; p++ {
+			del, ins := &lines[delStart+p], &lines[insStart+p]
+			for _, segment := range WordDiff(del.Text, ins.Text) {
+				if segment.Op != Insert {
+					del.Words = appendSegment(del.Words, segment)
+				}
+				if segment.Op != Delete {
+					ins.Words = appendSegment(ins.Words, segment)
+				}
+			}
+		}
+	}
+}
+
+// WordDiff 按词对比两行文本，中日韩文字没有分隔符，按单个字对比
+func WordDiff(old string, new string) []Segment {
+	oldTokens, newTokens := Tokenize(old), Tokenize(new)
+	var segments []Segment
+	i, j := 0, 0
+	for _, op := range diffStrings(oldTokens, newTokens) {
+		switch op {
+		case Equal:
+			segments = appendSegment(segments, Segment{Op: Equal, Text: oldTokens[i]})
+			i++
+			j++
+		case Delete:
+			segments = appendSegment(segments, Segment{Op: Delete, Text: oldTokens[i]})
+			i++
+		case Insert:
+			segments = appendSegment(segments, Segment{Op: Insert, Text: newTokens[j]})
+			j++
+		}
+	}
+	return segments
+}
+
+// appendSegment 和前一段的Op相同时合并
+func appendSegment(segments []Segment, segment Segment) []Segment {
+	if n := len(segments); n > 0 && segments[n-1].Op == segment.Op {
+		segments[n-1].Text += segment.Text
+		return segments
+	}
+	return append(segments, segment)
+}
+
+// 分词时字符的类型
+const (
+	classWord = iota
+	classSpace
+	classCJK
+	classPunct
+)
+
+func runeClass(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return classCJK
+	case unicode.IsSpace(r):
+		return classSpace
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+		return classWord
+	}
+	return classPunct
+}
+
+// Tokenize 把一行文本分为词：连续的字母数字、连续的空白各为一个词，中日韩文字和标点每个字符一个词
+func Tokenize(text string) []string {
+	var tokens []string
+	start, last := 0, -1
+	for i, r := range text {
+		class := runeClass(r)
+		if i > start && (class != last || class == classCJK || class == classPunct) {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		last = class
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// diffStrings 两组字符串的编辑序列，先去掉相同的开头和结尾再用Myers算法
+func diffStrings(a []string, b []string) []Op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	// 字符串转为编号，比较更快
+	ids := map[string]int{}
+	toIds := func(items []string) []int {
+		out := make([]int, len(items))
+		for i, item := range items {
+			id, ok := ids[item]
+			if !ok {
+				id = len(ids)
+				ids[item] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	middle, ok := myers(toIds(middleA), toIds(middleB))
+	if !ok {
+		middle = make([]Op, 0, len(middleA)+len(middleB))
+		for range middleA {
+			middle = append(middle, Delete)
+		}
+		for range middleB {
+			middle = append(middle, Insert)
+		}
+	}
+
+	ops := make([]Op, 0, prefix+len(middle)+suffix)
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, Equal)
+	}
+	ops = append(ops, middle...)
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, Equal)
+	}
+	return ops
+}
+
+// myers 最短编辑序列，编辑距离超过maxEditDistance时返回false
+func myers(a []int, b []int) ([]Op, bool) {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxEditDistance)
+	offset := limit + 1
+	v := make([]int, 2*limit+3) // v[offset+k]: 对角线k上走得最远的x
+	var trace [][]int           // trace[d]: 第d步结束时对角线-d..d上的x
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // 从k+1向下走一步：新增
+			} else {
+				x = v[offset+k-1] + 1 // 从k-1向右走一步：删除
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, d, n, m), true
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+	return nil, false
+}
+
+// backtrack 从终点沿trace倒推出编辑序列
+func backtrack(trace [][]int, d int, n int, m int) []Op {
+	ops := make([]Op, 0, n+m)
+	x, y := n, m
+	for ; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, Equal)
+			x--
+			y--
+		}
+		if prevK == k+1 {
+			ops = append(ops, Insert)
+		} else {
+			ops = append(ops, Delete)
+		}
+		x, y = prevX, prevY
+	}
+	for ; x > 0; x-- {
+		ops = append(ops, Equal)
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// Unified 生成unified diff格式的文本
+func Unified(oldName string, newName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(hunk.OldStart, hunk.OldLines), hunkRange(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			switch line.Op {
+			case Equal:
+				sb.WriteByte(' ')
+			case Delete:
+				sb.WriteByte('-')
+			case Insert:
+				sb.WriteByte('+')
+			}
+			sb.WriteString(line.Text)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// hunkRange 行数为1时省略行数
+func hunkRange(start int, lines int) string {
+	if lines == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
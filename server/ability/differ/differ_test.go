@@ -0,0 +1,159 @@
+// -------------------------------------------------
+// Package differ
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package differ
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// apply 按编辑序列由a得到b，用来验证编辑序列
+func apply(a []string, b []string, ops []Op) []string {
+	var out []string
+	i, j := 0, 0
+	for _, op := range ops {
+		switch op {
+		case Equal:
+			out = append(out, a[i])
+			i++
+			j++
+		case Delete:
+			i++
+		case Insert:
+			out = append(out, b[j])
+			j++
+		}
+	}
+	return out
+}
+
+func TestDiffStrings(t *testing.T) {
+	cases := []struct {
+		a, b  string
+		edits int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"abcabba", "cbabac", 5},
+		{"xaxbxc", "abc", 3},
+	}
+	for _, c := range cases {
+		a, b := strings.Split(c.a, ""), strings.Split(c.b, "")
+		ops := diffStrings(a, b)
+		if got := apply(a, b, ops); strings.Join(got, "") != c.b {
+			t.Errorf("%q -> %q: applied %q", c.a, c.b, strings.Join(got, ""))
+		}
+		edits := 0
+		for _, op := range ops {
+			if op != Equal {
+				edits++
+			}
+		}
+		if edits != c.edits {
+			t.Errorf("%q -> %q: %d edits, want %d", c.a, c.b, edits, c.edits)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Hello, world 你好世界 go1.23")
+	want := []string{"Hello", ",", " ", "world", " ", "你", "好", "世", "界", " ", "go1", ".", "23"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestWordDiff(t *testing.T) {
+	got := WordDiff("今天天气很好", "今天天气不好")
+	want := []Segment{{Equal, "今天天气"}, {Delete, "很"}, {Insert, "不"}, {Equal, "好"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v", got)
+	}
+	got = WordDiff("the quick fox", "the slow fox")
+	want = []Segment{{Equal, "the "}, {Delete, "quick"}, {Insert, "slow"}, {Equal, " fox"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := "# 标题\na\nb\nc\nd\ne\nf\ng\nh\n"
+	new := "# 标题\na\nB\nc\nd\ne\nf\ng\nh\ni\n"
+	result := Diff(old, new, 1)
+	if result.Added != 2 || result.Deleted != 1 || len(result.Hunks) != 2 {
+		t.Fatalf("got %+v", result)
+	}
+	changed := result.Hunks[0].Lines[2]
+	if changed.Op != Insert || changed.NewNo != 3 || !reflect.DeepEqual(changed.Words, []Segment{{Insert, "B"}}) {
+		t.Errorf("changed line %+v", changed)
+	}
+	want := `--- a.md
++++ b.md
+@@ -2,3 +2,3 @@
+ a
+-b
++B
+ c
+@@ -9 +9,2 @@
+ h
++i
+`
+	if got := Unified("a.md", "b.md", result.Hunks); got != want {
+		t.Errorf("got:\n%s", got)
+	}
+
+	// 空文件
+	if got := Unified("a", "b", Diff("", "x\n", 3).Hunks); got != "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n" {
+		t.Errorf("got:\n%s", got)
+	}
+	if result = Diff(old, old, 3); len(result.Hunks) != 0 {
+		t.Errorf("expected no hunks, got %+v", result.Hunks)
+	}
+}
+
+func TestDiffLimit(t *testing.T) {
+	// 编辑距离超过限制时整体替换，结果仍然正确
+	var a, b []string
+	for i := 0; i < maxEditDistance; i++ {
+		a = append(a, "a")
+		b = append(b, "b")
+	}
+	ops := diffStrings(a, b)
+	if got := apply(a, b, ops); !reflect.DeepEqual(got, b) {
+		t.Error("applied result differs")
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	cases := []struct {
+		ours, theirs, merged string
+		conflicts            int
+	}{
+		// 修改不同的行
+		{"A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", 0},
+		// 两边改成一样
+		{"a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", 0},
+		// 一边删除、一边新增
+		{"a\nc\nd\ne\n", "a\nb\nc\nd\ne\nf\n", "a\nc\nd\ne\nf\n", 0},
+		// 修改同一行
+		{"a\nb1\nc\nd\ne\n", "a\nb2\nc\nd\ne\n",
+			"a\n" + ConflictOurs + "\nb1\n" + ConflictSep + "\nb2\n" + ConflictTheirs + "\nc\nd\ne\n", 1},
+		// 相邻的行也算冲突
+		{"a\nB\nc\nd\ne\n", "a\nb\nC\nd\ne\n",
+			"a\n" + ConflictOurs + "\nB\nc\n" + ConflictSep + "\nb\nC\n" + ConflictTheirs + "\nd\ne\n", 1},
+	}
+	for _, c := range cases {
+		merged, conflicts := Merge3(base, c.ours, c.theirs)
+		if merged != c.merged || conflicts != c.conflicts {
+			t.Errorf("ours %q theirs %q: got %q, %d conflicts", c.ours, c.theirs, merged, conflicts)
+		}
+	}
+}
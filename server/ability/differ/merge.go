@@ -0,0 +1,131 @@
+// -------------------------------------------------
+// Package differ
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package differ
+
+import (
+	"slices"
+	"strings"
+)
+
+// 冲突标记，和git一致
+const (
+	ConflictOurs   = "<<<<<<< 你的修改"
+	ConflictSep    = "======="
+	ConflictTheirs = ">>>>>>> 当前内容"
+)
+
+// change base[start:end)被替换为lines
+type change struct {
+	start, end int
+	lines      []string
+}
+
+// changes 由base到other按行的修改
+func changes(base []string, other []string) []change {
+	var result []change
+	var cur *change
+	i, j := 0, 0
+	for _, op := range diffStrings(base, other) {
+		if op == Equal {
+			if cur != nil {
+				result = append(result, *cur)
+				cur = nil
+			}
+			i++
+			j++
+			continue
+		}
+		if cur == nil {
+			cur = &change{start: i, end: i}
+		}
+		if op == Delete {
+			i++
+			cur.end = i
+		} else {
+			cur.lines = append(cur.lines, other[j])
+			j++
+		}
+	}
+	if cur != nil {
+		result = append(result, *cur)
+	}
+	return result
+}
+
+// applyChanges 把落在base[start:end)内的修改应用到这一段上
+func applyChanges(base []string, start int, end int, cs []change) []string {
+	var out []string
+	pos := start
+	for _, c := range cs {
+		out = append(out, base[pos:c.start]...)
+		out = append(out, c.lines...)
+		pos = c.end
+	}
+	return append(out, base[pos:end]...)
+}
+
+// Merge3 按行三方合并：ours和theirs都是由base修改而来，只有一方修改的地方直接采用，
+// 两边修改了相同或相邻的行且结果不同时，用冲突标记同时保留两边的内容。返回合并的结果和冲突的数量
+func Merge3(base string, ours string, theirs string) (string, int) {
+	baseLines := splitLines(base)
+	oursChanges := changes(baseLines, splitLines(ours))
+	theirsChanges := changes(baseLines, splitLines(theirs))
+
+	var out []string
+	conflicts := 0
+	pos := 0
+	for len(oursChanges) > 0 || len(theirsChanges) > 0 {
+		// 从最靠前的修改开始，把和它重叠或相邻的修改合成一组
+		var groupOurs, groupTheirs []change
+		var start, end int
+		if len(theirsChanges) == 0 || (len(oursChanges) > 0 && oursChanges[0].start <= theirsChanges[0].start) {
+			start, end = oursChanges[0].start, oursChanges[0].end
+		} else {
+			start, end = theirsChanges[0].start, theirsChanges[0].end
+		}
+		for {
+			if len(oursChanges) > 0 && oursChanges[0].start <= end {
+				end = max(end, oursChanges[0].end)
+				groupOurs = append(groupOurs, oursChanges[0])
+				oursChanges = oursChanges[1:]
+				continue
+			}
+			if len(theirsChanges) > 0 && theirsChanges[0].start <= end {
+				end = max(end, theirsChanges[0].end)
+				groupTheirs = append(groupTheirs, theirsChanges[0])
+				theirsChanges = theirsChanges[1:]
+				continue
+			}
+			break
+		}
+
+		out = append(out, baseLines[pos:start]...)
+		oursLines := applyChanges(baseLines, start, end, groupOurs)
+		theirsLines := applyChanges(baseLines, start, end, groupTheirs)
+		switch {
+		case len(groupTheirs) == 0:
+			out = append(out, oursLines...)
+		case len(groupOurs) == 0 || slices.Equal(oursLines, theirsLines):
+			out = append(out, theirsLines...)
+		default:
+			conflicts++
+			out = append(out, ConflictOurs)
+			out = append(out, oursLines...)
+			out = append(out, ConflictSep)
+			out = append(out, theirsLines...)
+			out = append(out, ConflictTheirs)
+		}
+		pos = end
+	}
+	out = append(out, baseLines[pos:]...)
+
+	merged := strings.Join(out, "\n")
+	if len(out) > 0 && (strings.HasSuffix(ours, "\n") || strings.HasSuffix(theirs, "\n")) {
+		merged += "\n"
+	}
+	return merged, conflicts
+}
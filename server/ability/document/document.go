@@ -62,10 +62,11 @@ const (
 	TEXT DocType = iota
 	IMAGE
 	TABLE
+	CODE
 )
 
 func (docType DocType) String() string {
-	return [...]string{"TEXT", "IMAGE", "TABLE"}[docType]
+	return [...]string{"TEXT", "IMAGE", "TABLE", "CODE"}[docType]
 }
 
 func ShowDocumentExample() *Document {
@@ -0,0 +1,169 @@
+// -------------------------------------------------
+// Package search_engine
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package search_engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"go.uber.org/zap"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ListIndexes 列出名称以prefix开头的索引，value为索引的创建时间
+func ListIndexes(client *elasticsearch.Client, prefix string) (map[string]time.Time, error) {
+	res, err := client.Cat.Indices(
+		client.Cat.Indices.WithContext(context.Background()),
+		client.Cat.Indices.WithIndex(prefix+"*"),
+		client.Cat.Indices.WithH("index", "creation.date"),
+		client.Cat.Indices.WithFormat("json"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			zap.S().Error("close error %v", err)
+		}
+	}(res.Body)
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to list indexes: %s", res.String())
+	}
+	var rows []struct {
+		Index        string `json:"index"`
+		CreationDate string `json:"creation.date"` // 毫秒时间戳
+	}
+	if err = json.NewDecoder(res.Body).Decode(&rows); err != nil {
+		return nil, err
+	}
+	indexes := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		ms, err := strconv.ParseInt(row.CreationDate, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid creation date of index %s: %w", row.Index, err)
+		}
+		indexes[row.Index] = time.UnixMilli(ms)
+	}
+	return indexes, nil
+}
+
+// 每次聚合读取的文档数
+const countChunksPageSize = 1000
+
+// FileChunks 一个文档在es中的切片
+type FileChunks struct {
+	Count     int
+	IndexedAt time.Time // 最近一次写入的时间，切片都没有记录写入时间时为零值
+}
+
+// CountChunksByFile 统计索引中每个文档的切片数和最近的写入时间，key为kb_file_id
+func CountChunksByFile(client *elasticsearch.Client, indexName string) (map[string]FileChunks, error) {
+	counts := map[string]FileChunks{}
+	var after map[string]interface{}
+	for {
+		composite := map[string]interface{}{
+			"size": countChunksPageSize,
+			"sources": []interface{}{
+				map[string]interface{}{"kb_file_id": map[string]interface{}{
+					"terms": map[string]interface{}{"field": "metadata.kb_file_id"},
+				}},
+			},
+		}
+		if after != nil {
+			composite["after"] = after
+		}
+		query := map[string]interface{}{
+			"size": 0,
+			"aggs": map[string]interface{}{
+				"files": map[string]interface{}{
+					"composite": composite,
+					"aggs": map[string]interface{}{
+						"latest": map[string]interface{}{"max": map[string]interface{}{"field": "metadata." + IndexedAtKey}},
+					},
+				},
+			},
+		}
+		body, err := json.Marshal(query)
+		if err != nil {
+			return nil, err
+		}
+		result, err := searchAggs(client, indexName, body)
+		if err != nil {
+			return nil, err
+		}
+		for _, bucket := range result.Aggregations.Files.Buckets {
+			chunks := FileChunks{Count: bucket.DocCount}
+			if bucket.Latest.Value != nil {
+				chunks.IndexedAt = time.UnixMilli(int64(*bucket.Latest.Value))
+			}
+			counts[bucket.Key.KBFileId] = chunks
+		}
+		if len(result.Aggregations.Files.Buckets) < countChunksPageSize || result.Aggregations.Files.AfterKey == nil {
+			return counts, nil
+		}
+		after = result.Aggregations.Files.AfterKey
+	}
+}
+
+// DeleteChunksIndexedBefore 删除文档在before之前写入的切片，包括没有记录写入时间的；之后写入的切片保留
+func DeleteChunksIndexedBefore(client *elasticsearch.Client, indexName string, kbFileId string, before time.Time) error {
+	return deleteByQuery(client, indexName, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": map[string]interface{}{"term": map[string]interface{}{"metadata.kb_file_id": kbFileId}},
+			"must_not": map[string]interface{}{"range": map[string]interface{}{
+				"metadata." + IndexedAtKey: map[string]interface{}{"gte": before.UTC().Format(time.RFC3339)},
+			}},
+		},
+	})
+}
+
+type filesAggResult struct {
+	Aggregations struct {
+		Files struct {
+			AfterKey map[string]interface{} `json:"after_key"`
+			Buckets  []struct {
+				Key struct {
+					KBFileId string `json:"kb_file_id"`
+				} `json:"key"`
+				DocCount int `json:"doc_count"`
+				Latest   struct {
+					Value *float64 `json:"value"` // 毫秒时间戳，没有值时为null
+				} `json:"latest"`
+			} `json:"buckets"`
+		} `json:"files"`
+	} `json:"aggregations"`
+}
+
+func searchAggs(client *elasticsearch.Client, indexName string, body []byte) (*filesAggResult, error) {
+	res, err := client.Search(
+		client.Search.WithContext(context.Background()),
+		client.Search.WithIndex(indexName),
+		client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			zap.S().Error("close error %v", err)
+		}
+	}(res.Body)
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to count chunks: %s", res.String())
+	}
+	var result filesAggResult
+	if err = json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -16,14 +16,30 @@ import (
 	"go.uber.org/zap"
 	"io"
 	"strings"
+	"time"
 )
 
+// IndexedAtKey 元数据中切片写入es的时间，一致性检查据此跳过刚写入、数据库还没有提交的切片
+const IndexedAtKey = "indexed_at"
+
+// chunkSource 写入es的切片内容：元数据加上写入时间，再加上向量；复制元数据，不修改传入的文档
+func chunkSource(doc *document.Document, vector []float64) map[string]interface{} {
+	metadata := make(map[string]string, len(doc.Metadata)+1)
+	for key, value := range doc.Metadata {
+		metadata[key] = value
+	}
+	metadata[IndexedAtKey] = time.Now().UTC().Format(time.RFC3339)
+	docMap := doc.ToMap()
+	docMap["metadata"] = metadata
+	docMap["vector"] = vector
+	return docMap
+}
+
 func AddDocuments(client *elasticsearch.Client, indexName string, documents []*document.Document, embedding [][]float64) error {
 	//client := config.ElasticClient
 	for i, doc := range documents {
 		// todo 这里应该将document转为一个map，然后再map里添加vector - embedding
-		docMap := doc.ToMap()
-		docMap["vector"] = embedding[i]
+		docMap := chunkSource(doc, embedding[i])
 
 		// 首先将documents转为
 		jsonData, err := json.Marshal(docMap)
@@ -87,6 +87,20 @@ func _defaultMappings() map[string]interface{} {
 					"image_path": map[string]interface{}{
 						"type": "keyword",
 					},
+					"language": map[string]interface{}{ // 代码块的语言
+						"type": "keyword",
+					},
+					"symbols": map[string]interface{}{ // 代码块里的函数名、key等，空格分隔
+						"type":     "text",
+						"analyzer": "standard",
+					},
+					"chunk_hash": map[string]interface{}{ // 切片内容的SHA-256，增量更新时使用
+						"type":  "keyword",
+						"index": false,
+					},
+					IndexedAtKey: map[string]interface{}{ // 切片写入的时间，一致性检查时使用
+						"type": "date",
+					},
 				},
 			},
 		},
@@ -0,0 +1,363 @@
+// -------------------------------------------------
+// Package search_engine
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package search_engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gcnote/server/ability/document"
+	"gcnote/server/ability/splitter"
+	"github.com/elastic/go-elasticsearch/v8"
+	"go.uber.org/zap"
+	"io"
+	"strings"
+	"time"
+)
+
+// IndexedChunk es中已有的一个切片
+type IndexedChunk struct {
+	Id    string // es的_id
+	DocId string // metadata.doc_id，切片在文档中的位置
+	Hash  string // 切片内容的SHA-256，旧数据没有metadata.chunk_hash时由内容计算
+}
+
+// ChunkDiff 更新文档时新旧切片的差异
+type ChunkDiff struct {
+	Added   []int             // 需要写入的新切片的下标
+	Moved   map[string]string // 内容没变但位置变化的切片：es _id -> 新的doc_id
+	Deleted []string          // 需要删除的切片的es _id
+}
+
+// Empty 新旧切片完全相同
+func (d ChunkDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Moved) == 0 && len(d.Deleted) == 0
+}
+
+// DiffChunks 按内容hash对比es中已有的切片和新切片，内容相同的切片保留，不用重新计算向量；
+// 相同内容出现多次时逐个对应
+func DiffChunks(indexed []IndexedChunk, docs []*document.Document) ChunkDiff {
+	byHash := make(map[string][]IndexedChunk, len(indexed))
+	for _, chunk := range indexed {
+		byHash[chunk.Hash] = append(byHash[chunk.Hash], chunk)
+	}
+	diff := ChunkDiff{Moved: map[string]string{}}
+	for i, doc := range docs {
+		hash := doc.Metadata["chunk_hash"]
+		if hash == "" {
+			hash = splitter.ChunkHash(doc.PageContent)
+		}
+		same := byHash[hash]
+		if len(same) == 0 {
+			diff.Added = append(diff.Added, i)
+			continue
+		}
+		kept := same[0]
+		byHash[hash] = same[1:]
+		if kept.DocId != doc.Metadata["doc_id"] {
+			diff.Moved[kept.Id] = doc.Metadata["doc_id"]
+		}
+	}
+	// 按es中的顺序删除，结果稳定
+	for _, chunk := range indexed {
+		rest := byHash[chunk.Hash]
+		if len(rest) > 0 && rest[0].Id == chunk.Id {
+			diff.Deleted = append(diff.Deleted, chunk.Id)
+			byHash[chunk.Hash] = rest[1:]
+		}
+	}
+	return diff
+}
+
+// 每次scroll读取的切片数
+const listChunksPageSize = 1000
+
+// ListChunks 读取文档在es中的所有切片，不读取向量
+func ListChunks(client *elasticsearch.Client, indexName string, kbFileId string) ([]IndexedChunk, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"metadata.kb_file_id": kbFileId},
+		},
+		"_source": []string{"page_content", "metadata.doc_id", "metadata.chunk_hash"},
+	}
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Search(
+		client.Search.WithContext(context.Background()),
+		client.Search.WithIndex(indexName),
+		client.Search.WithBody(bytes.NewReader(body)),
+		client.Search.WithSize(listChunksPageSize),
+		client.Search.WithScroll(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []IndexedChunk
+	scrollId, hits, err := readScrollPage(res.Body, res.IsError(), res.String)
+	for err == nil {
+		chunks = append(chunks, hits...)
+		if len(hits) == 0 || scrollId == "" {
+			break
+		}
+		res, err = client.Scroll(
+			client.Scroll.WithContext(context.Background()),
+			client.Scroll.WithScrollID(scrollId),
+			client.Scroll.WithScroll(time.Minute),
+		)
+		if err != nil {
+			break
+		}
+		scrollId, hits, err = readScrollPage(res.Body, res.IsError(), res.String)
+	}
+	if scrollId != "" {
+		if clearRes, clearErr := client.ClearScroll(client.ClearScroll.WithScrollID(scrollId)); clearErr == nil {
+			_ = clearRes.Body.Close()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+// readScrollPage 解析一页scroll结果并关闭body
+func readScrollPage(body io.ReadCloser, isError bool, describe func() string) (string, []IndexedChunk, error) {
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			zap.S().Error("close error %v", err)
+		}
+	}(body)
+	if isError {
+		return "", nil, fmt.Errorf("failed to list chunks: %s", describe())
+	}
+	var result struct {
+		ScrollId string `json:"_scroll_id"`
+		Hits     struct {
+			Hits []struct {
+				Id     string `json:"_id"`
+				Source struct {
+					PageContent string            `json:"page_content"`
+					Metadata    map[string]string `json:"metadata"`
+				} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return "", nil, err
+	}
+	chunks := make([]IndexedChunk, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		hash := hit.Source.Metadata["chunk_hash"]
+		if hash == "" {
+			hash = splitter.ChunkHash(hit.Source.PageContent)
+		}
+		chunks = append(chunks, IndexedChunk{Id: hit.Id, DocId: hit.Source.Metadata["doc_id"], Hash: hash})
+	}
+	return result.ScrollId, chunks, nil
+}
+
+// buildBulkBody 依次写入新切片、更新位置、删除旧切片的_bulk请求体；embedding与diff.Added一一对应
+func buildBulkBody(indexName string, docs []*document.Document, embedding [][]float64, diff ChunkDiff) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	write := func(lines ...interface{}) error {
+		for _, line := range lines {
+			if err := enc.Encode(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for j, i := range diff.Added {
+		err := write(map[string]interface{}{"index": map[string]interface{}{"_index": indexName}},
+			chunkSource(docs[i], embedding[j]))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for id, docId := range diff.Moved {
+		err := write(map[string]interface{}{"update": map[string]interface{}{"_index": indexName, "_id": id}},
+			map[string]interface{}{"doc": map[string]interface{}{"metadata": map[string]string{"doc_id": docId}}})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range diff.Deleted {
+		if err := write(map[string]interface{}{"delete": map[string]interface{}{"_index": indexName, "_id": id}}); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// bulkResult _bulk的返回，只解析需要的字段
+type bulkResult struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Id     string          `json:"_id"`
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// ApplyChunkDiff 分两步更新文档的切片，每步都等到刷新后才返回：先写入新切片、更新位置，再删除旧切片。
+// _bulk不是原子的，执行中的刷新会暴露一部分结果，所以先写后删：检索可能短暂地同时看到新旧切片，但不会缺少内容。
+// 第一步失败时删除已经写入的新切片，es中仍是完整的旧版本；第二步失败时会残留部分旧切片。
+// 返回错误时调用方需要用ReplaceChunks重新写入整个文档
+func ApplyChunkDiff(client *elasticsearch.Client, indexName string, docs []*document.Document,
+	embedding [][]float64, diff ChunkDiff) error {
+	if diff.Empty() {
+		return nil
+	}
+	if len(embedding) != len(diff.Added) {
+		return fmt.Errorf("embedding count %d does not match added chunks %d", len(embedding), len(diff.Added))
+	}
+	if len(diff.Added) > 0 || len(diff.Moved) > 0 {
+		body, err := buildBulkBody(indexName, docs, embedding, ChunkDiff{Added: diff.Added, Moved: diff.Moved})
+		if err != nil {
+			return err
+		}
+		if _, err = applyBulk(client, indexName, body); err != nil {
+			return fmt.Errorf("failed to write new chunks: %w", err)
+		}
+	}
+	if len(diff.Deleted) > 0 {
+		body, err := buildBulkBody(indexName, nil, nil, ChunkDiff{Deleted: diff.Deleted})
+		if err != nil {
+			return err
+		}
+		if _, err = applyBulk(client, indexName, body); err != nil {
+			return fmt.Errorf("failed to delete old chunks: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReplaceChunks 重新写入文档的所有切片：先写入新切片并刷新，再删除文档的其他切片，
+// 和ApplyChunkDiff一样检索不会看到缺少内容的文档。用于增量更新失败后的修复
+func ReplaceChunks(client *elasticsearch.Client, indexName string, kbFileId string, docs []*document.Document,
+	embedding [][]float64) error {
+	if len(embedding) != len(docs) {
+		return fmt.Errorf("embedding count %d does not match chunks %d", len(embedding), len(docs))
+	}
+	var keep []string
+	if len(docs) > 0 {
+		all := make([]int, len(docs))
+		for i := range all {
+			all[i] = i
+		}
+		body, err := buildBulkBody(indexName, docs, embedding, ChunkDiff{Added: all})
+		if err != nil {
+			return err
+		}
+		if keep, err = applyBulk(client, indexName, body); err != nil {
+			return fmt.Errorf("failed to write chunks: %w", err)
+		}
+	}
+	return deleteChunksExcept(client, indexName, kbFileId, keep)
+}
+
+// applyBulk 执行_bulk，返回写入的新切片的_id；有失败的操作时删除已经写入的新切片并返回错误，
+// 要删除的切片已经不存在不算失败
+func applyBulk(client *elasticsearch.Client, indexName string, body []byte) ([]string, error) {
+	result, err := bulk(client, body)
+	if err != nil {
+		return nil, err
+	}
+	var added []string
+	var firstErr string
+	for _, item := range result.Items {
+		for action, r := range item {
+			if r.Status >= 300 && firstErr == "" && !(action == "delete" && r.Status == 404) {
+				firstErr = fmt.Sprintf("%s %s: %s", action, r.Id, r.Error)
+			}
+			if action == "index" && r.Status < 300 {
+				added = append(added, r.Id)
+			}
+		}
+	}
+	if firstErr == "" {
+		return added, nil
+	}
+	if len(added) > 0 {
+		rollback, err := buildBulkBody(indexName, nil, nil, ChunkDiff{Deleted: added})
+		if err == nil {
+			_, err = bulk(client, rollback)
+		}
+		if err != nil {
+			zap.S().Errorf("Failed to roll back added chunks in %s: %v", indexName, err)
+		}
+	}
+	return nil, errors.New(firstErr)
+}
+
+// deleteChunksExcept 删除文档中_id不在keep里的切片，等到刷新后返回
+func deleteChunksExcept(client *elasticsearch.Client, indexName string, kbFileId string, keep []string) error {
+	return deleteByQuery(client, indexName, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter":   map[string]interface{}{"term": map[string]interface{}{"metadata.kb_file_id": kbFileId}},
+			"must_not": map[string]interface{}{"ids": map[string]interface{}{"values": keep}},
+		},
+	})
+}
+
+// deleteByQuery 删除匹配的切片，等到刷新后返回
+func deleteByQuery(client *elasticsearch.Client, indexName string, query map[string]interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query})
+	if err != nil {
+		return err
+	}
+	res, err := client.DeleteByQuery(
+		[]string{indexName},
+		bytes.NewReader(body),
+		client.DeleteByQuery.WithContext(context.Background()),
+		client.DeleteByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			zap.S().Error("close error %v", err)
+		}
+	}(res.Body)
+	if res.IsError() {
+		return fmt.Errorf("failed to delete chunks: %s", res.String())
+	}
+	return nil
+}
+
+func bulk(client *elasticsearch.Client, body []byte) (*bulkResult, error) {
+	res, err := client.Bulk(
+		bytes.NewReader(body),
+		client.Bulk.WithContext(context.Background()),
+		client.Bulk.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			zap.S().Error("close error %v", err)
+		}
+	}(res.Body)
+	if res.IsError() {
+		return nil, fmt.Errorf("bulk request failed: %s", strings.TrimSpace(res.String()))
+	}
+	var result bulkResult
+	if err = json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,166 @@
+// -------------------------------------------------
+// Package search_engine
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package search_engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"gcnote/server/ability/splitter"
+	"github.com/elastic/go-elasticsearch/v8"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffChunks(t *testing.T) {
+	old := splitter.Chunk2Doc([]string{"a", "b", "c", "a"}, "kb1", "idx")
+	indexed := make([]IndexedChunk, len(old))
+	for i, doc := range old {
+		indexed[i] = IndexedChunk{Id: "es" + doc.Metadata["doc_id"], DocId: doc.Metadata["doc_id"],
+			Hash: doc.Metadata["chunk_hash"]}
+	}
+	// 旧数据没有chunk_hash，按内容计算
+	indexed[1].Hash = splitter.ChunkHash("b")
+
+	// 删除c，开头插入x，第二个a不再出现
+	docs := splitter.Chunk2Doc([]string{"x", "a", "b"}, "kb1", "idx")
+	diff := DiffChunks(indexed, docs)
+	if !reflect.DeepEqual(diff.Added, []int{0}) {
+		t.Errorf("added %v", diff.Added)
+	}
+	if want := map[string]string{"es0": "1", "es1": "2"}; !reflect.DeepEqual(diff.Moved, want) {
+		t.Errorf("moved %v, want %v", diff.Moved, want)
+	}
+	if want := []string{"es2", "es3"}; !reflect.DeepEqual(diff.Deleted, want) {
+		t.Errorf("deleted %v, want %v", diff.Deleted, want)
+	}
+
+	if diff = DiffChunks(indexed, old); !diff.Empty() {
+		t.Errorf("unchanged file should have empty diff: %+v", diff)
+	}
+}
+
+func TestBuildBulkBody(t *testing.T) {
+	docs := splitter.Chunk2Doc([]string{"x", "a"}, "kb1", "idx")
+	diff := ChunkDiff{Added: []int{0}, Moved: map[string]string{"es0": "1"}, Deleted: []string{"es2"}}
+	body, err := buildBulkBody("gcnote-idx", docs, [][]float64{{0.5}}, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines:\n%s", len(lines), body)
+	}
+	actions := []string{"index", "", "update", "", "delete"}
+	for i, action := range actions {
+		var line map[string]json.RawMessage
+		if err = json.Unmarshal([]byte(lines[i]), &line); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := line[action]; action != "" && !ok {
+			t.Errorf("line %d: want %s action, got %s", i, action, lines[i])
+		}
+	}
+	if !strings.Contains(lines[1], `"page_content":"x"`) || !strings.Contains(lines[1], `"vector":[0.5]`) {
+		t.Errorf("unexpected added document %s", lines[1])
+	}
+	if lines[3] != `{"doc":{"metadata":{"doc_id":"1"}}}` {
+		t.Errorf("unexpected update %s", lines[3])
+	}
+}
+
+// fakeBulkServer 记录收到的_bulk和_delete_by_query请求，page_content为bad的切片写入失败
+type fakeBulkServer struct {
+	requests [][]string // 每个请求里依次执行的操作，如 "index:x"、"delete:es1"、"delete_by_query"
+}
+
+func (f *fakeBulkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	if strings.HasSuffix(r.URL.Path, "/_delete_by_query") {
+		f.requests = append(f.requests, []string{"delete_by_query"})
+		_, _ = w.Write([]byte(`{"deleted":1}`))
+		return
+	}
+	var actions []string
+	var items []map[string]interface{}
+	dec := json.NewDecoder(r.Body)
+	for {
+		var line map[string]map[string]interface{}
+		if err := dec.Decode(&line); err != nil {
+			break
+		}
+		for action, meta := range line {
+			status, id := 200, fmt.Sprint(meta["_id"])
+			if action != "delete" {
+				var source map[string]interface{}
+				_ = dec.Decode(&source)
+				if action == "index" {
+					id = fmt.Sprint(source["page_content"])
+				}
+			}
+			if id == "bad" {
+				status = 500
+			}
+			actions = append(actions, action+":"+id)
+			items = append(items, map[string]interface{}{action: map[string]interface{}{"_id": id, "status": status}})
+		}
+	}
+	f.requests = append(f.requests, actions)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": strings.Contains(fmt.Sprint(actions), "bad"),
+		"items": items})
+}
+
+func newFakeClient(t *testing.T, handler http.Handler) *elasticsearch.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestApplyChunkDiff(t *testing.T) {
+	fake := &fakeBulkServer{}
+	client := newFakeClient(t, fake)
+	docs := splitter.Chunk2Doc([]string{"x", "a"}, "kb1", "idx")
+	diff := ChunkDiff{Added: []int{0}, Moved: map[string]string{"es0": "1"}, Deleted: []string{"es2"}}
+	if err := ApplyChunkDiff(client, "gcnote-idx", docs, [][]float64{{0.5}}, diff); err != nil {
+		t.Fatal(err)
+	}
+	// 先写入新切片、更新位置并刷新，之后才删除旧切片
+	want := [][]string{{"index:x", "update:es0"}, {"delete:es2"}}
+	if !reflect.DeepEqual(fake.requests, want) {
+		t.Errorf("got %v, want %v", fake.requests, want)
+	}
+
+	// 写入失败时删除已经写入的新切片，旧切片保持不变
+	fake.requests = nil
+	docs = splitter.Chunk2Doc([]string{"y", "bad"}, "kb1", "idx")
+	diff = ChunkDiff{Added: []int{0, 1}, Deleted: []string{"es2"}}
+	if err := ApplyChunkDiff(client, "gcnote-idx", docs, [][]float64{{0.5}, {0.5}}, diff); err == nil {
+		t.Fatal("expected error")
+	}
+	want = [][]string{{"index:y", "index:bad"}, {"delete:y"}}
+	if !reflect.DeepEqual(fake.requests, want) {
+		t.Errorf("got %v, want %v", fake.requests, want)
+	}
+
+	// 重新写入整个文档：先写入所有切片，再删除其他切片
+	fake.requests = nil
+	docs = splitter.Chunk2Doc([]string{"y", "z"}, "kb1", "idx")
+	if err := ReplaceChunks(client, "gcnote-idx", "kb1", docs, [][]float64{{0.5}, {0.5}}); err != nil {
+		t.Fatal(err)
+	}
+	want = [][]string{{"index:y", "index:z"}, {"delete_by_query"}}
+	if !reflect.DeepEqual(fake.requests, want) {
+		t.Errorf("got %v, want %v", fake.requests, want)
+	}
+}
@@ -73,11 +73,21 @@ func BaseSearch(query string, client *elasticsearch.Client, indexName string) ([
 }
 
 func FullTextSearch(client *elasticsearch.Client, indexName string, userQuery string, topK int) ([]*document.Document, error) {
-	//query := `{"query": {"match": {}}}`
-	query := fmt.Sprintf(
-		`{"query": {"match": { "page_content": "%s" } },"size": %v }`,
-		userQuery, topK)
-	return BaseSearch(query, client, indexName)
+	// 同时匹配代码块的函数名、key等，命中符号的权重更高
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":  userQuery,
+				"fields": []string{"page_content", "metadata.symbols^2"},
+			},
+		},
+		"size": topK,
+	}
+	queryString, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	return BaseSearch(string(queryString), client, indexName)
 }
 
 func VectorSearch(client *elasticsearch.Client, indexName string, queryVector []float64, topK int) ([]*document.Document, error) {
@@ -100,11 +110,21 @@ func VectorSearch(client *elasticsearch.Client, indexName string, queryVector []
 }
 
 func KeywordsSearch(client *elasticsearch.Client, indexName string, word string) ([]*document.Document, error) {
-	query := fmt.Sprintf(
-		`"query":{
-                    "match_phrase": {
-                        "page_content": "%v"
-                    }
-                }`, word)
-	return BaseSearch(query, client, indexName)
+	// 正文的短语匹配，或者代码块里声明了这个符号
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should": []interface{}{
+					map[string]interface{}{"match_phrase": map[string]interface{}{"page_content": word}},
+					map[string]interface{}{"match": map[string]interface{}{"metadata.symbols": word}},
+				},
+				"minimum_should_match": 1,
+			},
+		},
+	}
+	queryString, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	return BaseSearch(string(queryString), client, indexName)
 }
@@ -7,6 +7,8 @@
 package splitter
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"gcnote/server/ability/document"
 	"strconv"
 	"strings"
@@ -31,18 +33,32 @@ func Chunk2Doc(chunks []string, kbFileId, indexId string) []*document.Document {
 				"index_id":   indexId,
 				"type":       getType(chunk),
 				"image_path": imagePath,
+				"chunk_hash": ChunkHash(chunk),
 			},
 		}
+		// 代码块记录语言和声明的函数名、key等，用于关键词检索
+		if _, language, code, ok := parseFence(chunk); ok && strings.HasPrefix(chunk, "```") {
+			docList[i].Metadata["language"] = language
+			docList[i].Metadata["symbols"] = strings.Join(CodeSymbols(code, language), " ")
+		}
 	}
 	return docList
 }
 
+// ChunkHash 切片内容的SHA-256，更新文档时据此找出没有变化的切片
+func ChunkHash(chunk string) string {
+	sum := sha256.Sum256([]byte(chunk))
+	return hex.EncodeToString(sum[:])
+}
+
 func getType(chunk string) string {
 	switch {
 	case strings.HasPrefix(chunk, "|"):
 		return document.TABLE.String()
 	case strings.HasPrefix(chunk, "!"):
 		return document.IMAGE.String()
+	case strings.HasPrefix(chunk, "```"):
+		return document.CODE.String()
 	default:
 		// 如果没有匹配的情况发生，可以返回一个默认值或空字符串
 		return document.TEXT.String()
@@ -0,0 +1,595 @@
+// -------------------------------------------------
+// Package splitter
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package splitter
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// declRules 各语言的声明：匹配到的行(去掉缩进后)是切分点，第一个分组是符号名
+var declRules = map[string][]*regexp.Regexp{
+	"go": compileAll(
+		`^func\s+(?:\([^)]*\)\s*)?(\w+)`,
+		`^type\s+(\w+)`,
+		`^(?:var|const)\s+(\w+)`,
+	),
+	"python": compileAll(
+		`^(?:async\s+)?def\s+(\w+)`,
+		`^class\s+(\w+)`,
+	),
+	"javascript": jsRules,
+	"typescript": jsRules,
+	"java":       jvmRules,
+	"csharp":     jvmRules,
+	"kotlin": append(compileAll(
+		`^(?:(?:public|private|protected|internal|override|suspend|inline|open)\s+)*fun\s+(?:<[^>]*>\s*)?(?:[\w.]+\.)?(\w+)`,
+	), jvmRules...),
+	"scala": append(compileAll(
+		`^(?:(?:override|private|protected|final)\s+)*def\s+(\w+)`,
+	), jvmRules...),
+	"swift": append(compileAll(
+		`^(?:(?:public|private|internal|fileprivate|open|static|override|mutating)\s+)*func\s+(\w+)`,
+	), jvmRules...),
+	"c":   cRules,
+	"cpp": cRules,
+	"rust": compileAll(
+		`^(?:pub(?:\([^)]*\))?\s+)?(?:(?:async|const|unsafe|extern\s+"[^"]*")\s+)*(?:fn|struct|enum|trait|mod|type|union|static|const)\s+(\w+)`,
+		`^(?:unsafe\s+)?impl(?:<[^>]*>)?\s+(?:[\w:]+(?:<[^>]*>)?\s+for\s+)?(\w+)`,
+	),
+	"ruby": compileAll(
+		`^def\s+(?:self\.)?([\w?!=]+)`,
+		`^(?:class|module)\s+([\w:]+)`,
+	),
+	"php": compileAll(
+		`^(?:(?:public|private|protected|static|abstract|final)\s+)*function\s+&?(\w+)`,
+		`^(?:(?:abstract|final)\s+)?(?:class|interface|trait|enum)\s+(\w+)`,
+	),
+	"bash": compileAll(
+		`^function\s+([\w-]+)`,
+		`^([\w-]+)\s*\(\)`,
+	),
+	"lua": compileAll(
+		`^(?:local\s+)?function\s+([\w.:]+)`,
+	),
+	"yaml": compileAll(
+		`^("[^"]*"|'[^']*'|[\w.\-/$]+(?:\s+[\w.\-/$]+)*)\s*:(?:\s|$)`,
+	),
+	"toml": compileAll(
+		`^\[\[?\s*([^\]]+?)\s*\]\]?\s*(?:#.*)?$`,
+	),
+}
+
+var (
+	jsRules = compileAll(
+		`^(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)`,
+		`^(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+(\w+)`,
+		`^(?:export\s+)?(?:declare\s+)?(?:interface|type|enum|namespace)\s+(\w+)`,
+		`^(?:export\s+)?(?:const|let|var)\s+(\w+)\s*=\s*(?:async\s+)?(?:function|\([^)]*\)\s*=>|\w+\s*=>)`,
+		`^(?:(?:static|async|public|private|protected|readonly|get|set)\s+)*(\w+)\s*\([^)]*\)\s*(?::\s*[^{]+)?\{\s*$`,
+	)
+	jvmRules = compileAll(
+		`^(?:(?:public|private|protected|internal|static|final|abstract|sealed|partial|open|data|override|async|virtual|readonly)\s+)*(?:class|interface|enum|record|struct|object|trait)\s+(\w+)`,
+		`^(?:(?:public|private|protected|internal|static|final|abstract|override|async|virtual|synchronized|native)\s+)*[\w<>\[\],.?]+\s+(\w+)\s*\([^;]*$`,
+	)
+	cRules = compileAll(
+		`^(?:typedef\s+)?(?:struct|class|enum|union|namespace)\s+(\w+)`,
+		`^#define\s+(\w+)`,
+		`^(?:[\w*&:<>,]+\s+)+[*&]*((?:\w+::)*~?\w+)\s*\([^;]*$`,
+	)
+
+	// sql按语句切分，符号是建表、建视图等语句里的对象名
+	sqlObjectRe = regexp.MustCompile("(?i)^\\s*(?:create|alter)\\s+(?:or\\s+replace\\s+)?(?:temporary\\s+|temp\\s+)?(?:unique\\s+)?" +
+		"(?:table|view|function|procedure|index|trigger|type|sequence|schema|materialized\\s+view)\\s+" +
+		"(?:if\\s+not\\s+exists\\s+)?([\\w.\"`\\[\\]]+)")
+	// toml表之前的顶层key
+	tomlKeyRe = regexp.MustCompile(`^([\w\-"'.]+)\s*=`)
+)
+
+// nonDeclWords 形如 if (...) { 的行也能匹配上函数的规则，第一个词或符号名是这些关键字时不算声明
+var nonDeclWords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true, "else": true,
+	"new": true, "throw": true, "case": true, "do": true, "try": true, "elif": true, "with": true,
+	"sizeof": true, "await": true, "yield": true, "typeof": true, "function": true, "using": true,
+	"lock": true, "foreach": true,
+}
+
+// commentPrefixes 紧挨着声明的注释、装饰器、注解算作声明的一部分
+var commentPrefixes = []string{"//", "#", "/*", "*", "@", "--", ";"}
+
+func compileAll(patterns ...string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		res[i] = regexp.MustCompile(p)
+	}
+	return res
+}
+
+// languageAliases 代码块上常见的语言写法 => 统一的名称
+var languageAliases = map[string]string{
+	"golang": "go", "py": "python", "python3": "python", "js": "javascript", "jsx": "javascript",
+	"ts": "typescript", "tsx": "typescript", "cs": "csharp", "c#": "csharp", "c++": "cpp", "cc": "cpp",
+	"hpp": "cpp", "h": "c", "rs": "rust", "rb": "ruby", "kt": "kotlin", "sh": "bash", "shell": "bash",
+	"zsh": "bash", "yml": "yaml", "jsonc": "json", "mysql": "sql", "postgresql": "sql", "postgres": "sql",
+}
+
+// NormalizeLanguage 代码块语言的统一写法，只取第一个词（```go title="x" 这种写法）
+func NormalizeLanguage(info string) string {
+	fields := strings.Fields(strings.ToLower(info))
+	if len(fields) == 0 {
+		return ""
+	}
+	if language, ok := languageAliases[fields[0]]; ok {
+		return language
+	}
+	return fields[0]
+}
+
+// IsCodeLanguage 是否能按声明切分该语言的代码块
+func IsCodeLanguage(language string) bool {
+	_, ok := declRules[language]
+	return ok || language == "json" || language == "sql"
+}
+
+// SplitCode 按函数、类型、顶层key等边界切分代码，每块尽量不超过maxLength：
+// 相邻的小声明合并为一块，单个声明超长时按内部的声明(方法、子key)再切，每块都带上外层声明的第一行，
+// 还是超长时按空行、按行切
+func SplitCode(code string, language string, maxLength int) []string {
+	code = strings.Trim(code, "\n")
+	if len(code) <= maxLength {
+		return []string{code}
+	}
+	switch language {
+	case "json":
+		if pieces, ok := splitJSON(code, maxLength); ok {
+			return pieces
+		}
+		return splitLines(code, "", maxLength)
+	case "sql":
+		return pack(sqlStatements(code), "", maxLength)
+	}
+	rules, ok := declRules[language]
+	if !ok {
+		return splitLines(code, "", maxLength)
+	}
+	lines := strings.Split(code, "\n")
+	var result []string
+	var small []string
+	for _, unit := range splitUnits(lines, rules, 0) {
+		if len(unit) <= maxLength {
+			small = append(small, unit)
+			continue
+		}
+		result = append(result, pack(small, "", maxLength)...)
+		small = nil
+		result = append(result, splitNested(unit, rules, maxLength)...)
+	}
+	return append(result, pack(small, "", maxLength)...)
+}
+
+// splitUnits 在缩进为indent的声明处切分，声明前面紧挨着的注释归到声明里
+func splitUnits(lines []string, rules []*regexp.Regexp, indent int) []string {
+	var starts []int
+	for i, line := range lines {
+		if lineIndent(line) == indent && matchDecl(strings.TrimSpace(line), rules) {
+			start := i
+			for start > 0 && lineIndent(lines[start-1]) == indent && isComment(lines[start-1]) &&
+				(len(starts) == 0 || start-1 > starts[len(starts)-1]) {
+				start--
+			}
+			starts = append(starts, start)
+		}
+	}
+	if len(starts) == 0 || starts[0] != 0 {
+		starts = append([]int{0}, starts...)
+	}
+	var units []string
+	for i, start := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		if unit := strings.Trim(strings.Join(lines[start:end], "\n"), "\n"); unit != "" {
+			units = append(units, unit)
+		}
+	}
+	return units
+}
+
+// splitNested 超长的声明按内部缩进最小的声明(方法、子key)切分，每块前面都加上外层声明的第一行，
+// 声明前面的注释只放在第一块
+func splitNested(unit string, rules []*regexp.Regexp, maxLength int) []string {
+	lines := strings.Split(unit, "\n")
+	headerIdx := 0
+	for headerIdx < len(lines)-1 && isComment(lines[headerIdx]) {
+		headerIdx++
+	}
+	comments := strings.Join(lines[:headerIdx], "\n")
+	header := lines[headerIdx]
+	inner := lines[headerIdx+1:]
+
+	nestedIndent := -1
+	for _, line := range inner {
+		indent := lineIndent(line)
+		if indent > lineIndent(header) && matchDecl(strings.TrimSpace(line), rules) &&
+			(nestedIndent < 0 || indent < nestedIndent) {
+			nestedIndent = indent
+		}
+	}
+	var result []string
+	if nestedIndent < 0 {
+		result = splitLines(strings.Join(inner, "\n"), header, maxLength)
+	} else {
+		var fitting []string
+		for _, u := range splitUnits(inner, rules, nestedIndent) {
+			if len(header)+1+len(u) <= maxLength {
+				fitting = append(fitting, u)
+				continue
+			}
+			result = append(result, pack(fitting, header, maxLength)...)
+			fitting = nil
+			result = append(result, splitLines(u, header, maxLength)...)
+		}
+		result = append(result, pack(fitting, header, maxLength)...)
+	}
+	if len(result) == 0 {
+		return []string{unit}
+	}
+	if comments != "" {
+		result[0] = comments + "\n" + result[0]
+	}
+	return result
+}
+
+// pack 把相邻的块合并到不超过maxLength，header不为空时每块前面都加上header
+func pack(units []string, header string, maxLength int) []string {
+	var result []string
+	current := ""
+	for _, unit := range units {
+		if current != "" && len(header)+len(current)+2+len(unit) > maxLength {
+			result = append(result, withHeader(header, current))
+			current = ""
+		}
+		if current != "" {
+			current += "\n\n"
+		}
+		current += unit
+	}
+	if current != "" {
+		result = append(result, withHeader(header, current))
+	}
+	return result
+}
+
+// splitLines 没有可用的声明边界时，按空行切分，一段还是超长就按行切；单独一行超长时也至少放一行
+func splitLines(text string, header string, maxLength int) []string {
+	var result []string
+	var current []string
+	size := len(header)
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, withHeader(header, strings.Trim(strings.Join(current, "\n"), "\n")))
+			current, size = nil, len(header)
+		}
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if len(current) > 0 && size+len(line)+1 > maxLength {
+			// 往回找最近的空行，在那里断开
+			cut := -1
+			for j := len(current) - 1; j > 0; j-- {
+				if strings.TrimSpace(current[j]) == "" {
+					cut = j
+					break
+				}
+			}
+			if cut > 0 {
+				rest := current[cut+1:]
+				current = current[:cut]
+				flush()
+				current = append(current, rest...)
+				size = len(header) + len(strings.Join(rest, "\n"))
+			} else {
+				flush()
+			}
+		}
+		if len(current) == 0 && strings.TrimSpace(line) == "" && i > 0 {
+			continue
+		}
+		current = append(current, line)
+		size += len(line) + 1
+	}
+	flush()
+	return result
+}
+
+func withHeader(header string, body string) string {
+	if header == "" {
+		return body
+	}
+	return header + "\n" + body
+}
+
+// sqlStatements 按以分号结尾的行切分sql语句
+func sqlStatements(code string) []string {
+	var statements []string
+	var current []string
+	for _, line := range strings.Split(code, "\n") {
+		if len(current) == 0 && strings.TrimSpace(line) == "" {
+			continue
+		}
+		current = append(current, line)
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			statements = append(statements, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		statements = append(statements, strings.TrimRight(strings.Join(current, "\n"), "\n"))
+	}
+	return statements
+}
+
+// splitJSON 按顶层key切分json对象(顶层是数组时按元素切分)，每块都是完整的json：
+// 超长的值是对象时继续按它的key切，外层的key保留为 {"外层": {"内层": ...}}
+func splitJSON(code string, maxLength int) ([]string, bool) {
+	var raw json.RawMessage
+	if err := json.Unmarshal([]byte(code), &raw); err != nil {
+		return nil, false
+	}
+	return splitJSONValue(raw, nil, maxLength), true
+}
+
+func splitJSONValue(raw json.RawMessage, path []string, maxLength int) []string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '{' {
+		keys, values, err := objectMembers(raw)
+		if err == nil && len(keys) > 0 {
+			var result []string
+			var members []string
+			flush := func() {
+				if len(members) > 0 {
+					result = append(result, wrapJSON(path, "{"+strings.Join(members, ",")+"}"))
+					members = nil
+				}
+			}
+			for i, key := range keys {
+				keyJSON, _ := json.Marshal(key)
+				member := string(keyJSON) + ":" + string(values[i])
+				childPath := append(append([]string(nil), path...), key)
+				if len(indentJSON(wrapJSON(childPath, string(values[i])))) > maxLength {
+					flush()
+					result = append(result, splitJSONValue(values[i], childPath, maxLength)...)
+					continue
+				}
+				if len(members) > 0 && len(indentJSON(wrapJSON(path, "{"+strings.Join(append(members, member), ",")+"}"))) > maxLength {
+					flush()
+				}
+				members = append(members, member)
+			}
+			flush()
+			return indentAll(result)
+		}
+	}
+	if len(raw) > 0 && raw[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err == nil && len(items) > 0 {
+			var result []string
+			var group []string
+			for _, item := range items {
+				candidate := append(append([]string(nil), group...), string(item))
+				if len(group) > 0 && len(indentJSON(wrapJSON(path, "["+strings.Join(candidate, ",")+"]"))) > maxLength {
+					result = append(result, wrapJSON(path, "["+strings.Join(group, ",")+"]"))
+					candidate = []string{string(item)}
+				}
+				group = candidate
+			}
+			result = append(result, wrapJSON(path, "["+strings.Join(group, ",")+"]"))
+			return indentAll(result)
+		}
+	}
+	// 标量或者只有一个元素的超长值，不再切
+	return []string{indentJSON(wrapJSON(path, string(raw)))}
+}
+
+// objectMembers 按原来的顺序返回json对象的key和值
+func objectMembers(raw json.RawMessage) ([]string, []json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := decoder.Token(); err != nil {
+		return nil, nil, err
+	}
+	var keys []string
+	var values []json.RawMessage
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		if err = decoder.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return keys, values, nil
+}
+
+// wrapJSON 用外层的key把值包起来，path为 [a b] 时结果为 {"a":{"b":value}}
+func wrapJSON(path []string, value string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		key, _ := json.Marshal(path[i])
+		value = "{" + string(key) + ":" + value + "}"
+	}
+	return value
+}
+
+func indentJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+func indentAll(pieces []string) []string {
+	for i := range pieces {
+		pieces[i] = indentJSON(pieces[i])
+	}
+	return pieces
+}
+
+// CodeSymbols 代码块里声明的函数、类型名，json/yaml/toml的key，sql的表名等，按出现顺序去重
+func CodeSymbols(code string, language string) []string {
+	var symbols []string
+	seen := map[string]bool{}
+	add := func(symbol string) {
+		symbol = strings.Trim(symbol, "\"'`[]")
+		if symbol != "" && !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	switch language {
+	case "json":
+		var raw json.RawMessage
+		if json.Unmarshal([]byte(code), &raw) == nil {
+			jsonSymbols(raw, "", add)
+		}
+		return symbols
+	case "sql":
+		for _, statement := range sqlStatements(code) {
+			if m := sqlObjectRe.FindStringSubmatch(statement); m != nil {
+				add(m[1])
+			}
+		}
+		return symbols
+	case "yaml":
+		yamlSymbols(code, add)
+		return symbols
+	case "toml":
+		inTable := false
+		for _, line := range strings.Split(code, "\n") {
+			if lineIndent(line) > 0 {
+				continue
+			}
+			if m := declRules["toml"][0].FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+				add(m[1])
+				inTable = true
+			} else if m = tomlKeyRe.FindStringSubmatch(line); m != nil && !inTable {
+				add(m[1])
+			}
+		}
+		return symbols
+	}
+	rules := declRules[language]
+	for _, line := range strings.Split(code, "\n") {
+		if symbol, ok := declSymbol(strings.TrimSpace(line), rules); ok {
+			add(symbol)
+		}
+	}
+	return symbols
+}
+
+// jsonSymbols 顶层的key；只有一个key且值是对象时（切分时包起来的外层key），继续列出内层的key
+func jsonSymbols(raw json.RawMessage, prefix string, add func(string)) {
+	keys, values, err := objectMembers(bytes.TrimSpace(raw))
+	if err != nil {
+		return
+	}
+	for _, key := range keys {
+		add(prefix + key)
+	}
+	if len(keys) == 1 {
+		jsonSymbols(values[0], prefix+keys[0]+".", add)
+	}
+}
+
+// yamlSymbols 顶层的key；只有一个顶层key时（切分时带上的外层key），列出缩进最小的一层子key
+func yamlSymbols(code string, add func(string)) {
+	var top []string
+	childIndent := -1
+	var children []string
+	for _, line := range strings.Split(code, "\n") {
+		symbol, ok := declSymbol(strings.TrimSpace(line), declRules["yaml"])
+		if !ok || strings.HasPrefix(strings.TrimSpace(line), "-") {
+			continue
+		}
+		indent := lineIndent(line)
+		switch {
+		case indent == 0:
+			top = append(top, symbol)
+		case childIndent < 0 || indent < childIndent:
+			childIndent = indent
+			children = []string{symbol}
+		case indent == childIndent:
+			children = append(children, symbol)
+		}
+	}
+	for _, key := range top {
+		add(key)
+	}
+	if len(top) == 1 {
+		for _, child := range children {
+			add(top[0] + "." + child)
+		}
+	}
+}
+
+func matchDecl(trimmed string, rules []*regexp.Regexp) bool {
+	_, ok := declSymbol(trimmed, rules)
+	return ok
+}
+
+func declSymbol(trimmed string, rules []*regexp.Regexp) (string, bool) {
+	if trimmed == "" || isComment(trimmed) && !strings.HasPrefix(trimmed, "#define") {
+		return "", false
+	}
+	if firstWord := strings.FieldsFunc(trimmed, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '('
+	}); len(firstWord) > 0 && nonDeclWords[firstWord[0]] {
+		return "", false
+	}
+	for _, rule := range rules {
+		if m := rule.FindStringSubmatch(trimmed); m != nil && m[1] != "" && !nonDeclWords[m[1]] {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+func isComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return false
+	}
+	for _, prefix := range commentPrefixes {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lineIndent 行首的缩进宽度，制表符算4个空格；空行返回-1
+func lineIndent(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return -1
+}
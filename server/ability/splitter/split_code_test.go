@@ -0,0 +1,167 @@
+// -------------------------------------------------
+// Package splitter
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package splitter
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const goSource = `package demo
+
+import "fmt"
+
+// Add 两数相加
+func Add(a, b int) int {
+	return a + b
+}
+
+type Counter struct {
+	n int
+}
+
+// Inc 计数加一
+func (c *Counter) Inc() {
+	c.n++
+	fmt.Println(c.n)
+}`
+
+func TestSplitCodeGo(t *testing.T) {
+	chunks := SplitCode(goSource, "go", 80)
+	if len(chunks) < 3 {
+		t.Fatalf("expect the code to be split at declarations, got %q", chunks)
+	}
+	for _, chunk := range chunks {
+		if len(chunk) > 80 {
+			t.Errorf("chunk longer than 80 bytes: %q", chunk)
+		}
+	}
+	// 注释和它后面的函数在同一块里
+	found := false
+	for _, chunk := range chunks {
+		if strings.Contains(chunk, "func (c *Counter) Inc()") {
+			found = strings.Contains(chunk, "// Inc 计数加一")
+		}
+	}
+	if !found {
+		t.Errorf("comment should stay with its function: %q", chunks)
+	}
+	if got := strings.Join(chunks, "\n\n"); strings.Count(got, "func ") != 2 {
+		t.Errorf("lost declarations: %q", got)
+	}
+
+	want := []string{"Add", "Counter", "Inc"}
+	if got := CodeSymbols(goSource, "go"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbols %v, want %v", got, want)
+	}
+}
+
+func TestSplitCodePythonNested(t *testing.T) {
+	code := `class Store:
+    def get(self, key):
+        return self.data[key]
+
+    def put(self, key, value):
+        self.data[key] = value
+
+    def delete(self, key):
+        del self.data[key]`
+	chunks := SplitCode(code, "python", 70)
+	if len(chunks) < 2 {
+		t.Fatalf("expect the class to be split at methods, got %q", chunks)
+	}
+	// 拆开的方法带上类的声明行，检索到时能看出属于哪个类
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk, "class Store:") {
+			t.Errorf("chunk without class header: %q", chunk)
+		}
+	}
+	want := []string{"Store", "get", "put", "delete"}
+	if got := CodeSymbols(code, "python"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbols %v, want %v", got, want)
+	}
+}
+
+func TestSplitCodeJSON(t *testing.T) {
+	code := `{
+  "server": {"host": "127.0.0.1", "port": 8080, "timeout": "30s"},
+  "database": {"dsn": "root:pass@tcp(localhost:3306)/gcnote", "max_open": 20}
+}`
+	chunks := SplitCode(code, "json", 120)
+	if len(chunks) != 2 {
+		t.Fatalf("expect one chunk per top-level key, got %q", chunks)
+	}
+	for _, chunk := range chunks {
+		if !json.Valid([]byte(chunk)) {
+			t.Errorf("chunk is not valid json: %q", chunk)
+		}
+	}
+	if !strings.Contains(chunks[1], `"database"`) {
+		t.Errorf("unexpected chunk %q", chunks[1])
+	}
+	want := []string{"server", "database"}
+	if got := CodeSymbols(code, "json"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbols %v, want %v", got, want)
+	}
+}
+
+func TestCodeSymbols(t *testing.T) {
+	cases := []struct {
+		language string
+		code     string
+		want     []string
+	}{
+		{"sql", "CREATE TABLE IF NOT EXISTS kb_file (id int);\nALTER TABLE `users` ADD age int;", []string{"kb_file", "users"}},
+		{"yaml", "server:\n  port: 8080\ndatabase:\n  dsn: x\n", []string{"server", "database"}},
+		{"toml", "title = \"demo\"\n\n[server]\nport = 8080\n", []string{"title", "server"}},
+		{"javascript", "export async function load() {}\nconst save = (x) => x\nif (x) {}\n", []string{"load", "save"}},
+	}
+	for _, c := range cases {
+		if got := CodeSymbols(c.code, c.language); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got symbols %v, want %v", c.language, got, c.want)
+		}
+	}
+}
+
+func TestSplitMarkdownCode(t *testing.T) {
+	markdown := "# demo.go\n\n```go\n" + strings.Repeat(goSource+"\n\n", 8) + "```\n"
+	chunks := SplitMarkdown(markdown, 128)
+	codeChunks := 0
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk, "```") {
+			continue
+		}
+		codeChunks++
+		if !strings.HasPrefix(chunk, "```go\n") || !strings.HasSuffix(chunk, "\n```") {
+			t.Errorf("code chunk is not fenced: %q", chunk)
+		}
+	}
+	if codeChunks < 2 {
+		t.Fatalf("expect the code block to be split, got %q", chunks)
+	}
+
+	docs := Chunk2Doc(chunks, "file", "index")
+	for _, doc := range docs {
+		if !strings.HasPrefix(doc.PageContent, "```") {
+			continue
+		}
+		if doc.Metadata["type"] != "CODE" || doc.Metadata["language"] != "go" {
+			t.Errorf("unexpected metadata %v", doc.Metadata)
+		}
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	cases := map[string]string{"py": "python", "Golang": "go", "yml": "yaml", `ts title="a.ts"`: "typescript", "": ""}
+	for info, want := range cases {
+		if got := NormalizeLanguage(info); got != want {
+			t.Errorf("NormalizeLanguage(%q) = %q, want %q", info, got, want)
+		}
+	}
+}
@@ -58,6 +58,10 @@ func SplitMarkdown(input string, maxTextLength int) []string {
 	for _, segment := range finalResult {
 		if isTextSegment(segment) && len(segment) > maxTextLength {
 			processedResult = append(processedResult, splitLargeText(segment, maxTextLength)...)
+		} else if strings.HasPrefix(segment, "|") && len(segment) > maxTextLength {
+			processedResult = append(processedResult, splitLargeTable(segment, maxTextLength)...)
+		} else if strings.HasPrefix(segment, "```") && len(segment) > maxTextLength*codeLengthFactor {
+			processedResult = append(processedResult, splitLargeCode(segment, maxTextLength*codeLengthFactor)...)
 		} else {
 			processedResult = append(processedResult, segment)
 		}
@@ -95,6 +99,76 @@ func splitLargeText(text string, maxTextLength int) []string {
 	return result
 }
 
+// splitLargeTable 按行切分过长的表格，每一块都重复表头和分隔行，保证每个chunk单独看也是完整的表格
+func splitLargeTable(table string, maxTextLength int) []string {
+	lines := strings.Split(table, "\n")
+	if len(lines) < 3 || !isTableSeparator(lines[1]) {
+		return []string{table}
+	}
+	header := lines[0] + "\n" + lines[1]
+	var result []string
+	current := header
+	rowCount := 0
+	for _, row := range lines[2:] {
+		// 单独一行就超长时也至少放一行，不再往下切
+		if rowCount > 0 && len(current)+len(row)+1 > maxTextLength {
+			result = append(result, current)
+			current = header
+			rowCount = 0
+		}
+		current += "\n" + row
+		rowCount++
+	}
+	if rowCount > 0 {
+		result = append(result, current)
+	}
+	return result
+}
+
+// codeLengthFactor 代码块按函数等边界切分，一个函数常常超过普通文本的长度限制，允许的长度是文本的4倍
+const codeLengthFactor = 4
+
+// splitLargeCode 按声明切分过长的代码块，每一块都重新用同样的围栏和语言包起来
+func splitLargeCode(block string, maxCodeLength int) []string {
+	fence, language, code, ok := parseFence(block)
+	if !ok {
+		return []string{block}
+	}
+	var result []string
+	for _, piece := range SplitCode(code, language, maxCodeLength) {
+		result = append(result, fence+language+"\n"+piece+"\n"+fence)
+	}
+	return result
+}
+
+// parseFence 拆出代码块的围栏、语言(已归一化，如py => python)和代码
+func parseFence(block string) (fence string, language string, code string, ok bool) {
+	firstLine, rest, found := strings.Cut(block, "\n")
+	if !found {
+		return "", "", "", false
+	}
+	fence = firstLine[:len(firstLine)-len(strings.TrimLeft(firstLine, "`"))]
+	language = NormalizeLanguage(strings.TrimSpace(firstLine[len(fence):]))
+	rest = strings.TrimRight(rest, " \t\n")
+	code = strings.TrimSuffix(rest, fence)
+	return fence, language, strings.Trim(code, "\n"), true
+}
+
+// isTableSeparator 形如 | --- | :---: | 的表头分隔行
+func isTableSeparator(line string) bool {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "|") {
+		return false
+	}
+	for _, cell := range strings.Split(strings.Trim(line, "|"), "|") {
+		cell = strings.TrimSpace(cell)
+		if strings.Trim(cell, ":-") != "" || !strings.Contains(cell, "-") {
+			return false
+		}
+	}
+	return true
+}
+
 // SplitMarkdownEasy 仅仅依靠规则切分，不考虑字数之类的东西，为了展示用的
 func SplitMarkdownEasy(input string) []string {
 	// Regular expressions for splitting
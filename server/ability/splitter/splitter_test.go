@@ -8,6 +8,7 @@ package splitter
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -43,3 +44,32 @@ func TestDemo3(t *testing.T) {
 	)
 	fmt.Println(x)
 }
+
+func TestSplitLargeTable(t *testing.T) {
+	table := "| 地区 | 金额 |\n| --- | --- |\n"
+	for i := 0; i < 20; i++ {
+		table += fmt.Sprintf("| 华东%d | %d |\n", i, i*100)
+	}
+	input := "# 销售\n\n" + table + "\n结尾"
+	chunks := SplitMarkdown(input, 100)
+	if chunks[0] != "# 销售" || chunks[len(chunks)-1] != "结尾" {
+		t.Fatalf("unexpected chunks: %q", chunks)
+	}
+	tableChunks := chunks[1 : len(chunks)-1]
+	if len(tableChunks) < 2 {
+		t.Fatalf("table should be split, got %q", tableChunks)
+	}
+	rows := 0
+	for _, chunk := range tableChunks {
+		if !strings.HasPrefix(chunk, "| 地区 | 金额 |\n| --- | --- |\n| ") {
+			t.Errorf("chunk does not repeat header: %q", chunk)
+		}
+		if len(chunk) > 100 {
+			t.Errorf("chunk too long (%d): %q", len(chunk), chunk)
+		}
+		rows += strings.Count(chunk, "\n") - 1
+	}
+	if rows != 20 {
+		t.Errorf("expected 20 data rows in total, got %d", rows)
+	}
+}
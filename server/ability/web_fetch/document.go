@@ -0,0 +1,118 @@
+// -------------------------------------------------
+// Package web_fetch
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package web_fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gcnote/server/router/wrench"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrUnsupportedType = errors.New("unsupported content type")
+
+// 网页返回的Content-Type对应的文件后缀，和convert.AutoConvert支持的格式一致
+var contentTypeSuffix = map[string]string{
+	"text/html":             ".html",
+	"application/xhtml+xml": ".html",
+	"text/plain":            ".txt",
+	"text/markdown":         ".md",
+	"text/csv":              ".csv",
+	"application/pdf":       ".pdf",
+	"application/epub+zip":  ".epub",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   ".docx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         ".xlsx",
+}
+
+// Document 抓取并保存到本地的文档
+type Document struct {
+	URL       string    // 跟随重定向后的最终地址，html中的相对路径以此为基准
+	Title     string    // 网页标题，没有时取url最后一段
+	Suffix    string    // 文件后缀，如 .html
+	Path      string    // 保存到本地的路径
+	FetchedAt time.Time // 抓取时间
+}
+
+// FetchDocument 下载rawURL并保存到dir下，按Content-Type判断文件格式
+func FetchDocument(ctx context.Context, rawURL string, opts Options, dir string) (*Document, error) {
+	resp, err := Get(ctx, rawURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.ContentType)
+	suffix, ok := contentTypeSuffix[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, resp.ContentType)
+	}
+
+	doc := &Document{URL: resp.URL, Suffix: suffix, FetchedAt: time.Now()}
+	if suffix == ".html" {
+		doc.Title = htmlTitle(resp.Data)
+	}
+	if doc.Title == "" {
+		doc.Title = urlName(resp.URL)
+	}
+	doc.Title = wrench.SanitizeKBName(doc.Title)
+
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	doc.Path = filepath.Join(dir, doc.Title+suffix)
+	if err = os.WriteFile(doc.Path, resp.Data, 0644); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func htmlTitle(data []byte) string {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	var title string
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Title {
+			if n.FirstChild != nil {
+				title = n.FirstChild.Data
+			}
+			return true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(doc)
+	return strings.TrimSpace(title)
+}
+
+// urlName url路径的最后一段（去掉后缀），没有路径时用域名
+func urlName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	name := strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	if name == "" || name == "." || name == "/" {
+		return u.Hostname()
+	}
+	return name
+}
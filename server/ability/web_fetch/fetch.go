@@ -0,0 +1,154 @@
+// -------------------------------------------------
+// Package web_fetch
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package web_fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrTooLarge         = errors.New("response too large")
+	ErrHostNotAllowed   = errors.New("host not allowed")
+	ErrPrivateAddress   = errors.New("private address not allowed")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// Options 服务端抓取网络资源的限制
+type Options struct {
+	Timeout      time.Duration // 整个请求的超时时间，<=0时使用默认值10秒
+	MaxSize      int64         // 响应体最大字节数，<=0时使用默认值5MB
+	MaxRedirects int           // 最多跟随的重定向次数，0表示不跟随重定向
+	AllowHosts   []string      // 允许访问的域名（含子域名），为空时不限制
+	BlockPrivate bool          // 禁止访问内网、回环等地址，防止通过服务端请求访问内部服务
+}
+
+// Response 抓取结果
+type Response struct {
+	URL         string // 跟随重定向后的最终地址
+	ContentType string // 服务端返回的Content-Type
+	Data        []byte
+}
+
+const (
+	defaultTimeout = 10 * time.Second
+	defaultMaxSize = 5 << 20
+)
+
+// Get 下载url对应的资源，只支持http和https
+func Get(ctx context.Context, rawURL string, opts Options) (*Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkURL(u, opts); err != nil {
+		return nil, err
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.BlockPrivate {
+		// 在建立连接时检查解析出来的ip，防止域名解析到内网地址（包括DNS重绑定）
+		// 走代理时检查的是代理的地址，所以不使用环境变量里的代理
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{
+			Timeout: timeout,
+			Control: func(network, address string, c syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || IsPrivateIP(ip) {
+					return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+				}
+				return nil
+			},
+		}).DialContext
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > opts.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return checkURL(req.URL, opts)
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "gcnote")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, ErrTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrTooLarge
+	}
+	return &Response{
+		URL:         resp.Request.URL.String(),
+		ContentType: resp.Header.Get("Content-Type"),
+		Data:        data,
+	}, nil
+}
+
+var cgnatNet = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPrivateIP 内网、回环、链路本地、组播、未指定地址以及运营商NAT地址
+func IsPrivateIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || cgnatNet.Contains(ip)
+}
+
+// checkURL 检查协议和域名白名单，重定向后的地址也要检查
+func checkURL(u *url.URL, opts Options) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if len(opts.AllowHosts) == 0 {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allow := range opts.AllowHosts {
+		allow = strings.ToLower(strings.TrimPrefix(allow, "."))
+		if host == allow || strings.HasSuffix(host, "."+allow) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+}
@@ -0,0 +1,124 @@
+// -------------------------------------------------
+// Package web_fetch
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package web_fetch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			_, _ = w.Write([]byte("hello"))
+		case "/big":
+			_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+		case "/r1":
+			http.Redirect(w, r, "/r2", http.StatusFound)
+		case "/r2":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	resp, err := Get(ctx, server.URL+"/r1", Options{MaxRedirects: 2})
+	if err != nil || string(resp.Data) != "hello" || resp.URL != server.URL+"/ok" {
+		t.Fatalf("unexpected result %+v %v", resp, err)
+	}
+	if _, err = Get(ctx, server.URL+"/r1", Options{MaxRedirects: 1}); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("got %v, want %v", err, ErrTooManyRedirects)
+	}
+	if _, err = Get(ctx, server.URL+"/big", Options{MaxSize: 10}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got %v, want %v", err, ErrTooLarge)
+	}
+	if _, err = Get(ctx, server.URL+"/missing", Options{}); err == nil {
+		t.Error("404 should be an error")
+	}
+	if _, err = Get(ctx, server.URL+"/ok", Options{AllowHosts: []string{"example.com"}}); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("got %v, want %v", err, ErrHostNotAllowed)
+	}
+	if _, err = Get(ctx, "file:///etc/passwd", Options{}); err == nil {
+		t.Error("file scheme should be rejected")
+	}
+}
+
+func TestGetBlockPrivate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	// httptest监听在127.0.0.1上
+	_, err := Get(context.Background(), server.URL, Options{BlockPrivate: true})
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("got %v, want %v", err, ErrPrivateAddress)
+	}
+	for ip, want := range map[string]bool{
+		"10.1.2.3": true, "192.168.0.1": true, "127.0.0.1": true, "169.254.169.254": true,
+		"100.64.0.1": true, "::1": true, "fd00::1": true, "0.0.0.0": true,
+		"8.8.8.8": false, "2001:4860:4860::8888": false,
+	} {
+		if got := IsPrivateIP(net.ParseIP(ip)); got != want {
+			t.Errorf("IsPrivateIP(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestFetchDocument(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(`<html><head><title> 发布说明 | v2.0 </title></head><body><p>hi</p></body></html>`))
+		case "/files/report.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write([]byte("%PDF-1.4"))
+		case "/old":
+			http.Redirect(w, r, "/post", http.StatusMovedPermanently)
+		default:
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte{0, 1, 2})
+		}
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	doc, err := FetchDocument(ctx, server.URL+"/old", Options{MaxRedirects: 1}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "发布说明 _ v2.0" || doc.Suffix != ".html" || doc.URL != server.URL+"/post" {
+		t.Errorf("unexpected document %+v", doc)
+	}
+	if data, _ := os.ReadFile(doc.Path); !strings.Contains(string(data), "<p>hi</p>") {
+		t.Errorf("page not saved to %s", doc.Path)
+	}
+
+	doc, err = FetchDocument(ctx, server.URL+"/files/report.pdf", Options{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "report" || filepath.Base(doc.Path) != "report.pdf" {
+		t.Errorf("unexpected document %+v", doc)
+	}
+
+	if _, err = FetchDocument(ctx, server.URL+"/bin", Options{}, dir); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v, want %v", err, ErrUnsupportedType)
+	}
+}
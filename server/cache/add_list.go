@@ -8,63 +8,241 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"gcnote/server/config"
-	"log"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"time"
 )
 
+// Redis key规范
+// s:task:info:{task_id} - 存储单个导入结果
+// s:task:list:{user_id} - 存储用户的导入结果id列表，新的在前
+
+// 用户最多保留的导入结果条数，更早的从列表中删掉，详情由过期时间清理
+const maxUserTasks = 1000
+
+// 默认保留7天
+const defaultTaskTTL = 7 * 24 * time.Hour
+
+var ErrTaskNotFound = errors.New("task not found")
+
+func taskInfoKey(taskId string) string {
+	return fmt.Sprintf("s:task:info:%v", taskId)
+}
+
+func taskListKey(userId string) string {
+	return fmt.Sprintf("s:task:list:%v", userId)
+}
+
+func taskTTL() time.Duration {
+	if hours := config.ServerCfg.JobConf.TaskTTL; hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultTaskTTL
+}
+
+// Task 一个文件的导入结果
 type Task struct {
+	TaskId         string `json:"taskId"`
+	JobId          string `json:"jobId"` // 所属的导入任务，压缩包等多文件任务会有多条结果
+	UserId         string `json:"userId"`
 	KbFileName     string `json:"kbFileName"`
+	KbFileId       string `json:"kbFileId"` // 导入成功后的文档id
 	TaskCreateTime string `json:"taskCreateTime"`
 	State          string `json:"state"`
 	Reason         string `json:"reason"`
+	Encoding       string `json:"encoding"` // 文本文件识别出的源编码，如GBK、Big5
+	Acked          bool   `json:"acked"`    // 用户是否已经看过
 }
 
 // EnqueueTask adds a new task to the specified user's queue.
 func EnqueueTask(ctx context.Context, userID string, task Task) (string, error) {
-	// Generate unique task ID
-	taskID := fmt.Sprintf("task:%s:%d", userID, time.Now().UnixNano())
-	rdb := config.RedisClient
-	// Add task details as a hash with the unique task ID
-	err := rdb.HSet(ctx, taskID, map[string]interface{}{
-		"kbFileName":     task.KbFileName,
-		"TaskCreateTime": task.TaskCreateTime,
-		"State":          task.State,
-		"Reason":         task.Reason,
-	}).Err()
+	task.TaskId = uuid.New().String()
+	task.UserId = userID
+	marshal, err := json.Marshal(task)
 	if err != nil {
 		return "", err
 	}
-
-	// Push the task ID into the user's list(queue)
-	err = rdb.LPush(ctx, userID, taskID).Err()
+	ttl := taskTTL()
+	rdb := config.RedisClient
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, taskInfoKey(task.TaskId), marshal, ttl)
+		// Push the task ID into the user's list(queue)
+		pipe.LPush(ctx, taskListKey(userID), task.TaskId)
+		pipe.LTrim(ctx, taskListKey(userID), 0, maxUserTasks-1)
+		pipe.Expire(ctx, taskListKey(userID), ttl)
+		return nil
+	})
 	if err != nil {
 		return "", err
 	}
+	return task.TaskId, nil
+}
 
-	return taskID, nil
+// ListTasks 分页获取用户的导入结果，新的在前，page从1开始；返回列表中的总条数
+func ListTasks(ctx context.Context, userID string, page int, pageSize int) ([]Task, int64, error) {
+	rdb := config.RedisClient
+	start := int64((page - 1) * pageSize)
+	taskIDs, err := rdb.LRange(ctx, taskListKey(userID), start, start+int64(pageSize)-1).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	tasks, err := getTasks(ctx, userID, taskIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := rdb.LLen(ctx, taskListKey(userID)).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	return tasks, total, nil
 }
 
 // DequeueAllTasks fetches all tasks from the specified user's queue and returns them.
-func DequeueAllTasks(ctx context.Context, userID string) ([]map[string]string, error) {
-	var tasks []map[string]string
+func DequeueAllTasks(ctx context.Context, userID string) ([]Task, error) {
+	taskIDs, err := config.RedisClient.LRange(ctx, taskListKey(userID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	return getTasks(ctx, userID, taskIDs)
+}
+
+// getTasks 获取详情，已经过期的从列表中删掉
+func getTasks(ctx context.Context, userID string, taskIDs []string) ([]Task, error) {
+	tasks := make([]Task, 0, len(taskIDs))
+	if len(taskIDs) == 0 {
+		return tasks, nil
+	}
+	keys := make([]string, len(taskIDs))
+	for i, taskID := range taskIDs {
+		keys[i] = taskInfoKey(taskID)
+	}
 	rdb := config.RedisClient
-	// Get all task IDs from the user's queue
-	taskIDs, err := rdb.LRange(ctx, userID, 0, -1).Result()
+	values, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			rdb.LRem(ctx, taskListKey(userID), 0, taskIDs[i])
+			continue
+		}
+		var task Task
+		if err = json.Unmarshal([]byte(str), &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// GetTask 获取用户的一条导入结果
+func GetTask(ctx context.Context, userID string, taskID string) (*Task, error) {
+	result, err := config.RedisClient.Get(ctx, taskInfoKey(taskID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTaskNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
+	var task Task
+	if err = json.Unmarshal([]byte(result), &task); err != nil {
+		return nil, err
+	}
+	if task.UserId != userID {
+		return nil, ErrTaskNotFound
+	}
+	return &task, nil
+}
 
+// AckTasks 标记为已读，返回实际标记的条数，不存在或者不属于该用户的忽略
+func AckTasks(ctx context.Context, userID string, taskIDs []string) (int, error) {
+	count := 0
 	for _, taskID := range taskIDs {
-		// Fetch the task details by its ID
-		vals, err := rdb.HGetAll(ctx, taskID).Result()
+		acked, err := ackTask(ctx, userID, taskID)
 		if err != nil {
-			log.Printf("Failed to get task details for taskID %s: %v", taskID, err)
+			return count, err
+		}
+		if acked {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// 标记已读时其他连接同时修改了这条结果，重新读取的次数
+const maxAckRetries = 3
+
+// ackTask 在WATCH事务中读取、修改一条结果：期间被其他连接修改时重新读取；
+// 写入使用XX并保留原来的过期时间，期间已经过期的不会被重新创建
+func ackTask(ctx context.Context, userID string, taskID string) (bool, error) {
+	key := taskInfoKey(taskID)
+	for i := 0; i < maxAckRetries; i++ {
+		acked := false
+		err := config.RedisClient.Watch(ctx, func(tx *redis.Tx) error {
+			result, err := tx.Get(ctx, key).Result()
+			if errors.Is(err, redis.Nil) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			var task Task
+			if err = json.Unmarshal([]byte(result), &task); err != nil {
+				return err
+			}
+			if task.UserId != userID || task.Acked {
+				return nil
+			}
+			task.Acked = true
+			marshal, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.SetArgs(ctx, key, marshal, redis.SetArgs{Mode: "XX", KeepTTL: true})
+				return nil
+			})
+			if errors.Is(err, redis.Nil) {
+				// 已经过期删除
+				return nil
+			}
+			acked = err == nil
+			return err
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
 			continue
 		}
-		tasks = append(tasks, vals)
+		return acked, err
 	}
+	return false, redis.TxFailedErr
+}
 
-	return tasks, nil
+// ClearTasks 清空用户的导入结果，ackedOnly为true时只删除已读的，返回删除的条数
+func ClearTasks(ctx context.Context, userID string, ackedOnly bool) (int, error) {
+	tasks, err := DequeueAllTasks(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	rdb := config.RedisClient
+	count := 0
+	for _, task := range tasks {
+		if ackedOnly && !task.Acked {
+			continue
+		}
+		_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, taskInfoKey(task.TaskId))
+			pipe.LRem(ctx, taskListKey(userID), 0, task.TaskId)
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
 }
@@ -25,3 +25,44 @@ func TestDequeue(t *testing.T) {
 	}
 	fmt.Println(tasks)
 }
+
+func TestTaskAckAndClear(t *testing.T) {
+	server.InitConfig()
+	server.InitRedis()
+
+	var ctx = context.Background()
+	userId := "test-task-user"
+	if _, err := ClearTasks(ctx, userId, false); err != nil {
+		t.Fatal(err)
+	}
+	first, err := EnqueueTask(ctx, userId, Task{KbFileName: "a", State: "success", KbFileId: "kb-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = EnqueueTask(ctx, userId, Task{KbFileName: "b", State: "fail", Reason: "unsupported file type: .exe"}); err != nil {
+		t.Fatal(err)
+	}
+	tasks, total, err := ListTasks(ctx, userId, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(tasks) != 1 || tasks[0].KbFileName != "b" {
+		t.Fatalf("unexpected page %v, total %d", tasks, total)
+	}
+	if _, err = GetTask(ctx, "other-user", first); err != ErrTaskNotFound {
+		t.Errorf("other user's task: got %v", err)
+	}
+	if count, err := AckTasks(ctx, userId, []string{first, "missing"}); err != nil || count != 1 {
+		t.Fatalf("ack count %d, err %v", count, err)
+	}
+	if count, err := ClearTasks(ctx, userId, true); err != nil || count != 1 {
+		t.Fatalf("clear acked count %d, err %v", count, err)
+	}
+	tasks, err = DequeueAllTasks(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Acked {
+		t.Errorf("unexpected tasks after clear %v", tasks)
+	}
+}
@@ -0,0 +1,65 @@
+// -------------------------------------------------
+// Package cache
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"gcnote/server/config"
+	"gcnote/server/model"
+	"strings"
+)
+
+// 一致性检查时读取所有info缓存，每次SCAN的数量
+const scanCount = 500
+
+// ScanIndexInfo 读取所有缓存的index信息，key为index_id
+func ScanIndexInfo(ctx context.Context) (map[string]model.Index, error) {
+	return scanInfo[model.Index](ctx, indexInfoKey(""))
+}
+
+// ScanKBInfo 读取所有缓存的kb文件信息，key为kb_file_id
+func ScanKBInfo(ctx context.Context) (map[string]model.KBFile, error) {
+	return scanInfo[model.KBFile](ctx, kbInfoKey(""))
+}
+
+// ScanRecycleInfo 读取所有缓存的回收站文件信息，key为kb_file_id
+func ScanRecycleInfo(ctx context.Context) (map[string]model.Recycle, error) {
+	return scanInfo[model.Recycle](ctx, recycleInfoKey(""))
+}
+
+// scanInfo 按前缀读取info缓存，无法解析的缓存返回零值，由调用方当作过期的缓存处理
+func scanInfo[T any](ctx context.Context, prefix string) (map[string]T, error) {
+	rdb := config.RedisClient
+	result := map[string]T{}
+	var cursor uint64
+	for {
+		keys, next, err := rdb.Scan(ctx, cursor, prefix+"*", scanCount).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) > 0 {
+			values, err := rdb.MGet(ctx, keys...).Result()
+			if err != nil {
+				return nil, err
+			}
+			for i, value := range values {
+				str, ok := value.(string)
+				if !ok {
+					continue
+				}
+				var item T
+				_ = json.Unmarshal([]byte(str), &item)
+				result[strings.TrimPrefix(keys[i], prefix)] = item
+			}
+		}
+		if next == 0 {
+			return result, nil
+		}
+		cursor = next
+	}
+}
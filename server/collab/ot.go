@@ -0,0 +1,277 @@
+// -------------------------------------------------
+// Package collab
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gcnote/server/ability/differ"
+	"unicode/utf8"
+)
+
+var ErrBaseLength = errors.New("operation does not match the document length")
+
+// Component 修改的一部分，三个字段只有一个不为零值
+type Component struct {
+	Retain int    // 保留的字符数
+	Delete int    // 删除的字符数
+	Insert string // 插入的内容
+}
+
+// MarshalJSON 和ot.js的格式相同：正数为保留，负数为删除，字符串为插入
+func (c Component) MarshalJSON() ([]byte, error) {
+	switch {
+	case c.Insert != "":
+		return json.Marshal(c.Insert)
+	case c.Delete > 0:
+		return json.Marshal(-c.Delete)
+	}
+	return json.Marshal(c.Retain)
+}
+
+func (c *Component) UnmarshalJSON(data []byte) error {
+	var insert string
+	if err := json.Unmarshal(data, &insert); err == nil {
+		if insert == "" {
+			return errors.New("empty insert")
+		}
+		*c = Component{Insert: insert}
+		return nil
+	}
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid component %s", data)
+	}
+	switch {
+	case n > 0:
+		*c = Component{Retain: n}
+	case n < 0:
+		*c = Component{Delete: -n}
+	default:
+		return errors.New("empty retain")
+	}
+	return nil
+}
+
+// Op 对文档的一次修改，依次由保留、删除、插入组成，覆盖修改前的整个文档。
+// 位置和长度按Unicode字符(rune)计算，前端需要把UTF-16的位置换算过来
+type Op []Component
+
+func (op *Op) retain(n int) {
+	if n <= 0 {
+		return
+	}
+	if last := len(*op) - 1; last >= 0 && (*op)[last].Retain > 0 {
+		(*op)[last].Retain += n
+		return
+	}
+	*op = append(*op, Component{Retain: n})
+}
+
+func (op *Op) delete(n int) {
+	if n <= 0 {
+		return
+	}
+	if last := len(*op) - 1; last >= 0 && (*op)[last].Delete > 0 {
+		(*op)[last].Delete += n
+		return
+	}
+	*op = append(*op, Component{Delete: n})
+}
+
+// insert 连续的删除和插入统一为先插入后删除，同样的修改只有一种表示
+func (op *Op) insert(s string) {
+	if s == "" {
+		return
+	}
+	o := *op
+	last := len(o) - 1
+	switch {
+	case last >= 0 && o[last].Insert != "":
+		o[last].Insert += s
+	case last >= 0 && o[last].Delete > 0:
+		if last > 0 && o[last-1].Insert != "" {
+			o[last-1].Insert += s
+			return
+		}
+		*op = append(o, o[last])
+		(*op)[last] = Component{Insert: s}
+	default:
+		*op = append(o, Component{Insert: s})
+	}
+}
+
+// BaseLen 修改前文档的长度
+func (op Op) BaseLen() int {
+	n := 0
+	for _, c := range op {
+		n += c.Retain + c.Delete
+	}
+	return n
+}
+
+// TargetLen 修改后文档的长度
+func (op Op) TargetLen() int {
+	n := 0
+	for _, c := range op {
+		n += c.Retain + utf8.RuneCountInString(c.Insert)
+	}
+	return n
+}
+
+// Validate 检查每一部分只有一个字段不为零值
+func (op Op) Validate() error {
+	for _, c := range op {
+		set := 0
+		if c.Retain != 0 {
+			set++
+		}
+		if c.Delete != 0 {
+			set++
+		}
+		if c.Insert != "" {
+			set++
+		}
+		if set != 1 || c.Retain < 0 || c.Delete < 0 {
+			return fmt.Errorf("invalid component %+v", c)
+		}
+	}
+	return nil
+}
+
+// Apply 把修改应用到文档上
+func (op Op) Apply(doc []rune) ([]rune, error) {
+	if err := op.Validate(); err != nil {
+		return nil, err
+	}
+	if op.BaseLen() != len(doc) {
+		return nil, ErrBaseLength
+	}
+	out := make([]rune, 0, op.TargetLen())
+	pos := 0
+	for _, c := range op {
+		switch {
+		case c.Retain > 0:
+			out = append(out, doc[pos:pos+c.Retain]...)
+			pos += c.Retain
+		case c.Delete > 0:
+			pos += c.Delete
+		default:
+			out = append(out, []rune(c.Insert)...)
+		}
+	}
+	return out, nil
+}
+
+// Transform 变换两个基于同一个文档的并发修改：apply(apply(doc, a), b1) == apply(apply(doc, b), a1)。
+// 在同一位置插入时a的内容在前，和ot.js一致：服务器以客户端的修改为a
+func Transform(a Op, b Op) (Op, Op, error) {
+	if err := a.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := b.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if a.BaseLen() != b.BaseLen() {
+		return nil, nil, ErrBaseLength
+	}
+	var a1, b1 Op
+	i, j := 0, 0
+	next := func(op Op, k *int) (Component, bool) {
+		if *k < len(op) {
+			*k++
+			return op[*k-1], true
+		}
+		return Component{}, false
+	}
+	x, hasX := next(a, &i)
+	y, hasY := next(b, &j)
+	for hasX || hasY {
+		if hasX && x.Insert != "" {
+			a1.insert(x.Insert)
+			b1.retain(utf8.RuneCountInString(x.Insert))
+			x, hasX = next(a, &i)
+			continue
+		}
+		if hasY && y.Insert != "" {
+			a1.retain(utf8.RuneCountInString(y.Insert))
+			b1.insert(y.Insert)
+			y, hasY = next(b, &j)
+			continue
+		}
+		if !hasX || !hasY {
+			return nil, nil, ErrBaseLength
+		}
+		switch {
+		case x.Retain > 0 && y.Retain > 0:
+			n := min(x.Retain, y.Retain)
+			a1.retain(n)
+			b1.retain(n)
+			x.Retain -= n
+			y.Retain -= n
+		case x.Delete > 0 && y.Delete > 0:
+			// 两边删除了相同的内容
+			n := min(x.Delete, y.Delete)
+			x.Delete -= n
+			y.Delete -= n
+		case x.Delete > 0:
+			n := min(x.Delete, y.Retain)
+			a1.delete(n)
+			x.Delete -= n
+			y.Retain -= n
+		default:
+			n := min(x.Retain, y.Delete)
+			b1.delete(n)
+			x.Retain -= n
+			y.Delete -= n
+		}
+		if x.Retain == 0 && x.Delete == 0 {
+			x, hasX = next(a, &i)
+		}
+		if y.Retain == 0 && y.Delete == 0 {
+			y, hasY = next(b, &j)
+		}
+	}
+	return a1, b1, nil
+}
+
+// TransformIndex 修改之后光标的位置：光标前的插入和删除使光标移动，在光标处插入时光标移到插入的内容之后
+func TransformIndex(index int, op Op) int {
+	newIndex, pos := index, 0
+	for _, c := range op {
+		if pos > index {
+			break
+		}
+		switch {
+		case c.Retain > 0:
+			pos += c.Retain
+		case c.Delete > 0:
+			newIndex -= min(c.Delete, index-pos)
+			pos += c.Delete
+		default:
+			newIndex += utf8.RuneCountInString(c.Insert)
+		}
+	}
+	return newIndex
+}
+
+// FromDiff 由两段文本的差异生成修改，用来合并其他途径的保存
+func FromDiff(old string, new string) Op {
+	var op Op
+	for _, segment := range differ.WordDiff(old, new) {
+		switch segment.Op {
+		case differ.Equal:
+			op.retain(utf8.RuneCountInString(segment.Text))
+		case differ.Delete:
+			op.delete(utf8.RuneCountInString(segment.Text))
+		case differ.Insert:
+			op.insert(segment.Text)
+		}
+	}
+	return op
+}
@@ -0,0 +1,114 @@
+// -------------------------------------------------
+// Package collab
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package collab
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomOp 随机生成基于doc的修改
+func randomOp(r *rand.Rand, doc []rune) Op {
+	var op Op
+	pos := 0
+	for pos < len(doc) {
+		n := 1 + r.Intn(len(doc)-pos)
+		switch r.Intn(3) {
+		case 0:
+			op.retain(n)
+		case 1:
+			op.delete(n)
+		default:
+			op.insert(string([]rune("ab你好\n")[r.Intn(5)]))
+			continue
+		}
+		pos += n
+	}
+	if r.Intn(2) == 0 {
+		op.insert("末尾")
+	}
+	return op
+}
+
+func TestTransform(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		doc := []rune("协同编辑 collab\n第二行")
+		a, b := randomOp(r, doc), randomOp(r, doc)
+		a1, b1, err := Transform(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		docA, _ := a.Apply(doc)
+		docB, _ := b.Apply(doc)
+		left, err := b1.Apply(docA)
+		if err != nil {
+			t.Fatalf("apply b1: %v, a %v b %v", err, a, b)
+		}
+		right, err := a1.Apply(docB)
+		if err != nil {
+			t.Fatalf("apply a1: %v, a %v b %v", err, a, b)
+		}
+		if string(left) != string(right) {
+			t.Fatalf("diverged: %q != %q, a %v b %v", string(left), string(right), a, b)
+		}
+	}
+}
+
+func TestTransformInsertOrder(t *testing.T) {
+	a := Op{{Retain: 1}, {Insert: "A"}, {Retain: 1}}
+	b := Op{{Retain: 1}, {Insert: "B"}, {Retain: 1}}
+	a1, _, err := Transform(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docB, _ := b.Apply([]rune("xy"))
+	got, _ := a1.Apply(docB)
+	if string(got) != "xABy" {
+		t.Errorf("got %q", string(got))
+	}
+}
+
+func TestOpJSON(t *testing.T) {
+	var op Op
+	if err := json.Unmarshal([]byte(`[3,"你好",-2,1]`), &op); err != nil {
+		t.Fatal(err)
+	}
+	want := Op{{Retain: 3}, {Insert: "你好"}, {Delete: 2}, {Retain: 1}}
+	if !reflect.DeepEqual(op, want) {
+		t.Errorf("got %+v", op)
+	}
+	data, _ := json.Marshal(op)
+	if string(data) != `[3,"你好",-2,1]` {
+		t.Errorf("got %s", data)
+	}
+	if err := json.Unmarshal([]byte(`[0]`), &op); err == nil {
+		t.Error("expected error for empty retain")
+	}
+	if _, err := (Op{{Retain: 5}}).Apply([]rune("abc")); err == nil {
+		t.Error("expected length error")
+	}
+}
+
+func TestTransformIndex(t *testing.T) {
+	op := Op{{Retain: 2}, {Insert: "xx"}, {Retain: 2}, {Delete: 3}, {Retain: 3}}
+	for index, want := range map[int]int{0: 0, 2: 4, 4: 6, 5: 6, 7: 6, 10: 9} {
+		if got := TransformIndex(index, op); got != want {
+			t.Errorf("TransformIndex(%d) = %d, want %d", index, got, want)
+		}
+	}
+}
+
+func TestFromDiff(t *testing.T) {
+	old, new := "今天天气很好\nhello world\n", "今天天气不好\nhello go world\n"
+	got, err := FromDiff(old, new).Apply([]rune(old))
+	if err != nil || string(got) != new {
+		t.Errorf("got %q, %v", string(got), err)
+	}
+}
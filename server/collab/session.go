@@ -0,0 +1,523 @@
+// -------------------------------------------------
+// Package collab
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gcnote/server/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// 协同编辑：同一个文档的所有连接共享一个Session，服务器保存文档的内容和修改历史。
+// 客户端带着它所基于的版本号提交修改，服务器按之后的修改变换、应用后广播给其他客户端（和ot.js的服务器相同）。
+// 内容定期写入md文件，编辑停止一段时间或者所有人离开后记录版本并重新索引。
+// Session只在当前进程内，多个实例各自的Session通过写入md文件时的合并保持一致
+
+// 消息类型
+const (
+	MsgInit   = "init"   // 服务器 => 新连接：当前的版本、内容和其他连接
+	MsgOp     = "op"     // 客户端 => 服务器：基于Revision的修改；服务器 => 客户端：其他人的修改，Revision为应用之后的版本
+	MsgAck    = "ack"    // 服务器 => 客户端：修改已经应用，Revision为应用之后的版本
+	MsgCursor = "cursor" // 客户端 => 服务器：基于Revision的光标，为空表示离开编辑器；服务器 => 客户端：其他人的光标
+	MsgJoin   = "join"
+	MsgLeave  = "leave"
+	MsgError  = "error" // 修改无法应用时客户端需要重新连接
+	MsgPing   = "ping"
+)
+
+// Cursor 光标和选区，位置按Unicode字符计算
+type Cursor struct {
+	Anchor int `json:"anchor"`
+	Head   int `json:"head"`
+}
+
+func (c *Cursor) transform(op Op) *Cursor {
+	if c == nil {
+		return nil
+	}
+	return &Cursor{Anchor: TransformIndex(c.Anchor, op), Head: TransformIndex(c.Head, op)}
+}
+
+// Presence 一个连接的用户和光标
+type Presence struct {
+	ClientId string  `json:"client_id"`
+	UserId   string  `json:"user_id"`
+	Cursor   *Cursor `json:"cursor,omitempty"`
+}
+
+// Message websocket上收发的消息
+type Message struct {
+	Type     string     `json:"type"`
+	Revision int        `json:"revision"`
+	Op       Op         `json:"op,omitempty"`
+	Content  string     `json:"content,omitempty"`
+	ClientId string     `json:"client_id,omitempty"`
+	UserId   string     `json:"user_id,omitempty"`
+	Cursor   *Cursor    `json:"cursor,omitempty"`
+	Clients  []Presence `json:"clients,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+var (
+	ErrRevision      = errors.New("revision is out of range, reconnect to resync")
+	ErrDocumentGone  = errors.New("document has been deleted")
+	ErrAccessRevoked = errors.New("access to the knowledge base has been revoked")
+	// errRebase 已经写入md文件的合并无法应用到最新的版本，Session的内容和md文件不再对应
+	errRebase = errors.New("failed to merge the saved content, reconnect to resync")
+)
+
+// Store 文档的读写，由kb_apis实现
+type Store interface {
+	// Load 开始编辑时读取文档的内容
+	Load(kbFile *model.KBFile) (string, error)
+	// Read 读取md文件当前的内容，文档已经删除时返回ErrDocumentGone
+	Read(kbFile *model.KBFile) (string, error)
+	// Write 锁住文档，读取md文件当前的内容交给update，写入它返回的内容
+	Write(kbFile *model.KBFile, update func(current string) (string, error)) error
+	// Save 记录版本并重新索引，content必须是md文件当前的内容
+	Save(kbFile *model.KBFile, content string, author string) error
+}
+
+const (
+	SnapshotInterval = 5 * time.Second  // 写入md文件的间隔
+	IdleTimeout      = 30 * time.Second // 没有修改这么久之后记录版本并重新索引
+	maxHistory       = 1000             // 保留的修改历史，基于更早版本的修改需要重新连接
+	sendBuffer       = 256              // 每个连接待发送的消息数，发送不过来时断开
+)
+
+// Client 一个websocket连接
+type Client struct {
+	Id     string
+	UserId string
+	cursor *Cursor
+	send   chan Message
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Send 待发送的消息
+func (c *Client) Send() <-chan Message {
+	return c.send
+}
+
+// Done 连接需要关闭：离开、发送不过来或者文档已经删除
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// push 不阻塞地发送，缓冲区满时断开连接
+func (c *Client) push(msg Message) {
+	select {
+	case c.send <- msg:
+	default:
+		zap.S().Warnf("Collab client %s is too slow, disconnect", c.Id)
+		c.close()
+	}
+}
+
+// Hub 所有正在编辑的文档
+type Hub struct {
+	store    Store
+	mu       sync.Mutex
+	sessions map[string]*Session
+	loading  map[string]*loadCall // 正在读取内容的文档，同一个文档只读取一次
+}
+
+// loadCall 一次正在进行的Load，结束后关闭done
+type loadCall struct {
+	done chan struct{}
+	err  error
+}
+
+func NewHub(store Store) *Hub {
+	return &Hub{store: store, sessions: map[string]*Session{}, loading: map[string]*loadCall{}}
+}
+
+// Session 一个正在编辑的文档
+type Session struct {
+	hub    *Hub
+	kbFile model.KBFile
+
+	mu           sync.Mutex
+	doc          []rune
+	revision     int
+	history      []Op // history[i]把版本historyStart+i变为下一个版本
+	historyStart int
+	clients      map[string]*Client
+	closed       bool
+	saved        string // 最后一次写入md文件的内容
+	savedRev     int
+	savedTail    []Op // 依次应用到saved上得到版本savedRev的内容，写入期间有新的修改时不为空
+	lastEdit     time.Time
+	lastAuthor   string
+	indexed      bool // 写入的内容已经记录版本并重新索引
+
+	flushMu sync.Mutex // 写入md文件和重新索引依次执行
+	stop    chan struct{}
+}
+
+// Join 加入文档的编辑，没有Session时从md文件读取内容。返回的Client已经有一条init消息。
+// 读取在hub的锁之外进行，不影响其他文档；同一个文档同时加入的连接等待同一次读取
+func (h *Hub) Join(kbFile *model.KBFile, userId string) (*Session, *Client, error) {
+	for {
+		h.mu.Lock()
+		if s, ok := h.sessions[kbFile.KBFileId]; ok {
+			c := s.join(userId)
+			h.mu.Unlock()
+			return s, c, nil
+		}
+		if call, ok := h.loading[kbFile.KBFileId]; ok {
+			h.mu.Unlock()
+			<-call.done
+			if call.err != nil {
+				return nil, nil, call.err
+			}
+			// Session可能在等待期间已经结束，重新查找
+			continue
+		}
+		call := &loadCall{done: make(chan struct{})}
+		h.loading[kbFile.KBFileId] = call
+		h.mu.Unlock()
+
+		content, err := h.store.Load(kbFile)
+
+		h.mu.Lock()
+		delete(h.loading, kbFile.KBFileId)
+		call.err = err
+		close(call.done)
+		if err != nil {
+			h.mu.Unlock()
+			return nil, nil, err
+		}
+		s := &Session{
+			hub:     h,
+			kbFile:  *kbFile,
+			doc:     []rune(content),
+			clients: map[string]*Client{},
+			saved:   content,
+			indexed: true,
+			stop:    make(chan struct{}),
+		}
+		h.sessions[kbFile.KBFileId] = s
+		go s.run()
+		c := s.join(userId)
+		h.mu.Unlock()
+		return s, c, nil
+	}
+}
+
+// Kick 断开用户在知识库中所有文档上的连接，用于移除知识库成员
+func (h *Hub) Kick(indexId string, userId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.sessions {
+		if s.kbFile.IndexId != indexId {
+			continue
+		}
+		s.mu.Lock()
+		for _, c := range s.clients {
+			if c.UserId == userId {
+				c.push(Message{Type: MsgError, Revision: s.revision, Error: ErrAccessRevoked.Error()})
+				c.close()
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// join 添加连接，调用时持有hub的锁，Session不会同时结束
+func (s *Session) join(userId string) *Client {
+	c := &Client{Id: uuid.NewString(), UserId: userId, send: make(chan Message, sendBuffer), done: make(chan struct{})}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	presence := make([]Presence, 0, len(s.clients))
+	for _, other := range s.clients {
+		presence = append(presence, Presence{ClientId: other.Id, UserId: other.UserId, Cursor: other.cursor})
+	}
+	s.clients[c.Id] = c
+	c.push(Message{Type: MsgInit, Revision: s.revision, Content: string(s.doc), ClientId: c.Id, UserId: userId,
+		Clients: presence})
+	s.broadcast(Message{Type: MsgJoin, ClientId: c.Id, UserId: userId}, c)
+	return c
+}
+
+// Leave 连接断开；最后一个连接离开时写入md文件、重新索引，结束Session
+func (s *Session) Leave(c *Client) {
+	c.close()
+	s.hub.mu.Lock()
+	s.mu.Lock()
+	if _, ok := s.clients[c.Id]; !ok {
+		s.mu.Unlock()
+		s.hub.mu.Unlock()
+		return
+	}
+	delete(s.clients, c.Id)
+	s.broadcast(Message{Type: MsgLeave, ClientId: c.Id, UserId: c.UserId}, nil)
+	last := len(s.clients) == 0 && !s.closed
+	if last {
+		s.closed = true
+		close(s.stop)
+		// 之后加入的连接使用新的Session，从md文件读取，写入时合并这里最后写入的内容
+		if s.hub.sessions[s.kbFile.KBFileId] == s {
+			delete(s.hub.sessions, s.kbFile.KBFileId)
+		}
+	}
+	s.mu.Unlock()
+	s.hub.mu.Unlock()
+	if last {
+		s.flush(true)
+	}
+}
+
+// ReceiveJSON 处理websocket收到的一条消息
+func (s *Session) ReceiveJSON(c *Client, data []byte) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		s.mu.Lock()
+		c.push(Message{Type: MsgError, Revision: s.revision, Error: "invalid message: " + err.Error()})
+		s.mu.Unlock()
+		return
+	}
+	s.Receive(c, msg)
+}
+
+// Receive 处理客户端的消息
+func (s *Session) Receive(c *Client, msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	switch msg.Type {
+	case MsgOp:
+		op, err := s.transformFrom(msg.Revision, msg.Op)
+		var doc []rune
+		if err == nil {
+			doc, err = op.Apply(s.doc)
+		}
+		if err != nil {
+			c.push(Message{Type: MsgError, Revision: s.revision, Error: err.Error()})
+			return
+		}
+		s.commit(op, doc, c.UserId)
+		c.push(Message{Type: MsgAck, Revision: s.revision})
+		s.broadcast(Message{Type: MsgOp, Revision: s.revision, Op: op, ClientId: c.Id, UserId: c.UserId}, c)
+	case MsgCursor:
+		cursor, err := s.transformCursor(msg.Revision, msg.Cursor)
+		if err != nil {
+			c.push(Message{Type: MsgError, Revision: s.revision, Error: err.Error()})
+			return
+		}
+		c.cursor = cursor
+		s.broadcast(Message{Type: MsgCursor, Revision: s.revision, ClientId: c.Id, UserId: c.UserId, Cursor: cursor}, c)
+	case MsgPing:
+	default:
+		c.push(Message{Type: MsgError, Revision: s.revision, Error: fmt.Sprintf("unknown message type %q", msg.Type)})
+	}
+}
+
+// broadcast 发送给除了except之外的所有连接
+func (s *Session) broadcast(msg Message, except *Client) {
+	for _, c := range s.clients {
+		if c != except {
+			c.push(msg)
+		}
+	}
+}
+
+// transformFrom 把基于revision的修改变换为基于当前版本的修改
+func (s *Session) transformFrom(revision int, op Op) (Op, error) {
+	if revision < s.historyStart || revision > s.revision {
+		return nil, ErrRevision
+	}
+	for _, h := range s.history[revision-s.historyStart:] {
+		var err error
+		if op, _, err = Transform(op, h); err != nil {
+			return nil, err
+		}
+	}
+	return op, nil
+}
+
+func (s *Session) transformCursor(revision int, cursor *Cursor) (*Cursor, error) {
+	if revision < s.historyStart || revision > s.revision {
+		return nil, ErrRevision
+	}
+	for _, h := range s.history[revision-s.historyStart:] {
+		cursor = cursor.transform(h)
+	}
+	if cursor != nil {
+		cursor.Anchor = min(max(cursor.Anchor, 0), len(s.doc))
+		cursor.Head = min(max(cursor.Head, 0), len(s.doc))
+	}
+	return cursor, nil
+}
+
+// commit 应用已经变换到当前版本的修改
+func (s *Session) commit(op Op, doc []rune, author string) {
+	s.doc = doc
+	s.history = append(s.history, op)
+	s.revision++
+	s.lastEdit = time.Now()
+	s.indexed = false
+	if author != "" {
+		s.lastAuthor = author
+	}
+	for _, c := range s.clients {
+		c.cursor = c.cursor.transform(op)
+	}
+	// 历史太长时丢掉较早的部分，但要保留最后一次写入之后的修改，用于合并其他途径的保存
+	if len(s.history) > 2*maxHistory {
+		keep := min(max(s.revision-maxHistory, s.historyStart), s.savedRev)
+		s.history = append([]Op(nil), s.history[keep-s.historyStart:]...)
+		s.historyStart = keep
+	}
+}
+
+func (s *Session) run() {
+	ticker := time.NewTicker(SnapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.flush(false)
+		}
+	}
+}
+
+// flush 内容有变化时写入md文件；编辑停止超过IdleTimeout或者final时记录版本并重新索引
+func (s *Session) flush(final bool) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	if err := s.snapshot(); err != nil {
+		if errors.Is(err, ErrDocumentGone) || errors.Is(err, errRebase) {
+			s.terminate(err)
+			return
+		}
+		zap.S().Errorf("Failed to snapshot collab document %s: %v", s.kbFile.KBFileId, err)
+		return
+	}
+
+	s.mu.Lock()
+	index := !s.indexed && string(s.doc) == s.saved && (final || time.Since(s.lastEdit) >= IdleTimeout)
+	content, author := s.saved, s.lastAuthor
+	s.mu.Unlock()
+	if !index {
+		return
+	}
+	if author == "" {
+		author = s.kbFile.UserId
+	}
+	if err := s.hub.store.Save(&s.kbFile, content, author); err != nil {
+		// md文件被其他途径修改时，下次写入合并之后再保存
+		zap.S().Errorf("Failed to save collab document %s: %v", s.kbFile.KBFileId, err)
+		return
+	}
+	s.mu.Lock()
+	if s.saved == content {
+		s.indexed = true
+	}
+	s.mu.Unlock()
+}
+
+// snapshot 把内容写入md文件。md文件在上次写入之后被其他途径（update_file、恢复版本）修改过时，
+// 把这部分修改当作基于上次写入的内容的修改合并进来。
+// 在锁内复制当前的状态，写入时不持有锁，编辑可以继续；写入之后在锁内把合并的修改变换到最新的版本
+func (s *Session) snapshot() error {
+	current, err := s.hub.store.Read(&s.kbFile)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if current == s.saved && string(s.doc) == s.saved {
+		s.mu.Unlock()
+		return nil
+	}
+	base, doc, saved := s.revision, s.doc, s.saved
+	// doc不会被原地修改，只需要复制修改历史
+	pending := append(append([]Op(nil), s.savedTail...), s.history[s.savedRev-s.historyStart:base-s.historyStart]...)
+	s.mu.Unlock()
+
+	var merged Op
+	var content string
+	err = s.hub.store.Write(&s.kbFile, func(current string) (string, error) {
+		merged, content = nil, string(doc)
+		if current == saved {
+			return content, nil
+		}
+		op := FromDiff(saved, current)
+		for _, h := range pending {
+			var err error
+			if op, _, err = Transform(op, h); err != nil {
+				return "", err
+			}
+		}
+		merged = op
+		result, err := op.Apply(doc)
+		if err != nil {
+			return "", err
+		}
+		content = string(result)
+		return content, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if merged == nil {
+		s.saved, s.savedRev, s.savedTail = content, base, nil
+		return nil
+	}
+	// 写入的内容是版本base加上merged，之后的修改变换到写入的内容上作为savedTail
+	tail := make([]Op, 0, s.revision-base)
+	for _, h := range s.history[base-s.historyStart:] {
+		if merged, h, err = Transform(merged, h); err != nil {
+			return fmt.Errorf("%w: %v", errRebase, err)
+		}
+		tail = append(tail, h)
+	}
+	result, err := merged.Apply(s.doc)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRebase, err)
+	}
+	s.commit(merged, result, "")
+	s.broadcast(Message{Type: MsgOp, Revision: s.revision, Op: merged}, nil)
+	s.saved, s.savedRev, s.savedTail = content, s.revision, tail
+	return nil
+}
+
+// terminate 文档已经不能编辑，结束Session，断开所有连接
+func (s *Session) terminate(err error) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hub.sessions[s.kbFile.KBFileId] == s {
+		delete(s.hub.sessions, s.kbFile.KBFileId)
+	}
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+	for _, c := range s.clients {
+		c.push(Message{Type: MsgError, Revision: s.revision, Error: err.Error()})
+		c.close()
+	}
+}
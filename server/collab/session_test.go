@@ -0,0 +1,319 @@
+// -------------------------------------------------
+// Package collab
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package collab
+
+import (
+	"gcnote/server/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStore 内存中的md文件
+type fakeStore struct {
+	mu          sync.Mutex
+	content     string
+	saved       []string // Save的内容
+	gone        bool
+	loads       map[string]int           // 每个文档Load的次数
+	loadGate    map[string]chan struct{} // 读取这些文档时等待通道关闭
+	beforeWrite func()                   // 在Write开始时调用
+}
+
+func (f *fakeStore) Load(kbFile *model.KBFile) (string, error) {
+	f.mu.Lock()
+	if f.loads == nil {
+		f.loads = map[string]int{}
+	}
+	f.loads[kbFile.KBFileId]++
+	gate := f.loadGate[kbFile.KBFileId]
+	f.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	return f.Read(nil)
+}
+
+func (f *fakeStore) Read(*model.KBFile) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.gone {
+		return "", ErrDocumentGone
+	}
+	return f.content, nil
+}
+
+func (f *fakeStore) Write(_ *model.KBFile, update func(current string) (string, error)) error {
+	if f.beforeWrite != nil {
+		f.beforeWrite()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	content, err := update(f.content)
+	if err != nil {
+		return err
+	}
+	f.content = content
+	return nil
+}
+
+func (f *fakeStore) Save(_ *model.KBFile, content string, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.saved = append(f.saved, content)
+	return nil
+}
+
+// drain 取出已经收到的消息
+func drain(c *Client) []Message {
+	var msgs []Message
+	for {
+		select {
+		case msg := <-c.Send():
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	store := &fakeStore{content: "hello\n"}
+	hub := NewHub(store)
+	kbFile := &model.KBFile{KBFileId: "k1", UserId: "u1"}
+	s, a, err := hub.Join(kbFile, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, b, err := hub.Join(kbFile, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs := drain(a); len(msgs) != 2 || msgs[0].Type != MsgInit || msgs[1].Type != MsgJoin {
+		t.Fatalf("a got %+v", msgs)
+	}
+	if msgs := drain(b); len(msgs) != 1 || msgs[0].Content != "hello\n" || len(msgs[0].Clients) != 1 {
+		t.Fatalf("b got %+v", msgs)
+	}
+
+	// 两个客户端基于版本0并发修改
+	s.Receive(a, Message{Type: MsgOp, Revision: 0, Op: Op{{Insert: "A "}, {Retain: 6}}})
+	s.Receive(b, Message{Type: MsgOp, Revision: 0, Op: Op{{Retain: 5}, {Insert: " B"}, {Retain: 1}}})
+	if string(s.doc) != "A hello B\n" || s.revision != 2 {
+		t.Fatalf("doc %q revision %d", string(s.doc), s.revision)
+	}
+	msgs := drain(b)
+	if len(msgs) != 2 || msgs[0].Type != MsgOp || msgs[1].Type != MsgAck || msgs[1].Revision != 2 {
+		t.Fatalf("b got %+v", msgs)
+	}
+
+	// 光标随修改移动
+	s.Receive(a, Message{Type: MsgCursor, Revision: 1, Cursor: &Cursor{Anchor: 7, Head: 7}})
+	if msgs = drain(b); len(msgs) != 1 || *msgs[0].Cursor != (Cursor{Anchor: 9, Head: 9}) {
+		t.Fatalf("b got %+v", msgs)
+	}
+	if msgs = drain(a); len(msgs) != 2 || msgs[0].Type != MsgAck || msgs[1].Type != MsgOp {
+		t.Fatalf("a got %+v", msgs)
+	}
+	s.Receive(a, Message{Type: MsgOp, Revision: 9, Op: Op{{Retain: 10}}})
+	if msgs = drain(a); len(msgs) != 1 || msgs[0].Type != MsgError {
+		t.Fatalf("a got %+v", msgs)
+	}
+
+	// 写入md文件时合并其他途径的保存
+	s.flush(false)
+	if store.content != "A hello B\n" {
+		t.Fatalf("content %q", store.content)
+	}
+	store.content = "A hello B\nupdated\n"
+	s.Receive(b, Message{Type: MsgOp, Revision: 2, Op: Op{{Delete: 2}, {Retain: 8}}})
+	s.flush(false)
+	if store.content != "hello B\nupdated\n" || string(s.doc) != store.content {
+		t.Fatalf("content %q doc %q", store.content, string(s.doc))
+	}
+
+	// 最后一个连接离开时保存
+	s.Leave(a)
+	s.Leave(b)
+	if len(store.saved) != 1 || store.saved[0] != store.content {
+		t.Fatalf("saved %q", store.saved)
+	}
+	if len(hub.sessions) != 0 {
+		t.Error("session not removed")
+	}
+}
+
+func TestSessionDocumentGone(t *testing.T) {
+	store := &fakeStore{content: "x"}
+	hub := NewHub(store)
+	s, c, err := hub.Join(&model.KBFile{KBFileId: "k1"}, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.gone = true
+	s.flush(false)
+	select {
+	case <-c.Done():
+	default:
+		t.Error("client not closed")
+	}
+	if len(hub.sessions) != 0 {
+		t.Error("session not removed")
+	}
+	s.Leave(c)
+}
+
+func TestReceiveJSON(t *testing.T) {
+	hub := NewHub(&fakeStore{content: "ab"})
+	s, c, err := hub.Join(&model.KBFile{KBFileId: "k1"}, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(c)
+	s.ReceiveJSON(c, []byte(`{"type":"op","revision":0,"op":[1,"中",1]}`))
+	s.ReceiveJSON(c, []byte(`{"type":"op","revision":1,"op":[0]}`))
+	msgs := drain(c)
+	if string(s.doc) != "a中b" || len(msgs) != 2 || msgs[0].Type != MsgAck || msgs[1].Type != MsgError {
+		t.Errorf("doc %q, got %+v", string(s.doc), msgs)
+	}
+	s.Leave(c)
+}
+
+func TestJoinLoadsOutsideHubLock(t *testing.T) {
+	gate := make(chan struct{})
+	store := &fakeStore{content: "x", loadGate: map[string]chan struct{}{"k1": gate}}
+	hub := NewHub(store)
+
+	// 两个连接同时加入k1，读取被阻塞
+	type joined struct {
+		s   *Session
+		c   *Client
+		err error
+	}
+	results := make(chan joined, 2)
+	for range 2 {
+		go func() {
+			s, c, err := hub.Join(&model.KBFile{KBFileId: "k1"}, "u1")
+			results <- joined{s, c, err}
+		}()
+	}
+
+	// 其他文档不受影响
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s, c, err := hub.Join(&model.KBFile{KBFileId: "k2"}, "u2")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.Leave(c)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("join of k2 is blocked by the load of k1")
+	}
+
+	close(gate)
+	first, second := <-results, <-results
+	if first.err != nil || second.err != nil {
+		t.Fatal(first.err, second.err)
+	}
+	if first.s != second.s || store.loads["k1"] != 1 {
+		t.Errorf("same session expected, k1 loaded %d times", store.loads["k1"])
+	}
+	first.s.Leave(first.c)
+	second.s.Leave(second.c)
+}
+
+func TestSnapshotDoesNotBlockEdits(t *testing.T) {
+	store := &fakeStore{content: "hello\n"}
+	hub := NewHub(store)
+	s, c, err := hub.Join(&model.KBFile{KBFileId: "k1"}, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Receive(c, Message{Type: MsgOp, Revision: 0, Op: Op{{Insert: "A "}, {Retain: 6}}})
+	store.content = "hello\nupdated\n"
+
+	// 写入md文件期间收到修改
+	writing, release := make(chan struct{}), make(chan struct{})
+	store.beforeWrite = func() {
+		close(writing)
+		<-release
+	}
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		s.flush(false)
+	}()
+	<-writing
+	store.beforeWrite = nil
+	received := make(chan struct{})
+	go func() {
+		defer close(received)
+		s.Receive(c, Message{Type: MsgOp, Revision: 1, Op: Op{{Retain: 8}, {Insert: "!"}}})
+	}()
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("receive is blocked by the snapshot")
+	}
+	close(release)
+	<-flushed
+
+	// 写入的是版本1合并其他途径的修改，之后的修改变换到合并之后
+	if store.content != "A hello\nupdated\n" || string(s.doc) != "A hello\nupdated\n!" {
+		t.Fatalf("content %q doc %q", store.content, string(s.doc))
+	}
+
+	// 下次写入前md文件又被修改，基于上次写入的内容合并
+	store.content = "A hello\nupdated\nmore\n"
+	s.flush(false)
+	if store.content != "A hello\nupdated\nmore\n!" || string(s.doc) != store.content {
+		t.Fatalf("content %q doc %q", store.content, string(s.doc))
+	}
+	s.Leave(c)
+}
+
+func TestHubKick(t *testing.T) {
+	hub := NewHub(&fakeStore{content: "x"})
+	s, owner, err := hub.Join(&model.KBFile{KBFileId: "k1", IndexId: "i1"}, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, member, err := hub.Join(&model.KBFile{KBFileId: "k1", IndexId: "i1"}, "u2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, elsewhere, err := hub.Join(&model.KBFile{KBFileId: "k2", IndexId: "i2"}, "u2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(member)
+
+	hub.Kick("i1", "u2")
+	select {
+	case <-member.Done():
+	default:
+		t.Fatal("member not closed")
+	}
+	if msgs := drain(member); len(msgs) != 1 || msgs[0].Error != ErrAccessRevoked.Error() {
+		t.Errorf("member got %+v", msgs)
+	}
+	for _, c := range []*Client{owner, elsewhere} {
+		select {
+		case <-c.Done():
+			t.Errorf("client %s of %s should stay connected", c.Id, c.UserId)
+		default:
+		}
+	}
+	s.Leave(member)
+	s.Leave(owner)
+	other.Leave(elsewhere)
+}
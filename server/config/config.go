@@ -18,10 +18,15 @@ type ServerConfig struct {
 	Host        string              `mapstructure:"host" json:"host"`                   // 主机地址
 	Port        int                 `mapstructure:"port" json:"port"`                   // 启动端口
 	Mode        string              `mapstructure:"mode" json:"mode"`                   // 启动端口
+	AdminUsers  []string            `mapstructure:"admin_users" json:"admin_users"`     // 管理员的user_id，可以调用/admin下的接口
+	Origins     []string            `mapstructure:"allow_origins" json:"allow_origins"` // 允许的前端地址，用于CORS和websocket的Origin检查
 	RedisConf   redisConfig         `mapstructure:"redis" json:"redis"`                 // Redis配置
 	MysqlConf   mysqlConfig         `mapstructure:"mysql" json:"mysql"`                 // Mysql配置
 	LogConf     logsConfig          `mapstructure:"logs" json:"logs"`                   // 日志配置
 	ElasticConf elasticSearchConfig `mapstructure:"elasticsearch" json:"elasticsearch"` // es的配置
+	ConvertConf convertConfig       `mapstructure:"convert" json:"convert"`             // 文档转换配置
+	ImportConf  importConfig        `mapstructure:"import" json:"import"`               // 导入配置
+	JobConf     jobConfig           `mapstructure:"job" json:"job"`                     // 导入任务队列配置
 }
 
 type redisConfig struct {
@@ -55,6 +60,53 @@ type elasticSearchConfig struct {
 	UseCert  bool   `mapstructure:"use_cert" json:"use_cert"`   // 是否使用许可证
 }
 
+type convertConfig struct {
+	PdfBackend string `mapstructure:"pdf_backend" json:"pdf_backend"` // pdf转换后端 go(默认)、python
+	PythonExe  string `mapstructure:"python_exe" json:"python_exe"`   // python解释器路径，为空时读取环境变量GC_NOTE_PYTHON_EXE
+	// 表格(xlsx、csv)导入
+	SheetMaxRows int    `mapstructure:"sheet_max_rows" json:"sheet_max_rows"` // 每个sheet最多导入的行数，0表示默认值1000
+	SheetHeader  string `mapstructure:"sheet_header" json:"sheet_header"`     // 表头识别 auto(默认)、first_row、none
+	// html导入
+	HtmlReadability       bool     `mapstructure:"html_readability" json:"html_readability"`                 // 只提取正文，去掉导航、页脚、脚本等
+	HtmlImageMaxSize      int64    `mapstructure:"html_image_max_size" json:"html_image_max_size"`           // 单张图片最大字节数，0表示默认值5MB
+	HtmlImageTypes        []string `mapstructure:"html_image_types" json:"html_image_types"`                 // 允许保存的图片类型，为空时为png、jpeg、gif、webp
+	HtmlImageHosts        []string `mapstructure:"html_image_hosts" json:"html_image_hosts"`                 // 允许下载图片的域名，为空时不限制
+	HtmlImageTimeout      int      `mapstructure:"html_image_timeout" json:"html_image_timeout"`             // 下载单张图片的超时时间(秒)，0表示默认值10秒
+	HtmlImageBlockPrivate bool     `mapstructure:"html_image_block_private" json:"html_image_block_private"` // 禁止从内网地址下载图片
+}
+
+type importConfig struct {
+	// 从url导入
+	UrlTimeout      int      `mapstructure:"url_timeout" json:"url_timeout"`             // 抓取网页的超时时间(秒)
+	UrlMaxSize      int64    `mapstructure:"url_max_size" json:"url_max_size"`           // 网页最大字节数
+	UrlMaxRedirects int      `mapstructure:"url_max_redirects" json:"url_max_redirects"` // 最多跟随的重定向次数
+	UrlBlockPrivate bool     `mapstructure:"url_block_private" json:"url_block_private"` // 禁止访问内网地址
+	UrlAllowHosts   []string `mapstructure:"url_allow_hosts" json:"url_allow_hosts"`     // 允许访问的域名，为空时不限制
+	// 批量导入压缩包
+	ArchiveMaxFiles int   `mapstructure:"archive_max_files" json:"archive_max_files"` // 压缩包内最多的文件数，0表示默认值1000
+	ArchiveMaxSize  int64 `mapstructure:"archive_max_size" json:"archive_max_size"`   // 解压后的总字节数，0表示默认值512MB
+}
+
+type jobConfig struct {
+	Workers     int `mapstructure:"workers" json:"workers"`           // 同时执行的导入任务数，0表示默认值2
+	MaxAttempts int `mapstructure:"max_attempts" json:"max_attempts"` // 每个任务最多执行的次数，0表示默认值3
+	BackoffBase int `mapstructure:"backoff_base" json:"backoff_base"` // 第一次重试的等待时间(秒)，之后每次翻倍，0表示默认值10秒
+	BackoffMax  int `mapstructure:"backoff_max" json:"backoff_max"`   // 重试等待时间的上限(秒)，0表示默认值300秒
+	Lease       int `mapstructure:"lease" json:"lease"`               // 执行中任务的租约(秒)，实例崩溃后超过这个时间任务被重新执行，0表示默认值60秒
+	TaskTTL     int `mapstructure:"task_ttl" json:"task_ttl"`         // 导入结果保留的时间(小时)，0表示默认值168小时
+}
+
+// defaultOrigins 没有配置allow_origins时允许的前端地址
+var defaultOrigins = []string{"http://localhost:8080", "http://localhost:8090"}
+
+// AllowOrigins 允许的前端地址，没有配置时为本地开发的地址
+func (c ServerConfig) AllowOrigins() []string {
+	if len(c.Origins) == 0 {
+		return defaultOrigins
+	}
+	return c.Origins
+}
+
 var ServerCfg ServerConfig
 var DB *gorm.DB
 var RedisClient redis.UniversalClient
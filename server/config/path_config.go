@@ -33,6 +33,7 @@ type PathConfig struct {
 	JwtPublicKeyPath  string
 	KnowledgeBasePath string
 	RecycleBinPath    string
+	RevisionPath      string // 文档历史版本的内容，按内容的hash保存
 	TempDirPath       string
 	ImageServerURL    string
 }
@@ -44,6 +45,7 @@ func Constructor() PathConfig {
 	jwtPublicKeyPath := filepath.Join(baseProjectPath, "server/router/middleware/public.key")
 	knowledgeBasePath := filepath.Join(baseProjectPath, "data/local/knowledge_base")
 	recycleBinPath := filepath.Join(baseProjectPath, "data/local/recycle_bin")
+	revisionPath := filepath.Join(baseProjectPath, "data/local/revisions")
 	tempFilePath := filepath.Join(baseProjectPath, "data/tmp")
 	imageServerURL := "http://localhost:8086/images"
 
@@ -54,6 +56,7 @@ func Constructor() PathConfig {
 		JwtPrivateKeyPath: jwtPrivateKeyPath,
 		KnowledgeBasePath: knowledgeBasePath,
 		RecycleBinPath:    recycleBinPath,
+		RevisionPath:      revisionPath,
 		TempDirPath:       tempFilePath,
 		ImageServerURL:    imageServerURL,
 	}
@@ -0,0 +1,249 @@
+// -------------------------------------------------
+// Package consistency
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package consistency
+
+import (
+	"fmt"
+	"gcnote/server/ability/search_engine"
+	"gcnote/server/model"
+	"sort"
+	"time"
+)
+
+// 问题类型
+const (
+	IndexDirMissing     = "index_dir_missing"      // 有知识库记录，没有目录
+	IndexDirOrphan      = "index_dir_orphan"       // 有目录，没有知识库记录
+	ESIndexMissing      = "es_index_missing"       // 有知识库记录，没有es索引
+	ESIndexOrphan       = "es_index_orphan"        // 有es索引，没有知识库记录
+	KBFileIndexMissing  = "kb_file_index_missing"  // 文档记录所属的知识库不存在
+	KBFileMissing       = "kb_file_missing"        // 有文档记录，没有目录或者md文件
+	KBFileDirOrphan     = "kb_file_dir_orphan"     // 有文档目录，没有文档记录
+	KBFileChunksMissing = "kb_file_chunks_missing" // 文档不为空，es中没有切片
+	ESChunksOrphan      = "es_chunks_orphan"       // es中有切片，没有文档记录
+	RecycleDirMissing   = "recycle_dir_missing"    // 有回收站记录，没有目录
+	RecycleDirOrphan    = "recycle_dir_orphan"     // 回收站中有目录，没有记录
+	CacheStale          = "cache_stale"            // 缓存的记录已经不存在或者和数据库不一致
+	LeftoverDir         = "leftover_dir"           // 删除时移开、没有清理掉的目录
+)
+
+// Kinds 所有问题类型
+var Kinds = []string{
+	IndexDirMissing, IndexDirOrphan, ESIndexMissing, ESIndexOrphan, KBFileIndexMissing, KBFileMissing,
+	KBFileDirOrphan, KBFileChunksMissing, ESChunksOrphan, RecycleDirMissing, RecycleDirOrphan, CacheStale, LeftoverDir,
+}
+
+// 修复方式
+const (
+	RepairCreateDir     = "create_dir"
+	RepairRemoveDir     = "remove_dir"
+	RepairCreateESIndex = "create_es_index"
+	RepairDeleteESIndex = "delete_es_index"
+	RepairDeleteRecord  = "delete_record" // 删除文档或回收站记录，同时删除元数据和es切片
+	RepairReingest      = "reingest"      // 按md文件重新切分写入es
+	RepairDeleteChunks  = "delete_chunks"
+	RepairDeleteCache   = "delete_cache"
+)
+
+// Issue 一个不一致的地方
+type Issue struct {
+	Kind     string `json:"kind"`
+	UserId   string `json:"userId,omitempty"`
+	IndexId  string `json:"indexId,omitempty"`
+	KBFileId string `json:"kbFileId,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Cache    string `json:"cache,omitempty"` // 过期的缓存：index、kb、recycle
+	Detail   string `json:"detail"`
+	Repair   string `json:"repair"`
+	Repaired bool   `json:"repaired"`
+	Error    string `json:"error,omitempty"` // 修复失败的原因
+}
+
+// OrphanGrace 修改时间不到这么久的目录、写入时间不到这么久的切片可能属于正在执行的写操作（数据库在最后提交），
+// 不算孤立的数据
+const OrphanGrace = 10 * time.Minute
+
+// Dir 知识库、回收站下的目录
+type Dir struct {
+	ModTime   time.Time
+	Children  map[string]*Dir  // 知识库目录下的文档目录，key为kb_file_id
+	Markdowns map[string]int64 // 文档目录下的md文件，key为文件名（不含扩展名），value为文件大小
+}
+
+// Snapshot 各个存储中的数据
+type Snapshot struct {
+	Indexes  []model.Index
+	KBFiles  []model.KBFile
+	Recycles []model.Recycle
+
+	Time         time.Time            // 读取的时间
+	KBDirs       map[string]*Dir      // 知识库目录，key为index_id
+	RecycleDirs  map[string]*Dir      // 回收站中按知识库分的目录，key为index_id
+	LeftoverDirs map[string]time.Time // 删除时移开的目录，value为移开的时间
+
+	ESIndexes map[string]time.Time                           // 存在的es索引，key为index_id，value为创建时间
+	ESChunks  map[string]map[string]search_engine.FileChunks // index_id -> kb_file_id -> 切片
+
+	CachedIndexes  map[string]model.Index
+	CachedKBFiles  map[string]model.KBFile
+	CachedRecycles map[string]model.Recycle
+}
+
+// Check 交叉对比各个存储，返回发现的问题，按类型和id排序
+func Check(snap *Snapshot) []Issue {
+	var issues []Issue
+	add := func(issue Issue) {
+		issues = append(issues, issue)
+	}
+
+	indexes := make(map[string]model.Index, len(snap.Indexes))
+	for _, index := range snap.Indexes {
+		indexes[index.IndexId] = index
+		if _, ok := snap.KBDirs[index.IndexId]; !ok {
+			add(Issue{Kind: IndexDirMissing, UserId: index.UserId, IndexId: index.IndexId,
+				Detail: "知识库目录不存在", Repair: RepairCreateDir})
+		}
+		if _, ok := snap.ESIndexes[index.IndexId]; !ok {
+			add(Issue{Kind: ESIndexMissing, UserId: index.UserId, IndexId: index.IndexId,
+				Detail: "es索引不存在", Repair: RepairCreateESIndex})
+		}
+	}
+	orphan := func(modTime time.Time) bool {
+		return snap.Time.Sub(modTime) >= OrphanGrace
+	}
+	for indexId, dir := range snap.KBDirs {
+		if _, ok := indexes[indexId]; !ok && orphan(dir.ModTime) {
+			add(Issue{Kind: IndexDirOrphan, IndexId: indexId, Detail: "知识库目录没有对应的记录", Repair: RepairRemoveDir})
+		}
+	}
+	// 新建知识库时es索引在记录提交之前创建，刚创建的索引可能还没有记录
+	for indexId, createdAt := range snap.ESIndexes {
+		if _, ok := indexes[indexId]; !ok && orphan(createdAt) {
+			add(Issue{Kind: ESIndexOrphan, IndexId: indexId, Detail: "es索引没有对应的知识库", Repair: RepairDeleteESIndex})
+		}
+	}
+
+	kbFiles := make(map[string]model.KBFile, len(snap.KBFiles))
+	for _, kbFile := range snap.KBFiles {
+		kbFiles[kbFile.KBFileId] = kbFile
+		issue := Issue{UserId: kbFile.UserId, IndexId: kbFile.IndexId, KBFileId: kbFile.KBFileId}
+		if _, ok := indexes[kbFile.IndexId]; !ok {
+			issue.Kind, issue.Detail, issue.Repair = KBFileIndexMissing, "文档所属的知识库不存在", RepairDeleteRecord
+			add(issue)
+			continue
+		}
+		var size int64
+		hasMd := false
+		if indexDir, ok := snap.KBDirs[kbFile.IndexId]; ok {
+			if dir, ok := indexDir.Children[kbFile.KBFileId]; ok {
+				size, hasMd = dir.Markdowns[kbFile.KBFileName]
+			}
+		}
+		if !hasMd {
+			issue.Kind, issue.Detail, issue.Repair = KBFileMissing, "文档目录或md文件不存在", RepairDeleteRecord
+			add(issue)
+			continue
+		}
+		// 空文档没有切片；es索引不存在时先重建索引，再重新导入
+		if size > 0 && snap.ESChunks[kbFile.IndexId][kbFile.KBFileId].Count == 0 {
+			issue.Kind, issue.Detail, issue.Repair = KBFileChunksMissing, "es中没有文档的切片", RepairReingest
+			add(issue)
+		}
+	}
+	for indexId, indexDir := range snap.KBDirs {
+		if _, ok := indexes[indexId]; !ok {
+			continue // 整个知识库目录作为孤立目录处理
+		}
+		for kbFileId, dir := range indexDir.Children {
+			if kbFile, ok := kbFiles[kbFileId]; (!ok || kbFile.IndexId != indexId) && orphan(dir.ModTime) {
+				add(Issue{Kind: KBFileDirOrphan, IndexId: indexId, KBFileId: kbFileId,
+					Detail: "文档目录没有对应的记录", Repair: RepairRemoveDir})
+			}
+		}
+	}
+	for indexId, chunks := range snap.ESChunks {
+		if _, ok := indexes[indexId]; !ok {
+			continue // 整个es索引作为孤立索引处理
+		}
+		for kbFileId, fileChunks := range chunks {
+			// 导入、恢复时先写es，最后才提交数据库
+			if kbFile, ok := kbFiles[kbFileId]; (!ok || kbFile.IndexId != indexId) && orphan(fileChunks.IndexedAt) {
+				add(Issue{Kind: ESChunksOrphan, IndexId: indexId, KBFileId: kbFileId,
+					Detail: fmt.Sprintf("es中有%d个切片没有对应的文档", fileChunks.Count), Repair: RepairDeleteChunks})
+			}
+		}
+	}
+
+	recycles := make(map[string]model.Recycle, len(snap.Recycles))
+	for _, recycle := range snap.Recycles {
+		recycles[recycle.KBFileId] = recycle
+		hasDir := false
+		if indexDir, ok := snap.RecycleDirs[recycle.SourceIndexId]; ok {
+			_, hasDir = indexDir.Children[recycle.KBFileId]
+		}
+		if !hasDir {
+			add(Issue{Kind: RecycleDirMissing, UserId: recycle.UserId, IndexId: recycle.SourceIndexId,
+				KBFileId: recycle.KBFileId, Detail: "回收站目录不存在", Repair: RepairDeleteRecord})
+		}
+	}
+	for indexId, indexDir := range snap.RecycleDirs {
+		for kbFileId, dir := range indexDir.Children {
+			if recycle, ok := recycles[kbFileId]; (!ok || recycle.SourceIndexId != indexId) && orphan(dir.ModTime) {
+				add(Issue{Kind: RecycleDirOrphan, IndexId: indexId, KBFileId: kbFileId,
+					Detail: "回收站目录没有对应的记录", Repair: RepairRemoveDir})
+			}
+		}
+	}
+
+	for path, modTime := range snap.LeftoverDirs {
+		if !orphan(modTime) {
+			continue // 可能是还没有Complete的删除
+		}
+		add(Issue{Kind: LeftoverDir, Path: path, Detail: "删除时移开的目录没有清理", Repair: RepairRemoveDir})
+	}
+
+	// 缓存只检查会被直接读取的info，列表缓存在修复时刷新
+	for indexId, cached := range snap.CachedIndexes {
+		index, ok := indexes[indexId]
+		if !ok || index.UserId != cached.UserId || index.IndexName != cached.IndexName {
+			add(Issue{Kind: CacheStale, IndexId: indexId, Cache: "index",
+				Detail: "知识库缓存和数据库不一致", Repair: RepairDeleteCache})
+		}
+	}
+	for kbFileId, cached := range snap.CachedKBFiles {
+		kbFile, ok := kbFiles[kbFileId]
+		if !ok || kbFile.IndexId != cached.IndexId || kbFile.KBFileName != cached.KBFileName {
+			add(Issue{Kind: CacheStale, IndexId: cached.IndexId, KBFileId: kbFileId, Cache: "kb",
+				Detail: "文档缓存和数据库不一致", Repair: RepairDeleteCache})
+		}
+	}
+	for kbFileId, cached := range snap.CachedRecycles {
+		recycle, ok := recycles[kbFileId]
+		if !ok || recycle.SourceIndexId != cached.SourceIndexId || recycle.KBFileName != cached.KBFileName {
+			add(Issue{Kind: CacheStale, IndexId: cached.SourceIndexId, KBFileId: kbFileId, Cache: "recycle",
+				Detail: "回收站缓存和数据库不一致", Repair: RepairDeleteCache})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.IndexId != b.IndexId {
+			return a.IndexId < b.IndexId
+		}
+		if a.KBFileId != b.KBFileId {
+			return a.KBFileId < b.KBFileId
+		}
+		if a.Cache != b.Cache {
+			return a.Cache < b.Cache
+		}
+		return a.Path < b.Path
+	})
+	return issues
+}
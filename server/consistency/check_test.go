@@ -0,0 +1,123 @@
+// -------------------------------------------------
+// Package consistency
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package consistency
+
+import (
+	"gcnote/server/ability/search_engine"
+	"gcnote/server/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	fileDir := func(modTime time.Time, markdowns map[string]int64) *Dir {
+		return &Dir{ModTime: modTime, Markdowns: markdowns}
+	}
+	snap := &Snapshot{
+		Time: now,
+		Indexes: []model.Index{
+			{IndexId: "i1", IndexName: "笔记", UserId: "u1"},
+			{IndexId: "i2", IndexName: "空", UserId: "u1"},
+		},
+		KBFiles: []model.KBFile{
+			{KBFileId: "ok", IndexId: "i1", KBFileName: "a", UserId: "u1"},
+			{KBFileId: "empty", IndexId: "i1", KBFileName: "b", UserId: "u1"},
+			{KBFileId: "no_chunks", IndexId: "i1", KBFileName: "c", UserId: "u1"},
+			{KBFileId: "no_md", IndexId: "i1", KBFileName: "d", UserId: "u1"},
+			{KBFileId: "no_index", IndexId: "gone", KBFileName: "e", UserId: "u1"},
+		},
+		Recycles: []model.Recycle{
+			{KBFileId: "r1", SourceIndexId: "i1", KBFileName: "f", UserId: "u1"},
+			{KBFileId: "r2", SourceIndexId: "i1", KBFileName: "g", UserId: "u1"},
+		},
+		KBDirs: map[string]*Dir{
+			"i1": {ModTime: old, Children: map[string]*Dir{
+				"ok":        fileDir(old, map[string]int64{"a": 10}),
+				"empty":     fileDir(old, map[string]int64{"b": 0}),
+				"no_chunks": fileDir(old, map[string]int64{"c": 10}),
+				"no_md":     fileDir(old, map[string]int64{"renamed": 10}),
+				"stray":     fileDir(old, nil),
+				"creating":  fileDir(now.Add(-time.Minute), nil), // 正在创建，还没有提交
+			}},
+			"orphan": {ModTime: old, Children: map[string]*Dir{"x": fileDir(old, nil)}},
+		},
+		RecycleDirs: map[string]*Dir{
+			"i1": {ModTime: old, Children: map[string]*Dir{
+				"r1":    fileDir(old, map[string]int64{"f": 10}),
+				"stray": fileDir(old, nil),
+			}},
+		},
+		LeftoverDirs: map[string]time.Time{
+			"/kb/i1/.del.removing":      old,
+			"/kb/i1/.deleting.removing": now.Add(-time.Minute), // 删除还没有Complete
+		},
+		ESIndexes: map[string]time.Time{
+			"i1":       old,
+			"orphan":   old,
+			"creating": now.Add(-time.Minute), // 知识库记录还没有写入
+		},
+		ESChunks: map[string]map[string]search_engine.FileChunks{
+			"i1": {
+				"ok":        {Count: 3, IndexedAt: old},
+				"deleted":   {Count: 2}, // 没有记录写入时间的旧切片
+				"stale":     {Count: 1, IndexedAt: old},
+				"importing": {Count: 4, IndexedAt: now.Add(-time.Minute)}, // 正在导入，还没有提交
+			},
+			"orphan": {"x": {Count: 1, IndexedAt: old}},
+		},
+		CachedIndexes: map[string]model.Index{"i1": {IndexId: "i1", IndexName: "旧名称", UserId: "u1"}},
+		CachedKBFiles: map[string]model.KBFile{
+			"ok":      {KBFileId: "ok", IndexId: "i1", KBFileName: "a"},
+			"deleted": {KBFileId: "deleted", IndexId: "i1", KBFileName: "z"},
+		},
+		CachedRecycles: map[string]model.Recycle{"r1": {KBFileId: "r1", SourceIndexId: "i1", KBFileName: "f"}},
+	}
+
+	var got []string
+	for _, issue := range Check(snap) {
+		got = append(got, issue.Kind+" "+issue.IndexId+"/"+issue.KBFileId+" "+issue.Cache+issue.Path+" "+issue.Repair)
+	}
+	want := []string{
+		"cache_stale i1/ index delete_cache",
+		"cache_stale i1/deleted kb delete_cache",
+		"es_chunks_orphan i1/deleted  delete_chunks",
+		"es_chunks_orphan i1/stale  delete_chunks",
+		"es_index_missing i2/  create_es_index",
+		"es_index_orphan orphan/  delete_es_index",
+		"index_dir_missing i2/  create_dir",
+		"index_dir_orphan orphan/  remove_dir",
+		"kb_file_chunks_missing i1/no_chunks  reingest",
+		"kb_file_dir_orphan i1/stray  remove_dir",
+		"kb_file_index_missing gone/no_index  delete_record",
+		"kb_file_missing i1/no_md  delete_record",
+		"leftover_dir / /kb/i1/.del.removing remove_dir",
+		"recycle_dir_missing i1/r2  delete_record",
+		"recycle_dir_orphan i1/stray  remove_dir",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestCheckConsistent(t *testing.T) {
+	snap := &Snapshot{
+		Time:    time.Now(),
+		Indexes: []model.Index{{IndexId: "i1", UserId: "u1"}},
+		KBFiles: []model.KBFile{{KBFileId: "k1", IndexId: "i1", KBFileName: "a"}},
+		KBDirs: map[string]*Dir{"i1": {Children: map[string]*Dir{
+			"k1": {Markdowns: map[string]int64{"a": 1}},
+		}}},
+		ESIndexes: map[string]time.Time{"i1": time.Now()},
+		ESChunks:  map[string]map[string]search_engine.FileChunks{"i1": {"k1": {Count: 1}}},
+	}
+	if issues := Check(snap); len(issues) != 0 {
+		t.Errorf("unexpected issues %+v", issues)
+	}
+}
@@ -0,0 +1,163 @@
+// -------------------------------------------------
+// Package consistency
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package consistency
+
+import (
+	"context"
+	"gcnote/server/ability/search_engine"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/saga"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// es索引名称的前缀，后面是index_id
+const esIndexPrefix = "gcnote-"
+
+// Collect 读取各个存储中的数据。先读数据库再读文件系统和es：
+// 写操作都在最后提交数据库，读到的记录对应的目录和切片一定已经写完
+func Collect(ctx context.Context) (*Snapshot, error) {
+	snap := &Snapshot{Time: time.Now()}
+	db := config.DB
+	if err := db.Find(&snap.Indexes).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Find(&snap.KBFiles).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Find(&snap.Recycles).Error; err != nil {
+		return nil, err
+	}
+
+	var err error
+	snap.LeftoverDirs = map[string]time.Time{}
+	if snap.KBDirs, err = scanRoot(config.PathCfg.KnowledgeBasePath, snap.LeftoverDirs); err != nil {
+		return nil, err
+	}
+	if snap.RecycleDirs, err = scanRoot(config.PathCfg.RecycleBinPath, snap.LeftoverDirs); err != nil {
+		return nil, err
+	}
+
+	esIndexes, err := search_engine.ListIndexes(config.ElasticClient, esIndexPrefix)
+	if err != nil {
+		return nil, err
+	}
+	snap.ESIndexes = make(map[string]time.Time, len(esIndexes))
+	snap.ESChunks = make(map[string]map[string]search_engine.FileChunks, len(esIndexes))
+	for name, createdAt := range esIndexes {
+		indexId := strings.TrimPrefix(name, esIndexPrefix)
+		snap.ESIndexes[indexId] = createdAt
+		if snap.ESChunks[indexId], err = search_engine.CountChunksByFile(config.ElasticClient, name); err != nil {
+			return nil, err
+		}
+	}
+
+	if snap.CachedIndexes, err = cache.ScanIndexInfo(ctx); err != nil {
+		return nil, err
+	}
+	if snap.CachedKBFiles, err = cache.ScanKBInfo(ctx); err != nil {
+		return nil, err
+	}
+	if snap.CachedRecycles, err = cache.ScanRecycleInfo(ctx); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// scanRoot 读取知识库或回收站根目录，两层目录：index_id/kb_file_id；根目录不存在时返回空。
+// 删除时移开的目录记录到leftovers
+func scanRoot(root string, leftovers map[string]time.Time) (map[string]*Dir, error) {
+	dirs := map[string]*Dir{}
+	indexEntries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return dirs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, indexEntry := range indexEntries {
+		indexPath := filepath.Join(root, indexEntry.Name())
+		if !indexEntry.IsDir() {
+			continue
+		}
+		if saga.IsRemovingDir(indexEntry.Name()) {
+			if err = addLeftover(leftovers, indexPath, indexEntry); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if strings.HasPrefix(indexEntry.Name(), ".") {
+			continue
+		}
+		indexDir, err := readDir(indexPath)
+		if err != nil {
+			return nil, err
+		}
+		indexDir.Children = map[string]*Dir{}
+		fileEntries, err := os.ReadDir(indexPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, fileEntry := range fileEntries {
+			filePath := filepath.Join(indexPath, fileEntry.Name())
+			if !fileEntry.IsDir() {
+				continue
+			}
+			if saga.IsRemovingDir(fileEntry.Name()) {
+				if err = addLeftover(leftovers, filePath, fileEntry); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if strings.HasPrefix(fileEntry.Name(), ".") {
+				continue
+			}
+			if indexDir.Children[fileEntry.Name()], err = readDir(filePath); err != nil {
+				return nil, err
+			}
+		}
+		dirs[indexEntry.Name()] = indexDir
+	}
+	return dirs, nil
+}
+
+// addLeftover 记录移开的目录和它的修改时间（saga移开时会更新）
+func addLeftover(leftovers map[string]time.Time, path string, entry os.DirEntry) error {
+	info, err := entry.Info()
+	if err != nil {
+		return err
+	}
+	leftovers[path] = info.ModTime()
+	return nil
+}
+
+// readDir 读取目录的修改时间和其中的md文件
+func readDir(path string) (*Dir, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := &Dir{ModTime: info.ModTime(), Markdowns: map[string]int64{}}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		entryInfo, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		dir.Markdowns[strings.TrimSuffix(entry.Name(), ".md")] = entryInfo.Size()
+	}
+	return dir, nil
+}
@@ -0,0 +1,326 @@
+// -------------------------------------------------
+// Package consistency
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package consistency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gcnote/server/ability/embeds"
+	"gcnote/server/ability/search_engine"
+	"gcnote/server/ability/splitter"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/model"
+	"gcnote/server/saga"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Options 检查参数
+type Options struct {
+	Repair bool     // 为true时修复发现的问题
+	Kinds  []string // 只修复这些类型的问题，为空时修复全部
+}
+
+// Report 一次检查的结果
+type Report struct {
+	CheckedAt time.Time      `json:"checkedAt"`
+	Issues    []Issue        `json:"issues"`
+	Counts    map[string]int `json:"counts"` // 各类问题的数量
+	Repaired  int            `json:"repaired"`
+	Failed    int            `json:"failed"`
+}
+
+// Run 检查所有存储，按需修复
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	snap, err := Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{CheckedAt: snap.Time, Issues: Check(snap), Counts: map[string]int{}}
+	for _, issue := range report.Issues {
+		report.Counts[issue.Kind]++
+	}
+	if opts.Repair {
+		Repair(ctx, snap, report.Issues, opts.Kinds)
+	}
+	for _, issue := range report.Issues {
+		if issue.Repaired {
+			report.Repaired++
+		} else if issue.Error != "" {
+			report.Failed++
+		}
+	}
+	zap.S().Infof("Consistency check done, issues: %d, repaired: %d, failed: %d",
+		len(report.Issues), report.Repaired, report.Failed)
+	return report, nil
+}
+
+// 修复的顺序：先补齐目录和es索引，再删除，最后重新导入
+var repairOrder = map[string]int{
+	RepairCreateDir:     0,
+	RepairCreateESIndex: 0,
+	RepairDeleteCache:   1,
+	RepairRemoveDir:     2,
+	RepairDeleteESIndex: 2,
+	RepairDeleteChunks:  2,
+	RepairDeleteRecord:  3,
+	RepairReingest:      4,
+}
+
+// errStillReferenced 修复前再次确认时发现记录已经存在（检查之后有新的写入），跳过
+var errStillReferenced = errors.New("record exists now, skipped")
+
+// errRecentlyModified 修复前再次确认时发现目录刚被修改过，可能属于正在执行的删除，跳过
+var errRecentlyModified = errors.New("modified recently, skipped")
+
+// Repair 修复问题，结果写回issues；kinds为空时修复全部类型。
+// 删除之前会再次确认数据库，避免删掉检查之后才提交的数据
+func Repair(ctx context.Context, snap *Snapshot, issues []Issue, kinds []string) {
+	allowed := make(map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		allowed[kind] = true
+	}
+	order := make([]int, 0, len(issues))
+	for i, issue := range issues {
+		if len(allowed) == 0 || allowed[issue.Kind] {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return repairOrder[issues[order[a]].Repair] < repairOrder[issues[order[b]].Repair]
+	})
+
+	users := map[string]bool{}
+	indexes := map[string]bool{}
+	for _, i := range order {
+		issue := &issues[i]
+		if err := repairIssue(ctx, snap, issue); err != nil {
+			issue.Error = err.Error()
+			zap.S().Errorf("Repair %s %s/%s failed: %v", issue.Kind, issue.IndexId, issue.KBFileId, err)
+			continue
+		}
+		issue.Repaired = true
+		zap.S().Infof("Repaired %s %s/%s by %s", issue.Kind, issue.IndexId, issue.KBFileId, issue.Repair)
+		if issue.UserId != "" {
+			users[issue.UserId] = true
+		}
+		if issue.IndexId != "" {
+			indexes[issue.IndexId] = true
+		}
+	}
+
+	// 刷新受影响的列表缓存
+	for userId := range users {
+		if _, err := cache.RefreshUserIndexList(ctx, userId); err != nil {
+			zap.S().Errorf("Failed to refresh user index list cache: %v", err)
+		}
+		if _, err := cache.RefreshRecentKBList(ctx, userId); err != nil {
+			zap.S().Errorf("Failed to refresh user recent kb list cache: %v", err)
+		}
+		if _, err := cache.RefreshUserRecycleList(ctx, userId); err != nil {
+			zap.S().Errorf("Failed to refresh user recycle list cache: %v", err)
+		}
+	}
+	for indexId := range indexes {
+		if err := cache.DelIndexKBList(ctx, indexId); err != nil {
+			zap.S().Errorf("Failed to delete index kb list cache: %v", err)
+		}
+	}
+}
+
+func repairIssue(ctx context.Context, snap *Snapshot, issue *Issue) error {
+	kbPath := config.PathCfg.KnowledgeBasePath
+	recyclePath := config.PathCfg.RecycleBinPath
+	esIndex := esIndexPrefix + issue.IndexId
+	switch issue.Repair {
+	case RepairCreateDir:
+		issue.Path = filepath.Join(kbPath, issue.IndexId)
+		return os.MkdirAll(issue.Path, os.ModePerm)
+
+	case RepairCreateESIndex:
+		return search_engine.IndexCreate(config.ElasticClient, esIndex)
+
+	case RepairDeleteESIndex:
+		if err := ensureAbsent(&model.Index{}, "index_id = ?", issue.IndexId); err != nil {
+			return err
+		}
+		return search_engine.IndexDelete(config.ElasticClient, esIndex)
+
+	case RepairRemoveDir:
+		var err error
+		switch issue.Kind {
+		case IndexDirOrphan:
+			issue.Path = filepath.Join(kbPath, issue.IndexId)
+			err = ensureAbsent(&model.Index{}, "index_id = ?", issue.IndexId)
+		case KBFileDirOrphan:
+			issue.Path = filepath.Join(kbPath, issue.IndexId, issue.KBFileId)
+			err = ensureAbsent(&model.KBFile{}, "kb_file_id = ? AND index_id = ?", issue.KBFileId, issue.IndexId)
+		case RecycleDirOrphan:
+			issue.Path = filepath.Join(recyclePath, issue.IndexId, issue.KBFileId)
+			err = ensureAbsent(&model.Recycle{}, "kb_file_id = ? AND source_index_id = ?", issue.KBFileId, issue.IndexId)
+		case LeftoverDir:
+			err = ensureStale(issue.Path)
+		default:
+			return fmt.Errorf("unexpected kind %s", issue.Kind)
+		}
+		if err != nil {
+			return err
+		}
+		return os.RemoveAll(issue.Path)
+
+	case RepairDeleteChunks:
+		if err := ensureAbsent(&model.KBFile{}, "kb_file_id = ? AND index_id = ?", issue.KBFileId, issue.IndexId); err != nil {
+			return err
+		}
+		// 只删除检查时已经超过OrphanGrace的切片，检查之后重新写入的保留
+		return search_engine.DeleteChunksIndexedBefore(config.ElasticClient, esIndex, issue.KBFileId,
+			snap.Time.Add(-OrphanGrace))
+
+	case RepairDeleteRecord:
+		if issue.Kind == RecycleDirMissing {
+			return deleteRecycleRecord(ctx, issue)
+		}
+		return deleteKBFileRecord(ctx, snap, issue)
+
+	case RepairReingest:
+		return reingest(issue)
+
+	case RepairDeleteCache:
+		switch issue.Cache {
+		case "index":
+			return cache.DelIndexInfo(ctx, issue.IndexId)
+		case "kb":
+			return cache.DelKBInfo(ctx, issue.KBFileId)
+		case "recycle":
+			return cache.DelRecycleInfo(ctx, issue.KBFileId)
+		}
+		return fmt.Errorf("unexpected cache %s", issue.Cache)
+	}
+	return fmt.Errorf("unexpected repair %s", issue.Repair)
+}
+
+// ensureAbsent 确认记录仍然不存在
+func ensureAbsent(value interface{}, query string, args ...interface{}) error {
+	var count int64
+	if err := config.DB.Model(value).Where(query, args...).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errStillReferenced
+	}
+	return nil
+}
+
+// ensureStale 确认目录的修改时间仍然超过OrphanGrace，目录已经不存在时不用处理
+func ensureStale(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if time.Since(info.ModTime()) < OrphanGrace {
+		return errRecentlyModified
+	}
+	return nil
+}
+
+// deleteKBFileRecord 删除找不到文件的文档记录、元数据、版本记录和es切片
+func deleteKBFileRecord(ctx context.Context, snap *Snapshot, issue *Issue) error {
+	var kbFile model.KBFile
+	if err := config.DB.Where("kb_file_id = ?", issue.KBFileId).First(&kbFile).Error; err != nil {
+		return err
+	}
+	if issue.Kind == KBFileMissing {
+		mdPath := filepath.Join(config.PathCfg.KnowledgeBasePath, kbFile.IndexId, kbFile.KBFileId, kbFile.KBFileName+".md")
+		if _, err := os.Stat(mdPath); err == nil {
+			return errStillReferenced
+		}
+	}
+	sg := saga.New("repair kb file " + issue.KBFileId)
+	defer sg.Rollback()
+	tx, err := sg.Begin(config.DB)
+	if err != nil {
+		return err
+	}
+	err = sg.Do("delete kb file record", func() error {
+		if err := tx.Where("kb_file_id = ?", issue.KBFileId).Delete(&model.KBFile{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("kb_file_id = ?", issue.KBFileId).Delete(&model.KBFileMeta{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("kb_file_id = ?", issue.KBFileId).Delete(&model.KBFileRevision{}).Error
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if _, ok := snap.ESIndexes[kbFile.IndexId]; ok {
+		err = sg.Do("delete es chunks", func() error {
+			return search_engine.DeleteByTerm(config.ElasticClient, esIndexPrefix+kbFile.IndexId, "kb_file_id", issue.KBFileId)
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	if err = sg.Commit(tx); err != nil {
+		return err
+	}
+	sg.Complete()
+	if err = cache.DelKBInfo(ctx, issue.KBFileId); err != nil {
+		zap.S().Errorf("Failed to delete kb file cache: %v", err)
+	}
+	return nil
+}
+
+// deleteRecycleRecord 删除找不到文件的回收站记录和元数据
+func deleteRecycleRecord(ctx context.Context, issue *Issue) error {
+	if _, err := os.Stat(filepath.Join(config.PathCfg.RecycleBinPath, issue.IndexId, issue.KBFileId)); err == nil {
+		return errStillReferenced
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kb_file_id = ?", issue.KBFileId).Delete(&model.Recycle{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("kb_file_id = ?", issue.KBFileId).Delete(&model.KBFileMeta{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	if err = cache.DelRecycleInfo(ctx, issue.KBFileId); err != nil {
+		zap.S().Errorf("Failed to delete recycle cache: %v", err)
+	}
+	return nil
+}
+
+// reingest 按md文件重新切分，替换es中文档的切片
+func reingest(issue *Issue) error {
+	var kbFile model.KBFile
+	if err := config.DB.Where("kb_file_id = ?", issue.KBFileId).First(&kbFile).Error; err != nil {
+		return err
+	}
+	content, err := os.ReadFile(filepath.Join(config.PathCfg.KnowledgeBasePath, kbFile.IndexId, kbFile.KBFileId,
+		kbFile.KBFileName+".md"))
+	if err != nil {
+		return err
+	}
+	chunks := splitter.SplitMarkdown(string(content), 512)
+	docList := splitter.Chunk2Doc(chunks, kbFile.KBFileId, kbFile.IndexId)
+	embedList, err := embeds.RandEmbedding(docList) // fixme 之后换成正常的Embedding服务
+	if err != nil {
+		return err
+	}
+	return search_engine.ReplaceChunks(config.ElasticClient, esIndexPrefix+kbFile.IndexId, kbFile.KBFileId, docList,
+		embedList)
+}
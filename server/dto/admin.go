@@ -0,0 +1,12 @@
+// -------------------------------------------------
+// Package dto
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package dto
+
+type ConsistencyCheckRequest struct {
+	Repair bool     `json:"repair"` // 为true时修复发现的问题
+	Kinds  []string `json:"kinds"`  // 只修复这些类型的问题，为空时修复全部
+}
@@ -19,3 +19,8 @@ type IndexRenameRequest struct {
 type IndexRequest struct {
 	IndexId string `json:"index_id" binding:"required"`
 }
+
+type IndexMemberRequest struct {
+	IndexId string `json:"index_id" binding:"required"`
+	UserId  string `json:"user_id" binding:"required"` // 成员的user_id
+}
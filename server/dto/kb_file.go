@@ -6,7 +6,10 @@
 
 package dto
 
-import "mime/multipart"
+import (
+	"mime/multipart"
+	"time"
+)
 
 type KBFileCreateRequest struct {
 	KBFileName string `json:"kb_file_name" binding:"required"`
@@ -30,6 +33,23 @@ type KBFileAddRequest struct {
 	IndexId string `form:"index_id" binding:"required"`
 	//KBFileName string                `form:"kb_file_name" binding:"required"`
 	File *multipart.FileHeader `form:"file" binding:"required"`
+	// 用户的知识库中已有相同内容时的处理方式：reject拒绝导入，link导入一个指向已有文档的链接，create(默认)照常导入
+	OnDuplicate string `form:"on_duplicate" binding:"omitempty,oneof=reject link create"`
+}
+
+// 已有相同内容时的处理方式
+const (
+	DuplicateReject = "reject"
+	DuplicateLink   = "link"
+	DuplicateCreate = "create"
+)
+
+// DuplicateKBFile 内容相同的已有文档所在的位置
+type DuplicateKBFile struct {
+	IndexId    string `json:"index_id"`
+	IndexName  string `json:"index_name"`
+	KBFileId   string `json:"kb_file_id"`
+	KBFileName string `json:"kb_file_name"`
 }
 
 type KBFileShowRequest struct {
@@ -57,3 +77,113 @@ type KBFileReadRequest struct {
 type RecentDocsRequest struct {
 	Mode string `json:"mode" binding:"required"` // 模式: "modified" 或 "created"
 }
+
+type KBFileImportURLRequest struct {
+	IndexId    string `json:"index_id" binding:"required"`      // 知识库索引ID
+	URL        string `json:"url" binding:"required"`           // 网页地址，只支持http、https
+	KBFileName string `json:"kb_file_name" binding:"omitempty"` // 文档名，可选，默认使用网页标题
+	// 已有相同内容时的处理方式，同KBFileAddRequest
+	OnDuplicate string `json:"on_duplicate" binding:"omitempty,oneof=reject link create"`
+}
+
+// KBFileBulkImportRequest 都用form标签
+type KBFileBulkImportRequest struct {
+	IndexId string                `form:"index_id" binding:"required"`
+	File    *multipart.FileHeader `form:"file" binding:"required"` // zip或tar.gz压缩包
+	// 包内每个文件在已有相同内容时的处理方式，同KBFileAddRequest
+	OnDuplicate string `form:"on_duplicate" binding:"omitempty,oneof=reject link create"`
+}
+
+// ImportJobResponse 导入任务已提交，可以用JobId查询进度或取消
+type ImportJobResponse struct {
+	JobId      string            `json:"job_id"`
+	Message    string            `json:"message"`
+	Duplicates []DuplicateKBFile `json:"duplicates,omitempty"` // 上传的文件与这些文档相同
+}
+
+type ImportJobCancelRequest struct {
+	JobId string `json:"job_id" binding:"required"`
+}
+
+// DefaultRevisionPageSize 版本列表默认的每页条数，最大条数见binding
+const DefaultRevisionPageSize = 20
+
+type KBFileRevisionListRequest struct {
+	IndexId  string `json:"index_id" binding:"required"`
+	KBFileId string `json:"kb_file_id" binding:"required"`
+	Page     int    `json:"page" binding:"omitempty,min=1"`              // 从1开始，默认1
+	PageSize int    `json:"page_size" binding:"omitempty,min=1,max=100"` // 默认20，最大100
+}
+
+type KBFileRevisionRequest struct {
+	IndexId  string `json:"index_id" binding:"required"`
+	KBFileId string `json:"kb_file_id" binding:"required"`
+	Revision int    `json:"revision" binding:"required,min=1"`
+}
+
+type KBFileRevisionRestoreRequest struct {
+	IndexId  string `json:"index_id" binding:"required"`
+	KBFileId string `json:"kb_file_id" binding:"required"`
+	Revision int    `json:"revision" binding:"required,min=1"`
+	Message  string `json:"message" binding:"omitempty,max=255"` // 可选，默认"恢复到版本N"
+}
+
+// KBFileRevisionInfo 文档的一个版本
+type KBFileRevisionInfo struct {
+	Revision  int       `json:"revision"`
+	Hash      string    `json:"hash"` // 内容的SHA-256
+	Author    string    `json:"author"`
+	Size      int64     `json:"size"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type KBFileRevisionListResponse struct {
+	Revisions []KBFileRevisionInfo `json:"revisions"`
+	Total     int64                `json:"total"`
+	Page      int                  `json:"page"`
+	PageSize  int                  `json:"page_size"`
+}
+
+// KBFileRevisionResponse 版本的信息和内容，内容中的图片地址和读取文档时一样
+type KBFileRevisionResponse struct {
+	KBFileRevisionInfo
+	Content string `json:"content"`
+}
+
+// 版本对比默认的上下文行数
+const DefaultDiffContext = 3
+
+type KBFileRevisionDiffRequest struct {
+	IndexId  string `json:"index_id" binding:"required"`
+	KBFileId string `json:"kb_file_id" binding:"required"`
+	From     int    `json:"from" binding:"required,min=1"`             // 旧的版本号
+	To       int    `json:"to" binding:"omitempty,min=1"`              // 新的版本号，不传时和当前内容对比
+	Context  *int   `json:"context" binding:"omitempty,min=0,max=100"` // 每处修改前后保留的行数，默认3
+}
+
+// KBFileRevisionDiffResponse 两个版本的对比结果，Hunks为按行的对比，修改过的行带有按词对比的结果；
+// Unified为unified diff格式的文本
+type KBFileRevisionDiffResponse struct {
+	From    int         `json:"from"`
+	To      int         `json:"to"` // 0表示当前内容
+	Hunks   interface{} `json:"hunks"`
+	Added   int         `json:"added"`
+	Deleted int         `json:"deleted"`
+	Unified string      `json:"unified"`
+}
+
+// KBFileConflictResponse 保存时文档已经被修改：返回当前的版本和内容，以及提交的内容和当前内容三方合并的结果。
+// Conflicts为0时合并结果可以直接带着Version重新保存；找不到提交时基于的版本时没有合并结果
+type KBFileConflictResponse struct {
+	Version   string `json:"version"`
+	Content   string `json:"content"`
+	Merged    string `json:"merged,omitempty"`
+	Conflicts int    `json:"conflicts"`
+}
+
+// KBFileCollaborateRequest 协同编辑的websocket连接，参数都在query中
+type KBFileCollaborateRequest struct {
+	IndexId  string `form:"index_id" binding:"required"`
+	KBFileId string `form:"kb_file_id" binding:"required"`
+}
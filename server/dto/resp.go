@@ -37,6 +37,7 @@ const (
 	UserTokenErrCode       Code = 40101
 	UserPasswordErrCode    Code = 40102
 	UserEmailExistsErrCode Code = 40103
+	UserForbiddenErrCode   Code = 40104
 
 	// 知识库业务错误码 02
 	IndexExistErrCode    Code = 40200
@@ -44,10 +45,13 @@ const (
 	IndexNameErrCode     Code = 40202
 
 	// 文件业务错误码 03
-	KBFileNameErrCode     Code = 40300
-	KBFileExistErrCode    Code = 40301
-	KBFileNotExistErrCode Code = 40302
-	KBFileAddFileErrCode  Code = 40303
+	KBFileNameErrCode            Code = 40300
+	KBFileExistErrCode           Code = 40301
+	KBFileNotExistErrCode        Code = 40302
+	KBFileAddFileErrCode         Code = 40303
+	KBFileDuplicateErrCode       Code = 40304
+	KBFileVersionRequiredErrCode Code = 40305
+	KBFileVersionConflictErrCode Code = 40306
 
 	// 回收站业务错误码 04
 	//RecycleFileNameErrCode     Code = 40400
@@ -77,6 +81,7 @@ func init() {
 	message[UserTokenErrCode] = "登录信息错误"
 	message[UserPasswordErrCode] = "密码错误"
 	message[UserEmailExistsErrCode] = "邮箱已经存在"
+	message[UserForbiddenErrCode] = "没有权限"
 	// 402xx 知识库错误
 	message[IndexExistErrCode] = "当前知识库已经存在"
 	message[IndexNotExistErrCode] = "当前知识库不存在"
@@ -86,6 +91,9 @@ func init() {
 	message[KBFileExistErrCode] = "当前知文档已经存在"
 	message[KBFileNotExistErrCode] = "当前文档不存在"
 	message[KBFileAddFileErrCode] = "当前文件导入时发生错误"
+	message[KBFileDuplicateErrCode] = "知识库中已有相同内容的文档"
+	message[KBFileVersionRequiredErrCode] = "缺少文档的版本"
+	message[KBFileVersionConflictErrCode] = "文档已经被修改，请合并后重新保存"
 	// 404xx 回收站错误
 	message[RecycleFileNotExistErrCode] = "回收的文件不存在"
 	// 405xx 分享业务错误码
@@ -108,12 +116,16 @@ func init() {
 | `UserTokenErrCode`      | `http.StatusUnauthorized` (401) | 登录信息错误                             |
 | `UserPasswordErrCode`   | `http.StatusUnauthorized` (401) | 密码错误                                   |
 | `UserEmailExistsErrCode`| `http.StatusConflict` (409) | 邮箱已经存在                               |
+| `UserForbiddenErrCode`  | `http.StatusForbidden` (403) | 没有权限                                   |
 | `IndexExistErrCode`     | `http.StatusConflict` (409) | 当前知识库已经存在                         |
 | `IndexNotExistErrCode`  | `http.StatusNotFound` (404) | 当前知识库不存在                           |
 | `IndexNameErrCode`      | `http.StatusBadRequest` (400) | 知识库名称不允许出现特定符号               |
 | `KBFileNameErrCode`     | `http.StatusBadRequest` (400) | 文件名称不允许出现特定符号                 |
 | `KBFileExistErrCode`    | `http.StatusConflict` (409) | 当前知识库已经存在                         |
 | `KBFileNotExistErrCode` | `http.StatusNotFound` (404) | 当前知识库不存在                           |
+| `KBFileDuplicateErrCode`| `http.StatusConflict` (409) | 知识库中已有相同内容的文档                 |
+| `KBFileVersionRequiredErrCode` | `http.StatusPreconditionRequired` (428) | 缺少文档的版本                  |
+| `KBFileVersionConflictErrCode` | `http.StatusConflict` (409) | 文档已经被修改，请合并后重新保存           |
 | `InternalErrCode`       | `http.StatusInternalServerError` (500) | 系统内部发生错误                           |
 */
 
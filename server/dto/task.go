@@ -0,0 +1,38 @@
+// -------------------------------------------------
+// Package dto
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package dto
+
+// DefaultTaskPageSize 导入结果列表默认的每页条数，最大条数见binding
+const DefaultTaskPageSize = 20
+
+type TaskListRequest struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`              // 从1开始，默认1
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"` // 默认20，最大100
+}
+
+type TaskListResponse struct {
+	Tasks    interface{} `json:"tasks"`
+	Total    int64       `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+}
+
+type TaskGetRequest struct {
+	TaskId string `form:"task_id" binding:"required"`
+}
+
+type TaskAckRequest struct {
+	TaskIds []string `json:"task_ids" binding:"required,min=1,max=100"`
+}
+
+type TaskClearRequest struct {
+	AckedOnly bool `json:"acked_only"` // 为true时只删除已读的导入结果
+}
+
+type TaskCountResponse struct {
+	Count int `json:"count"` // 实际标记或删除的条数
+}
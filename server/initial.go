@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"gcnote/server/config"
+	"gcnote/server/job_queue"
 	"gcnote/server/model"
 	"github.com/allegro/bigcache/v3"
 	"github.com/elastic/go-elasticsearch/v8"
@@ -68,6 +69,10 @@ func InitMysql() {
 	err = config.DB.AutoMigrate(&model.Index{})
 	err = config.DB.AutoMigrate(&model.KBFile{})
 	err = config.DB.AutoMigrate(&model.Recycle{})
+	err = config.DB.AutoMigrate(&model.KBFileMeta{})
+	err = config.DB.AutoMigrate(&model.IngestJob{})
+	err = config.DB.AutoMigrate(&model.KBFileRevision{})
+	err = config.DB.AutoMigrate(&model.IndexMember{})
 	if err != nil {
 		zap.S().Panicf("初始化MySQL数据库失败 err:%v", err)
 	}
@@ -188,3 +193,15 @@ func InitElasticSearch() {
 	config.ElasticClient = elasticClient
 
 }
+
+// InitJobQueue 启动导入任务的worker，需要在注册处理函数(router.InitRouter)之后调用
+func InitJobQueue() {
+	c := config.ServerCfg.JobConf
+	job_queue.Start(context.Background(), job_queue.Options{
+		Workers:     c.Workers,
+		MaxAttempts: c.MaxAttempts,
+		BackoffBase: time.Duration(c.BackoffBase) * time.Second,
+		BackoffMax:  time.Duration(c.BackoffMax) * time.Second,
+		Lease:       time.Duration(c.Lease) * time.Second,
+	})
+}
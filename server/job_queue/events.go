@@ -0,0 +1,118 @@
+// -------------------------------------------------
+// Package job_queue
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package job_queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gcnote/server/config"
+	"gcnote/server/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Redis key规范
+// s:job:events:{user_id} - pub/sub频道，推送用户导入任务的进度，不保存
+
+func eventsChannel(userId string) string {
+	return fmt.Sprintf("s:job:events:%v", userId)
+}
+
+// 事件类型
+const (
+	EventState    = "state"    // 任务状态变化，包括排队、各阶段、结束和等待重试
+	EventProgress = "progress" // 多文件任务处理完一个文件
+	EventStage    = "stage"    // 当前阶段内的进度，如pdf转换的页数、计算向量和写入es的切片数
+)
+
+// 阶段内进度的单位
+const (
+	UnitPage  = "page"
+	UnitChunk = "chunk"
+)
+
+// 阶段内进度最多每隔这么久推送一次，阶段开始和结束时总会推送
+const stageThrottle = 300 * time.Millisecond
+
+// Event 推送给前端的任务进度
+type Event struct {
+	Type       string `json:"type"`
+	JobId      string `json:"job_id"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	State      string `json:"state"`
+	Reason     string `json:"reason,omitempty"` // 失败或者等待重试的原因
+	Attempts   int    `json:"attempts"`
+	Done       int    `json:"done"` // 多文件任务已经处理的文件数
+	Total      int    `json:"total"`
+	Unit       string `json:"unit,omitempty"`
+	StageDone  int    `json:"stage_done"`
+	StageTotal int    `json:"stage_total"`
+	Time       int64  `json:"time"` // 毫秒时间戳
+}
+
+// NewEvent 按任务当前的状态生成事件，也用于前端连接时推送执行中任务的快照
+func NewEvent(eventType string, job *model.IngestJob) Event {
+	return Event{
+		Type:     eventType,
+		JobId:    job.JobId,
+		Kind:     job.Kind,
+		Name:     job.Name,
+		State:    job.State,
+		Reason:   job.Reason,
+		Attempts: job.Attempts,
+		Done:     job.Done,
+		Total:    job.Total,
+		Time:     time.Now().UnixMilli(),
+	}
+}
+
+// Publisher 把用户任务的事件推送出去，多个实例时需要经过共享的消息通道
+type Publisher func(userId string, event Event)
+
+// publishRedis 通过redis的pub/sub推送，订阅方可以是任意一个实例
+func publishRedis(userId string, event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		zap.S().Errorf("Marshal job event error: %v", err)
+		return
+	}
+	err = config.RedisClient.Publish(context.Background(), eventsChannel(userId), data).Err()
+	if err != nil {
+		zap.S().Errorf("Publish job %s event error: %v", event.JobId, err)
+	}
+}
+
+// SubscribeEvents 订阅用户任务的事件，消息内容为Event的json，使用完需要Close
+func SubscribeEvents(ctx context.Context, userId string) *redis.PubSub {
+	return config.RedisClient.Subscribe(ctx, eventsChannel(userId))
+}
+
+func (q *Queue) emit(job *model.IngestJob, event Event) {
+	if q.publish == nil {
+		return
+	}
+	q.publish(job.UserId, event)
+}
+
+// SetStageProgress 处理函数上报当前阶段内的进度，如pdf转换了几页、写入了几个切片
+func SetStageProgress(ctx context.Context, unit string, done int, total int) {
+	r, ok := ctx.Value(runningKey{}).(*runningJob)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if done > 0 && done < total && now.Sub(r.lastStage) < stageThrottle {
+		return
+	}
+	r.lastStage = now
+	event := NewEvent(EventStage, r.job)
+	event.Unit, event.StageDone, event.StageTotal = unit, done, total
+	r.queue.emit(r.job, event)
+}
@@ -0,0 +1,404 @@
+// -------------------------------------------------
+// Package job_queue
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package job_queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gcnote/server/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrNotStarted  = errors.New("job queue not started")
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobFinished = errors.New("job already finished")
+	errLeaseLost   = errors.New("job lease lost")
+)
+
+// Handler 执行一个任务。返回Permanent包装的错误时不再重试；
+// ctx在任务被取消或者租约被其他实例领取时取消，处理函数应在各阶段之间检查ctx.Err()并直接返回
+type Handler func(ctx context.Context, job *model.IngestJob) error
+
+// Options 任务队列配置，为0的项使用默认值
+type Options struct {
+	Workers      int           // 同时执行的任务数
+	MaxAttempts  int           // 每个任务最多执行的次数
+	BackoffBase  time.Duration // 第一次重试的等待时间，之后每次翻倍
+	BackoffMax   time.Duration // 重试等待时间的上限
+	Lease        time.Duration // 执行中的任务的租约，续租间隔为它的1/3
+	PollInterval time.Duration // 没有任务时查询数据库的间隔
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = 2
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 3
+	}
+	if o.BackoffBase <= 0 {
+		o.BackoffBase = 10 * time.Second
+	}
+	if o.BackoffMax <= 0 {
+		o.BackoffMax = 5 * time.Minute
+	}
+	if o.Lease <= 0 {
+		o.Lease = time.Minute
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = 2 * time.Second
+	}
+	return o
+}
+
+// Backoff 第attempts次执行失败后的等待时间
+func (o Options) Backoff(attempts int) time.Duration {
+	o = o.withDefaults()
+	delay := o.BackoffBase
+	for i := 1; i < attempts && delay < o.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > o.BackoffMax {
+		delay = o.BackoffMax
+	}
+	return delay
+}
+
+var (
+	handlersMu sync.RWMutex
+	handlers   = map[string]Handler{}
+)
+
+// Register 注册一种任务的处理函数，需要在Start之前调用
+func Register(kind string, handler Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers[kind] = handler
+}
+
+func handlerOf(kind string) (Handler, bool) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	handler, ok := handlers[kind]
+	return handler, ok
+}
+
+// Queue 任务队列，任务保存在store中，多个实例可以共用一个数据库
+type Queue struct {
+	store   store
+	opts    Options
+	owner   string        // 实例标识，领取任务时记录在任务上
+	wake    chan struct{} // 有新任务时唤醒一个空闲的worker
+	publish Publisher     // 推送任务进度，为nil时不推送
+}
+
+func newQueue(s store, opts Options) *Queue {
+	host, _ := os.Hostname()
+	return &Queue{
+		store: s,
+		opts:  opts.withDefaults(),
+		owner: fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8]),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+var defaultQueue *Queue
+
+// Start 启动worker，执行数据库中排队的任务，包括上次退出时没有执行完的任务
+func Start(ctx context.Context, opts Options) {
+	defaultQueue = newQueue(newGormStore(), opts)
+	defaultQueue.publish = publishRedis
+	defaultQueue.Start(ctx)
+}
+
+// Enqueue 提交任务到默认队列
+func Enqueue(job *model.IngestJob) error {
+	if defaultQueue == nil {
+		return ErrNotStarted
+	}
+	return defaultQueue.Enqueue(job)
+}
+
+// Cancel 取消默认队列中用户的任务
+func Cancel(jobId string, userId string) error {
+	if defaultQueue == nil {
+		return ErrNotStarted
+	}
+	return defaultQueue.Cancel(jobId, userId)
+}
+
+// ActiveJobs 默认队列中用户还没有结束的任务
+func ActiveJobs(userId string) ([]model.IngestJob, error) {
+	if defaultQueue == nil {
+		return nil, ErrNotStarted
+	}
+	return defaultQueue.store.ListUnfinished(userId)
+}
+
+func (q *Queue) Start(ctx context.Context) {
+	for i := 0; i < q.opts.Workers; i++ {
+		go q.worker(ctx)
+	}
+	zap.S().Infof("Job queue started, owner: %s, workers: %d", q.owner, q.opts.Workers)
+}
+
+// Enqueue 保存任务，JobId为空时自动生成
+func (q *Queue) Enqueue(job *model.IngestJob) error {
+	if job.JobId == "" {
+		job.JobId = uuid.New().String()
+	}
+	job.State = model.JobQueued
+	job.NextRunAt = time.Now()
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = q.opts.MaxAttempts
+	}
+	if err := q.store.Create(job); err != nil {
+		return err
+	}
+	q.emit(job, NewEvent(EventState, job))
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Cancel 标记取消，处理函数看到ctx被取消后清理并返回
+func (q *Queue) Cancel(jobId string, userId string) error {
+	job, err := q.store.Get(jobId, userId)
+	if err != nil {
+		return err
+	}
+	if model.IsJobFinished(job.State) {
+		return ErrJobFinished
+	}
+	if err = q.store.RequestCancel(jobId, time.Now()); err != nil {
+		return err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (q *Queue) worker(ctx context.Context) {
+	for {
+		job, err := q.store.Claim(q.owner, time.Now(), q.opts.Lease)
+		if err != nil {
+			zap.S().Errorf("Claim job error: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+			case <-time.After(q.opts.PollInterval):
+			}
+			continue
+		}
+		q.run(ctx, job)
+	}
+}
+
+// runningJob 正在执行的任务，放在传给处理函数的ctx里，用于上报状态
+type runningJob struct {
+	queue     *Queue
+	job       *model.IngestJob
+	canceled  atomic.Bool // 用户取消
+	leaseLost atomic.Bool // 租约已被其他实例领取，任务由它继续执行
+	lastStage time.Time // 上一次推送阶段内进度的时间，只在处理函数中使用
+}
+
+type runningKey struct{}
+
+func (q *Queue) run(parent context.Context, job *model.IngestJob) {
+	r := &runningJob{queue: q, job: job}
+	q.emit(job, NewEvent(EventState, job))
+	handler, ok := handlerOf(job.Kind)
+	if !ok {
+		q.finish(r, Permanent(fmt.Errorf("unknown job kind %q", job.Kind)))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(parent, runningKey{}, r))
+	defer cancel()
+	if job.CancelRequested {
+		// 排队中被取消的任务，仍然交给处理函数清理临时文件、记录结果
+		r.canceled.Store(true)
+		cancel()
+	}
+	stop := make(chan struct{})
+	go q.heartbeat(r, cancel, stop)
+	err := safeRun(ctx, handler, job)
+	close(stop)
+	q.finish(r, err)
+}
+
+// safeRun 处理函数panic时作为一次失败处理，不影响worker
+func safeRun(ctx context.Context, handler Handler, job *model.IngestJob) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			zap.S().Errorf("Job %s panic: %v", job.JobId, p)
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// heartbeat 定期续租，发现任务被请求取消或者租约已被其他实例领取时取消ctx
+func (q *Queue) heartbeat(r *runningJob, cancel context.CancelFunc, stop chan struct{}) {
+	ticker := time.NewTicker(q.opts.Lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		cancelRequested, err := q.store.Renew(r.job.JobId, q.owner, time.Now().Add(q.opts.Lease))
+		if errors.Is(err, errLeaseLost) {
+			zap.S().Errorf("Job %s lease lost, stop running", r.job.JobId)
+			r.leaseLost.Store(true)
+			cancel()
+			return
+		}
+		if err != nil {
+			zap.S().Errorf("Renew job %s error: %v", r.job.JobId, err)
+			continue
+		}
+		if cancelRequested {
+			r.canceled.Store(true)
+			cancel()
+			return
+		}
+	}
+}
+
+// finish 根据执行结果更新任务：成功、取消、失败，或者退避后重试；租约已被其他实例领取时不更新
+func (q *Queue) finish(r *runningJob, err error) {
+	job := r.job
+	if r.leaseLost.Load() {
+		zap.S().Infof("Job %s is taken over by another owner, result discarded: %v", job.JobId, err)
+		return
+	}
+	fields := map[string]interface{}{"owner": ""}
+	switch {
+	case err == nil:
+		fields["state"] = model.JobDone
+		fields["reason"] = ""
+	case r.canceled.Load():
+		fields["state"] = model.JobCanceled
+		fields["reason"] = "canceled."
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		fields["state"] = model.JobFailed
+		fields["reason"] = err.Error()
+	default:
+		delay := q.opts.Backoff(job.Attempts)
+		fields["state"] = model.JobQueued
+		fields["reason"] = err.Error()
+		fields["next_run_at"] = time.Now().Add(delay)
+		zap.S().Infof("Job %s attempt %d failed, retry in %v: %v", job.JobId, job.Attempts, delay, err)
+	}
+	if updateErr := q.store.Update(job.JobId, q.owner, fields); updateErr != nil {
+		zap.S().Errorf("Update job %s error: %v", job.JobId, updateErr)
+		return
+	}
+	job.State = fields["state"].(string)
+	job.Reason = fields["reason"].(string)
+	q.emit(job, NewEvent(EventState, job))
+}
+
+// SetState 处理函数上报当前阶段，ctx不是任务的ctx时什么都不做
+func SetState(ctx context.Context, state string) {
+	r, ok := ctx.Value(runningKey{}).(*runningJob)
+	if !ok || r.job.State == state {
+		return
+	}
+	if err := r.queue.store.Update(r.job.JobId, r.queue.owner, map[string]interface{}{"state": state}); err != nil {
+		zap.S().Errorf("Update job %s state error: %v", r.job.JobId, err)
+		return
+	}
+	r.job.State = state
+	r.queue.emit(r.job, NewEvent(EventState, r.job))
+}
+
+// SetProgress 多文件任务上报进度
+func SetProgress(ctx context.Context, done int, total int) {
+	r, ok := ctx.Value(runningKey{}).(*runningJob)
+	if !ok {
+		return
+	}
+	if err := r.queue.store.Update(r.job.JobId, r.queue.owner,
+		map[string]interface{}{"done": done, "total": total}); err != nil {
+		zap.S().Errorf("Update job %s progress error: %v", r.job.JobId, err)
+		return
+	}
+	r.job.Done, r.job.Total = done, total
+	r.queue.emit(r.job, NewEvent(EventProgress, r.job))
+}
+
+// SavePayload 保存执行过程中确定下来的参数（如预先分配的文档id），重试时沿用
+func SavePayload(ctx context.Context, payload string) error {
+	r, ok := ctx.Value(runningKey{}).(*runningJob)
+	if !ok {
+		return nil
+	}
+	if err := r.queue.store.Update(r.job.JobId, r.queue.owner,
+		map[string]interface{}{"payload": payload}); err != nil {
+		return err
+	}
+	r.job.Payload = payload
+	return nil
+}
+
+// permanentError 不需要重试的错误，如文件损坏、格式不支持
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包装不需要重试的错误
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// IsFinal 返回err后任务是否就此结束（不再重试），处理函数据此决定是否记录最终结果、删除临时文件。
+// 用户取消的任务是最终结果；租约已被其他实例领取时不是，由新的执行者负责。ctx为任务的ctx
+func IsFinal(ctx context.Context, job *model.IngestJob, err error) bool {
+	r, ok := ctx.Value(runningKey{}).(*runningJob)
+	if ok && r.leaseLost.Load() {
+		return false
+	}
+	if ok && r.canceled.Load() {
+		return true
+	}
+	return IsPermanent(err) || job.Attempts >= job.MaxAttempts
+}
+
+// LeaseLost 任务的租约是否已被其他实例领取。之后处理函数不能再记录结果、撤销已经完成的步骤，
+// 这些文件和数据由重新领取任务的实例使用
+func LeaseLost(ctx context.Context) bool {
+	r, ok := ctx.Value(runningKey{}).(*runningJob)
+	return ok && r.leaseLost.Load()
+}
@@ -0,0 +1,376 @@
+// -------------------------------------------------
+// Package job_queue
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package job_queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gcnote/server/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore 测试用的内存store
+type memStore struct {
+	mu   sync.Mutex
+	jobs map[string]*model.IngestJob
+}
+
+func newMemStore() *memStore {
+	return &memStore{jobs: map[string]*model.IngestJob{}}
+}
+
+func (s *memStore) Create(job *model.IngestJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *job
+	s.jobs[job.JobId] = &saved
+	return nil
+}
+
+func (s *memStore) Get(jobId string, userId string) (*model.IngestJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[jobId]
+	if !ok || job.UserId != userId {
+		return nil, ErrJobNotFound
+	}
+	saved := *job
+	return &saved, nil
+}
+
+func (s *memStore) ListUnfinished(userId string) ([]model.IngestJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []model.IngestJob
+	for _, job := range s.jobs {
+		if job.UserId == userId && !model.IsJobFinished(job.State) {
+			jobs = append(jobs, *job)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *memStore) Claim(owner string, now time.Time, lease time.Duration) (*model.IngestJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.jobs {
+		running := job.State != model.JobQueued && !model.IsJobFinished(job.State)
+		if (job.State == model.JobQueued && !job.NextRunAt.After(now)) || (running && job.LeaseUntil.Before(now)) {
+			job.State = model.JobConverting
+			job.Owner = owner
+			job.LeaseUntil = now.Add(lease)
+			job.Attempts++
+			claimed := *job
+			return &claimed, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memStore) Renew(jobId string, owner string, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[jobId]
+	if job.Owner != owner {
+		return false, errLeaseLost
+	}
+	job.LeaseUntil = until
+	return job.CancelRequested, nil
+}
+
+func (s *memStore) Update(jobId string, owner string, fields map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[jobId]
+	if job.Owner != owner {
+		return nil
+	}
+	for key, value := range fields {
+		switch key {
+		case "state":
+			job.State = value.(string)
+		case "reason":
+			job.Reason = value.(string)
+		case "owner":
+			job.Owner = value.(string)
+		case "next_run_at":
+			job.NextRunAt = value.(time.Time)
+		case "done":
+			job.Done = value.(int)
+		case "total":
+			job.Total = value.(int)
+		case "payload":
+			job.Payload = value.(string)
+		}
+	}
+	return nil
+}
+
+func (s *memStore) RequestCancel(jobId string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[jobId]
+	job.CancelRequested = true
+	if job.State == model.JobQueued {
+		job.NextRunAt = now
+	}
+	return nil
+}
+
+func (s *memStore) get(jobId string) model.IngestJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.jobs[jobId]
+}
+
+// waitState 等待任务进入某个状态
+func waitState(t *testing.T, s *memStore, jobId string, state string) model.IngestJob {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if job := s.get(jobId); job.State == state {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	job := s.get(jobId)
+	t.Fatalf("job %s state %q, want %q", jobId, job.State, state)
+	return job
+}
+
+func startTestQueue(t *testing.T, opts Options) (*Queue, *memStore) {
+	opts.PollInterval = 5 * time.Millisecond
+	s := newMemStore()
+	q := newQueue(s, opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	q.Start(ctx)
+	return q, s
+}
+
+func TestQueueRetry(t *testing.T) {
+	var mu sync.Mutex
+	var states []string
+	Register("test_retry", func(ctx context.Context, job *model.IngestJob) error {
+		SetState(ctx, model.JobSplitting)
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, job.State)
+		if job.Attempts < 3 {
+			return errors.New("es upload error.")
+		}
+		SetProgress(ctx, 1, 1)
+		return nil
+	})
+	q, s := startTestQueue(t, Options{Workers: 2, MaxAttempts: 3, BackoffBase: time.Millisecond})
+	job := &model.IngestJob{UserId: "u1", Kind: "test_retry"}
+	if err := q.Enqueue(job); err != nil {
+		t.Fatal(err)
+	}
+	done := waitState(t, s, job.JobId, model.JobDone)
+	if done.Attempts != 3 || done.Done != 1 || done.Owner != "" {
+		t.Errorf("unexpected job %+v", done)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(states) != 3 || states[0] != model.JobSplitting {
+		t.Errorf("unexpected states %v", states)
+	}
+}
+
+func TestQueueFail(t *testing.T) {
+	Register("test_fail", func(ctx context.Context, job *model.IngestJob) error {
+		if job.Name == "permanent" {
+			return Permanent(errors.New("corrupt file"))
+		}
+		return errors.New("database error.")
+	})
+	q, s := startTestQueue(t, Options{MaxAttempts: 2, BackoffBase: time.Millisecond})
+
+	permanent := &model.IngestJob{UserId: "u1", Kind: "test_fail", Name: "permanent"}
+	transient := &model.IngestJob{UserId: "u1", Kind: "test_fail", Name: "transient"}
+	unknown := &model.IngestJob{UserId: "u1", Kind: "test_unknown"}
+	for _, job := range []*model.IngestJob{permanent, transient, unknown} {
+		if err := q.Enqueue(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job := waitState(t, s, permanent.JobId, model.JobFailed); job.Attempts != 1 || job.Reason != "corrupt file" {
+		t.Errorf("permanent error should not retry: %+v", job)
+	}
+	if job := waitState(t, s, transient.JobId, model.JobFailed); job.Attempts != 2 || job.Reason != "database error." {
+		t.Errorf("transient error should retry until MaxAttempts: %+v", job)
+	}
+	waitState(t, s, unknown.JobId, model.JobFailed)
+}
+
+func TestQueueCancel(t *testing.T) {
+	started := make(chan struct{})
+	Register("test_cancel", func(ctx context.Context, job *model.IngestJob) error {
+		close(started)
+		<-ctx.Done()
+		if !IsFinal(ctx, job, ctx.Err()) {
+			t.Error("canceled job should be final")
+		}
+		return ctx.Err()
+	})
+	q, s := startTestQueue(t, Options{Workers: 1, Lease: 30 * time.Millisecond})
+	job := &model.IngestJob{UserId: "u1", Kind: "test_cancel"}
+	if err := q.Enqueue(job); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := q.Cancel(job.JobId, "u2"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("other user's job: got %v", err)
+	}
+	if err := q.Cancel(job.JobId, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, s, job.JobId, model.JobCanceled)
+	if err := q.Cancel(job.JobId, "u1"); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("finished job: got %v", err)
+	}
+}
+
+func TestQueueCancelQueued(t *testing.T) {
+	var ran bool
+	Register("test_cancel_queued", func(ctx context.Context, job *model.IngestJob) error {
+		ran = ctx.Err() == nil
+		return ctx.Err()
+	})
+	s := newMemStore()
+	q := newQueue(s, Options{})
+	job := &model.IngestJob{UserId: "u1", Kind: "test_cancel_queued"}
+	if err := q.Enqueue(job); err != nil {
+		t.Fatal(err)
+	}
+	// 还没有worker时取消，启动后处理函数拿到的是已经取消的ctx
+	if err := q.Cancel(job.JobId, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q.Start(ctx)
+	waitState(t, s, job.JobId, model.JobCanceled)
+	if ran {
+		t.Error("handler should see a canceled ctx")
+	}
+}
+
+func TestQueueExpiredLease(t *testing.T) {
+	// 上一个实例执行到一半崩溃，租约过期后由其他实例重新执行
+	s := newMemStore()
+	_ = s.Create(&model.IngestJob{JobId: "crashed", UserId: "u1", Kind: "test_lease", State: model.JobEmbedding,
+		Owner: "dead", LeaseUntil: time.Now().Add(-time.Second), Attempts: 1, MaxAttempts: 3})
+	Register("test_lease", func(ctx context.Context, job *model.IngestJob) error {
+		return nil
+	})
+	q := newQueue(s, Options{PollInterval: 5 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q.Start(ctx)
+	if job := waitState(t, s, "crashed", model.JobDone); job.Attempts != 2 {
+		t.Errorf("unexpected attempts %d", job.Attempts)
+	}
+}
+
+func TestQueueLeaseLost(t *testing.T) {
+	started := make(chan struct{})
+	result := make(chan bool, 1)
+	Register("test_lease_lost", func(ctx context.Context, job *model.IngestJob) error {
+		close(started)
+		<-ctx.Done()
+		// 和kb_apis.finishJob一样，只有最终结果才记录失败、删除临时文件
+		result <- IsFinal(ctx, job, ctx.Err()) || !LeaseLost(ctx)
+		return ctx.Err()
+	})
+	q, s := startTestQueue(t, Options{Workers: 1, Lease: 30 * time.Millisecond})
+	job := &model.IngestJob{UserId: "u1", Kind: "test_lease_lost", MaxAttempts: 1}
+	if err := q.Enqueue(job); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	// 租约被其他实例领取，续租时返回errLeaseLost
+	s.mu.Lock()
+	s.jobs[job.JobId].Owner = "other"
+	s.mu.Unlock()
+	select {
+	case cleanup := <-result:
+		if cleanup {
+			t.Error("handler should not clean up after losing the lease")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("handler not canceled after losing the lease")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := s.get(job.JobId); got.Owner != "other" || got.State != model.JobConverting {
+		t.Errorf("job taken over should not be updated: %+v", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	opts := Options{BackoffBase: time.Second, BackoffMax: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := opts.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestQueueEvents(t *testing.T) {
+	Register("test_events", func(ctx context.Context, job *model.IngestJob) error {
+		SetState(ctx, model.JobConverting) // 领取时已经是converting，不重复推送
+		SetState(ctx, model.JobEmbedding)
+		SetStageProgress(ctx, UnitChunk, 0, 3)
+		SetStageProgress(ctx, UnitChunk, 1, 3) // 间隔太短，不推送
+		SetStageProgress(ctx, UnitChunk, 3, 3)
+		return nil
+	})
+	var mu sync.Mutex
+	var events []Event
+	s := newMemStore()
+	q := newQueue(s, Options{PollInterval: 5 * time.Millisecond})
+	q.publish = func(userId string, event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if userId != "u1" {
+			t.Errorf("unexpected user %s", userId)
+		}
+		events = append(events, event)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q.Start(ctx)
+	job := &model.IngestJob{UserId: "u1", Kind: "test_events", Name: "report.pdf"}
+	if err := q.Enqueue(job); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, s, job.JobId, model.JobDone)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"state queued 0/0", "state converting 0/0", "state embedding 0/0",
+		"stage embedding 0/3", "stage embedding 3/3", "state done 0/0",
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events %+v", len(events), events)
+	}
+	for i, event := range events {
+		got := fmt.Sprintf("%s %s %d/%d", event.Type, event.State, event.StageDone, event.StageTotal)
+		if got != want[i] || event.JobId != job.JobId || event.Name != "report.pdf" {
+			t.Errorf("event %d: got %q %+v, want %q", i, got, event, want[i])
+		}
+	}
+	if events[3].Unit != UnitChunk {
+		t.Errorf("unexpected unit %q", events[3].Unit)
+	}
+}
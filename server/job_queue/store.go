@@ -0,0 +1,124 @@
+// -------------------------------------------------
+// Package job_queue
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package job_queue
+
+import (
+	"errors"
+	"gcnote/server/config"
+	"gcnote/server/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+// store 任务的持久化，领取和更新都带条件，多个实例不会同时执行同一个任务
+type store interface {
+	Create(job *model.IngestJob) error
+	Get(jobId string, userId string) (*model.IngestJob, error)
+	// ListUnfinished 用户还没有结束的任务，按创建时间排序
+	ListUnfinished(userId string) ([]model.IngestJob, error)
+	// Claim 领取一个任务：排队中且到了执行时间，或者执行中但租约已经过期；没有可领取的任务时返回nil
+	Claim(owner string, now time.Time, lease time.Duration) (*model.IngestJob, error)
+	// Renew 续租，返回任务是否被请求取消；任务已不属于owner时返回errLeaseLost
+	Renew(jobId string, owner string, until time.Time) (bool, error)
+	// Update 更新owner正在执行的任务，任务已不属于owner时不更新
+	Update(jobId string, owner string, fields map[string]interface{}) error
+	// RequestCancel 标记取消，执行中的任务在续租时取消，排队中的任务马上被领取并取消
+	RequestCancel(jobId string, now time.Time) error
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+func newGormStore() *gormStore {
+	return &gormStore{db: config.DB}
+}
+
+func (s *gormStore) Create(job *model.IngestJob) error {
+	return s.db.Create(job).Error
+}
+
+func (s *gormStore) Get(jobId string, userId string) (*model.IngestJob, error) {
+	var job model.IngestJob
+	err := s.db.Where("job_id = ? AND user_id = ?", jobId, userId).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *gormStore) ListUnfinished(userId string) ([]model.IngestJob, error) {
+	var jobs []model.IngestJob
+	err := s.db.Where("user_id = ? AND state IN ?", userId, append([]string{model.JobQueued}, model.JobRunningStates...)).
+		Order("id").Find(&jobs).Error
+	return jobs, err
+}
+
+func (s *gormStore) Claim(owner string, now time.Time, lease time.Duration) (*model.IngestJob, error) {
+	var candidates []model.IngestJob
+	err := s.db.Where("(state = ? AND next_run_at <= ?) OR (state IN ? AND lease_until < ?)",
+		model.JobQueued, now, model.JobRunningStates, now).
+		Order("next_run_at").Limit(8).Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range candidates {
+		// 以attempts作为版本号，同时领取时只有一个实例能更新成功
+		res := s.db.Model(&model.IngestJob{}).
+			Where("id = ? AND state = ? AND attempts = ?", job.ID, job.State, job.Attempts).
+			Updates(map[string]interface{}{
+				"state":       model.JobConverting,
+				"owner":       owner,
+				"lease_until": now.Add(lease),
+				"attempts":    job.Attempts + 1,
+			})
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			job.State = model.JobConverting
+			job.Owner = owner
+			job.LeaseUntil = now.Add(lease)
+			job.Attempts++
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *gormStore) Renew(jobId string, owner string, until time.Time) (bool, error) {
+	res := s.db.Model(&model.IngestJob{}).Where("job_id = ? AND owner = ?", jobId, owner).
+		Update("lease_until", until)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return false, errLeaseLost
+	}
+	var job model.IngestJob
+	if err := s.db.Select("cancel_requested").Where("job_id = ?", jobId).First(&job).Error; err != nil {
+		return false, err
+	}
+	return job.CancelRequested, nil
+}
+
+func (s *gormStore) Update(jobId string, owner string, fields map[string]interface{}) error {
+	// 值没有变化时MySQL返回的影响行数为0，这里不用影响行数判断租约，由Renew判断
+	return s.db.Model(&model.IngestJob{}).Where("job_id = ? AND owner = ?", jobId, owner).Updates(fields).Error
+}
+
+func (s *gormStore) RequestCancel(jobId string, now time.Time) error {
+	// 排队中的任务不用等到重试时间，马上交给处理函数清理
+	return s.db.Model(&model.IngestJob{}).Where("job_id = ?", jobId).
+		Updates(map[string]interface{}{
+			"cancel_requested": true,
+			"next_run_at":      gorm.Expr("CASE WHEN state = ? THEN ? ELSE next_run_at END", model.JobQueued, now),
+		}).Error
+}
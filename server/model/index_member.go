@@ -0,0 +1,16 @@
+// -------------------------------------------------
+// Package model
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package model
+
+import "gorm.io/gorm"
+
+// IndexMember 知识库的成员，可以和所有者一起协同编辑知识库中的文档。移除时直接删除记录，可以再次添加
+type IndexMember struct {
+	gorm.Model
+	IndexId string `gorm:"uniqueIndex:idx_index_member;size:64"`
+	UserId  string `gorm:"uniqueIndex:idx_index_member;size:64;index"`
+}
@@ -0,0 +1,54 @@
+// -------------------------------------------------
+// Package model
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// 导入任务的状态
+const (
+	JobQueued     = "queued"     // 排队中，包括等待重试
+	JobConverting = "converting" // 转换为markdown（压缩包还包括解压）
+	JobSplitting  = "splitting"  // 切分
+	JobEmbedding  = "embedding"  // 计算向量
+	JobIndexing   = "indexing"   // 写入es和数据库
+	JobDone       = "done"
+	JobFailed     = "failed"
+	JobCanceled   = "canceled"
+)
+
+// JobRunningStates 执行中的几个状态
+var JobRunningStates = []string{JobConverting, JobSplitting, JobEmbedding, JobIndexing}
+
+// IsJobFinished 任务是否已经结束
+func IsJobFinished(state string) bool {
+	return state == JobDone || state == JobFailed || state == JobCanceled
+}
+
+// IngestJob 导入任务，保存在数据库中，服务重启后继续执行
+type IngestJob struct {
+	gorm.Model
+	JobId           string `gorm:"uniqueIndex;size:64"`
+	UserId          string `gorm:"index;size:64"`
+	IndexId         string
+	Kind            string // 任务类型，如file、url、archive
+	Name            string // 文档名或压缩包名，用于展示
+	KBFileId        string // 单个文件导入时预先分配的文档id
+	Payload         string `gorm:"type:text"` // 任务参数，json格式，由各类型的处理函数解析
+	State           string `gorm:"index;size:16"`
+	Reason          string `gorm:"type:text"` // 最近一次失败的原因
+	Attempts        int    // 已经执行的次数
+	MaxAttempts     int
+	NextRunAt       time.Time `gorm:"index"` // 排队中的任务到这个时间后才执行（重试退避）
+	Owner           string    // 正在执行的实例
+	LeaseUntil      time.Time // 执行中的任务超过这个时间没有续租，视为实例已崩溃，可以被重新领取
+	CancelRequested bool
+	Done            int // 多文件任务已经处理的文件数
+	Total           int
+}
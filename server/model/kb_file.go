@@ -6,7 +6,10 @@
 
 package model
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"net/url"
+)
 
 type KBFile struct {
 	gorm.Model
@@ -14,4 +17,13 @@ type KBFile struct {
 	IndexId    string
 	KBFileId   string
 	KBFileName string
+	// 上传的源文件和转换后markdown的SHA-256，用于发现重复导入；新建的文档没有源文件
+	SourceHash  string `gorm:"index;size:64"`
+	ContentHash string `gorm:"index;size:64"`
+}
+
+// KBFileLink 文档之间互相引用的链接，格式为 kbfile://<index_id>/<kb_file_id>，由前端解析后跳转
+func KBFileLink(indexId string, kbFileId string) string {
+	u := url.URL{Scheme: "kbfile", Host: indexId, Path: "/" + kbFileId}
+	return u.String()
 }
@@ -0,0 +1,26 @@
+// -------------------------------------------------
+// Package model
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package model
+
+import "gorm.io/gorm"
+
+// 文档元数据的key
+const (
+	MetaSourceURL  = "source_url"  // 从url导入时的原始地址
+	MetaFetchedAt  = "fetched_at"  // 从url导入时的抓取时间
+	MetaFolderPath = "folder_path" // 批量导入时文件在压缩包内所在的目录
+	MetaTag        = "tag"         // 标签，如Obsidian笔记的tags
+	MetaParentId   = "parent_id"   // 上级文档的KBFileId，如Notion的页面层级
+)
+
+// KBFileMeta 文档的元数据，一个key可以有多个值（如多个标签）
+type KBFileMeta struct {
+	gorm.Model
+	KBFileId string `gorm:"index"`
+	Key      string
+	Value    string
+}
@@ -0,0 +1,20 @@
+// -------------------------------------------------
+// Package model
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package model
+
+import "gorm.io/gorm"
+
+// KBFileRevision 文档保存过的一个版本，内容按hash保存在PathCfg.RevisionPath下
+type KBFileRevision struct {
+	gorm.Model
+	KBFileId string `gorm:"uniqueIndex:idx_kb_file_revision;size:64"`
+	Revision int    `gorm:"uniqueIndex:idx_kb_file_revision"` // 文档内的版本号，从1开始
+	Hash     string `gorm:"size:64"`                          // 内容的SHA-256
+	Author   string `gorm:"size:64"`                          // 保存这个版本的用户
+	Size     int64  // 内容的字节数
+	Message  string // 保存时的说明
+}
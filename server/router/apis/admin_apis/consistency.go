@@ -0,0 +1,60 @@
+// -------------------------------------------------
+// Package admin_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package admin_apis
+
+import (
+	"gcnote/server/consistency"
+	"gcnote/server/dto"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ConsistencyCheck
+// @Summary      一致性检查
+// @Description  交叉对比MySQL、知识库和回收站目录、es、redis缓存，返回孤立和不一致的数据；repair为true时修复（重新导入或者删除）
+// @ID           consistency-check
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.ConsistencyCheckRequest  false  "检查选项"
+// @Success      200  {object}  dto.BaseResponse{Data=consistency.Report}  "成功，返回检查结果"
+// @Failure      400  {object}  dto.BaseResponse                           "参数错误(code:40000)"
+// @Failure      401  {object}  dto.BaseResponse                           "Token错误(code:40101)"
+// @Failure      403  {object}  dto.BaseResponse                           "不是管理员(code:40104)"
+// @Failure      500  {object}  dto.BaseResponse                           "服务器内部错误(code:50000)"
+// @Router       /admin/consistency_check [post]
+func ConsistencyCheck(ctx *gin.Context) {
+	var req dto.ConsistencyCheckRequest
+	// 请求体可以为空，表示只检查不修复
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+			return
+		}
+	}
+	for _, kind := range req.Kinds {
+		if !slices.Contains(consistency.Kinds, kind) {
+			ctx.JSON(http.StatusBadRequest, dto.FailWithMessage(dto.ParamsErrCode, "unknown kind "+kind))
+			return
+		}
+	}
+	// 检查所有数据需要的时间可能超过服务器的WriteTimeout
+	if err := http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		zap.S().Errorf("Clear write deadline error: %v", err)
+	}
+
+	report, err := consistency.Run(ctx, consistency.Options{Repair: req.Repair, Kinds: req.Kinds})
+	if err != nil {
+		zap.S().Errorf("Consistency check failed: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(report))
+}
@@ -0,0 +1,363 @@
+// -------------------------------------------------
+// Package api_fixture
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+// 接口测试用的数据库、redis、es替身，可以让指定的步骤失败，只在测试中引用
+
+package api_fixture
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"gcnote/server/config"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ErrInjected 替身按要求返回的错误
+var ErrInjected = errors.New("injected failure")
+
+// Env 替换后的config
+type Env struct {
+	DB *DB
+	ES *ES
+}
+
+// Setup 把config中的数据库、redis、es和数据目录换成替身，测试结束后恢复。
+// redis只保存Get/Set/Del的值，其他命令都返回错误
+func Setup(t testing.TB) *Env {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	oldDB, oldRedis, oldES, oldPath := config.DB, config.RedisClient, config.ElasticClient, config.PathCfg
+	t.Cleanup(func() {
+		config.DB, config.RedisClient, config.ElasticClient, config.PathCfg = oldDB, oldRedis, oldES, oldPath
+	})
+
+	env := &Env{DB: &DB{}, ES: &ES{indexes: map[string]bool{}}}
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(dbConnector{db: env.DB}),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.DB = gormDB
+
+	config.RedisClient = &fakeRedis{
+		UniversalClient: redis.NewClient(&redis.Options{
+			MaxRetries: -1,
+			Dialer: func(context.Context, string, string) (net.Conn, error) {
+				return nil, errors.New("redis is not available in tests")
+			},
+		}),
+		values: map[string]string{},
+	}
+
+	server := httptest.NewServer(env.ES)
+	t.Cleanup(server.Close)
+	if config.ElasticClient, err = elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{server.URL}}); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	config.PathCfg.KnowledgeBasePath = filepath.Join(root, "knowledge_base")
+	config.PathCfg.RecycleBinPath = filepath.Join(root, "recycle_bin")
+	config.PathCfg.RevisionPath = filepath.Join(root, "revisions")
+	config.PathCfg.TempDirPath = filepath.Join(root, "tmp")
+	return env
+}
+
+// Call 以userId的身份调用接口，body序列化为json
+func Call(t testing.TB, handler gin.HandlerFunc, userId string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	ctx.Set("claims", jwt.MapClaims{"sub": userId})
+	handler(ctx)
+	return w
+}
+
+// WriteFile 写入文件，按需创建上级目录
+func WriteFile(t testing.TB, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// DB 记录执行的语句，包含failOn的语句返回ErrInjected；查询返回Return设置的结果，没有设置时为空
+type DB struct {
+	mu         sync.Mutex
+	statements []string
+	failOn     string
+	results    []queryResult
+}
+
+type queryResult struct {
+	substr  string
+	columns []string
+	rows    [][]driver.Value
+}
+
+// Return 之后包含substr的查询返回这些行，每行的值和columns一一对应
+func (d *DB) Return(substr string, columns []string, rows ...[]driver.Value) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.results = append(d.results, queryResult{substr: substr, columns: columns, rows: rows})
+}
+
+// FailOn 之后执行的语句包含substr时失败，如 "COMMIT"、"INSERT INTO `recycles`"
+func (d *DB) FailOn(substr string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.failOn = substr
+}
+
+// Statements 执行过的语句，事务记为BEGIN、COMMIT、ROLLBACK
+func (d *DB) Statements() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.statements...)
+}
+
+func (d *DB) exec(statement string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, statement)
+	if d.failOn != "" && strings.Contains(statement, d.failOn) {
+		return ErrInjected
+	}
+	return nil
+}
+
+type dbConnector struct {
+	db *DB
+}
+
+func (c dbConnector) Connect(context.Context) (driver.Conn, error) {
+	return &dbConn{db: c.db}, nil
+}
+
+func (c dbConnector) Driver() driver.Driver {
+	return dbDriver{}
+}
+
+type dbDriver struct{}
+
+func (dbDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("open through the connector")
+}
+
+// dbConn 实现ExecerContext和QueryerContext，database/sql不会走Prepare
+type dbConn struct {
+	db *DB
+}
+
+func (c *dbConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *dbConn) Close() error {
+	return nil
+}
+
+func (c *dbConn) Begin() (driver.Tx, error) {
+	if err := c.db.exec("BEGIN"); err != nil {
+		return nil, err
+	}
+	return dbTx{db: c.db}, nil
+}
+
+func (c *dbConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if err := c.db.exec(query); err != nil {
+		return nil, err
+	}
+	return execResult{}, nil
+}
+
+// execResult 每条语句都影响一行，插入的id为1
+type execResult struct{}
+
+func (execResult) LastInsertId() (int64, error) {
+	return 1, nil
+}
+
+func (execResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+func (c *dbConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := c.db.exec(query); err != nil {
+		return nil, err
+	}
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	for _, result := range c.db.results {
+		if strings.Contains(query, result.substr) {
+			return &fakeRows{columns: result.columns, rows: result.rows}, nil
+		}
+	}
+	return &fakeRows{}, nil
+}
+
+type dbTx struct {
+	db *DB
+}
+
+func (t dbTx) Commit() error {
+	return t.db.exec("COMMIT")
+}
+
+func (t dbTx) Rollback() error {
+	return t.db.exec("ROLLBACK")
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// fakeRedis 只实现接口检查时用到的字符串读写，其他命令由连不上的客户端返回错误
+type fakeRedis struct {
+	redis.UniversalClient
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (r *fakeRedis) Get(ctx context.Context, key string) *redis.StringCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.values[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (r *fakeRedis) Set(ctx context.Context, key string, value interface{}, _ time.Duration) *redis.StatusCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch v := value.(type) {
+	case []byte:
+		r.values[key] = string(v)
+	case string:
+		r.values[key] = v
+	default:
+		data, _ := json.Marshal(v)
+		r.values[key] = string(data)
+	}
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (r *fakeRedis) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		delete(r.values, key)
+	}
+	return redis.NewIntResult(int64(len(keys)), nil)
+}
+
+// ES 记录收到的请求（如 "PUT /gcnote-i1"），包含failOn的请求返回500。
+// 创建过或者AddIndex的索引HEAD时返回200，其他返回404
+type ES struct {
+	mu       sync.Mutex
+	requests []string
+	failOn   string
+	indexes  map[string]bool
+}
+
+// FailOn 之后的请求包含substr时失败，如 "PUT "、"_delete_by_query"
+func (e *ES) FailOn(substr string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failOn = substr
+}
+
+// AddIndex 记为已经存在的索引
+func (e *ES) AddIndex(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.indexes[name] = true
+}
+
+// Requests 收到的请求
+func (e *ES) Requests() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.requests...)
+}
+
+func (e *ES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	request := r.Method + " " + r.URL.Path
+	e.requests = append(e.requests, request)
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	if e.failOn != "" && strings.Contains(request, e.failOn) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"injected failure"}`))
+		return
+	}
+	name := strings.Trim(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodHead:
+		if !e.indexes[name] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return
+	case http.MethodPut:
+		e.indexes[name] = true
+	case http.MethodDelete:
+		delete(e.indexes, name)
+	}
+	_, _ = w.Write([]byte(`{}`))
+}
@@ -0,0 +1,76 @@
+// -------------------------------------------------
+// Package index_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package index_apis
+
+import (
+	"database/sql/driver"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/router/apis/api_fixture"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCreateIndexCompensation(t *testing.T) {
+	env := api_fixture.Setup(t)
+	env.DB.FailOn("COMMIT")
+
+	w := api_fixture.Call(t, CreateIndex, "u1", dto.IndexCreateRequest{IndexName: "笔记"})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	// 提交失败时删除已经创建的es索引和目录
+	var created string
+	for _, request := range env.ES.Requests() {
+		if strings.HasPrefix(request, "PUT ") {
+			created = strings.TrimPrefix(request, "PUT ")
+		}
+	}
+	if created == "" || !slices.Contains(env.ES.Requests(), "DELETE "+created) {
+		t.Errorf("es index should be deleted, requests %v", env.ES.Requests())
+	}
+	entries, err := os.ReadDir(config.PathCfg.KnowledgeBasePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("index dir should be removed, left %v", entries)
+	}
+}
+
+func TestDeleteIndexCompensation(t *testing.T) {
+	env := api_fixture.Setup(t)
+	env.DB.Return("FROM `indices`", []string{"index_id", "index_name", "user_id"}, []driver.Value{"i1", "笔记", "u1"})
+	env.ES.AddIndex("gcnote-i1")
+	env.ES.FailOn("DELETE ")
+	mdPath := filepath.Join(config.PathCfg.KnowledgeBasePath, "i1", "k1", "a.md")
+	api_fixture.WriteFile(t, mdPath, "# a")
+
+	w := api_fixture.Call(t, DeleteIndex, "u1", dto.IndexRequest{IndexId: "i1"})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	// 删除es索引失败时移回知识库目录，回滚数据库
+	if _, err := os.Stat(mdPath); err != nil {
+		t.Errorf("index dir should be restored: %v", err)
+	}
+	entries, err := os.ReadDir(config.PathCfg.KnowledgeBasePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("moved aside dir should not be left, got %v", entries)
+	}
+	statements := env.DB.Statements()
+	if slices.Contains(statements, "COMMIT") || !slices.Contains(statements, "ROLLBACK") {
+		t.Errorf("transaction should be rolled back, statements %v", statements)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"gcnote/server/dto"
 	"gcnote/server/model"
 	"gcnote/server/router/wrench"
+	"gcnote/server/saga"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
@@ -90,25 +91,29 @@ func CreateIndex(ctx *gin.Context) {
 		return
 	}
 	// ------------------------------------------------------------------
+	// 数据库、文件系统、es依次创建，失败时撤销已经完成的步骤
+	sg := saga.New("create index " + indexId)
+	defer sg.Rollback()
 	// 开始事务
-	tx = config.DB.Begin()
-	if tx.Error != nil {
-		zap.S().Errorf("Failed to begin transaction, err: %v", tx.Error)
+	tx, err = sg.Begin(config.DB)
+	if err != nil {
+		zap.S().Errorf("Failed to begin transaction, err: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
-	// 成功创建
-	err = tx.Create(&indexNew).Error
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		zap.S().Errorf("Create index %v, err: %v", indexNew.IndexName, tx.Error)
-		tx.Rollback()
+	// 成功创建，由事务回滚撤销
+	err = sg.Do("create index record", func() error {
+		return tx.Create(&indexNew).Error
+	}, nil)
+	if err != nil {
+		zap.S().Errorf("Create index %v, err: %v", indexNew.IndexName, err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
 	// ------------------------------------------------------------------
 	// 文件系统创建
 	kbPath := config.PathCfg.KnowledgeBasePath
-	// 检查路径是否存在
+	// 检查路径是否存在，知识库根目录是共用的，不需要撤销
 	_, err = os.Stat(kbPath)
 	if os.IsNotExist(err) {
 		zap.S().Infof("路径 %v 不存在，执行创建操作", kbPath)
@@ -116,23 +121,24 @@ func CreateIndex(ctx *gin.Context) {
 		zap.S().Infof("路径 %v 创建成功。", kbPath)
 		if err != nil {
 			zap.S().Errorf("创建路径 %v 失败， err: %v", kbPath, err)
-			tx.Rollback()
 			ctx.JSON(http.StatusInternalServerError, dto.FailWithMessage(dto.InternalErrCode, "create file dir error"))
 			return
 		}
 	} else if err != nil {
 		zap.S().Errorf("检查路径 %s 时出错: %v\n", kbPath, err)
-		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, dto.FailWithMessage(dto.InternalErrCode, "create file dir error"))
 		return
 	}
 
 	// 创建名为 xy 的文件夹
 	xyPath := filepath.Join(kbPath, indexId)
-	err = os.Mkdir(xyPath, os.ModePerm)
+	err = sg.Do("create index dir", func() error {
+		return os.Mkdir(xyPath, os.ModePerm)
+	}, func() error {
+		return os.RemoveAll(xyPath)
+	})
 	if err != nil {
 		zap.S().Errorf("创建文件夹 %s 失败: %v\n", xyPath, err)
-		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, dto.FailWithMessage(dto.InternalErrCode, "create file dir error"))
 		return
 	}
@@ -150,7 +156,11 @@ func CreateIndex(ctx *gin.Context) {
 		return
 	}
 	// 只有404才创建
-	err = search_engine.IndexCreate(config.ElasticClient, "gcnote-"+indexId)
+	err = sg.Do("create es index", func() error {
+		return search_engine.IndexCreate(config.ElasticClient, "gcnote-"+indexId)
+	}, func() error {
+		return search_engine.IndexDelete(config.ElasticClient, "gcnote-"+indexId)
+	})
 	if err != nil {
 		zap.S().Errorf("Failed to create index, err: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
@@ -159,12 +169,13 @@ func CreateIndex(ctx *gin.Context) {
 
 	// ----------------------------------------------------
 
-	err = tx.Commit().Error
+	err = sg.Commit(tx)
 	if err != nil {
 		zap.S().Errorf("Failed to commit transaction, err: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
+	sg.Complete()
 
 	// 设置缓存
 	err = cache.SetIndexInfo(ctx, indexNew)
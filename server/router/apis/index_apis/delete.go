@@ -12,11 +12,11 @@ import (
 	"gcnote/server/config"
 	"gcnote/server/dto"
 	"gcnote/server/model"
+	"gcnote/server/saga"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 	"net/http"
-	"os"
 	"path/filepath"
 )
 
@@ -58,14 +58,21 @@ func DeleteIndex(ctx *gin.Context) {
 		return
 	}
 
+	// 数据库、文件系统、es依次删除，失败时撤销已经完成的步骤
+	sg := saga.New("delete index " + req.IndexId)
+	defer sg.Rollback()
 	// 开始事务
-	tx := config.DB.Begin()
+	tx, err := sg.Begin(config.DB)
+	if err != nil {
+		zap.S().Errorf("Failed to begin transaction: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
 
 	// 检查知识库是否存在
 	var indexInfo model.Index
 	if err := tx.Where("index_id = ?", req.IndexId).First(&indexInfo).Error; err != nil {
 		zap.S().Errorf("Knowledge base not found: %v", req.IndexId)
-		tx.Rollback()
 		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
 		return
 	}
@@ -74,39 +81,45 @@ func DeleteIndex(ctx *gin.Context) {
 	var kbFiles []model.KBFile
 	if err := tx.Where("index_id = ?", req.IndexId).Find(&kbFiles).Error; err != nil {
 		zap.S().Errorf("Failed to get KBFile records for index_id %v: %v", req.IndexId, err)
-		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
 
-	// 删除关联的KBFile记录
-	if err := tx.Where("index_id = ?", req.IndexId).Delete(&model.KBFile{}).Error; err != nil {
-		zap.S().Errorf("Failed to delete KBFile records for index_id %v: %v", req.IndexId, err)
-		tx.Rollback()
-		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
-		return
+	// 删除关联的KBFile记录、元数据、版本记录、成员和知识库记录，由事务回滚撤销
+	kbFileIds := make([]string, 0, len(kbFiles))
+	for _, kbFile := range kbFiles {
+		kbFileIds = append(kbFileIds, kbFile.KBFileId)
 	}
-
-	// 删除知识库记录
-	if err := tx.Delete(&indexInfo).Error; err != nil {
-		zap.S().Errorf("Failed to delete index %v: %v", req.IndexId, err)
-		tx.Rollback()
+	err = sg.Do("delete index records", func() error {
+		if err := tx.Where("index_id = ?", req.IndexId).Delete(&model.KBFile{}).Error; err != nil {
+			return err
+		}
+		if len(kbFileIds) > 0 {
+			if err := tx.Where("kb_file_id IN ?", kbFileIds).Delete(&model.KBFileMeta{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("kb_file_id IN ?", kbFileIds).Delete(&model.KBFileRevision{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("index_id = ?", req.IndexId).Delete(&model.IndexMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&indexInfo).Error
+	}, nil)
+	if err != nil {
+		zap.S().Errorf("Failed to delete records of index %v: %v", req.IndexId, err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
 
-	// 删除文件系统中的知识库及关联文件
+	// 删除文件系统中的知识库及关联文件，先移开，全部成功后才真正删除
 	kbPath := config.PathCfg.KnowledgeBasePath
 	xyPath := filepath.Join(kbPath, req.IndexId)
-	if _, err := os.Stat(xyPath); os.IsNotExist(err) {
-		zap.S().Infof("Path %v does not exist, skipping deletion", xyPath)
-	} else {
-		if err := os.RemoveAll(xyPath); err != nil {
-			zap.S().Errorf("Failed to delete path %v: %v", xyPath, err)
-			tx.Rollback()
-			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
-			return
-		}
+	if err = sg.RemoveDir(xyPath); err != nil {
+		zap.S().Errorf("Failed to delete path %v: %v", xyPath, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
 	}
 
 	// -----------------------------------------------
@@ -122,8 +135,10 @@ func DeleteIndex(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
-	// 删除index
-	err = search_engine.IndexDelete(config.ElasticClient, "gcnote-"+req.IndexId)
+	// 删除index，无法撤销，放在提交之前的最后一步
+	err = sg.Do("delete es index", func() error {
+		return search_engine.IndexDelete(config.ElasticClient, "gcnote-"+req.IndexId)
+	}, nil)
 	if err != nil {
 		zap.S().Errorf("Failed to delete the index, err: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
@@ -131,11 +146,12 @@ func DeleteIndex(ctx *gin.Context) {
 	}
 
 	// 提交事务
-	if err = tx.Commit().Error; err != nil {
+	if err = sg.Commit(tx); err != nil {
 		zap.S().Errorf("Failed to commit transaction: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
+	sg.Complete()
 
 	// 清理缓存
 	// 1. 删除index信息缓存
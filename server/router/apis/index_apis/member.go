@@ -0,0 +1,170 @@
+// -------------------------------------------------
+// Package index_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package index_apis
+
+import (
+	"errors"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/model"
+	"gcnote/server/router/apis/kb_apis"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// AddIndexMember
+// @Summary      添加知识库成员
+// @Description  只有知识库的所有者可以添加，成员可以读取、编辑、协同编辑、创建、重命名、删除知识库中的文档和恢复它们的版本、回收站中的文件，
+// @Description  导入文件和管理知识库只有所有者可以
+// @ID           add-index-member
+// @Tags         index
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.IndexMemberRequest true "成员请求体"
+// @Success      200      {object}  dto.BaseResponse       "成功响应，返回success"
+// @Failure      400      {object}  dto.BaseResponse       "参数错误，或者添加自己(code:40000)"
+// @Failure      401      {object}  dto.BaseResponse       "Token错误(code:40101)"
+// @Failure      404      {object}  dto.BaseResponse       "知识库不存在(code:40201)、用户不存在(code:40001)"
+// @Failure      409      {object}  dto.BaseResponse       "已经是成员(code:40502)"
+// @Failure      500      {object}  dto.BaseResponse       "服务器内部错误(code:50000)"
+// @Router       /index/add_member [post]
+func AddIndexMember(ctx *gin.Context) {
+	var req dto.IndexMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		zap.S().Debugf("Invalid parameters: %+v", req)
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 验证用户身份
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" || req.UserId == currentUserId {
+		zap.S().Debugf("currentUserId is empty or equals to the member")
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 只有所有者可以管理成员
+	var index model.Index
+	err := config.DB.Where("index_id = ? AND user_id = ?", req.IndexId, currentUserId).First(&index).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index %v: %v", req.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	var user model.User
+	err = config.DB.Where("user_id = ?", req.UserId).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.RecordNotFoundErrCode))
+		return
+	} else if err != nil {
+		zap.S().Errorf("Failed to get user %v: %v", req.UserId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	var count int64
+	if err = config.DB.Model(&model.IndexMember{}).
+		Where("index_id = ? AND user_id = ?", req.IndexId, req.UserId).
+		Count(&count).Error; err != nil {
+		zap.S().Errorf("Failed to query index member: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if count > 0 {
+		ctx.JSON(http.StatusConflict, dto.Fail(dto.ShareAlreadyExistErrCode))
+		return
+	}
+	if err = config.DB.Create(&model.IndexMember{IndexId: req.IndexId, UserId: req.UserId}).Error; err != nil {
+		zap.S().Errorf("Failed to add member %v to index %v: %v", req.UserId, req.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	zap.S().Infof("Added member %v to index %v", req.UserId, req.IndexId)
+	ctx.JSON(http.StatusOK, dto.Success())
+}
+
+// RemoveIndexMember
+// @Summary      移除知识库成员
+// @Description  只有知识库的所有者可以移除，同时断开该用户在知识库中的协同编辑连接
+// @ID           remove-index-member
+// @Tags         index
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.IndexMemberRequest true "成员请求体"
+// @Success      200      {object}  dto.BaseResponse       "成功响应，返回success"
+// @Failure      400      {object}  dto.BaseResponse       "参数错误(code:40000)"
+// @Failure      401      {object}  dto.BaseResponse       "Token错误(code:40101)"
+// @Failure      404      {object}  dto.BaseResponse       "知识库不存在(code:40201)、不是成员(code:40001)"
+// @Failure      500      {object}  dto.BaseResponse       "服务器内部错误(code:50000)"
+// @Router       /index/remove_member [post]
+func RemoveIndexMember(ctx *gin.Context) {
+	var req dto.IndexMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		zap.S().Debugf("Invalid parameters: %+v", req)
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 验证用户身份
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		zap.S().Debugf("currentUserId is empty")
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 只有所有者可以管理成员
+	var index model.Index
+	err := config.DB.Where("index_id = ? AND user_id = ?", req.IndexId, currentUserId).First(&index).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index %v: %v", req.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	tx := config.DB.Unscoped().Where("index_id = ? AND user_id = ?", req.IndexId, req.UserId).Delete(&model.IndexMember{})
+	if tx.Error != nil {
+		zap.S().Errorf("Failed to remove member %v from index %v: %v", req.UserId, req.IndexId, tx.Error)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if tx.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.RecordNotFoundErrCode))
+		return
+	}
+
+	kb_apis.KickCollaborator(req.IndexId, req.UserId)
+
+	zap.S().Infof("Removed member %v from index %v", req.UserId, req.IndexId)
+	ctx.JSON(http.StatusOK, dto.Success())
+}
@@ -0,0 +1,52 @@
+// -------------------------------------------------
+// Package index_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package index_apis
+
+import (
+	"database/sql/driver"
+	"gcnote/server/dto"
+	"gcnote/server/router/apis/api_fixture"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAddIndexMember(t *testing.T) {
+	env := api_fixture.Setup(t)
+	env.DB.Return("FROM `indices`", []string{"index_id", "index_name", "user_id"}, []driver.Value{"i1", "笔记", "u1"})
+	env.DB.Return("FROM `users`", []string{"user_id"}, []driver.Value{"u2"})
+
+	if w := api_fixture.Call(t, AddIndexMember, "u1", dto.IndexMemberRequest{IndexId: "i1", UserId: "u1"}); w.Code != http.StatusBadRequest {
+		t.Errorf("adding the owner: got %d", w.Code)
+	}
+	if w := api_fixture.Call(t, AddIndexMember, "u1", dto.IndexMemberRequest{IndexId: "i1", UserId: "u2"}); w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	inserted := false
+	for _, statement := range env.DB.Statements() {
+		inserted = inserted || strings.HasPrefix(statement, "INSERT INTO `index_members`")
+	}
+	if !inserted {
+		t.Errorf("member not inserted, statements %v", env.DB.Statements())
+	}
+}
+
+func TestRemoveIndexMember(t *testing.T) {
+	env := api_fixture.Setup(t)
+	env.DB.Return("FROM `indices`", []string{"index_id", "index_name", "user_id"}, []driver.Value{"i1", "笔记", "u1"})
+
+	if w := api_fixture.Call(t, RemoveIndexMember, "u1", dto.IndexMemberRequest{IndexId: "i1", UserId: "u2"}); w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	deleted := false
+	for _, statement := range env.DB.Statements() {
+		deleted = deleted || strings.HasPrefix(statement, "DELETE FROM `index_members`")
+	}
+	if !deleted {
+		t.Errorf("member not deleted, statements %v", env.DB.Statements())
+	}
+}
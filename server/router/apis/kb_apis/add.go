@@ -7,22 +7,25 @@
 package kb_apis
 
 import (
+	"context"
 	"errors"
 	"gcnote/server/ability/convert"
+	"gcnote/server/ability/convert/convert_base"
 	"gcnote/server/ability/embeds"
 	"gcnote/server/ability/search_engine"
 	"gcnote/server/ability/splitter"
 	"gcnote/server/cache"
 	"gcnote/server/config"
 	"gcnote/server/dto"
+	"gcnote/server/job_queue"
 	"gcnote/server/model"
 	"gcnote/server/router/wrench"
+	"gcnote/server/saga"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
-	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -32,16 +35,17 @@ import (
 
 // AddKBFile
 // @Summary		导入文件
-// @Description	 将文件导入对应的知识库
+// @Description	 将文件导入对应的知识库。用户的知识库中已有相同内容（源文件或转换后的markdown的SHA-256相同）时，
+// @Description	 按on_duplicate处理：reject拒绝，link导入一个链接到已有文档的文档，create(默认)照常导入并在结果中返回重复的文档
 // @ID			 add_kb_file
 // @Tags		 index
 // @Accept       json
 // @Produce      json
 // @Param		request		body		dto.KBFileAddRequest true "文档添加请求体"
-// @Success		 200	{object} 		dto.BaseResponse "成功"
+// @Success		 200	{object} 		dto.BaseResponse{Data=dto.ImportJobResponse} "成功，返回导入任务id"
 // @Failure		 400	{object} 		dto.BaseResponse "KBFileName无效等参数问题(40300)"
 // @Failure		 401	{object} 		dto.BaseResponse "未授权，用户未登录(40101)"
-// @Failure		 409	{object} 		dto.BaseResponse "知识库不存在（40201）"
+// @Failure		 409	{object} 		dto.BaseResponse{Data=[]dto.DuplicateKBFile} "知识库不存在（40201）、已有相同的文件(40304)，返回重复文档的位置"
 // @Failure      500	{object} 		dto.BaseResponse "服务器内部错误(code:50000)"
 // @Router		 /index/add_file [post]
 func AddKBFile(ctx *gin.Context) {
@@ -113,150 +117,322 @@ func AddKBFile(ctx *gin.Context) {
 	}
 	// -------------------------------------------------
 
-	// 启动 goroutine 处理文件，上传至ES等
-	go processFileAsync(KBFileNew, req, ctx, currentUserId, callback)
-
-	ctx.JSON(http.StatusOK, dto.SuccessWithData("正在导入中"))
-}
-
-func processFileAsync(
-	KBFileNew model.KBFile,
-	req dto.KBFileAddRequest,
-	ctx *gin.Context,
-	currentUserId string,
-	callback func(ctx *gin.Context, KBFileName, state, failReason string, userId string),
-) {
-	// 正式开始文件处理
-	// 获取文件名和文件扩展名
-	zap.S().Debugf("以下部分为goroutine中执行")
-	zap.S().Debugf("goroutine start ---------------------------------------------------------------")
-	// 开始导入
-	tx := config.DB.Begin()
-	if tx.Error != nil {
-		zap.S().Errorf("Failed to begin transaction, err:%v", tx.Error)
+	// 先保存上传的文件，再提交导入任务，由任务队列的worker处理，服务重启后也会继续
+	jobId := wrench.IdGenerator()
+	tmpFileDirPath := filepath.Join(config.PathCfg.TempDirPath, jobId)
+	tmpFilePath := filepath.Join(tmpFileDirPath, filepath.Base(req.File.Filename))
+	err = ctx.SaveUploadedFile(req.File, tmpFilePath)
+	if err != nil {
+		zap.S().Errorf("Save file %s error: %v", req.File.Filename, err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
 
-	err := tx.Create(&KBFileNew).Error
+	// 源文件相同的可以马上发现，转换后markdown相同的由worker检查
+	req.OnDuplicate = onDuplicateOrDefault(req.OnDuplicate)
+	sourceHash, err := wrench.FileSHA256(tmpFilePath)
 	if err != nil {
-		zap.S().Errorf("Create kbfile %v, Error: %v", KBFileNew.KBFileName, tx.Error)
-		tx.Rollback()
+		zap.S().Errorf("Hash file %s error: %v", req.File.Filename, err)
+		_ = wrench.RemoveContents(tmpFileDirPath)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
-	// 文件处理
-	fileName := req.File.Filename
-	fileExt := filepath.Ext(fileName)
-	// 获取上传的文件
-	file, err := req.File.Open()
+	duplicates, err := findDuplicates(currentUserId, sourceHash, "", KBFileNew.KBFileId)
 	if err != nil {
-		tx.Rollback()
-		callback(ctx, KBFileNew.KBFileName, "fail", "param file error.", currentUserId)
+		zap.S().Errorf("Find duplicates of %s error: %v", req.File.Filename, err)
+		_ = wrench.RemoveContents(tmpFileDirPath)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
-	defer func(file multipart.File) {
-		err = file.Close()
-		if err != nil {
-			zap.S().Errorf("Close File Error %s Error: %v\n", fileName, err)
-			ctx.JSON(http.StatusInternalServerError, dto.FailWithMessage(dto.InternalErrCode, "create file dir error"))
-			return
-		}
-	}(file)
-	// 保存到本地tmp目录里面先
-	tmpDirPath := config.PathCfg.TempDirPath
-	tmpFileDirPath := filepath.Join(tmpDirPath, wrench.IdGenerator())
-	tmpFilePath := filepath.Join(tmpFileDirPath, fileName)
-	// 保存到这里
-	dst := filepath.Join(tmpFileDirPath, fileName)
-	err = ctx.SaveUploadedFile(req.File, dst)
+	if len(duplicates) > 0 && req.OnDuplicate == dto.DuplicateReject {
+		_ = wrench.RemoveContents(tmpFileDirPath)
+		ctx.JSON(http.StatusConflict, dto.FailWithData(dto.KBFileDuplicateErrCode, duplicates))
+		return
+	}
+
+	job, err := newIngestJob(jobId, jobKindFile, KBFileNew, fileJobPayload{
+		Path:        tmpFilePath,
+		Ext:         filepath.Ext(req.File.Filename),
+		TmpDir:      tmpFileDirPath,
+		SourceHash:  sourceHash,
+		OnDuplicate: req.OnDuplicate,
+	})
+	if err == nil {
+		err = job_queue.Enqueue(job)
+	}
 	if err != nil {
-		tx.Rollback()
-		callback(ctx, KBFileNew.KBFileName, "fail", "file save error.", currentUserId)
+		zap.S().Errorf("Enqueue job for %s error: %v", req.File.Filename, err)
+		_ = wrench.RemoveContents(tmpFileDirPath)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
-	// 导入没问题 那么我就后续处理了
-	// ------------------------------------
+
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.ImportJobResponse{JobId: job.JobId, Message: "正在导入中",
+		Duplicates: duplicates}))
+}
+
+// ingestSource 已经保存到临时目录、等待导入的文件
+type ingestSource struct {
+	path    string                                                       // 临时文件路径
+	ext     string                                                       // 文件后缀
+	convert func(documentPath, outputDir string) (string, string, error) // 为空时使用convert.AutoConvert
+	meta    []model.KBFileMeta                                           // 需要记录的元数据，KBFileId会自动填上
+	// 源文件的SHA-256，为空时导入时计算
+	sourceHash string
+	// 已有相同内容时的处理方式，dto.DuplicateReject或dto.DuplicateLink时检查，为空时照常导入
+	onDuplicate string
+}
+
+// importError 导入失败，reason展示在导入任务列表中
+type importError struct {
+	reason string
+	err    error
+}
+
+func (e *importError) Error() string {
+	if e.err == nil {
+		return e.reason
+	}
+	return e.reason + " " + e.err.Error()
+}
+
+func (e *importError) Unwrap() error {
+	return e.err
+}
+
+// importReason 展示在导入任务列表中的失败原因
+func importReason(err error) string {
+	var ie *importError
+	if errors.As(err, &ie) {
+		return ie.reason
+	}
+	return err.Error()
+}
+
+// 计算向量和写入es时每批的切片数
+const ingestBatchSize = 32
+
+// ingestFile 转换、切分并上传到es，成功后写入数据库和缓存，返回文本文件识别出的源编码。
+// 转换和写入es都在事务之外进行，最后才在一个短事务里写入文档记录和元数据，导入大文件时不占用数据库连接和行锁。
+// 各步骤由saga记录，失败时删除文档目录和已经写入es的切片，同一个KBFileId可以重新执行；
+// 转换失败等重试也不会成功的错误用job_queue.Permanent包装
+func ingestFile(ctx context.Context, KBFileNew model.KBFile, src ingestSource) (string, error) {
+	// 上一次执行已经提交，只是没来得及更新任务状态
+	var count int64
+	if err := config.DB.Model(&model.KBFile{}).Where("kb_file_id = ?", KBFileNew.KBFileId).
+		Count(&count).Error; err != nil {
+		return "", &importError{reason: "database error.", err: err}
+	}
+	if count > 0 {
+		return "", nil
+	}
 	// 文件系统操作
 	kbPath := config.PathCfg.KnowledgeBasePath
 	kbDirPath := filepath.Join(kbPath, KBFileNew.IndexId, KBFileNew.KBFileId)
-	err = os.Mkdir(kbDirPath, os.ModePerm)
+	indexName := "gcnote-" + KBFileNew.IndexId
+	// 上一次执行中途退出时留下了目录，es里也可能有一部分切片
+	_, statErr := os.Stat(kbDirPath)
+	retried := statErr == nil
+	sg := saga.New("ingest kb file " + KBFileNew.KBFileId)
+	defer sg.Rollback()
+	// owned 租约已被其他实例领取时目录和切片由它继续使用，不能撤销
+	owned := func(compensate func() error) func() error {
+		return func() error {
+			if job_queue.LeaseLost(ctx) {
+				return nil
+			}
+			return compensate()
+		}
+	}
+	err := sg.Do("create kb file dir", func() error {
+		if err := os.RemoveAll(kbDirPath); err != nil {
+			return err
+		}
+		return os.Mkdir(kbDirPath, os.ModePerm)
+	}, owned(func() error {
+		return os.RemoveAll(kbDirPath)
+	}))
 	if err != nil {
-		tx.Rollback()
 		zap.S().Errorf("Create KBFile Dir %s Error: %v\n", kbDirPath, err)
-		callback(ctx, KBFileNew.KBFileName, "fail", "create file dir error.", currentUserId)
-		return
+		return "", &importError{reason: "create file dir error.", err: err}
+	}
+	// 先删除上一次执行留下的切片；之后写入的切片在失败时删除
+	esWritten := false
+	err = sg.Do("delete leftover es documents", func() error {
+		if !retried {
+			return nil
+		}
+		return search_engine.DeleteByTerm(config.ElasticClient, indexName, "kb_file_id", KBFileNew.KBFileId)
+	}, owned(func() error {
+		if !esWritten {
+			return nil
+		}
+		return search_engine.DeleteByTerm(config.ElasticClient, indexName, "kb_file_id", KBFileNew.KBFileId)
+	}))
+	if err != nil {
+		zap.S().Errorf("Failed to delete es documents of %s: %v", KBFileNew.KBFileId, err)
+		return "", &importError{reason: "es delete error.", err: err}
 	}
 	// 文件导入操作
-	var mdString string
-	_, mdString, err = convert.AutoConvert(tmpFilePath, kbDirPath, fileExt) // 第二个 mdString
+	job_queue.SetState(ctx, model.JobConverting)
+	// 文本文件同时返回识别出的源编码（GBK、Big5等），在任务结果中展示
+	var mdPath, mdString, encoding string
+	if src.convert != nil {
+		mdPath, mdString, err = src.convert(src.path, kbDirPath)
+	} else {
+		mdPath, mdString, encoding, err = convert.AutoConvertWithProgress(src.path, kbDirPath, src.ext, func(done int, total int) {
+			job_queue.SetStageProgress(ctx, job_queue.UnitPage, done, total)
+		})
+	}
 	if err != nil {
-		tx.Rollback()
 		zap.S().Errorf("Convert File Error: %v", err)
-		callback(ctx, KBFileNew.KBFileName, "fail", "convert file error.", currentUserId)
-		return
+		return "", job_queue.Permanent(&importError{reason: convert_base.Reason(err), err: err})
+	}
+	// 读取、重命名、更新都按 文档名.md 找文件，文档名和源文件名不一致时（自定义名称、重名加序号等）改过来
+	kbFilePath := filepath.Join(kbDirPath, KBFileNew.KBFileName+".md")
+	if mdPath != kbFilePath {
+		if err = os.Rename(mdPath, kbFilePath); err != nil {
+			zap.S().Errorf("Rename md file %s Error: %v", mdPath, err)
+			return "", &importError{reason: "save file error.", err: err}
+		}
 	}
 
-	// 删除临时文件夹 tmpFileDirPath
-	err = wrench.RemoveContents(tmpFileDirPath)
-	if err != nil {
-		zap.S().Errorf("Failed to remove temp dir, err: %v", err)
+	// 记录源文件和markdown的hash，用户的知识库中已有相同内容时按请求的方式处理
+	if src.sourceHash == "" {
+		if src.sourceHash, err = wrench.FileSHA256(src.path); err != nil {
+			return encoding, &importError{reason: "read file error.", err: err}
+		}
+	}
+	KBFileNew.SourceHash = src.sourceHash
+	KBFileNew.ContentHash = wrench.StringSHA256(mdString)
+	if src.onDuplicate == dto.DuplicateReject || src.onDuplicate == dto.DuplicateLink {
+		// 只有空白的文档（空的txt、没有文字的pdf等）和其他空文档不算重复
+		contentHash := KBFileNew.ContentHash
+		if strings.TrimSpace(mdString) == "" {
+			contentHash = ""
+		}
+		duplicates, err := findDuplicates(KBFileNew.UserId, KBFileNew.SourceHash, contentHash, KBFileNew.KBFileId)
+		if err != nil {
+			return encoding, &importError{reason: "database error.", err: err}
+		}
+		if len(duplicates) > 0 && src.onDuplicate == dto.DuplicateReject {
+			return encoding, job_queue.Permanent(&importError{reason: duplicateReason(duplicates), err: errDuplicate})
+		}
+		if len(duplicates) > 0 {
+			// 链接文档不记录源文件的hash，之后的重复检查只指向有内容的文档
+			mdString = linkMarkdown(KBFileNew.KBFileName, duplicates)
+			if err = os.WriteFile(kbFilePath, []byte(mdString), 0644); err != nil {
+				return encoding, &importError{reason: "save file error.", err: err}
+			}
+			KBFileNew.SourceHash = ""
+			KBFileNew.ContentHash = wrench.StringSHA256(mdString)
+		}
+	}
+	if err = ctx.Err(); err != nil {
+		return encoding, &importError{reason: "canceled.", err: err}
 	}
 
 	// 将mdString切片，并提交到es中
+	job_queue.SetState(ctx, model.JobSplitting)
 	chunks := splitter.SplitMarkdown(mdString, 512)
 	docList := splitter.Chunk2Doc(chunks, KBFileNew.KBFileId, KBFileNew.IndexId)
-	embedList, err := embeds.RandEmbedding(docList) // fixme 之后换成正常的Embedding服务
-	err = search_engine.AddDocuments(config.ElasticClient, "gcnote-"+KBFileNew.IndexId, docList, embedList)
+	// 分批计算向量和写入es，每批结束后上报进度
+	job_queue.SetState(ctx, model.JobEmbedding)
+	job_queue.SetStageProgress(ctx, job_queue.UnitChunk, 0, len(docList))
+	embedList := make([][]float64, 0, len(docList))
+	for start := 0; start < len(docList); start += ingestBatchSize {
+		end := min(start+ingestBatchSize, len(docList))
+		embedBatch, err := embeds.RandEmbedding(docList[start:end]) // fixme 之后换成正常的Embedding服务
+		if err != nil {
+			return encoding, &importError{reason: "embedding error.", err: err}
+		}
+		embedList = append(embedList, embedBatch...)
+		job_queue.SetStageProgress(ctx, job_queue.UnitChunk, end, len(docList))
+		if err = ctx.Err(); err != nil {
+			return encoding, &importError{reason: "canceled.", err: err}
+		}
+	}
+	job_queue.SetState(ctx, model.JobIndexing)
+	job_queue.SetStageProgress(ctx, job_queue.UnitChunk, 0, len(docList))
+	// 开始写入es后不再响应取消，失败时由saga删除已经写入的切片
+	ctx = context.WithoutCancel(ctx)
+	esWritten = true
+	for start := 0; start < len(docList); start += ingestBatchSize {
+		end := min(start+ingestBatchSize, len(docList))
+		err = search_engine.AddDocuments(config.ElasticClient, indexName, docList[start:end], embedList[start:end])
+		if err != nil {
+			return encoding, &importError{reason: "es upload error.", err: err}
+		}
+		job_queue.SetStageProgress(ctx, job_queue.UnitChunk, end, len(docList))
+	}
+
+	// 写入文档记录和元数据
+	if job_queue.LeaseLost(ctx) {
+		return encoding, &importError{reason: "canceled.", err: context.Canceled}
+	}
+	for i := range src.meta {
+		src.meta[i].KBFileId = KBFileNew.KBFileId
+	}
+	tx, err := sg.Begin(config.DB)
 	if err != nil {
-		tx.Rollback()
-		callback(ctx, KBFileNew.KBFileName, "fail", "es upload error.", currentUserId)
-		return
+		zap.S().Errorf("Failed to begin transaction, err:%v", err)
+		return encoding, &importError{reason: "database error.", err: err}
+	}
+	err = sg.Do("create kb file records", func() error {
+		if err := tx.Create(&KBFileNew).Error; err != nil {
+			return err
+		}
+		if len(src.meta) > 0 {
+			return tx.Create(&src.meta).Error
+		}
+		return nil
+	}, nil)
+	if err == nil {
+		err = sg.Commit(tx)
+	}
+	if err != nil {
+		zap.S().Errorf("Create kbfile %v, Error: %v", KBFileNew.KBFileName, err)
+		return encoding, &importError{reason: "database error.", err: err}
 	}
+	sg.Complete()
 
-	// 更新缓存
+	// 更新缓存，缓存出错不影响导入结果
 	// 1. 设置kb文件信息缓存
 	err = cache.SetKBInfo(ctx, KBFileNew)
 	if err != nil {
-		tx.Rollback()
 		zap.S().Errorf("Failed to set kb file cache: %v", err)
 	}
 	// 2. 刷新index的kb文件列表缓存
-	_, err = cache.RefreshIndexKBList(ctx, req.IndexId)
+	_, err = cache.RefreshIndexKBList(ctx, KBFileNew.IndexId)
 	if err != nil {
-		tx.Rollback()
 		zap.S().Errorf("Failed to refresh index kb list cache: %v", err)
 	}
 	// 3. 刷新用户的最近访问kb列表缓存
-	_, err = cache.RefreshRecentKBList(ctx, currentUserId)
+	_, err = cache.RefreshRecentKBList(ctx, KBFileNew.UserId)
 	if err != nil {
-		tx.Rollback()
 		zap.S().Errorf("Failed to refresh user recent kb list cache: %v", err)
 	}
 
 	zap.S().Infof("Create file name %v done.", KBFileNew.KBFileName)
+	return encoding, nil
+}
 
-	// SQL 提交事务
-	if err = tx.Commit().Error; err != nil {
-		zap.S().Errorf("Failed to commit transaction, err:%v", err)
-		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+// callback 导入结果进入用户的导入任务列表，任务被取消时也要记录，所以不使用ctx的取消；
+// 导入成功时带上文档id，失败时KBFileId为空。租约已被其他实例领取时由它记录
+func callback(ctx context.Context, job *model.IngestJob, KBFileName string, KBFileId string, state string, failReason string, encoding string) {
+	if job_queue.LeaseLost(ctx) {
 		return
 	}
-
-	callback(ctx, KBFileNew.KBFileName, "success", "", currentUserId)
-
-	zap.S().Debugf("goroutine end ---------------------------------------------------------------")
-	return
-}
-
-func callback(ctx *gin.Context, KBFileName string, state string, failReason string, userId string) {
 	task := cache.Task{
-		KBFileName,
-		time.Now().Format("2006-01-02 15:04:05"),
-		state,
-		failReason,
+		JobId:          job.JobId,
+		KbFileName:     KBFileName,
+		KbFileId:       KBFileId,
+		TaskCreateTime: time.Now().Format("2006-01-02 15:04:05"),
+		State:          state,
+		Reason:         failReason,
+		Encoding:       encoding,
 	}
-	_, err := cache.EnqueueTask(ctx, userId, task)
+	_, err := cache.EnqueueTask(context.WithoutCancel(ctx), job.UserId, task)
 	if err != nil {
 		zap.S().Errorf("call error %v", err)
 		return
@@ -0,0 +1,202 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gcnote/server/ability/archive"
+	"gcnote/server/ability/convert"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/job_queue"
+	"gcnote/server/model"
+	"gcnote/server/router/wrench"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// BulkImport
+// @Summary		批量导入
+// @Description	 上传zip或tar.gz压缩包，包内每个支持的文档导入为一个知识库文件，所在目录记录在元数据中，每个文件的结果进入导入任务列表
+// @ID			 bulk_import
+// @Tags		 index
+// @Accept       mpfd
+// @Produce      json
+// @Param		index_id	formData	string	true	"知识库ID"
+// @Param		file		formData	file	true	"zip或tar.gz压缩包"
+// @Param		on_duplicate	formData	string	false	"已有相同内容时的处理方式：reject、link、create(默认)"
+// @Success		 200	{object} 		dto.BaseResponse{Data=dto.ImportJobResponse} "成功，返回导入任务id"
+// @Failure		 400	{object} 		dto.BaseResponse "参数问题、不支持的压缩包格式(40000)"
+// @Failure		 401	{object} 		dto.BaseResponse "未授权，用户未登录(40101)"
+// @Failure		 409	{object} 		dto.BaseResponse "知识库不存在（40201）"
+// @Failure      500	{object} 		dto.BaseResponse "服务器内部错误(code:50000)"
+// @Router		 /index/bulk_import [post]
+func BulkImport(ctx *gin.Context) {
+	var req dto.KBFileBulkImportRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+	if !archive.IsArchive(req.File.Filename) {
+		ctx.JSON(http.StatusBadRequest, dto.FailWithMessage(dto.ParamsErrCode, "只支持zip和tar.gz格式的压缩包"))
+		return
+	}
+	// 获取userId
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 先从缓存验证index是否存在
+	index, err := cache.GetIndexInfo(ctx, req.IndexId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		index, err = cache.RefreshIndexInfo(ctx, req.IndexId)
+		if err != nil {
+			zap.S().Errorf("Failed to get index info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if index == nil || index.IndexId == "" || index.UserId != currentUserId {
+		ctx.JSON(http.StatusConflict, dto.FailWithMessage(dto.IndexNotExistErrCode,
+			"知识库"+req.IndexId+"不存在"))
+		return
+	}
+
+	// 压缩包先保存到临时目录，解压和导入在goroutine中进行
+	jobId := wrench.IdGenerator()
+	tmpDirPath := filepath.Join(config.PathCfg.TempDirPath, jobId)
+	archivePath := filepath.Join(tmpDirPath, filepath.Base(req.File.Filename))
+	if err = ctx.SaveUploadedFile(req.File, archivePath); err != nil {
+		zap.S().Errorf("Save archive %s error: %v", req.File.Filename, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	job, err := newBatchJob(jobId, jobKindArchive, currentUserId, req.IndexId, filepath.Base(archivePath),
+		batchJobPayload{Path: archivePath, TmpDir: tmpDirPath, OnDuplicate: onDuplicateOrDefault(req.OnDuplicate)})
+	if err == nil {
+		err = job_queue.Enqueue(job)
+	}
+	if err != nil {
+		zap.S().Errorf("Enqueue job for %s error: %v", req.File.Filename, err)
+		_ = wrench.RemoveContents(tmpDirPath)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.ImportJobResponse{JobId: job.JobId, Message: "正在导入中"}))
+}
+
+// runArchiveJob 逐个导入压缩包中的文档，单个文件失败只记录到任务列表，不影响其他文件；
+// 重试或服务重启后从上次处理到的文件继续
+func runArchiveJob(ctx context.Context, job *model.IngestJob) error {
+	var payload batchJobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return job_queue.Permanent(err)
+	}
+	err := importArchive(ctx, job, &payload)
+	finishJob(ctx, job, payload.TmpDir, err, "")
+	return err
+}
+
+func importArchive(ctx context.Context, job *model.IngestJob, payload *batchJobPayload) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	conf := config.ServerCfg.ImportConf
+	entries, err := archive.Extract(payload.Path, filepath.Join(payload.TmpDir, "files"), archive.Limits{
+		MaxFiles: conf.ArchiveMaxFiles,
+		MaxSize:  conf.ArchiveMaxSize,
+	})
+	if err != nil {
+		zap.S().Errorf("Extract archive %s error: %v", job.Name, err)
+		return job_queue.Permanent(&importError{reason: "extract archive error: " + err.Error(), err: err})
+	}
+
+	assigner := newBatchAssigner(ctx, job, payload)
+	for i, entry := range entries {
+		if i < job.Done {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		ext := strings.ToLower(path.Ext(entry.Name))
+		if !convert.IsSupported(ext) {
+			callback(ctx, job, entry.Name, "", "fail", "unsupported file type: "+ext, "")
+			job_queue.SetProgress(ctx, i+1, len(entries))
+			continue
+		}
+		name := wrench.SanitizeKBName(strings.TrimSuffix(path.Base(entry.Name), path.Ext(entry.Name)))
+		KBFileNew, err := assigner.assign(entry.Name, name)
+		if err == nil {
+			err = assigner.save()
+		}
+		if err != nil {
+			zap.S().Errorf("Failed to assign kb file for %s: %v", entry.Name, err)
+			return &importError{reason: "database error.", err: err}
+		}
+		src := ingestSource{path: entry.LocalPath, ext: ext}
+		if dir := entry.Dir(); dir != "" {
+			src.meta = append(src.meta, model.KBFileMeta{Key: model.MetaFolderPath, Value: dir})
+		}
+		if err = ingestBatchFile(ctx, job, payload, KBFileNew, src); err != nil {
+			return err
+		}
+		job_queue.SetProgress(ctx, i+1, len(entries))
+	}
+	zap.S().Infof("Bulk import %s done, %d files.", job.Name, len(entries))
+	return nil
+}
+
+// uniqueKBFileName 知识库中已有同名文档时依次尝试 name (2)、name (3)...
+// reserved为本次导入已经分配、还没写入数据库的名称，不为nil时会把结果加进去
+func uniqueKBFileName(indexId string, name string, reserved map[string]bool) (string, error) {
+	candidate := name
+	for i := 2; ; i++ {
+		if reserved[candidate] {
+			candidate = fmt.Sprintf("%s (%d)", name, i)
+			continue
+		}
+		var count int64
+		err := config.DB.Model(&model.KBFile{}).Where("kb_file_name = ? AND index_id = ?",
+			candidate, indexId).Count(&count).Error
+		if err != nil {
+			return "", err
+		}
+		if count == 0 {
+			if reserved != nil {
+				reserved[candidate] = true
+			}
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s (%d)", name, i)
+	}
+}
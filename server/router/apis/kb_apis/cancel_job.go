@@ -0,0 +1,65 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"errors"
+	"gcnote/server/dto"
+	"gcnote/server/job_queue"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// CancelImportJob
+// @Summary		取消导入任务
+// @Description	 取消排队中或执行中的导入任务；执行中的任务在当前阶段结束后停止，已经写入es的文档不受影响，多文件任务中已经导入的文件保留
+// @ID			 cancel_import_job
+// @Tags		 index
+// @Accept       json
+// @Produce      json
+// @Param		request		body		dto.ImportJobCancelRequest true "取消请求体"
+// @Success		 200	{object} 		dto.BaseResponse "成功"
+// @Failure		 400	{object} 		dto.BaseResponse "参数问题、任务已经结束(40000)"
+// @Failure		 401	{object} 		dto.BaseResponse "未授权，用户未登录(40101)"
+// @Failure		 404	{object} 		dto.BaseResponse "任务不存在(40001)"
+// @Failure      500	{object} 		dto.BaseResponse "服务器内部错误(code:50000)"
+// @Router		 /index/cancel_job [post]
+func CancelImportJob(ctx *gin.Context) {
+	var req dto.ImportJobCancelRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+	// 获取userId
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	err := job_queue.Cancel(req.JobId, currentUserId)
+	switch {
+	case errors.Is(err, job_queue.ErrJobNotFound):
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.RecordNotFoundErrCode))
+	case errors.Is(err, job_queue.ErrJobFinished):
+		ctx.JSON(http.StatusBadRequest, dto.FailWithMessage(dto.ParamsErrCode, "任务已经结束"))
+	case err != nil:
+		zap.S().Errorf("Cancel job %s error: %v", req.JobId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+	default:
+		ctx.JSON(http.StatusOK, dto.Success())
+	}
+}
@@ -0,0 +1,343 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"context"
+	"errors"
+	"gcnote/server/cache"
+	"gcnote/server/collab"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/model"
+	"gcnote/server/router/wrench"
+	"gcnote/server/saga"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	collabRevisionMessage = "协同编辑"           // 协同编辑停止后记录的版本的说明
+	collabMaxMessageBytes = 4 << 20          // 一条消息的最大字节数
+	collabPingInterval    = 30 * time.Second // 定期发送ping，避免代理断开空闲连接
+)
+
+var collabHub = collab.NewHub(collabStore{})
+
+// KickCollaborator 断开用户在知识库中的协同编辑连接
+func KickCollaborator(indexId string, userId string) {
+	collabHub.Kick(indexId, userId)
+}
+
+// Collaborate
+// @Summary      协同编辑文档
+// @Description  websocket连接，知识库的所有者和成员可以加入，同一个文档的所有连接实时共享修改和光标。消息为collab.Message的json：
+// @Description  连接后收到init（当前的版本和内容），之后发送op（基于某个版本的修改，格式和ot.js相同，位置按Unicode字符计算）、cursor，
+// @Description  收到ack、其他人的op和cursor、join、leave；收到error时需要重新连接。
+// @Description  内容每5秒写入md文件，停止编辑30秒或者所有人离开后记录版本并重新索引。浏览器的WebSocket不能设置请求头，token可以放在query参数中；
+// @Description  浏览器发起的连接的Origin必须是配置文件allow_origins中的前端地址
+// @ID           collaborate
+// @Tags         index
+// @Param        token       query     string  false  "登录token，没有token请求头时使用"
+// @Param        index_id    query     string  true   "知识库id"
+// @Param        kb_file_id  query     string  true   "文档id"
+// @Success      101  {object}  collab.Message    "切换为websocket"
+// @Failure      400  {object}  dto.BaseResponse  "参数错误(code:40000)"
+// @Failure      401  {object}  dto.BaseResponse  "Token错误(code:40101)"
+// @Failure      403  {object}  dto.BaseResponse  "Origin不允许(code:40104)"
+// @Failure      404  {object}  dto.BaseResponse  "知识库或文档不存在(code:40201、40302)"
+// @Failure      500  {object}  dto.BaseResponse  "服务器内部错误(code:50000)"
+// @Router       /index/collaborate [get]
+func Collaborate(ctx *gin.Context) {
+	var req dto.KBFileCollaborateRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 获取用户信息
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		zap.S().Debugf("currentUserId is empty")
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 先从缓存验证index是否存在
+	index, err := cache.GetIndexInfo(ctx, req.IndexId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		index, err = cache.RefreshIndexInfo(ctx, req.IndexId)
+		if err != nil {
+			zap.S().Errorf("Failed to get index info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	if index == nil || index.IndexId == "" {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+	canAccess, err := wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+
+	// 从缓存验证kb文件是否存在
+	kbFile, err := cache.GetKBInfo(ctx, req.KBFileId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		kbFile, err = cache.RefreshKBInfo(ctx, req.KBFileId)
+		if err != nil {
+			zap.S().Errorf("Failed to get kb file info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get kb file from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	if kbFile == nil || kbFile.KBFileId == "" || kbFile.IndexId != req.IndexId {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.KBFileNotExistErrCode))
+		return
+	}
+
+	session, client, err := collabHub.Join(kbFile, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to join collab session of %s: %v", kbFile.KBFileId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	defer session.Leave(client)
+	// 检查之后、加入之前成员可能已经被移除，那时的Kick断开不了这个连接，加入后再检查一次
+	canAccess, err = wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+
+	// 长连接不受服务器ReadTimeout、WriteTimeout的限制
+	rc := http.NewResponseController(ctx.Writer)
+	if err = rc.SetReadDeadline(time.Time{}); err != nil {
+		zap.S().Errorf("Clear read deadline error: %v", err)
+	}
+	if err = rc.SetWriteDeadline(time.Time{}); err != nil {
+		zap.S().Errorf("Clear write deadline error: %v", err)
+	}
+	// Origin已经由路由上的CheckOrigin按配置文件allow_origins检查
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		ws.MaxPayloadBytes = collabMaxMessageBytes
+		go writeCollabMessages(ws, client)
+		for {
+			var data []byte
+			if err := websocket.Message.Receive(ws, &data); err != nil {
+				return
+			}
+			session.ReceiveJSON(client, data)
+		}
+	}}
+	server.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// writeCollabMessages 把消息发送给客户端，连接需要关闭时先发送剩下的消息（比如错误的原因）
+func writeCollabMessages(ws *websocket.Conn, client *collab.Client) {
+	defer func() {
+		if err := ws.Close(); err != nil {
+			zap.S().Debugf("Close collab websocket error: %v", err)
+		}
+	}()
+	ticker := time.NewTicker(collabPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg := <-client.Send():
+			if err := websocket.JSON.Send(ws, msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := websocket.JSON.Send(ws, collab.Message{Type: collab.MsgPing}); err != nil {
+				return
+			}
+		case <-client.Done():
+			for {
+				select {
+				case msg := <-client.Send():
+					if err := websocket.JSON.Send(ws, msg); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// collabStore 协同编辑时读写md文件。文档在编辑期间可能被重命名、删除，每次都从数据库读取文档记录
+type collabStore struct{}
+
+func kbFileMarkdownPath(kbFile *model.KBFile) string {
+	return filepath.Join(config.PathCfg.KnowledgeBasePath, kbFile.IndexId, kbFile.KBFileId, kbFile.KBFileName+".md")
+}
+
+// Load 协同编辑会定期覆盖md文件，没有版本记录的文档先把原来的内容记为初始版本
+func (store collabStore) Load(kbFile *model.KBFile) (string, error) {
+	content, err := store.Read(kbFile)
+	if err != nil {
+		return "", err
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.KBFileRevision{}).Where("kb_file_id = ?", kbFile.KBFileId).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		_, err := saveRevision(tx, kbFile.KBFileId, content, kbFile.UserId, baselineRevisionMessage)
+		return err
+	})
+	return content, err
+}
+
+func (collabStore) Read(kbFile *model.KBFile) (string, error) {
+	var current model.KBFile
+	err := config.DB.Where("kb_file_id = ?", kbFile.KBFileId).First(&current).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", collab.ErrDocumentGone
+	} else if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(kbFileMarkdownPath(&current))
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// Write 锁住文档记录，和saveKBFileContent互斥
+func (collabStore) Write(kbFile *model.KBFile, update func(current string) (string, error)) error {
+	sg := saga.New("collab snapshot " + kbFile.KBFileId)
+	defer sg.Rollback()
+	tx, err := sg.Begin(config.DB)
+	if err != nil {
+		return err
+	}
+	var locked model.KBFile
+	var oldContent []byte
+	oldExists := false
+	var content string
+	err = sg.Do("lock kb file", func() error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("kb_file_id = ?", kbFile.KBFileId).First(&locked).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return collab.ErrDocumentGone
+		} else if err != nil {
+			return err
+		}
+		oldContent, err = os.ReadFile(kbFileMarkdownPath(&locked))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		oldExists = err == nil
+		content, err = update(string(oldContent))
+		return err
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	if !oldExists || content != string(oldContent) {
+		kbFilePath := kbFileMarkdownPath(&locked)
+		err = sg.Do("write md", func() error {
+			if err := os.MkdirAll(filepath.Dir(kbFilePath), os.ModePerm); err != nil {
+				return err
+			}
+			return wrench.WriteFileAtomic(kbFilePath, []byte(content), 0644)
+		}, func() error {
+			if !oldExists {
+				return os.Remove(kbFilePath)
+			}
+			return wrench.WriteFileAtomic(kbFilePath, oldContent, 0644)
+		})
+		if err != nil {
+			return err
+		}
+		err = sg.Do("update kb file", func() error {
+			return tx.Model(&locked).Updates(map[string]interface{}{
+				"UpdatedAt":    time.Now(),
+				"content_hash": wrench.StringSHA256(content),
+			}).Error
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	if err = sg.Commit(tx); err != nil {
+		return err
+	}
+	sg.Complete()
+	return nil
+}
+
+// Save 记录版本并按差异更新es切片；md文件期间被修改时返回errStaleVersion
+func (collabStore) Save(kbFile *model.KBFile, content string, author string) error {
+	var current model.KBFile
+	if err := config.DB.Where("kb_file_id = ?", kbFile.KBFileId).First(&current).Error; err != nil {
+		return err
+	}
+	_, err := saveKBFileContent(&current, content, contentVersion([]byte(content)), author, collabRevisionMessage)
+	if err != nil {
+		return err
+	}
+
+	// 更新缓存
+	ctx := context.Background()
+	if _, err = cache.RefreshKBInfo(ctx, current.KBFileId); err != nil {
+		zap.S().Errorf("Failed to refresh kb file cache: %v", err)
+	}
+	if _, err = cache.RefreshIndexKBList(ctx, current.IndexId); err != nil {
+		zap.S().Errorf("Failed to refresh index kb list cache: %v", err)
+	}
+	if _, err = cache.RefreshRecentKBList(ctx, current.UserId); err != nil {
+		zap.S().Errorf("Failed to refresh user recent kb list cache: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"database/sql/driver"
+	"gcnote/server/collab"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collabServer 以query参数user的身份调用Collaborate，测试结束前等待所有连接处理完
+func collabServer(t *testing.T) (*httptest.Server, *sync.WaitGroup) {
+	var wg sync.WaitGroup
+	route := gin.New()
+	route.GET("/collaborate", func(ctx *gin.Context) {
+		defer wg.Done()
+		ctx.Set("claims", jwt.MapClaims{"sub": ctx.Query("user")})
+		Collaborate(ctx)
+	})
+	server := httptest.NewServer(route)
+	t.Cleanup(server.Close)
+	t.Cleanup(wg.Wait)
+	return server, &wg
+}
+
+func dialCollab(t *testing.T, server *httptest.Server, wg *sync.WaitGroup, userId string) *websocket.Conn {
+	t.Helper()
+	wg.Add(1)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/collaborate?index_id=i1&kb_file_id=k1&user=" + userId
+	ws, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+func receiveCollab(t *testing.T, ws *websocket.Conn) collab.Message {
+	t.Helper()
+	if err := ws.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	var msg collab.Message
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestCollaborateWithMember(t *testing.T) {
+	env, mdPath := setupKB(t)
+	env.DB.Return("FROM `kb_files`", []string{"id", "kb_file_id", "kb_file_name", "index_id", "user_id"},
+		[]driver.Value{int64(1), "k1", "a", "i1", "u1"})
+	// u2是知识库i1的成员
+	env.DB.Return("FROM `index_members`", []string{"count(*)"}, []driver.Value{int64(1)})
+	server, wg := collabServer(t)
+
+	owner := dialCollab(t, server, wg, "u1")
+	if msg := receiveCollab(t, owner); msg.Type != collab.MsgInit || msg.Content != "# a\n\n第一段" {
+		t.Fatalf("owner got %+v", msg)
+	}
+	member := dialCollab(t, server, wg, "u2")
+	if msg := receiveCollab(t, member); msg.Type != collab.MsgInit || len(msg.Clients) != 1 || msg.Clients[0].UserId != "u1" {
+		t.Fatalf("member got %+v", msg)
+	}
+	if msg := receiveCollab(t, owner); msg.Type != collab.MsgJoin || msg.UserId != "u2" {
+		t.Fatalf("owner got %+v", msg)
+	}
+
+	// 成员的修改转发给所有者
+	op := collab.Message{Type: collab.MsgOp, Revision: 0, Op: collab.Op{{Retain: 8}, {Insert: "！"}}}
+	if err := websocket.JSON.Send(member, op); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receiveCollab(t, member); msg.Type != collab.MsgAck || msg.Revision != 1 {
+		t.Fatalf("member got %+v", msg)
+	}
+	if msg := receiveCollab(t, owner); msg.Type != collab.MsgOp || msg.UserId != "u2" || msg.Revision != 1 {
+		t.Fatalf("owner got %+v", msg)
+	}
+
+	// 所有人离开后写入md文件
+	_ = member.Close()
+	_ = owner.Close()
+	wg.Wait()
+	content, err := os.ReadFile(mdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "# a\n\n第一段！" {
+		t.Errorf("md content %q", content)
+	}
+}
+
+func TestCollaborateKickRemovedMember(t *testing.T) {
+	env, _ := setupKB(t)
+	env.DB.Return("FROM `kb_files`", []string{"id", "kb_file_id", "kb_file_name", "index_id", "user_id"},
+		[]driver.Value{int64(1), "k1", "a", "i1", "u1"})
+	env.DB.Return("FROM `index_members`", []string{"count(*)"}, []driver.Value{int64(1)})
+	server, wg := collabServer(t)
+
+	owner := dialCollab(t, server, wg, "u1")
+	receiveCollab(t, owner)
+	member := dialCollab(t, server, wg, "u2")
+	receiveCollab(t, member)
+	receiveCollab(t, owner)
+
+	// 移除成员后断开它的连接，所有者不受影响
+	KickCollaborator("i1", "u2")
+	if msg := receiveCollab(t, member); msg.Type != collab.MsgError || msg.Error != collab.ErrAccessRevoked.Error() {
+		t.Fatalf("member got %+v", msg)
+	}
+	if msg := receiveCollab(t, owner); msg.Type != collab.MsgLeave || msg.UserId != "u2" {
+		t.Fatalf("owner got %+v", msg)
+	}
+	_ = member.Close()
+	_ = owner.Close()
+}
+
+func TestCollaborateWithoutAccess(t *testing.T) {
+	setupKB(t)
+	server, wg := collabServer(t)
+
+	// u3不是所有者也不是成员
+	wg.Add(1)
+	resp, err := http.Get(server.URL + "/collaborate?index_id=i1&kb_file_id=k1&user=u3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,124 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"context"
+	"encoding/json"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/model"
+	"gcnote/server/router/apis/api_fixture"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// setupKB 知识库i1中有文档k1（a.md），都已经在缓存中
+func setupKB(t *testing.T) (*api_fixture.Env, string) {
+	env := api_fixture.Setup(t)
+	ctx := context.Background()
+	if err := cache.SetIndexInfo(ctx, model.Index{IndexId: "i1", IndexName: "笔记", UserId: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.SetKBInfo(ctx, model.KBFile{KBFileId: "k1", KBFileName: "a", IndexId: "i1", UserId: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	mdPath := filepath.Join(config.PathCfg.KnowledgeBasePath, "i1", "k1", "a.md")
+	api_fixture.WriteFile(t, mdPath, "# a\n\n第一段")
+	return env, mdPath
+}
+
+func TestCreateKBFileCompensation(t *testing.T) {
+	env, _ := setupKB(t)
+	env.DB.FailOn("COMMIT")
+
+	w := api_fixture.Call(t, CreateKBFile, "u1", dto.KBFileCreateRequest{KBFileName: "b", IndexId: "i1"})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	// 提交失败时删除已经创建的文档目录
+	entries, err := os.ReadDir(filepath.Join(config.PathCfg.KnowledgeBasePath, "i1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "k1" {
+		t.Errorf("kb file dir should be removed, got %v", entries)
+	}
+}
+
+func TestRecycleKBFileCompensation(t *testing.T) {
+	env, mdPath := setupKB(t)
+	env.DB.FailOn("COMMIT")
+
+	w := api_fixture.Call(t, RecycleKBFile, "u1", dto.KBFileUDRequest{KBFileId: "k1", KBFileName: "a", IndexId: "i1"})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	// 提交失败时重新写入es切片，移回文档目录，删除回收站中的副本
+	if !slices.Contains(env.ES.Requests(), "POST /gcnote-i1/_doc") {
+		t.Errorf("es chunks should be written back, requests %v", env.ES.Requests())
+	}
+	if _, err := os.Stat(mdPath); err != nil {
+		t.Errorf("kb file dir should be restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.PathCfg.RecycleBinPath, "i1", "k1")); !os.IsNotExist(err) {
+		t.Errorf("recycle copy should be removed: %v", err)
+	}
+}
+
+func TestRenameKBFileCompensation(t *testing.T) {
+	env, mdPath := setupKB(t)
+	env.DB.FailOn("UPDATE `kb_files`")
+
+	w := api_fixture.Call(t, RenameKBFile, "u1", dto.KBFileRenameRequest{IndexId: "i1", KBFileId: "k1",
+		KBFileName: "a", DestKBFileName: "b"})
+	var resp dto.BaseResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != dto.InternalErrCode {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	// 数据库更新失败时改回原来的文件名
+	if _, err := os.Stat(mdPath); err != nil {
+		t.Errorf("md file should be renamed back: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(mdPath), "b.md")); !os.IsNotExist(err) {
+		t.Errorf("renamed md file should not be left: %v", err)
+	}
+}
+
+func TestIngestFileCompensation(t *testing.T) {
+	env := api_fixture.Setup(t)
+	env.DB.FailOn("COMMIT")
+	srcPath := filepath.Join(config.PathCfg.TempDirPath, "j1", "a.txt")
+	api_fixture.WriteFile(t, srcPath, "第一段")
+	if err := os.MkdirAll(filepath.Join(config.PathCfg.KnowledgeBasePath, "i1"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	kbFile := model.KBFile{UserId: "u1", IndexId: "i1", KBFileId: "k1", KBFileName: "a"}
+	_, err := ingestFile(context.Background(), kbFile, ingestSource{path: srcPath, ext: ".txt",
+		convert: func(documentPath, outputDir string) (string, string, error) {
+			mdPath := filepath.Join(outputDir, "a.md")
+			return mdPath, "# a\n\n第一段", os.WriteFile(mdPath, []byte("# a\n\n第一段"), 0644)
+		}})
+	if importReason(err) != "database error." {
+		t.Fatalf("got %v", err)
+	}
+	// 提交失败时删除已经写入的es切片和文档目录
+	if !slices.Contains(env.ES.Requests(), "POST /gcnote-i1/_doc") {
+		t.Fatalf("es chunks should be written, requests %v", env.ES.Requests())
+	}
+	if !slices.Contains(env.ES.Requests(), "POST /gcnote-i1/_delete_by_query") {
+		t.Errorf("es chunks should be deleted, requests %v", env.ES.Requests())
+	}
+	if _, err = os.Stat(filepath.Join(config.PathCfg.KnowledgeBasePath, "i1", "k1")); !os.IsNotExist(err) {
+		t.Errorf("kb file dir should be removed: %v", err)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"gcnote/server/dto"
 	"gcnote/server/model"
 	"gcnote/server/router/wrench"
+	"gcnote/server/saga"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
@@ -95,7 +96,18 @@ func CreateKBFile(ctx *gin.Context) {
 		return
 	}
 
-	if index == nil || index.IndexId == "" || index.UserId != currentUserId {
+	if index == nil || index.IndexId == "" {
+		ctx.JSON(http.StatusConflict, dto.FailWithMessage(dto.IndexNotExistErrCode,
+			"知识库"+req.IndexId+"不存在"))
+		return
+	}
+	canAccess, err := wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
 		ctx.JSON(http.StatusConflict, dto.FailWithMessage(dto.IndexNotExistErrCode,
 			"知识库"+req.IndexId+"不存在"))
 		return
@@ -104,7 +116,7 @@ func CreateKBFile(ctx *gin.Context) {
 	// 创建一个KBFile
 	KBFileId := wrench.IdGenerator()
 	KBFileNew := model.KBFile{
-		UserId:     currentUserId,
+		UserId:     index.UserId, // 成员创建的文档也属于知识库的所有者
 		IndexId:    req.IndexId,
 		KBFileId:   KBFileId,
 		KBFileName: req.KBFileName,
@@ -126,31 +138,42 @@ func CreateKBFile(ctx *gin.Context) {
 		return
 	}
 
+	// 数据库和文件系统依次创建，失败时撤销已经完成的步骤
+	sg := saga.New("create kb file " + KBFileId)
+	defer sg.Rollback()
 	// 开始事务
-	tx = config.DB.Begin()
-	err = tx.Create(&KBFileNew).Error
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		zap.S().Errorf("Create kbfile %v, Error: %v", KBFileNew.KBFileName, tx.Error)
-		tx.Rollback()
+	tx, err = sg.Begin(config.DB)
+	if err != nil {
+		zap.S().Errorf("Failed to begin transaction, err: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	err = sg.Do("create kb file record", func() error {
+		return tx.Create(&KBFileNew).Error
+	}, nil)
+	if err != nil {
+		zap.S().Errorf("Create kbfile %v, Error: %v", KBFileNew.KBFileName, err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
 	// 文件系统
 	kbPath := config.PathCfg.KnowledgeBasePath
 	kbDirPath := filepath.Join(kbPath, index.IndexId, KBFileId)
-	err = os.Mkdir(kbDirPath, os.ModePerm)
+	err = sg.Do("create kb file dir", func() error {
+		return os.Mkdir(kbDirPath, os.ModePerm)
+	}, func() error {
+		return os.RemoveAll(kbDirPath)
+	})
 	if err != nil {
 		zap.S().Errorf("Create KBFile Dir %s Error: %v\n", kbDirPath, err)
-		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, dto.FailWithMessage(dto.InternalErrCode, "create file dir error"))
 		return
 	}
-	// 生成文件夹 images
+	// 生成文件夹 images，和md文件一起由删除kb文件夹撤销
 	imagesDirPath := filepath.Join(kbDirPath, "images")
 	err = os.Mkdir(imagesDirPath, os.ModePerm)
 	if err != nil {
 		zap.S().Errorf("Create Image Dir %s Error: %v\n", imagesDirPath, err)
-		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, dto.FailWithMessage(dto.InternalErrCode, "create file dir error"))
 		return
 	}
@@ -159,30 +182,26 @@ func CreateKBFile(ctx *gin.Context) {
 	file, err := os.Create(mdPath)
 	if err != nil {
 		zap.S().Errorf("Create File %s Error: %v", mdPath, err)
-		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, dto.FailWithMessage(dto.InternalErrCode, "create file dir error"))
 		return
 	}
-
-	defer func(file *os.File) {
-		err = file.Close()
-		if err != nil {
-			zap.S().Errorf("Close File Error %s Error: %v\n", mdPath, err)
-			tx.Rollback()
-			ctx.JSON(http.StatusInternalServerError, dto.FailWithMessage(dto.InternalErrCode, "create file dir error"))
-			return
-		}
-	}(file)
+	err = file.Close()
+	if err != nil {
+		zap.S().Errorf("Close File Error %s Error: %v\n", mdPath, err)
+		ctx.JSON(http.StatusInternalServerError, dto.FailWithMessage(dto.InternalErrCode, "create file dir error"))
+		return
+	}
 
 	// 不需要对ES做操作，因为是空的
 
 	// 提交事务
-	err = tx.Commit().Error
+	err = sg.Commit(tx)
 	if err != nil {
 		zap.S().Errorf("Failed to commit transaction, err: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
+	sg.Complete()
 
 	// 更新缓存
 	// 1. 设置kb文件信息缓存
@@ -196,7 +215,7 @@ func CreateKBFile(ctx *gin.Context) {
 		zap.S().Errorf("Failed to refresh index kb list cache: %v", err)
 	}
 	// 3. 刷新用户的最近访问kb列表缓存
-	_, err = cache.RefreshRecentKBList(ctx, currentUserId)
+	_, err = cache.RefreshRecentKBList(ctx, index.UserId)
 	if err != nil {
 		zap.S().Errorf("Failed to refresh user recent kb list cache: %v", err)
 	}
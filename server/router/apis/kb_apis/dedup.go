@@ -0,0 +1,107 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"errors"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/model"
+	"gcnote/server/router/wrench"
+	"strings"
+)
+
+// 最多返回的重复文档数
+const maxDuplicates = 10
+
+var errDuplicate = errors.New("duplicate content")
+
+// emptyHash 空文件的SHA-256，空文档之间不算重复
+var emptyHash = wrench.StringSHA256("")
+
+// onDuplicateOrDefault 导入请求没有指定on_duplicate时照常导入，和加入重复检查之前的行为一致
+func onDuplicateOrDefault(onDuplicate string) string {
+	if onDuplicate == "" {
+		return dto.DuplicateCreate
+	}
+	return onDuplicate
+}
+
+// findDuplicates 用户的知识库中源文件或markdown的hash相同的文档，excludeId为正在导入的文档；hash为空或者是空文件的hash时忽略
+func findDuplicates(userId string, sourceHash string, contentHash string, excludeId string) ([]dto.DuplicateKBFile, error) {
+	var conds []string
+	var args []interface{}
+	if sourceHash == emptyHash {
+		sourceHash = ""
+	}
+	if contentHash == emptyHash {
+		contentHash = ""
+	}
+	if sourceHash != "" {
+		conds = append(conds, "source_hash = ?")
+		args = append(args, sourceHash)
+	}
+	if contentHash != "" {
+		conds = append(conds, "content_hash = ?")
+		args = append(args, contentHash)
+	}
+	if len(conds) == 0 {
+		return nil, nil
+	}
+	var files []model.KBFile
+	err := config.DB.Where("user_id = ? AND kb_file_id <> ?", userId, excludeId).
+		Where("("+strings.Join(conds, " OR ")+")", args...).
+		Order("id").Limit(maxDuplicates).Find(&files).Error
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+
+	// 补上知识库名称
+	indexIds := make([]string, 0, len(files))
+	for _, file := range files {
+		indexIds = append(indexIds, file.IndexId)
+	}
+	var indexes []model.Index
+	if err = config.DB.Where("index_id IN ?", indexIds).Find(&indexes).Error; err != nil {
+		return nil, err
+	}
+	indexNames := make(map[string]string, len(indexes))
+	for _, index := range indexes {
+		indexNames[index.IndexId] = index.IndexName
+	}
+	duplicates := make([]dto.DuplicateKBFile, 0, len(files))
+	for _, file := range files {
+		duplicates = append(duplicates, dto.DuplicateKBFile{
+			IndexId:    file.IndexId,
+			IndexName:  indexNames[file.IndexId],
+			KBFileId:   file.KBFileId,
+			KBFileName: file.KBFileName,
+		})
+	}
+	return duplicates, nil
+}
+
+// duplicateReason 拒绝导入时展示在导入任务列表中的原因
+func duplicateReason(duplicates []dto.DuplicateKBFile) string {
+	places := make([]string, 0, len(duplicates))
+	for _, dup := range duplicates {
+		places = append(places, dup.IndexName+"/"+dup.KBFileName)
+	}
+	return "duplicate content: " + strings.Join(places, ", ")
+}
+
+// linkMarkdown link方式导入时文档的内容，链接到已有的文档
+func linkMarkdown(name string, duplicates []dto.DuplicateKBFile) string {
+	var sb strings.Builder
+	sb.WriteString("# " + name + "\n\n")
+	sb.WriteString("知识库中已有相同内容的文档，没有重复导入：\n\n")
+	for _, dup := range duplicates {
+		sb.WriteString("- [" + dup.IndexName + " / " + dup.KBFileName + "](" +
+			model.KBFileLink(dup.IndexId, dup.KBFileId) + ")\n")
+	}
+	return sb.String()
+}
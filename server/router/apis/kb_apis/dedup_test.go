@@ -0,0 +1,128 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/job_queue"
+	"gcnote/server/model"
+	"gcnote/server/router/apis/api_fixture"
+	"gcnote/server/router/wrench"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 空文件的hash不参与查询，config.DB为空时查询数据库会panic
+func TestFindDuplicatesSkipsEmpty(t *testing.T) {
+	duplicates, err := findDuplicates("u1", emptyHash, emptyHash, "k1")
+	if err != nil || duplicates != nil {
+		t.Errorf("got %v, %v", duplicates, err)
+	}
+	duplicates, err = findDuplicates("u1", "", "", "k1")
+	if err != nil || duplicates != nil {
+		t.Errorf("got %v, %v", duplicates, err)
+	}
+}
+
+func TestOnDuplicateOrDefault(t *testing.T) {
+	if got := onDuplicateOrDefault(""); got != dto.DuplicateCreate {
+		t.Errorf("got %q", got)
+	}
+	if got := onDuplicateOrDefault(dto.DuplicateReject); got != dto.DuplicateReject {
+		t.Errorf("got %q", got)
+	}
+}
+
+// runDuplicateJob 用户在知识库“资料”中已有文档b，以onDuplicate的方式把内容相同的a.txt导入知识库i1
+func runDuplicateJob(t *testing.T, onDuplicate string) (*api_fixture.Env, error) {
+	env := api_fixture.Setup(t)
+	env.DB.Return("source_hash = ?", []string{"index_id", "kb_file_id", "kb_file_name", "user_id"},
+		[]driver.Value{"i2", "k2", "b", "u1"})
+	env.DB.Return("FROM `indices`", []string{"index_id", "index_name"}, []driver.Value{"i2", "资料"})
+	if err := os.MkdirAll(filepath.Join(config.PathCfg.KnowledgeBasePath, "i1"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	tmpDir := filepath.Join(config.PathCfg.TempDirPath, "j1")
+	srcPath := filepath.Join(tmpDir, "a.txt")
+	api_fixture.WriteFile(t, srcPath, "第一段")
+
+	payload, err := json.Marshal(fileJobPayload{Path: srcPath, Ext: ".txt", TmpDir: tmpDir,
+		SourceHash: wrench.StringSHA256("第一段"), OnDuplicate: onDuplicate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &model.IngestJob{JobId: "j1", UserId: "u1", IndexId: "i1", KBFileId: "k1", Name: "a",
+		Payload: string(payload), Attempts: 1, MaxAttempts: 3}
+	return env, runFileJob(context.Background(), job)
+}
+
+func insertedKBFile(env *api_fixture.Env) bool {
+	for _, statement := range env.DB.Statements() {
+		if strings.HasPrefix(statement, "INSERT INTO `kb_files`") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestImportRejectsDuplicate(t *testing.T) {
+	env, err := runDuplicateJob(t, dto.DuplicateReject)
+	if !job_queue.IsPermanent(err) || importReason(err) != "duplicate content: 资料/b" {
+		t.Fatalf("got %v", err)
+	}
+	if insertedKBFile(env) {
+		t.Error("kb file should not be created")
+	}
+	if _, err = os.Stat(filepath.Join(config.PathCfg.KnowledgeBasePath, "i1", "k1")); !os.IsNotExist(err) {
+		t.Errorf("kb file dir should be removed: %v", err)
+	}
+}
+
+func TestImportLinksDuplicate(t *testing.T) {
+	env, err := runDuplicateJob(t, dto.DuplicateLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !insertedKBFile(env) {
+		t.Fatal("kb file should be created")
+	}
+	want := linkMarkdown("a", []dto.DuplicateKBFile{{IndexId: "i2", IndexName: "资料", KBFileId: "k2", KBFileName: "b"}})
+	content, err := os.ReadFile(filepath.Join(config.PathCfg.KnowledgeBasePath, "i1", "k1", "a.md"))
+	if err != nil || string(content) != want {
+		t.Errorf("md content %q, %v", content, err)
+	}
+	// 链接文档不记录源文件的hash
+	kbFile, err := cache.GetKBInfo(context.Background(), "k1")
+	if err != nil || kbFile.SourceHash != "" || kbFile.ContentHash != wrench.StringSHA256(want) {
+		t.Errorf("got %+v, %v", kbFile, err)
+	}
+}
+
+func TestImportCreatesDuplicate(t *testing.T) {
+	env, err := runDuplicateJob(t, dto.DuplicateCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !insertedKBFile(env) {
+		t.Fatal("kb file should be created")
+	}
+	content, err := os.ReadFile(filepath.Join(config.PathCfg.KnowledgeBasePath, "i1", "k1", "a.md"))
+	if err != nil || !strings.Contains(string(content), "第一段") {
+		t.Errorf("md content %q, %v", content, err)
+	}
+	kbFile, err := cache.GetKBInfo(context.Background(), "k1")
+	if err != nil || kbFile.SourceHash != wrench.StringSHA256("第一段") {
+		t.Errorf("got %+v, %v", kbFile, err)
+	}
+}
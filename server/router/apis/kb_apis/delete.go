@@ -8,12 +8,15 @@ package kb_apis
 
 import (
 	"errors"
+	"gcnote/server/ability/embeds"
 	"gcnote/server/ability/search_engine"
+	"gcnote/server/ability/splitter"
 	"gcnote/server/cache"
 	"gcnote/server/config"
 	"gcnote/server/dto"
 	"gcnote/server/model"
 	"gcnote/server/router/wrench"
+	"gcnote/server/saga"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
@@ -80,7 +83,17 @@ func RecycleKBFile(ctx *gin.Context) {
 		return
 	}
 
-	if index == nil || index.IndexId == "" || index.UserId != currentUserId {
+	if index == nil || index.IndexId == "" {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+	canAccess, err := wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
 		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
 		return
 	}
@@ -106,34 +119,11 @@ func RecycleKBFile(ctx *gin.Context) {
 		return
 	}
 
-	// 回收文件
-	src := filepath.Join(config.PathCfg.KnowledgeBasePath, req.IndexId, req.KBFileId)
-	dst := filepath.Join(config.PathCfg.RecycleBinPath, req.IndexId, req.KBFileId)
-	err = os.MkdirAll(dst, os.ModePerm)
-	if err != nil {
-		zap.S().Errorf("Failed to create recycle dir, err: %v", err)
-		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
-		return
-	}
-
-	// 复制文件到要求的路径
-	err = wrench.CopyDir(src, dst)
-	if err != nil {
-		zap.S().Errorf("Failed to create recycle dir, err: %v", err)
-		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
-		return
-	}
-
-	// 删除原来的文件夹
-	err = wrench.RemoveContents(src)
-	if err != nil {
-		zap.S().Errorf("Delete kb_file id :%v err:%v", req.KBFileId, err)
-		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
-		return
-	}
-	// 然后sql操作
+	// 数据库、文件系统、es依次处理，失败时撤销已经完成的步骤
+	sg := saga.New("recycle kb file " + req.KBFileId)
+	defer sg.Rollback()
 	recycleModel := model.Recycle{
-		UserId:        currentUserId,
+		UserId:        index.UserId, // 放到所有者的回收站
 		SourceIndexId: req.IndexId,
 		KBFileId:      req.KBFileId,
 		KBFileName:    req.KBFileName,
@@ -143,45 +133,82 @@ func RecycleKBFile(ctx *gin.Context) {
 		KBFileId:   req.KBFileId,
 		KBFileName: req.KBFileName,
 	}
+	tx, err := sg.Begin(config.DB)
+	if err != nil {
+		zap.S().Errorf("Failed to begin transaction, err: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	// 首先删除旧表，然后放到回收站里，由事务回滚撤销
+	err = sg.Do("move kb file record to recycle", func() error {
+		err := tx.Model(&kbFileModel).Where("kb_file_id = ?", kbFileModel.KBFileId).Delete(&kbFileModel).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&recycleModel).Error
+	}, nil)
+	if err != nil {
+		zap.S().Errorf("recycle kb_file name :%v err:%v", req.KBFileName, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
 
-	// 首先删除旧表
-	tx := config.DB.Begin()
-	tx.Model(&kbFileModel).Where(
-		"kb_file_id = ?",
-		kbFileModel.KBFileId,
-	).Delete(&kbFileModel)
-	if tx.Error != nil {
-		zap.S().Errorf("Delete kb_file name :%v err:%v", req.KBFileName, tx.Error)
-		tx.Rollback()
+	// 回收文件，复制到回收站
+	src := filepath.Join(config.PathCfg.KnowledgeBasePath, req.IndexId, req.KBFileId)
+	dst := filepath.Join(config.PathCfg.RecycleBinPath, req.IndexId, req.KBFileId)
+	err = sg.Do("copy kb file to recycle bin", func() error {
+		if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+			return err
+		}
+		return wrench.CopyDir(src, dst)
+	}, func() error {
+		return os.RemoveAll(dst)
+	})
+	if err != nil {
+		zap.S().Errorf("Failed to create recycle dir, err: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
-	// 然后放到回收站里
-	tx.Create(&recycleModel)
-	if tx.Error != nil {
-		zap.S().Errorf("recycle kb_file name :%v err:%v", req.KBFileName, tx.Error)
-		tx.Rollback()
+
+	// 删除原来的文件夹，全部成功后才真正删除
+	err = sg.RemoveDir(src)
+	if err != nil {
+		zap.S().Errorf("Delete kb_file id :%v err:%v", req.KBFileId, err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
 
-	// 删除对应的所有切片
-	err = search_engine.DeleteByTerm(config.ElasticClient, "gcnote-"+req.IndexId, "kb_file_id", req.KBFileId)
+	// 删除对应的所有切片，撤销时按回收站里的文档重新写入
+	indexName := "gcnote-" + req.IndexId
+	err = sg.Do("delete es chunks", func() error {
+		return search_engine.DeleteByTerm(config.ElasticClient, indexName, "kb_file_id", req.KBFileId)
+	}, func() error {
+		content, err := os.ReadFile(filepath.Join(dst, req.KBFileName+".md"))
+		if err != nil {
+			return err
+		}
+		chunks := splitter.SplitMarkdown(string(content), 512)
+		docList := splitter.Chunk2Doc(chunks, req.KBFileId, req.IndexId)
+		embedList, err := embeds.RandEmbedding(docList) // fixme 之后换成正常的Embedding服务
+		if err != nil {
+			return err
+		}
+		return search_engine.AddDocuments(config.ElasticClient, indexName, docList, embedList)
+	})
 	if err != nil {
 		zap.S().Errorf("Failed to delete the document in elasticsearch index, err: %v", err)
-		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
 
 	// 提交事务
-	err = tx.Commit().Error
+	err = sg.Commit(tx)
 	if err != nil {
 		zap.S().Errorf("Failed to commit transaction, err: %v", err)
-		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
+	sg.Complete()
 
 	// 更新缓存
 	// 1. 删除kb文件信息缓存
@@ -195,12 +222,12 @@ func RecycleKBFile(ctx *gin.Context) {
 		zap.S().Errorf("Failed to refresh index kb list cache: %v", err)
 	}
 	// 3. 刷新用户的最近访问kb列表缓存
-	_, err = cache.RefreshRecentKBList(ctx, currentUserId)
+	_, err = cache.RefreshRecentKBList(ctx, index.UserId)
 	if err != nil {
 		zap.S().Errorf("Failed to refresh user recent kb list cache: %v", err)
 	}
 	// 4. 刷新用户的回收站列表缓存
-	_, err = cache.RefreshUserRecycleList(ctx, currentUserId)
+	_, err = cache.RefreshUserRecycleList(ctx, index.UserId)
 	if err != nil {
 		zap.S().Errorf("Failed to refresh user recycle list cache: %v", err)
 	}
@@ -0,0 +1,168 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"errors"
+	"fmt"
+	"gcnote/server/ability/differ"
+	"gcnote/server/ability/splitter"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/router/wrench"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// DiffRevisions
+// @Summary      对比文档的两个版本
+// @Description  按行对比两个版本（不传to时和当前内容对比），修改过的行再按词对比，中日韩文字按单个字对比；同时返回unified diff格式的文本
+// @ID           diff-revisions
+// @Tags         index
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.KBFileRevisionDiffRequest  true  "版本对比请求体"
+// @Success      200  {object}  dto.BaseResponse{Data=dto.KBFileRevisionDiffResponse}  "成功，返回对比结果"
+// @Failure      400  {object}  dto.BaseResponse  "参数错误(code:40000)"
+// @Failure      401  {object}  dto.BaseResponse  "Token错误(code:40101)"
+// @Failure      404  {object}  dto.BaseResponse  "知识库、文档或版本不存在(code:40201、40302、40001)"
+// @Failure      500  {object}  dto.BaseResponse  "服务器内部错误(code:50000)"
+// @Router       /index/diff_revisions [post]
+func DiffRevisions(ctx *gin.Context) {
+	var req dto.KBFileRevisionDiffRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+	context := dto.DefaultDiffContext
+	if req.Context != nil {
+		context = *req.Context
+	}
+
+	// 获取用户信息
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		zap.S().Debugf("currentUserId is empty")
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 先从缓存验证index是否存在
+	index, err := cache.GetIndexInfo(ctx, req.IndexId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		index, err = cache.RefreshIndexInfo(ctx, req.IndexId)
+		if err != nil {
+			zap.S().Errorf("Failed to get index info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	if index == nil || index.IndexId == "" {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+	canAccess, err := wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+
+	// 从缓存验证kb文件是否存在
+	kbFile, err := cache.GetKBInfo(ctx, req.KBFileId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		kbFile, err = cache.RefreshKBInfo(ctx, req.KBFileId)
+		if err != nil {
+			zap.S().Errorf("Failed to get kb file info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get kb file from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	if kbFile == nil || kbFile.KBFileId == "" || kbFile.IndexId != req.IndexId {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.KBFileNotExistErrCode))
+		return
+	}
+
+	// 读取两边的内容
+	_, oldContent, err := readRevision(kbFile.KBFileId, req.From)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.RecordNotFoundErrCode))
+		return
+	} else if err != nil {
+		zap.S().Errorf("Failed to get revision: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	var newContent []byte
+	newName := kbFile.KBFileName + ".md"
+	if req.To == 0 {
+		newContent, err = os.ReadFile(filepath.Join(config.PathCfg.KnowledgeBasePath, kbFile.IndexId, kbFile.KBFileId,
+			kbFile.KBFileName+".md"))
+		if err != nil {
+			zap.S().Errorf("Failed to read file: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else {
+		_, newContent, err = readRevision(kbFile.KBFileId, req.To)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, dto.Fail(dto.RecordNotFoundErrCode))
+			return
+		} else if err != nil {
+			zap.S().Errorf("Failed to get revision: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+		newName = fmt.Sprintf("%s.md@%d", kbFile.KBFileName, req.To)
+	}
+
+	// 和读取文档一样，把本地的图片路径转为图片服务的地址再对比
+	oldText := splitter.ChunkRead(splitter.SplitMarkdownEasy(string(oldContent)), config.PathCfg.ImageServerURL,
+		req.IndexId, req.KBFileId)
+	newText := splitter.ChunkRead(splitter.SplitMarkdownEasy(string(newContent)), config.PathCfg.ImageServerURL,
+		req.IndexId, req.KBFileId)
+	result := differ.Diff(oldText, newText, context)
+
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.KBFileRevisionDiffResponse{
+		From:    req.From,
+		To:      req.To,
+		Hunks:   result.Hunks,
+		Added:   result.Added,
+		Deleted: result.Deleted,
+		Unified: differ.Unified(fmt.Sprintf("%s.md@%d", kbFile.KBFileName, req.From), newName, result.Hunks),
+	}))
+}
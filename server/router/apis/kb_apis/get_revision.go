@@ -0,0 +1,128 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"errors"
+	"gcnote/server/ability/splitter"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/router/wrench"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// GetRevision
+// @Summary      获取文档的一个版本
+// @Description  返回版本的信息和内容，内容中的图片地址和读取文档时一样
+// @ID           get-revision
+// @Tags         index
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.KBFileRevisionRequest  true  "版本请求体"
+// @Success      200  {object}  dto.BaseResponse{Data=dto.KBFileRevisionResponse}  "成功，返回版本内容"
+// @Failure      400  {object}  dto.BaseResponse  "参数错误(code:40000)"
+// @Failure      401  {object}  dto.BaseResponse  "Token错误(code:40101)"
+// @Failure      404  {object}  dto.BaseResponse  "知识库、文档或版本不存在(code:40201、40302、40001)"
+// @Failure      500  {object}  dto.BaseResponse  "服务器内部错误(code:50000)"
+// @Router       /index/get_revision [post]
+func GetRevision(ctx *gin.Context) {
+	var req dto.KBFileRevisionRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 获取用户信息
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		zap.S().Debugf("currentUserId is empty")
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 先从缓存验证index是否存在
+	index, err := cache.GetIndexInfo(ctx, req.IndexId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		index, err = cache.RefreshIndexInfo(ctx, req.IndexId)
+		if err != nil {
+			zap.S().Errorf("Failed to get index info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	if index == nil || index.IndexId == "" {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+	canAccess, err := wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+
+	// 从缓存验证kb文件是否存在
+	kbFile, err := cache.GetKBInfo(ctx, req.KBFileId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		kbFile, err = cache.RefreshKBInfo(ctx, req.KBFileId)
+		if err != nil {
+			zap.S().Errorf("Failed to get kb file info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get kb file from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	if kbFile == nil || kbFile.KBFileId == "" || kbFile.IndexId != req.IndexId {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.KBFileNotExistErrCode))
+		return
+	}
+
+	revision, content, err := readRevision(kbFile.KBFileId, req.Revision)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.RecordNotFoundErrCode))
+		return
+	} else if err != nil {
+		zap.S().Errorf("Failed to get revision: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	// 和读取文档一样，把本地的图片路径转为图片服务的地址
+	chunks := splitter.SplitMarkdownEasy(string(content))
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.KBFileRevisionResponse{
+		KBFileRevisionInfo: revisionInfo(revision),
+		Content:            splitter.ChunkRead(chunks, config.PathCfg.ImageServerURL, req.IndexId, req.KBFileId),
+	}))
+}
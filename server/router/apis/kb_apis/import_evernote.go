@@ -0,0 +1,195 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gcnote/server/ability/convert/convert_enex"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/job_queue"
+	"gcnote/server/model"
+	"gcnote/server/router/wrench"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ImportEvernote
+// @Summary		导入Evernote笔记
+// @Description	 上传Evernote导出的.enex文件，每篇笔记导入为一个知识库文件；附件保存到文档的images目录，标签记录在元数据中，文档的创建和更新时间使用笔记原来的时间
+// @ID			 import_evernote
+// @Tags		 index
+// @Accept       mpfd
+// @Produce      json
+// @Param		index_id	formData	string	true	"知识库ID"
+// @Param		file		formData	file	true	"Evernote导出的.enex文件"
+// @Param		on_duplicate	formData	string	false	"已有相同内容时的处理方式：reject、link、create(默认)"
+// @Success		 200	{object} 		dto.BaseResponse{Data=dto.ImportJobResponse} "成功，返回导入任务id"
+// @Failure		 400	{object} 		dto.BaseResponse "参数问题、不是.enex文件(40000)"
+// @Failure		 401	{object} 		dto.BaseResponse "未授权，用户未登录(40101)"
+// @Failure		 409	{object} 		dto.BaseResponse "知识库不存在（40201）"
+// @Failure      500	{object} 		dto.BaseResponse "服务器内部错误(code:50000)"
+// @Router		 /index/import_evernote [post]
+func ImportEvernote(ctx *gin.Context) {
+	var req dto.KBFileBulkImportRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+	if !strings.EqualFold(filepath.Ext(req.File.Filename), ".enex") {
+		ctx.JSON(http.StatusBadRequest, dto.FailWithMessage(dto.ParamsErrCode, "请上传Evernote导出的.enex文件"))
+		return
+	}
+	// 获取userId
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 先从缓存验证index是否存在
+	index, err := cache.GetIndexInfo(ctx, req.IndexId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		index, err = cache.RefreshIndexInfo(ctx, req.IndexId)
+		if err != nil {
+			zap.S().Errorf("Failed to get index info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if index == nil || index.IndexId == "" || index.UserId != currentUserId {
+		ctx.JSON(http.StatusConflict, dto.FailWithMessage(dto.IndexNotExistErrCode,
+			"知识库"+req.IndexId+"不存在"))
+		return
+	}
+
+	jobId := wrench.IdGenerator()
+	tmpDirPath := filepath.Join(config.PathCfg.TempDirPath, jobId)
+	enexPath := filepath.Join(tmpDirPath, filepath.Base(req.File.Filename))
+	if err = ctx.SaveUploadedFile(req.File, enexPath); err != nil {
+		zap.S().Errorf("Save enex %s error: %v", req.File.Filename, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	job, err := newBatchJob(jobId, jobKindEvernote, currentUserId, req.IndexId, filepath.Base(enexPath),
+		batchJobPayload{Path: enexPath, TmpDir: tmpDirPath, OnDuplicate: onDuplicateOrDefault(req.OnDuplicate)})
+	if err == nil {
+		err = job_queue.Enqueue(job)
+	}
+	if err != nil {
+		zap.S().Errorf("Enqueue job for %s error: %v", req.File.Filename, err)
+		_ = wrench.RemoveContents(tmpDirPath)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.ImportJobResponse{JobId: job.JobId, Message: "正在导入中"}))
+}
+
+func runEnexJob(ctx context.Context, job *model.IngestJob) error {
+	var payload batchJobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return job_queue.Permanent(err)
+	}
+	err := importEnex(ctx, job, &payload)
+	finishJob(ctx, job, payload.TmpDir, err, "")
+	return err
+}
+
+// importEnex 边读边导入，单篇笔记失败只记录到任务列表，不影响后面的笔记；
+// 笔记的总数要读完才知道，进度里只有已处理的篇数
+func importEnex(ctx context.Context, job *model.IngestJob, payload *batchJobPayload) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	file, err := os.Open(payload.Path)
+	if err != nil {
+		zap.S().Errorf("Open enex %s error: %v", job.Name, err)
+		return job_queue.Permanent(&importError{reason: "open file error.", err: err})
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	assigner := newBatchAssigner(ctx, job, payload)
+	count := 0
+	err = convert_enex.ReadNotes(file, func(note *convert_enex.Note) error {
+		count++
+		if count <= job.Done {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		title := note.Title
+		if title == "" {
+			title = "Untitled"
+		}
+		// 同一个文件里的笔记没有路径，按顺序编号
+		KBFileNew, err := assigner.assign(strconv.Itoa(count), wrench.SanitizeKBName(title))
+		if err == nil {
+			err = assigner.save()
+		}
+		if err != nil {
+			zap.S().Errorf("Failed to assign kb file for note %s: %v", title, err)
+			return &importError{reason: "database error.", err: err}
+		}
+		// 为零值时gorm会自动填当前时间
+		KBFileNew.CreatedAt = note.Created
+		KBFileNew.UpdatedAt = note.Updated
+		if KBFileNew.UpdatedAt.IsZero() {
+			KBFileNew.UpdatedAt = note.Created
+		}
+		src := ingestSource{
+			path: payload.Path,
+			ext:  ".enex",
+			convert: func(_ string, outputDir string) (string, string, error) {
+				return note.Convert(outputDir)
+			},
+		}
+		for _, tag := range note.Tags {
+			src.meta = append(src.meta, model.KBFileMeta{Key: model.MetaTag, Value: tag})
+		}
+		if err = ingestBatchFile(ctx, job, payload, KBFileNew, src); err != nil {
+			return err
+		}
+		job_queue.SetProgress(ctx, count, 0)
+		return nil
+	})
+	var ie *importError
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.As(err, &ie) {
+		zap.S().Errorf("Read enex %s error: %v", job.Name, err)
+		return job_queue.Permanent(&importError{reason: "read enex error: " + err.Error(), err: err})
+	}
+	if err != nil {
+		return err
+	}
+	zap.S().Infof("Import enex %s done, %d notes.", job.Name, count)
+	return nil
+}
@@ -0,0 +1,194 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gcnote/server/ability/archive"
+	"gcnote/server/ability/convert/convert_notion"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/job_queue"
+	"gcnote/server/model"
+	"gcnote/server/router/wrench"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImportNotion
+// @Summary		导入Notion导出包
+// @Description	 上传Notion导出的zip（Markdown & CSV格式），每个页面和数据库导入为一个知识库文件；去掉名称后面的页面id，数据库转为表格，页面层级记录在元数据中，页面之间的链接改写为指向对应文档的链接
+// @ID			 import_notion
+// @Tags		 index
+// @Accept       mpfd
+// @Produce      json
+// @Param		index_id	formData	string	true	"知识库ID"
+// @Param		file		formData	file	true	"Notion导出的zip"
+// @Param		on_duplicate	formData	string	false	"已有相同内容时的处理方式：reject、link、create(默认)"
+// @Success		 200	{object} 		dto.BaseResponse{Data=dto.ImportJobResponse} "成功，返回导入任务id"
+// @Failure		 400	{object} 		dto.BaseResponse "参数问题、不是zip压缩包(40000)"
+// @Failure		 401	{object} 		dto.BaseResponse "未授权，用户未登录(40101)"
+// @Failure		 409	{object} 		dto.BaseResponse "知识库不存在（40201）"
+// @Failure      500	{object} 		dto.BaseResponse "服务器内部错误(code:50000)"
+// @Router		 /index/import_notion [post]
+func ImportNotion(ctx *gin.Context) {
+	var req dto.KBFileBulkImportRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+	if !strings.EqualFold(filepath.Ext(req.File.Filename), ".zip") {
+		ctx.JSON(http.StatusBadRequest, dto.FailWithMessage(dto.ParamsErrCode, "请上传Notion导出的zip文件"))
+		return
+	}
+	// 获取userId
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 先从缓存验证index是否存在
+	index, err := cache.GetIndexInfo(ctx, req.IndexId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		index, err = cache.RefreshIndexInfo(ctx, req.IndexId)
+		if err != nil {
+			zap.S().Errorf("Failed to get index info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if index == nil || index.IndexId == "" || index.UserId != currentUserId {
+		ctx.JSON(http.StatusConflict, dto.FailWithMessage(dto.IndexNotExistErrCode,
+			"知识库"+req.IndexId+"不存在"))
+		return
+	}
+
+	jobId := wrench.IdGenerator()
+	tmpDirPath := filepath.Join(config.PathCfg.TempDirPath, jobId)
+	archivePath := filepath.Join(tmpDirPath, filepath.Base(req.File.Filename))
+	if err = ctx.SaveUploadedFile(req.File, archivePath); err != nil {
+		zap.S().Errorf("Save notion export %s error: %v", req.File.Filename, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	job, err := newBatchJob(jobId, jobKindNotion, currentUserId, req.IndexId, filepath.Base(archivePath),
+		batchJobPayload{Path: archivePath, TmpDir: tmpDirPath, OnDuplicate: onDuplicateOrDefault(req.OnDuplicate)})
+	if err == nil {
+		err = job_queue.Enqueue(job)
+	}
+	if err != nil {
+		zap.S().Errorf("Enqueue job for %s error: %v", req.File.Filename, err)
+		_ = wrench.RemoveContents(tmpDirPath)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.ImportJobResponse{JobId: job.JobId, Message: "正在导入中"}))
+}
+
+func runNotionJob(ctx context.Context, job *model.IngestJob) error {
+	var payload batchJobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return job_queue.Permanent(err)
+	}
+	err := importNotion(ctx, job, &payload)
+	finishJob(ctx, job, payload.TmpDir, err, "")
+	return err
+}
+
+// importNotion 先按层级给所有页面分配文档id和名称，上级页面在前，然后逐个导入
+func importNotion(ctx context.Context, job *model.IngestJob, payload *batchJobPayload) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	conf := config.ServerCfg.ImportConf
+	exportDir := filepath.Join(payload.TmpDir, "files")
+	entries, err := archive.Extract(payload.Path, exportDir, archive.Limits{
+		MaxFiles: conf.ArchiveMaxFiles,
+		MaxSize:  conf.ArchiveMaxSize,
+	})
+	if err != nil {
+		zap.S().Errorf("Extract notion export %s error: %v", job.Name, err)
+		return job_queue.Permanent(&importError{reason: "extract archive error: " + err.Error(), err: err})
+	}
+	var files []string
+	for _, entry := range entries {
+		files = append(files, entry.Name)
+	}
+	export := convert_notion.NewExport(os.DirFS(exportDir), files)
+	pages := export.Pages()
+	if len(pages) == 0 {
+		return job_queue.Permanent(&importError{reason: "no notion pages found in archive"})
+	}
+
+	assigner := newBatchAssigner(ctx, job, payload)
+	kbFiles := map[*convert_notion.Page]model.KBFile{}
+	for _, page := range pages {
+		kbFiles[page], err = assigner.assign(page.Path, wrench.SanitizeKBName(page.Title))
+		if err != nil {
+			zap.S().Errorf("Failed to check kb file name: %v", err)
+			return &importError{reason: "database error.", err: err}
+		}
+		page.Link = model.KBFileLink(job.IndexId, kbFiles[page].KBFileId)
+	}
+	if err = assigner.save(); err != nil {
+		return &importError{reason: "database error.", err: err}
+	}
+
+	for i, page := range pages {
+		if i < job.Done {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		ext := ".md"
+		if page.Database {
+			ext = ".csv"
+		}
+		src := ingestSource{
+			path: filepath.Join(exportDir, filepath.FromSlash(page.Path)),
+			ext:  ext,
+			convert: func(_ string, outputDir string) (string, string, error) {
+				return export.Convert(page, outputDir)
+			},
+		}
+		if page.Parent != nil {
+			src.meta = append(src.meta,
+				model.KBFileMeta{Key: model.MetaFolderPath, Value: export.TitlePath(page)},
+				model.KBFileMeta{Key: model.MetaParentId, Value: kbFiles[page.Parent].KBFileId})
+		}
+		if err = ingestBatchFile(ctx, job, payload, kbFiles[page], src); err != nil {
+			return err
+		}
+		job_queue.SetProgress(ctx, i+1, len(pages))
+	}
+	zap.S().Infof("Import notion export %s done, %d pages.", job.Name, len(pages))
+	return nil
+}
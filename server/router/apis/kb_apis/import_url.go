@@ -0,0 +1,160 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"errors"
+	"gcnote/server/ability/web_fetch"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/job_queue"
+	"gcnote/server/model"
+	"gcnote/server/router/wrench"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+// ImportURL
+// @Summary		从url导入
+// @Description	 服务端抓取网页并导入对应的知识库，原始地址和抓取时间记录在文档元数据中
+// @ID			 import_url
+// @Tags		 index
+// @Accept       json
+// @Produce      json
+// @Param		request		body		dto.KBFileImportURLRequest true "url导入请求体"
+// @Success		 200	{object} 		dto.BaseResponse{Data=dto.ImportJobResponse} "成功，返回导入任务id"
+// @Failure		 400	{object} 		dto.BaseResponse "url无效、抓取失败等参数问题(40000、40303)"
+// @Failure		 401	{object} 		dto.BaseResponse "未授权，用户未登录(40101)"
+// @Failure		 409	{object} 		dto.BaseResponse "知识库不存在（40201），文档已存在（40301）"
+// @Failure      500	{object} 		dto.BaseResponse "服务器内部错误(code:50000)"
+// @Router		 /index/import_url [post]
+func ImportURL(ctx *gin.Context) {
+	var req dto.KBFileImportURLRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		ctx.JSON(http.StatusBadRequest, dto.FailWithMessage(dto.ParamsErrCode, "url无效，只支持http和https"))
+		return
+	}
+	if req.KBFileName != "" && !wrench.ValidateKBName(req.KBFileName) {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.KBFileNameErrCode))
+		return
+	}
+	// 获取userId
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 先从缓存验证index是否存在
+	index, err := cache.GetIndexInfo(ctx, req.IndexId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		index, err = cache.RefreshIndexInfo(ctx, req.IndexId)
+		if err != nil {
+			zap.S().Errorf("Failed to get index info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if index == nil || index.IndexId == "" || index.UserId != currentUserId {
+		ctx.JSON(http.StatusConflict, dto.FailWithMessage(dto.IndexNotExistErrCode,
+			"知识库"+req.IndexId+"不存在"))
+		return
+	}
+
+	// 抓取网页，文档名默认使用网页标题，所以这一步同步完成
+	conf := config.ServerCfg.ImportConf
+	opts := web_fetch.Options{
+		Timeout:      time.Duration(conf.UrlTimeout) * time.Second,
+		MaxSize:      conf.UrlMaxSize,
+		MaxRedirects: conf.UrlMaxRedirects,
+		AllowHosts:   conf.UrlAllowHosts,
+		BlockPrivate: conf.UrlBlockPrivate,
+	}
+	tmpFileDirPath := filepath.Join(config.PathCfg.TempDirPath, wrench.IdGenerator())
+	doc, err := web_fetch.FetchDocument(ctx, u.String(), opts, tmpFileDirPath)
+	if err != nil {
+		zap.S().Infof("Fetch url %s error: %v", req.URL, err)
+		_ = wrench.RemoveContents(tmpFileDirPath)
+		ctx.JSON(http.StatusBadRequest, dto.FailWithMessage(dto.KBFileAddFileErrCode, "网页抓取失败："+err.Error()))
+		return
+	}
+
+	KBFileNew := model.KBFile{
+		UserId:     currentUserId,
+		KBFileId:   wrench.IdGenerator(),
+		KBFileName: doc.Title,
+		IndexId:    req.IndexId,
+	}
+	if req.KBFileName != "" {
+		KBFileNew.KBFileName = req.KBFileName
+	}
+	// 检查是否存在同名的文件
+	var kbFile = model.KBFile{}
+	tx := config.DB.Model(&KBFileNew).Where("kb_file_name = ? AND index_id = ?",
+		KBFileNew.KBFileName, KBFileNew.IndexId).First(&kbFile)
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		_ = wrench.RemoveContents(tmpFileDirPath)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if tx.RowsAffected != 0 { // 行数不为0 ，说明已经存在了
+		_ = wrench.RemoveContents(tmpFileDirPath)
+		ctx.JSON(http.StatusConflict, dto.Fail(dto.KBFileExistErrCode))
+		return
+	}
+
+	payload := fileJobPayload{
+		Path:        doc.Path,
+		Ext:         doc.Suffix,
+		TmpDir:      tmpFileDirPath,
+		OnDuplicate: onDuplicateOrDefault(req.OnDuplicate),
+		Meta: []model.KBFileMeta{
+			{Key: model.MetaSourceURL, Value: req.URL},
+			{Key: model.MetaFetchedAt, Value: doc.FetchedAt.Format(time.RFC3339)},
+		},
+	}
+	if doc.Suffix == ".html" {
+		payload.BaseURL = doc.URL
+	}
+	job, err := newIngestJob(wrench.IdGenerator(), jobKindURL, KBFileNew, payload)
+	if err == nil {
+		err = job_queue.Enqueue(job)
+	}
+	if err != nil {
+		zap.S().Errorf("Enqueue job for %s error: %v", req.URL, err)
+		_ = wrench.RemoveContents(tmpFileDirPath)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.ImportJobResponse{JobId: job.JobId, Message: "正在导入中"}))
+}
@@ -0,0 +1,200 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gcnote/server/ability/archive"
+	"gcnote/server/ability/convert/convert_obsidian"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/job_queue"
+	"gcnote/server/model"
+	"gcnote/server/router/wrench"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ImportVault
+// @Summary		导入Obsidian仓库
+// @Description	 上传zip压缩的Obsidian仓库，每篇笔记导入为一个知识库文件；[[wikilink]]改写为指向对应文档的链接，嵌入的附件复制到文档的images目录，front matter和正文中的标签记录在元数据中
+// @ID			 import_vault
+// @Tags		 index
+// @Accept       mpfd
+// @Produce      json
+// @Param		index_id	formData	string	true	"知识库ID"
+// @Param		file		formData	file	true	"zip压缩的Obsidian仓库"
+// @Param		on_duplicate	formData	string	false	"已有相同内容时的处理方式：reject、link、create(默认)"
+// @Success		 200	{object} 		dto.BaseResponse{Data=dto.ImportJobResponse} "成功，返回导入任务id"
+// @Failure		 400	{object} 		dto.BaseResponse "参数问题、不是zip压缩包(40000)"
+// @Failure		 401	{object} 		dto.BaseResponse "未授权，用户未登录(40101)"
+// @Failure		 409	{object} 		dto.BaseResponse "知识库不存在（40201）"
+// @Failure      500	{object} 		dto.BaseResponse "服务器内部错误(code:50000)"
+// @Router		 /index/import_vault [post]
+func ImportVault(ctx *gin.Context) {
+	var req dto.KBFileBulkImportRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+	if !strings.EqualFold(filepath.Ext(req.File.Filename), ".zip") {
+		ctx.JSON(http.StatusBadRequest, dto.FailWithMessage(dto.ParamsErrCode, "Obsidian仓库需要压缩为zip格式"))
+		return
+	}
+	// 获取userId
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 先从缓存验证index是否存在
+	index, err := cache.GetIndexInfo(ctx, req.IndexId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		index, err = cache.RefreshIndexInfo(ctx, req.IndexId)
+		if err != nil {
+			zap.S().Errorf("Failed to get index info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if index == nil || index.IndexId == "" || index.UserId != currentUserId {
+		ctx.JSON(http.StatusConflict, dto.FailWithMessage(dto.IndexNotExistErrCode,
+			"知识库"+req.IndexId+"不存在"))
+		return
+	}
+
+	jobId := wrench.IdGenerator()
+	tmpDirPath := filepath.Join(config.PathCfg.TempDirPath, jobId)
+	archivePath := filepath.Join(tmpDirPath, filepath.Base(req.File.Filename))
+	if err = ctx.SaveUploadedFile(req.File, archivePath); err != nil {
+		zap.S().Errorf("Save vault %s error: %v", req.File.Filename, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	job, err := newBatchJob(jobId, jobKindVault, currentUserId, req.IndexId, filepath.Base(archivePath),
+		batchJobPayload{Path: archivePath, TmpDir: tmpDirPath, OnDuplicate: onDuplicateOrDefault(req.OnDuplicate)})
+	if err == nil {
+		err = job_queue.Enqueue(job)
+	}
+	if err != nil {
+		zap.S().Errorf("Enqueue job for %s error: %v", req.File.Filename, err)
+		_ = wrench.RemoveContents(tmpDirPath)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.ImportJobResponse{JobId: job.JobId, Message: "正在导入中"}))
+}
+
+func runVaultJob(ctx context.Context, job *model.IngestJob) error {
+	var payload batchJobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return job_queue.Permanent(err)
+	}
+	err := importVault(ctx, job, &payload)
+	finishJob(ctx, job, payload.TmpDir, err, "")
+	return err
+}
+
+// importVault 先给所有笔记分配文档id和名称，这样笔记之间的链接才能在转换时改写，然后逐篇导入
+func importVault(ctx context.Context, job *model.IngestJob, payload *batchJobPayload) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	conf := config.ServerCfg.ImportConf
+	vaultDir := filepath.Join(payload.TmpDir, "files")
+	// .obsidian配置目录和其他隐藏文件在解压时就跳过了
+	entries, err := archive.Extract(payload.Path, vaultDir, archive.Limits{
+		MaxFiles: conf.ArchiveMaxFiles,
+		MaxSize:  conf.ArchiveMaxSize,
+	})
+	if err != nil {
+		zap.S().Errorf("Extract vault %s error: %v", job.Name, err)
+		return job_queue.Permanent(&importError{reason: "extract archive error: " + err.Error(), err: err})
+	}
+	var files []string
+	for _, entry := range entries {
+		files = append(files, entry.Name)
+	}
+	vault := convert_obsidian.NewVault(os.DirFS(vaultDir), files)
+	notes := vault.Notes()
+	if len(notes) == 0 {
+		return job_queue.Permanent(&importError{reason: "no markdown notes found in vault"})
+	}
+
+	assigner := newBatchAssigner(ctx, job, payload)
+	kbFiles := make([]model.KBFile, len(notes))
+	for i, note := range notes {
+		kbFiles[i], err = assigner.assign(note.Path, wrench.SanitizeKBName(note.Name))
+		if err != nil {
+			zap.S().Errorf("Failed to check kb file name: %v", err)
+			return &importError{reason: "database error.", err: err}
+		}
+		note.Link = model.KBFileLink(job.IndexId, kbFiles[i].KBFileId)
+	}
+	if err = assigner.save(); err != nil {
+		return &importError{reason: "database error.", err: err}
+	}
+
+	for i, note := range notes {
+		if i < job.Done {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		tags, err := vault.Tags(note)
+		if err != nil {
+			zap.S().Errorf("Read note %s error: %v", note.Path, err)
+			callback(ctx, job, kbFiles[i].KBFileName, "", "fail", "read note error.", "")
+			job_queue.SetProgress(ctx, i+1, len(notes))
+			continue
+		}
+		src := ingestSource{
+			path: filepath.Join(vaultDir, filepath.FromSlash(note.Path)),
+			ext:  ".md",
+			convert: func(_ string, outputDir string) (string, string, error) {
+				return vault.Convert(note, outputDir)
+			},
+		}
+		if dir := path.Dir(note.Path); dir != "." {
+			src.meta = append(src.meta, model.KBFileMeta{Key: model.MetaFolderPath, Value: dir})
+		}
+		for _, tag := range tags {
+			src.meta = append(src.meta, model.KBFileMeta{Key: model.MetaTag, Value: tag})
+		}
+		if err = ingestBatchFile(ctx, job, payload, kbFiles[i], src); err != nil {
+			return err
+		}
+		job_queue.SetProgress(ctx, i+1, len(notes))
+	}
+	zap.S().Infof("Import vault %s done, %d notes.", job.Name, len(notes))
+	return nil
+}
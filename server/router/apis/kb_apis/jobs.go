@@ -0,0 +1,211 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gcnote/server/ability/convert/convert_html"
+	"gcnote/server/config"
+	"gcnote/server/job_queue"
+	"gcnote/server/model"
+	"gcnote/server/router/wrench"
+	"go.uber.org/zap"
+)
+
+// 导入任务的类型
+const (
+	jobKindFile     = "file"     // 上传单个文件
+	jobKindURL      = "url"      // 从url导入
+	jobKindArchive  = "archive"  // 批量导入压缩包
+	jobKindVault    = "vault"    // Obsidian仓库
+	jobKindNotion   = "notion"   // Notion导出包
+	jobKindEvernote = "evernote" // Evernote导出的.enex
+)
+
+// RegisterJobHandlers 注册各类导入任务的处理函数
+func RegisterJobHandlers() {
+	job_queue.Register(jobKindFile, runFileJob)
+	job_queue.Register(jobKindURL, runFileJob)
+	job_queue.Register(jobKindArchive, runArchiveJob)
+	job_queue.Register(jobKindVault, runVaultJob)
+	job_queue.Register(jobKindNotion, runNotionJob)
+	job_queue.Register(jobKindEvernote, runEnexJob)
+}
+
+// fileJobPayload 单个文件（上传、url）导入任务的参数
+type fileJobPayload struct {
+	Path    string             `json:"path"`
+	Ext     string             `json:"ext"`
+	TmpDir  string             `json:"tmp_dir"`            // 任务结束后删除
+	BaseURL string             `json:"base_url,omitempty"` // 从url导入的html，相对路径的图片以跟随重定向后的地址为基准
+	Meta    []model.KBFileMeta `json:"meta,omitempty"`
+	// 上传文件的SHA-256和已有相同内容时的处理方式（dto.DuplicateReject等），为空时照常导入
+	SourceHash  string `json:"source_hash,omitempty"`
+	OnDuplicate string `json:"on_duplicate,omitempty"`
+}
+
+// batchJobPayload 多文件（压缩包、笔记软件导出包）导入任务的参数
+type batchJobPayload struct {
+	Path        string                  `json:"path"`
+	TmpDir      string                  `json:"tmp_dir"`
+	OnDuplicate string                  `json:"on_duplicate,omitempty"` // 每个文件已有相同内容时的处理方式
+	Assigned    map[string]assignedFile `json:"assigned,omitempty"`     // 包内路径 => 已分配的文档，重试时沿用
+}
+
+type assignedFile struct {
+	KBFileId   string `json:"kb_file_id"`
+	KBFileName string `json:"kb_file_name"`
+}
+
+// newIngestJob 单个文件的任务，KBFile的id和名称在提交时就确定了
+func newIngestJob(jobId string, kind string, kbFile model.KBFile, payload interface{}) (*model.IngestJob, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &model.IngestJob{
+		JobId:    jobId,
+		UserId:   kbFile.UserId,
+		IndexId:  kbFile.IndexId,
+		Kind:     kind,
+		Name:     kbFile.KBFileName,
+		KBFileId: kbFile.KBFileId,
+		Payload:  string(data),
+	}, nil
+}
+
+// newBatchJob 多文件的任务，name为上传的文件名
+func newBatchJob(jobId string, kind string, userId string, indexId string, name string, payload batchJobPayload) (*model.IngestJob, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &model.IngestJob{
+		JobId:   jobId,
+		UserId:  userId,
+		IndexId: indexId,
+		Kind:    kind,
+		Name:    name,
+		Payload: string(data),
+	}, nil
+}
+
+func runFileJob(ctx context.Context, job *model.IngestJob) error {
+	var payload fileJobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return job_queue.Permanent(err)
+	}
+	if err := ctx.Err(); err != nil {
+		finishJob(ctx, job, payload.TmpDir, err, "")
+		return err
+	}
+	KBFileNew := model.KBFile{
+		UserId:     job.UserId,
+		KBFileId:   job.KBFileId,
+		KBFileName: job.Name,
+		IndexId:    job.IndexId,
+	}
+	src := ingestSource{path: payload.Path, ext: payload.Ext, meta: payload.Meta,
+		sourceHash: payload.SourceHash, onDuplicate: payload.OnDuplicate}
+	if payload.BaseURL != "" {
+		src.convert = func(documentPath, outputDir string) (string, string, error) {
+			return convert_html.HtmlConvertWithOptions(documentPath, outputDir, convert_html.Options{
+				BaseURL:     payload.BaseURL,
+				Readability: config.ServerCfg.ConvertConf.HtmlReadability,
+			})
+		}
+	}
+	encoding, err := ingestFile(ctx, KBFileNew, src)
+	finishJob(ctx, job, payload.TmpDir, err, encoding)
+	if err == nil {
+		callback(ctx, job, job.Name, job.KBFileId, "success", "", encoding)
+	}
+	return err
+}
+
+// finishJob 任务不再重试时记录失败结果并删除临时目录；还要重试、或者租约已被其他实例领取时保留临时文件
+func finishJob(ctx context.Context, job *model.IngestJob, tmpDir string, err error, encoding string) {
+	if job_queue.LeaseLost(ctx) || (err != nil && !job_queue.IsFinal(ctx, job, err)) {
+		return
+	}
+	if err != nil {
+		callback(ctx, job, job.Name, "", "fail", importReason(err), encoding)
+	}
+	if tmpDir == "" {
+		return
+	}
+	if rmErr := wrench.RemoveContents(tmpDir); rmErr != nil {
+		zap.S().Errorf("Failed to remove temp dir, err: %v", rmErr)
+	}
+}
+
+// batchAssigner 给多文件任务里的每个文件分配文档id和名称并保存在任务参数里，
+// 重试时已经导入的文件沿用同样的id，ingestFile会跳过
+type batchAssigner struct {
+	ctx      context.Context
+	job      *model.IngestJob
+	payload  *batchJobPayload
+	reserved map[string]bool
+}
+
+func newBatchAssigner(ctx context.Context, job *model.IngestJob, payload *batchJobPayload) *batchAssigner {
+	if payload.Assigned == nil {
+		payload.Assigned = map[string]assignedFile{}
+	}
+	reserved := map[string]bool{}
+	for _, file := range payload.Assigned {
+		reserved[file.KBFileName] = true
+	}
+	return &batchAssigner{ctx: ctx, job: job, payload: payload, reserved: reserved}
+}
+
+// assign key（包内路径）已经分配过时沿用，否则按name分配一个不重名的文档名
+func (a *batchAssigner) assign(key string, name string) (model.KBFile, error) {
+	file, ok := a.payload.Assigned[key]
+	if !ok {
+		unique, err := uniqueKBFileName(a.job.IndexId, name, a.reserved)
+		if err != nil {
+			return model.KBFile{}, err
+		}
+		file = assignedFile{KBFileId: wrench.IdGenerator(), KBFileName: unique}
+		a.payload.Assigned[key] = file
+	}
+	return model.KBFile{
+		UserId:     a.job.UserId,
+		KBFileId:   file.KBFileId,
+		KBFileName: file.KBFileName,
+		IndexId:    a.job.IndexId,
+	}, nil
+}
+
+// save 导入文件之前先保存分配结果
+func (a *batchAssigner) save() error {
+	data, err := json.Marshal(a.payload)
+	if err != nil {
+		return err
+	}
+	return job_queue.SavePayload(a.ctx, string(data))
+}
+
+// ingestBatchFile 导入多文件任务中的一个文件，结果进入导入任务列表，单个文件失败不影响其他文件；
+// 只有任务被取消时返回错误
+func ingestBatchFile(ctx context.Context, job *model.IngestJob, payload *batchJobPayload, kbFile model.KBFile,
+	src ingestSource) error {
+	src.onDuplicate = payload.OnDuplicate
+	encoding, err := ingestFile(ctx, kbFile, src)
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	if err != nil {
+		callback(ctx, job, kbFile.KBFileName, "", "fail", importReason(err), encoding)
+		return nil
+	}
+	callback(ctx, job, kbFile.KBFileName, kbFile.KBFileId, "success", "", encoding)
+	return nil
+}
@@ -0,0 +1,142 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"errors"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/model"
+	"gcnote/server/router/wrench"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// ListRevisions
+// @Summary      获取文档的版本列表
+// @Description  分页获取文档保存过的版本，新的在前，不包含内容
+// @ID           list-revisions
+// @Tags         index
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.KBFileRevisionListRequest  true  "版本列表请求体"
+// @Success      200  {object}  dto.BaseResponse{Data=dto.KBFileRevisionListResponse}  "成功，返回版本列表"
+// @Failure      400  {object}  dto.BaseResponse  "参数错误(code:40000)"
+// @Failure      401  {object}  dto.BaseResponse  "Token错误(code:40101)"
+// @Failure      404  {object}  dto.BaseResponse  "知识库或文档不存在(code:40201、40302)"
+// @Failure      500  {object}  dto.BaseResponse  "服务器内部错误(code:50000)"
+// @Router       /index/list_revisions [post]
+func ListRevisions(ctx *gin.Context) {
+	var req dto.KBFileRevisionListRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = dto.DefaultRevisionPageSize
+	}
+
+	// 获取用户信息
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		zap.S().Debugf("currentUserId is empty")
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 先从缓存验证index是否存在
+	index, err := cache.GetIndexInfo(ctx, req.IndexId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		index, err = cache.RefreshIndexInfo(ctx, req.IndexId)
+		if err != nil {
+			zap.S().Errorf("Failed to get index info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	if index == nil || index.IndexId == "" {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+	canAccess, err := wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+
+	// 从缓存验证kb文件是否存在
+	kbFile, err := cache.GetKBInfo(ctx, req.KBFileId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		kbFile, err = cache.RefreshKBInfo(ctx, req.KBFileId)
+		if err != nil {
+			zap.S().Errorf("Failed to get kb file info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get kb file from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	if kbFile == nil || kbFile.KBFileId == "" || kbFile.IndexId != req.IndexId {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.KBFileNotExistErrCode))
+		return
+	}
+
+	var total int64
+	query := config.DB.Model(&model.KBFileRevision{}).Where("kb_file_id = ?", kbFile.KBFileId)
+	if err = query.Count(&total).Error; err != nil {
+		zap.S().Errorf("Failed to count revisions: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	var revisions []model.KBFileRevision
+	err = query.Order("revision desc").Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).Find(&revisions).Error
+	if err != nil {
+		zap.S().Errorf("Failed to list revisions: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	infos := make([]dto.KBFileRevisionInfo, len(revisions))
+	for i := range revisions {
+		infos[i] = revisionInfo(&revisions[i])
+	}
+
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.KBFileRevisionListResponse{
+		Revisions: infos,
+		Total:     total,
+		Page:      req.Page,
+		PageSize:  req.PageSize,
+	}))
+}
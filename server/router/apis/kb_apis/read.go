@@ -8,10 +8,12 @@ package kb_apis
 
 import (
 	"errors"
+	"fmt"
 	"gcnote/server/ability/splitter"
 	"gcnote/server/cache"
 	"gcnote/server/config"
 	"gcnote/server/dto"
+	"gcnote/server/router/wrench"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
@@ -60,7 +62,17 @@ func ReadFile(ctx *gin.Context) {
 		return
 	}
 
-	if index == nil || index.IndexId == "" || index.UserId != currentUserId {
+	if index == nil || index.IndexId == "" {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+	canAccess, err := wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
 		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
 		return
 	}
@@ -103,12 +115,15 @@ func ReadFile(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
+	// 当前内容的版本，保存时通过If-Match带回，用于发现并发的修改
+	ctx.Header("ETag", fmt.Sprintf("%q", contentVersion(content)))
+
 	// 将content转为切片然后读取
 	chunks := splitter.SplitMarkdownEasy(string(content))
 	resultData := splitter.ChunkRead(chunks, config.PathCfg.ImageServerURL, req.IndexId, req.KBFileId)
 
 	// 更新最近访问列表缓存
-	_, err = cache.RefreshRecentKBList(ctx, currentUserId)
+	_, err = cache.RefreshRecentKBList(ctx, index.UserId)
 	if err != nil {
 		zap.S().Errorf("Failed to refresh user recent kb list cache: %v", err)
 	}
@@ -0,0 +1,29 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"database/sql/driver"
+	"gcnote/server/dto"
+	"gcnote/server/router/apis/api_fixture"
+	"net/http"
+	"testing"
+)
+
+func TestReadFileWithMember(t *testing.T) {
+	env, _ := setupKB(t)
+	req := dto.KBFileReadRequest{KBFileName: "a", KBFileId: "k1", IndexId: "i1"}
+
+	// u2还不是成员
+	if w := api_fixture.Call(t, ReadFile, "u2", req); w.Code != http.StatusNotFound {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	env.DB.Return("FROM `index_members`", []string{"count(*)"}, []driver.Value{int64(1)})
+	if w := api_fixture.Call(t, ReadFile, "u2", req); w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+}
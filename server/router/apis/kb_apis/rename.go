@@ -13,6 +13,7 @@ import (
 	"gcnote/server/dto"
 	"gcnote/server/model"
 	"gcnote/server/router/wrench"
+	"gcnote/server/saga"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
@@ -86,7 +87,17 @@ func RenameKBFile(ctx *gin.Context) {
 		return
 	}
 
-	if index == nil || index.IndexId == "" || index.UserId != currentUserId {
+	if index == nil || index.IndexId == "" {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+	canAccess, err := wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
 		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
 		return
 	}
@@ -126,12 +137,17 @@ func RenameKBFile(ctx *gin.Context) {
 		return
 	}
 
-	// 文件名修改
-	// os.Rename(oldPath, newPath)
+	// 文件名修改，数据库更新失败时改回原来的文件名
+	sg := saga.New("rename kb file " + req.KBFileId)
+	defer sg.Rollback()
 	documentPath := filepath.Join(config.PathCfg.KnowledgeBasePath, req.IndexId, req.KBFileId, req.KBFileName+".md")
 	renameDocumentPath := filepath.Join(config.PathCfg.KnowledgeBasePath, req.IndexId, req.KBFileId, req.DestKBFileName+".md")
 	zap.S().Debugf("修改文件，原始路径 %v, 新路径 %v", documentPath, renameDocumentPath)
-	err = os.Rename(documentPath, renameDocumentPath)
+	err = sg.Do("rename md file", func() error {
+		return os.Rename(documentPath, renameDocumentPath)
+	}, func() error {
+		return os.Rename(renameDocumentPath, documentPath)
+	})
 	if err != nil {
 		zap.S().Errorf("Failed to rename file, err: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.FailWithMessage(dto.InternalErrCode, "文件系统重命名文件失败"))
@@ -139,12 +155,16 @@ func RenameKBFile(ctx *gin.Context) {
 	}
 
 	// 修改SQL
-	err = config.DB.Model(&model.KBFile{}).Where("kb_file_id = ?", req.KBFileId).Update("kb_file_name", req.DestKBFileName).Error
+	err = sg.Do("update kb file name", func() error {
+		return config.DB.Model(&model.KBFile{}).Where("kb_file_id = ?", req.KBFileId).
+			Update("kb_file_name", req.DestKBFileName).Error
+	}, nil)
 	if err != nil {
 		zap.S().Errorf("Failed to update kb file, err: %v", err)
 		ctx.JSON(http.StatusOK, dto.FailWithMessage(dto.InternalErrCode, "更新文件名失败"))
 		return
 	}
+	sg.Complete()
 
 	// 更新缓存
 	// 1. 刷新kb文件信息缓存
@@ -158,7 +178,7 @@ func RenameKBFile(ctx *gin.Context) {
 		zap.S().Errorf("Failed to refresh index kb list cache: %v", err)
 	}
 	// 3. 刷新用户的最近访问kb列表缓存
-	_, err = cache.RefreshRecentKBList(ctx, currentUserId)
+	_, err = cache.RefreshRecentKBList(ctx, index.UserId)
 	if err != nil {
 		zap.S().Errorf("Failed to refresh user recent kb list cache: %v", err)
 	}
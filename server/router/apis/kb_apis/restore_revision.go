@@ -0,0 +1,151 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"errors"
+	"fmt"
+	"gcnote/server/cache"
+	"gcnote/server/dto"
+	"gcnote/server/router/wrench"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// RestoreRevision
+// @Summary      恢复文档的一个版本
+// @Description  将版本的内容保存为文档的当前内容，按新内容更新检索切片，并记录为一个新的版本；之前的版本都会保留
+// @ID           restore-revision
+// @Tags         index
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.KBFileRevisionRestoreRequest  true  "恢复版本请求体"
+// @Success      200  {object}  dto.BaseResponse{Data=dto.KBFileRevisionInfo}  "成功，返回新的版本"
+// @Failure      400  {object}  dto.BaseResponse  "参数错误(code:40000)"
+// @Failure      401  {object}  dto.BaseResponse  "Token错误(code:40101)"
+// @Failure      404  {object}  dto.BaseResponse  "知识库、文档或版本不存在(code:40201、40302、40001)"
+// @Failure      500  {object}  dto.BaseResponse  "服务器内部错误(code:50000)"
+// @Router       /index/restore_revision [post]
+func RestoreRevision(ctx *gin.Context) {
+	var req dto.KBFileRevisionRestoreRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 获取用户信息
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		zap.S().Debugf("currentUserId is empty")
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 先从缓存验证index是否存在
+	index, err := cache.GetIndexInfo(ctx, req.IndexId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		index, err = cache.RefreshIndexInfo(ctx, req.IndexId)
+		if err != nil {
+			zap.S().Errorf("Failed to get index info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get index from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	if index == nil || index.IndexId == "" {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+	canAccess, err := wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+
+	// 从缓存验证kb文件是否存在
+	kbFile, err := cache.GetKBInfo(ctx, req.KBFileId)
+	if errors.Is(err, redis.Nil) {
+		// 缓存未命中，从数据库查询
+		kbFile, err = cache.RefreshKBInfo(ctx, req.KBFileId)
+		if err != nil {
+			zap.S().Errorf("Failed to get kb file info: %v", err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+	} else if err != nil {
+		zap.S().Errorf("Failed to get kb file from cache: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	if kbFile == nil || kbFile.KBFileId == "" || kbFile.IndexId != req.IndexId {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.KBFileNotExistErrCode))
+		return
+	}
+
+	revision, content, err := readRevision(kbFile.KBFileId, req.Revision)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.RecordNotFoundErrCode))
+		return
+	} else if err != nil {
+		zap.S().Errorf("Failed to get revision: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	message := req.Message
+	if message == "" {
+		message = fmt.Sprintf("恢复到版本 %d", revision.Revision)
+	}
+	// 版本中保存的就是本地图片路径的内容，直接保存
+	restored, err := saveKBFileContent(kbFile, string(content), "", currentUserId, message)
+	if err != nil {
+		zap.S().Errorf("Failed to restore revision %d of %s, err: %v", revision.Revision, kbFile.KBFileId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	// 更新缓存
+	// 1. 刷新kb文件信息缓存
+	_, err = cache.RefreshKBInfo(ctx, kbFile.KBFileId)
+	if err != nil {
+		zap.S().Errorf("Failed to refresh kb file cache: %v", err)
+	}
+	// 2. 刷新index的kb文件列表缓存
+	_, err = cache.RefreshIndexKBList(ctx, req.IndexId)
+	if err != nil {
+		zap.S().Errorf("Failed to refresh index kb list cache: %v", err)
+	}
+	// 3. 刷新用户的最近访问kb列表缓存
+	_, err = cache.RefreshRecentKBList(ctx, index.UserId)
+	if err != nil {
+		zap.S().Errorf("Failed to refresh user recent kb list cache: %v", err)
+	}
+
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(revisionInfo(restored)))
+}
@@ -0,0 +1,255 @@
+// -------------------------------------------------
+// Package kb_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package kb_apis
+
+import (
+	"errors"
+	"fmt"
+	"gcnote/server/ability/blob"
+	"gcnote/server/ability/differ"
+	"gcnote/server/ability/document"
+	"gcnote/server/ability/embeds"
+	"gcnote/server/ability/search_engine"
+	"gcnote/server/ability/splitter"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/model"
+	"gcnote/server/router/wrench"
+	"gcnote/server/saga"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// errStaleVersion 保存时带的版本不是文档当前的版本，期间有其他保存
+var errStaleVersion = errors.New("kb file version is stale")
+
+// contentVersion 文档内容的版本，即md文件内容的SHA-256，和版本记录的hash相同；读取文档时通过ETag返回
+func contentVersion(content []byte) string {
+	return blob.Hash(content)
+}
+
+// parseVersion 从If-Match的值中取出版本，去掉弱校验前缀和引号
+func parseVersion(value string) string {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(value, "W/")
+	return strings.Trim(value, `"`)
+}
+
+// 还没有版本记录的文档第一次保存时，先把原来的内容记为这个版本
+const baselineRevisionMessage = "初始版本"
+
+// saveRevision 在事务中记录文档的一个版本，内容写入blob；和最新的版本内容相同时不新增，返回最新的版本
+func saveRevision(tx *gorm.DB, kbFileId string, content string, author string, message string) (*model.KBFileRevision, error) {
+	var latest model.KBFileRevision
+	err := tx.Where("kb_file_id = ?", kbFileId).Order("revision desc").First(&latest).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	hash, err := blob.Put(config.PathCfg.RevisionPath, []byte(content))
+	if err != nil {
+		return nil, err
+	}
+	if latest.ID != 0 && latest.Hash == hash {
+		return &latest, nil
+	}
+	revision := model.KBFileRevision{
+		KBFileId: kbFileId,
+		Revision: latest.Revision + 1,
+		Hash:     hash,
+		Author:   author,
+		Size:     int64(len(content)),
+		Message:  message,
+	}
+	if err = tx.Create(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// reindexKBFile 将mdString切片，和es中已有的切片按内容对比，只写入变化的切片、删除不再需要的切片；
+// 增量更新失败时重新写入文档的所有切片
+func reindexKBFile(kbFile *model.KBFile, mdString string) error {
+	indexName := "gcnote-" + kbFile.IndexId
+	chunks := splitter.SplitMarkdown(mdString, 512)
+	docList := splitter.Chunk2Doc(chunks, kbFile.KBFileId, kbFile.IndexId)
+	indexed, err := search_engine.ListChunks(config.ElasticClient, indexName, kbFile.KBFileId)
+	if err != nil {
+		return fmt.Errorf("list chunks: %w", err)
+	}
+	diff := search_engine.DiffChunks(indexed, docList)
+	// 先算好向量再改es，失败时es里仍然是完整的旧版本
+	addedDocs := make([]*document.Document, len(diff.Added))
+	for j, i := range diff.Added {
+		addedDocs[j] = docList[i]
+	}
+	embedList, err := embeds.RandEmbedding(addedDocs) // fixme 之后换成正常的Embedding服务
+	if err != nil {
+		return fmt.Errorf("embed chunks: %w", err)
+	}
+	if err = search_engine.ApplyChunkDiff(config.ElasticClient, indexName, docList, embedList, diff); err != nil {
+		zap.S().Warnf("Apply chunk diff of %s error, rewrite all chunks: %v", kbFile.KBFileId, err)
+		if embedList, err = embeds.RandEmbedding(docList); err != nil {
+			return fmt.Errorf("embed chunks: %w", err)
+		}
+		return search_engine.ReplaceChunks(config.ElasticClient, indexName, kbFile.KBFileId, docList, embedList)
+	}
+	zap.S().Infof("Reindex file %s: %d chunks added, %d moved, %d deleted, %d kept", kbFile.KBFileId,
+		len(diff.Added), len(diff.Moved), len(diff.Deleted), len(docList)-len(diff.Added))
+	return nil
+}
+
+// saveKBFileContent 保存文档的新内容（图片已经是本地路径）：更新es切片，记录版本，替换md文件，更新文档记录。
+// version不为空时，文档当前的版本必须和它相同，否则返回errStaleVersion（新内容和当前内容相同时除外）。
+// 任何一步失败都会撤销之前的步骤，原来的md文件保持不变
+func saveKBFileContent(kbFile *model.KBFile, mdString string, version string, author string,
+	message string) (*model.KBFileRevision, error) {
+	kbDirPath := filepath.Join(config.PathCfg.KnowledgeBasePath, kbFile.IndexId, kbFile.KBFileId)
+	kbFilePath := filepath.Join(kbDirPath, kbFile.KBFileName+".md")
+
+	sg := saga.New("save kb file " + kbFile.KBFileId)
+	defer sg.Rollback()
+	tx, err := sg.Begin(config.DB)
+	if err != nil {
+		return nil, err
+	}
+	// 锁住文档记录，同一个文档的保存依次执行，锁住之后读到的md文件就是最新的内容
+	var oldContent []byte
+	oldExists := false
+	err = sg.Do("lock kb file", func() error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("kb_file_id = ?", kbFile.KBFileId).
+			First(&model.KBFile{}).Error
+		if err != nil {
+			return err
+		}
+		oldContent, err = os.ReadFile(kbFilePath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		oldExists = err == nil
+		if version != "" && version != contentVersion(oldContent) && mdString != string(oldContent) {
+			return errStaleVersion
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = sg.Do("reindex chunks", func() error {
+		return reindexKBFile(kbFile, mdString)
+	}, func() error {
+		return reindexKBFile(kbFile, string(oldContent))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var revision *model.KBFileRevision
+	err = sg.Do("save revision", func() error {
+		var count int64
+		if err := tx.Model(&model.KBFileRevision{}).Where("kb_file_id = ?", kbFile.KBFileId).Count(&count).Error; err != nil {
+			return err
+		}
+		// 版本功能之前创建的文档，先保留原来的内容
+		if count == 0 && oldExists {
+			if _, err := saveRevision(tx, kbFile.KBFileId, string(oldContent), kbFile.UserId, baselineRevisionMessage); err != nil {
+				return err
+			}
+		}
+		var err error
+		revision, err = saveRevision(tx, kbFile.KBFileId, mdString, author, message)
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sg.Do("write md", func() error {
+		if err := os.MkdirAll(kbDirPath, os.ModePerm); err != nil {
+			return err
+		}
+		return wrench.WriteFileAtomic(kbFilePath, []byte(mdString), 0644)
+	}, func() error {
+		if !oldExists {
+			return os.Remove(kbFilePath)
+		}
+		return wrench.WriteFileAtomic(kbFilePath, oldContent, 0644)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 更新 KBFile 的修改时间和内容的hash
+	err = sg.Do("update kb file", func() error {
+		return tx.Model(kbFile).Updates(map[string]interface{}{
+			"UpdatedAt":    time.Now(),
+			"content_hash": wrench.StringSHA256(mdString),
+		}).Error
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = sg.Commit(tx); err != nil {
+		return nil, err
+	}
+	sg.Complete()
+	return revision, nil
+}
+
+// conflictResponse 保存的版本过期时，读取当前内容，和提交的内容按提交时基于的版本三方合并；
+// 返回的内容和读取文档时一样，图片是图片服务的地址
+func conflictResponse(kbFile *model.KBFile, version string, mdString string) (*dto.KBFileConflictResponse, error) {
+	current, err := os.ReadFile(filepath.Join(config.PathCfg.KnowledgeBasePath, kbFile.IndexId, kbFile.KBFileId,
+		kbFile.KBFileName+".md"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	toRead := func(content string) string {
+		return splitter.ChunkRead(splitter.SplitMarkdownEasy(content), config.PathCfg.ImageServerURL,
+			kbFile.IndexId, kbFile.KBFileId)
+	}
+	resp := &dto.KBFileConflictResponse{Version: contentVersion(current), Content: toRead(string(current))}
+	// 保存过的内容都有版本记录，按hash可以取到提交时基于的内容
+	base, err := blob.Get(config.PathCfg.RevisionPath, version)
+	if err != nil {
+		zap.S().Infof("Base version %s of %s not found, skip merge: %v", version, kbFile.KBFileId, err)
+		return resp, nil
+	}
+	merged, conflicts := differ.Merge3(string(base), mdString, string(current))
+	resp.Merged, resp.Conflicts = toRead(merged), conflicts
+	return resp, nil
+}
+
+// readRevision 读取文档的一个版本和它的内容，版本不存在时返回gorm.ErrRecordNotFound
+func readRevision(kbFileId string, number int) (*model.KBFileRevision, []byte, error) {
+	var revision model.KBFileRevision
+	err := config.DB.Where("kb_file_id = ? AND revision = ?", kbFileId, number).First(&revision).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := blob.Get(config.PathCfg.RevisionPath, revision.Hash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read revision %d of %s: %w", number, kbFileId, err)
+	}
+	return &revision, content, nil
+}
+
+// revisionInfo 版本记录转为返回给前端的信息
+func revisionInfo(revision *model.KBFileRevision) dto.KBFileRevisionInfo {
+	return dto.KBFileRevisionInfo{
+		Revision:  revision.Revision,
+		Hash:      revision.Hash,
+		Author:    revision.Author,
+		Size:      revision.Size,
+		Message:   revision.Message,
+		CreatedAt: revision.CreatedAt,
+	}
+}
@@ -9,12 +9,12 @@ package kb_apis
 import (
 	"bytes"
 	"errors"
-	"gcnote/server/ability/embeds"
-	"gcnote/server/ability/search_engine"
+	"fmt"
 	"gcnote/server/ability/splitter"
 	"gcnote/server/cache"
 	"gcnote/server/config"
 	"gcnote/server/dto"
+	"gcnote/server/router/wrench"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
@@ -22,14 +22,21 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
-	"time"
 )
 
 func UpdateKBFile(ctx *gin.Context) {
 	indexId := ctx.PostForm("index_id")
 	kbFileId := ctx.PostForm("kb_file_id")
+	message := ctx.PostForm("message") // 可选，版本的说明
+	// 读取文档时返回的版本(ETag)，通过If-Match或表单的version传入，必须和当前内容一致才能保存
+	version := parseVersion(ctx.GetHeader("If-Match"))
+	if version == "" {
+		version = parseVersion(ctx.PostForm("version"))
+	}
+	if version == "" {
+		ctx.JSON(http.StatusPreconditionRequired, dto.Fail(dto.KBFileVersionRequiredErrCode))
+		return
+	}
 	file, err := ctx.FormFile("file")
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
@@ -68,7 +75,17 @@ func UpdateKBFile(ctx *gin.Context) {
 		return
 	}
 
-	if index == nil || index.IndexId == "" || index.UserId != currentUserId {
+	if index == nil || index.IndexId == "" {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+	canAccess, err := wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
 		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
 		return
 	}
@@ -89,23 +106,11 @@ func UpdateKBFile(ctx *gin.Context) {
 		return
 	}
 
-	if kbFile == nil || kbFile.KBFileId == "" || kbFile.IndexId != indexId || kbFile.UserId != currentUserId {
+	if kbFile == nil || kbFile.KBFileId == "" || kbFile.IndexId != indexId {
 		ctx.JSON(http.StatusNotFound, dto.Fail(dto.KBFileNotExistErrCode))
 		return
 	}
 
-	// 首先清空es相应的文件
-	err = search_engine.DeleteByTerm(config.ElasticClient, "gcnote-"+kbFile.IndexId, "kb_file_id", kbFile.KBFileId)
-	if err != nil {
-		zap.S().Errorf("Failed to delete the document in elasticsearch index, err: %v", err)
-		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
-		return
-	}
-	// 然后是替换文件
-	kbDirPath := filepath.Join(config.PathCfg.KnowledgeBasePath, kbFile.IndexId,
-		kbFile.KBFileId)
-	kbFilePath := filepath.Join(kbDirPath, kbFile.KBFileName+".md")
-
 	// 先切片修改一下文件
 	// 读取file为字符串
 	src, err := file.Open()
@@ -125,6 +130,7 @@ func UpdateKBFile(ctx *gin.Context) {
 	if err != nil {
 		zap.S().Errorf("Failed to read file: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
 	}
 	content := buffer.String()
 
@@ -132,43 +138,28 @@ func UpdateKBFile(ctx *gin.Context) {
 	chunks := splitter.SplitMarkdownEasy(content)
 
 	mdString, err := splitter.ChunkReadReverse(chunks, config.PathCfg.ImageServerURL, indexId, kbFileId)
-
-	// 将mdString切片，并提交到es中
-	chunks = splitter.SplitMarkdown(mdString, 512)
-	docList := splitter.Chunk2Doc(chunks, kbFile.KBFileId, kbFile.IndexId)
-	embedList, err := embeds.RandEmbedding(docList) // fixme 之后换成正常的Embedding服务
-	err = search_engine.AddDocuments(config.ElasticClient, "gcnote-"+kbFile.IndexId, docList, embedList)
-	if err != nil {
-		zap.S().Errorf("Failed to add document into index, err: %v", err)
-		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
-		return
-	}
-
-	// 删除原始文件
-	err = os.Remove(kbFilePath)
 	if err != nil {
-		zap.S().Errorf("Failed to delete the file, err: %v", err)
+		zap.S().Errorf("Failed to restore image paths, err: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
 
-	// 将文件保存到这里(kbFilePath)
-	err = os.MkdirAll(kbDirPath, os.ModePerm)
-	if err != nil {
-		zap.S().Errorf("Failed to create kb dir, err: %v", err)
-		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+	// 保存新内容，同时记录一个版本
+	revision, err := saveKBFileContent(kbFile, mdString, version, currentUserId, message)
+	if errors.Is(err, errStaleVersion) {
+		// 期间有其他保存，返回当前内容和合并的结果，由用户确认后重新保存
+		resp, err := conflictResponse(kbFile, version, mdString)
+		if err != nil {
+			zap.S().Errorf("Failed to build conflict response for %s, err: %v", kbFile.KBFileId, err)
+			ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+			return
+		}
+		ctx.Header("ETag", fmt.Sprintf("%q", resp.Version))
+		ctx.JSON(http.StatusConflict, dto.FailWithData(dto.KBFileVersionConflictErrCode, resp))
 		return
 	}
-	err = os.WriteFile(kbFilePath, []byte(mdString), 0644)
 	if err != nil {
-		zap.S().Errorf("Failed to write file, err: %v", err)
-		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
-	}
-
-	// 更新 KBFile 的修改时间
-	if err := config.DB.Model(&kbFile).Updates(map[string]interface{}{
-		"UpdatedAt": time.Now(),
-	}).Error; err != nil {
+		zap.S().Errorf("Failed to save file %s, err: %v", kbFile.KBFileId, err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
@@ -185,10 +176,12 @@ func UpdateKBFile(ctx *gin.Context) {
 		zap.S().Errorf("Failed to refresh index kb list cache: %v", err)
 	}
 	// 3. 刷新用户的最近访问kb列表缓存
-	_, err = cache.RefreshRecentKBList(ctx, currentUserId)
+	_, err = cache.RefreshRecentKBList(ctx, index.UserId)
 	if err != nil {
 		zap.S().Errorf("Failed to refresh user recent kb list cache: %v", err)
 	}
 
-	ctx.JSON(http.StatusOK, dto.Success())
+	// 新内容的版本，之后的保存带上它
+	ctx.Header("ETag", fmt.Sprintf("%q", revision.Hash))
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(revisionInfo(revision)))
 }
@@ -0,0 +1,80 @@
+// -------------------------------------------------
+// Package recycle_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package recycle_apis
+
+import (
+	"context"
+	"gcnote/server/cache"
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"gcnote/server/model"
+	"gcnote/server/router/apis/api_fixture"
+	"gorm.io/gorm"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// setupRecycle 回收站中有知识库i1的文档k1（a.md），都已经在缓存中
+func setupRecycle(t *testing.T) (*api_fixture.Env, string) {
+	env := api_fixture.Setup(t)
+	ctx := context.Background()
+	if err := cache.SetIndexInfo(ctx, model.Index{IndexId: "i1", IndexName: "笔记", UserId: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	recycle := model.Recycle{Model: gorm.Model{ID: 1}, KBFileId: "k1", KBFileName: "a", SourceIndexId: "i1", UserId: "u1"}
+	if err := cache.SetRecycleInfo(ctx, recycle); err != nil {
+		t.Fatal(err)
+	}
+	mdPath := filepath.Join(config.PathCfg.RecycleBinPath, "i1", "k1", "a.md")
+	api_fixture.WriteFile(t, mdPath, "# a\n\n第一段")
+	return env, mdPath
+}
+
+func TestRestoreRecycleFileCompensation(t *testing.T) {
+	env, mdPath := setupRecycle(t)
+	env.DB.FailOn("COMMIT")
+
+	w := api_fixture.Call(t, RestoreRecycleFile, "u1", dto.RecycleRequest{KBFileId: "k1", IndexId: "i1"})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	// 提交失败时删除写入的es切片和知识库中的副本，移回回收站目录
+	requests := env.ES.Requests()
+	if !slices.Contains(requests, "POST /gcnote-i1/_doc") || !slices.Contains(requests, "POST /gcnote-i1/_delete_by_query") {
+		t.Errorf("es chunks should be written and deleted, requests %v", requests)
+	}
+	if _, err := os.Stat(mdPath); err != nil {
+		t.Errorf("recycle dir should be restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.PathCfg.KnowledgeBasePath, "i1", "k1")); !os.IsNotExist(err) {
+		t.Errorf("kb copy should be removed: %v", err)
+	}
+}
+
+func TestDeleteRecycleFileCompensation(t *testing.T) {
+	env, mdPath := setupRecycle(t)
+	env.DB.FailOn("COMMIT")
+
+	w := api_fixture.Call(t, DeleteRecycleFile, "u1", dto.RecycleRequest{KBFileId: "k1", IndexId: "i1"})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	// 提交失败时移回回收站目录
+	if _, err := os.Stat(mdPath); err != nil {
+		t.Errorf("recycle dir should be restored: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(config.PathCfg.RecycleBinPath, "i1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("moved aside dir should not be left, got %v", entries)
+	}
+}
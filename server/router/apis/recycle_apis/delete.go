@@ -12,7 +12,7 @@ import (
 	"gcnote/server/config"
 	"gcnote/server/dto"
 	"gcnote/server/model"
-	"gcnote/server/router/wrench"
+	"gcnote/server/saga"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
@@ -84,12 +84,22 @@ func DeleteRecycleFile(ctx *gin.Context) {
 		return
 	}
 
+	// 先删除数据库记录，文件移开后提交，全部成功后才真正删除文件
+	sg := saga.New("delete recycle file " + req.KBFileId)
+	defer sg.Rollback()
+	tx, err := sg.Begin(config.DB)
+	if err != nil {
+		zap.S().Errorf("Failed to begin transaction: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
 	// 开始删除操作
 	var recycleFile model.Recycle
-	tx := config.DB.Where("kb_file_id = ?", req.KBFileId).Delete(&recycleFile)
-
-	if tx.Error != nil {
-		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+	err = sg.Do("delete recycle record", func() error {
+		return tx.Where("kb_file_id = ?", req.KBFileId).Delete(&recycleFile).Error
+	}, nil)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			zap.S().Errorf(
 				"User with user_id %v source_index_name %v not found",
 				currentUserId, req.KBFileId)
@@ -106,14 +116,30 @@ func DeleteRecycleFile(ctx *gin.Context) {
 			return
 		}
 	}
+	// 删除文件的元数据
+	err = tx.Where("kb_file_id = ?", req.KBFileId).Delete(&model.KBFileMeta{}).Error
+	if err != nil {
+		zap.S().Errorf("Failed to delete meta for file %s: %v", req.KBFileId, err)
+	}
+	// 删除文件的版本记录，版本内容按hash共享，不删除
+	err = tx.Where("kb_file_id = ?", req.KBFileId).Delete(&model.KBFileRevision{}).Error
+	if err != nil {
+		zap.S().Errorf("Failed to delete revisions for file %s: %v", req.KBFileId, err)
+	}
 	// 删除文件
 	path := config.PathCfg.RecycleBinPath
-	err = wrench.RemoveContents(filepath.Join(path, req.IndexId, req.KBFileId))
+	err = sg.RemoveDir(filepath.Join(path, req.IndexId, req.KBFileId))
 	if err != nil {
 		zap.S().Errorf("Failed to remove file, err: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
+	if err = sg.Commit(tx); err != nil {
+		zap.S().Errorf("Failed to commit transaction: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	sg.Complete()
 
 	// 更新缓存
 	// 1. 删除回收站文件信息缓存
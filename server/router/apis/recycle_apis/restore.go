@@ -16,6 +16,7 @@ import (
 	"gcnote/server/dto"
 	"gcnote/server/model"
 	"gcnote/server/router/wrench"
+	"gcnote/server/saga"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
@@ -79,7 +80,7 @@ func RestoreRecycleFile(ctx *gin.Context) {
 		return
 	}
 
-	if recycle == nil || recycle.KBFileId == "" || recycle.UserId != currentUserId {
+	if recycle == nil || recycle.KBFileId == "" {
 		ctx.JSON(http.StatusNotFound, dto.Fail(dto.RecycleFileNotExistErrCode))
 		return
 	}
@@ -100,10 +101,48 @@ func RestoreRecycleFile(ctx *gin.Context) {
 		return
 	}
 
-	if index == nil || index.IndexId == "" || index.UserId != currentUserId {
+	if index == nil || index.IndexId == "" {
 		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
 		return
 	}
+	canAccess, err := wrench.CanAccessIndex(index, currentUserId)
+	if err != nil {
+		zap.S().Errorf("Failed to check access to index %v: %v", index.IndexId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	if !canAccess {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.IndexNotExistErrCode))
+		return
+	}
+
+	// 数据库、文件系统、es依次恢复，失败时撤销已经完成的步骤
+	sg := saga.New("restore recycle file " + recycle.KBFileId)
+	defer sg.Rollback()
+	tx, err := sg.Begin(config.DB)
+	if err != nil {
+		zap.S().Errorf("Failed to begin transaction: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	// 在知识库中创建文件记录，删除回收站中的记录，由事务回滚撤销
+	newFile := model.KBFile{
+		UserId:     recycle.UserId,
+		IndexId:    recycle.SourceIndexId,
+		KBFileId:   recycle.KBFileId,
+		KBFileName: recycle.KBFileName,
+	}
+	err = sg.Do("move recycle record to kb file", func() error {
+		if err := tx.Create(&newFile).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&recycle).Error
+	}, nil)
+	if err != nil {
+		zap.S().Errorf("Failed to restore KBFile record: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
 
 	// 文件从回收站恢复到知识库目录
 	recycleFilePath := filepath.Join(config.PathCfg.RecycleBinPath, recycle.SourceIndexId, recycle.KBFileId)
@@ -120,41 +159,25 @@ func RestoreRecycleFile(ctx *gin.Context) {
 	src := recycleFilePath
 	dst := knowledgeBaseFilePath
 	// 复制文件到要求的路径
-	err = wrench.CopyDir(src, dst)
+	err = sg.Do("copy recycle file to kb", func() error {
+		return wrench.CopyDir(src, dst)
+	}, func() error {
+		return os.RemoveAll(dst)
+	})
 	if err != nil {
 		zap.S().Errorf("Failed to create recycle dir, err: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
 
-	// 删除原来的文件夹
-	err = wrench.RemoveContents(src)
+	// 删除原来的文件夹，全部成功后才真正删除
+	err = sg.RemoveDir(src)
 	if err != nil {
 		zap.S().Errorf("Delete kb id :%v err:%v", req.KBFileId, err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
 
-	// 在知识库中创建文件记录
-	newFile := model.KBFile{
-		UserId:     currentUserId,
-		IndexId:    recycle.SourceIndexId,
-		KBFileId:   recycle.KBFileId,
-		KBFileName: recycle.KBFileName,
-	}
-	if err := config.DB.Create(&newFile).Error; err != nil {
-		zap.S().Errorf("Failed to create KBFile record: %v", err)
-		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
-		return
-	}
-
-	// 删除回收站中的记录
-	if err := config.DB.Delete(&recycle).Error; err != nil {
-		zap.S().Errorf("Failed to delete recycle record: %v", err)
-		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
-		return
-	}
-
 	// 恢复到ElasticSearch中
 	filePath := filepath.Join(dst, recycle.KBFileName+".md")
 	// 读取filePath为文件字符串
@@ -168,13 +191,31 @@ func RestoreRecycleFile(ctx *gin.Context) {
 	chunks := splitter.SplitMarkdown(mdString, 512)
 	docList := splitter.Chunk2Doc(chunks, recycle.KBFileId, recycle.SourceIndexId)
 	embedList, err := embeds.RandEmbedding(docList) // fixme 之后换成正常的Embedding服务
-	err = search_engine.AddDocuments(config.ElasticClient, "gcnote-"+recycle.SourceIndexId, docList, embedList)
+	if err != nil {
+		zap.S().Errorf("Failed to embed documents, err: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	indexName := "gcnote-" + recycle.SourceIndexId
+	err = sg.Do("add es chunks", func() error {
+		return search_engine.AddDocuments(config.ElasticClient, indexName, docList, embedList)
+	}, func() error {
+		return search_engine.DeleteByTerm(config.ElasticClient, indexName, "kb_file_id", recycle.KBFileId)
+	})
 	if err != nil {
 		zap.S().Errorf("Failed to add document into index, err: %v", err)
 		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
 		return
 	}
 
+	// 提交事务
+	if err = sg.Commit(tx); err != nil {
+		zap.S().Errorf("Failed to commit transaction: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	sg.Complete()
+
 	// 更新缓存
 	// 1. 删除回收站文件信息缓存
 	err = cache.DelRecycleInfo(ctx, req.KBFileId)
@@ -182,7 +223,7 @@ func RestoreRecycleFile(ctx *gin.Context) {
 		zap.S().Errorf("Failed to delete recycle cache: %v", err)
 	}
 	// 2. 刷新用户的回收站列表缓存
-	_, err = cache.RefreshUserRecycleList(ctx, currentUserId)
+	_, err = cache.RefreshUserRecycleList(ctx, recycle.UserId)
 	if err != nil {
 		zap.S().Errorf("Failed to refresh user recycle list cache: %v", err)
 	}
@@ -197,7 +238,7 @@ func RestoreRecycleFile(ctx *gin.Context) {
 		zap.S().Errorf("Failed to refresh index kb list cache: %v", err)
 	}
 	// 5. 刷新用户的最近访问kb列表缓存
-	_, err = cache.RefreshRecentKBList(ctx, currentUserId)
+	_, err = cache.RefreshRecentKBList(ctx, recycle.UserId)
 	if err != nil {
 		zap.S().Errorf("Failed to refresh user recent kb list cache: %v", err)
 	}
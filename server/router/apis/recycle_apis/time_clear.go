@@ -52,6 +52,17 @@ func CleanupOldRecycleFiles(ctx *gin.Context) {
 			return
 		}
 
+		// 删除文件的元数据
+		err = config.DB.Where("kb_file_id = ?", file.KBFileId).Delete(&model.KBFileMeta{}).Error
+		if err != nil {
+			zap.S().Errorf("Failed to delete meta for file %s: %v", file.KBFileId, err)
+		}
+		// 删除文件的版本记录
+		err = config.DB.Where("kb_file_id = ?", file.KBFileId).Delete(&model.KBFileRevision{}).Error
+		if err != nil {
+			zap.S().Errorf("Failed to delete revisions for file %s: %v", file.KBFileId, err)
+		}
+
 		// 删除每个文件的缓存
 		err = cache.DelRecycleInfo(ctx, file.KBFileId)
 		if err != nil {
@@ -0,0 +1,57 @@
+// -------------------------------------------------
+// Package task_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package task_apis
+
+import (
+	"gcnote/server/cache"
+	"gcnote/server/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// AckTasks
+// @Summary      标记导入结果为已读
+// @Description  标记当前用户的导入结果为已读，不存在、已过期或不属于当前用户的id忽略
+// @ID           ack-tasks
+// @Tags         task
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.TaskAckRequest  true  "导入结果id列表，最多100个"
+// @Success      200  {object}  dto.BaseResponse{Data=dto.TaskCountResponse}  "成功，返回实际标记的条数"
+// @Failure      400  {object}  dto.BaseResponse                        "参数错误(code:40000)"
+// @Failure      401  {object}  dto.BaseResponse                        "Token错误(code:40101)"
+// @Failure      500  {object}  dto.BaseResponse                        "服务器内部错误(code:50000)"
+// @Router       /task/ack [post]
+func AckTasks(ctx *gin.Context) {
+	var req dto.TaskAckRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	count, err := cache.AckTasks(ctx, currentUserId, req.TaskIds)
+	if err != nil {
+		zap.S().Errorf("Failed to ack tasks: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.TaskCountResponse{Count: count}))
+}
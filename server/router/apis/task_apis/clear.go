@@ -0,0 +1,60 @@
+// -------------------------------------------------
+// Package task_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package task_apis
+
+import (
+	"gcnote/server/cache"
+	"gcnote/server/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// ClearTasks
+// @Summary      清空导入结果
+// @Description  删除当前用户的导入结果，acked_only为true时只删除已读的；不影响已经导入的文档
+// @ID           clear-tasks
+// @Tags         task
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.TaskClearRequest  false  "清空选项"
+// @Success      200  {object}  dto.BaseResponse{Data=dto.TaskCountResponse}  "成功，返回删除的条数"
+// @Failure      400  {object}  dto.BaseResponse                        "参数错误(code:40000)"
+// @Failure      401  {object}  dto.BaseResponse                        "Token错误(code:40101)"
+// @Failure      500  {object}  dto.BaseResponse                        "服务器内部错误(code:50000)"
+// @Router       /task/clear [post]
+func ClearTasks(ctx *gin.Context) {
+	var req dto.TaskClearRequest
+	// 请求体可以为空，表示全部删除
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+			return
+		}
+	}
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	count, err := cache.ClearTasks(ctx, currentUserId, req.AckedOnly)
+	if err != nil {
+		zap.S().Errorf("Failed to clear user tasks: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.TaskCountResponse{Count: count}))
+}
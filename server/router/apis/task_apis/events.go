@@ -0,0 +1,123 @@
+// -------------------------------------------------
+// Package task_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package task_apis
+
+import (
+	"encoding/json"
+	"gcnote/server/dto"
+	"gcnote/server/job_queue"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time"
+)
+
+// 没有事件时定期发送ping，避免代理断开空闲连接
+const eventsPingInterval = 15 * time.Second
+
+// JobEvents
+// @Summary      导入任务进度推送
+// @Description  Server-Sent Events，推送当前用户导入任务的状态变化和阶段内进度(pdf转换的页数、计算向量和写入es的切片数)。
+// @Description  连接时先推送所有未结束任务的当前状态。事件名为job，数据为job_queue.Event的json；ping事件用于保活。
+// @Description  浏览器的EventSource不能设置请求头，token可以放在query参数中
+// @ID           job-events
+// @Tags         task
+// @Produce      text/event-stream
+// @Param        token   query     string  false  "登录token，没有token请求头时使用"
+// @Param        job_id  query     string  false  "只推送这个任务的事件"
+// @Success      200  {object}  job_queue.Event                        "事件流"
+// @Failure      400  {object}  dto.BaseResponse                        "参数错误(code:40000)"
+// @Failure      401  {object}  dto.BaseResponse                        "Token错误(code:40101)"
+// @Failure      500  {object}  dto.BaseResponse                        "服务器内部错误(code:50000)"
+// @Router       /task/events [get]
+func JobEvents(ctx *gin.Context) {
+	jobId := ctx.Query("job_id")
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	// 先订阅再查询快照，两者之间发生的变化不会丢失
+	reqCtx := ctx.Request.Context()
+	sub := job_queue.SubscribeEvents(reqCtx, currentUserId)
+	defer func() {
+		if err := sub.Close(); err != nil {
+			zap.S().Errorf("Close job events subscription error: %v", err)
+		}
+	}()
+	if _, err := sub.Receive(reqCtx); err != nil {
+		zap.S().Errorf("Subscribe job events error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	jobs, err := job_queue.ActiveJobs(currentUserId)
+	if err != nil {
+		zap.S().Errorf("Get active jobs error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+
+	// 长连接不受服务器WriteTimeout的限制
+	if err = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		zap.S().Errorf("Clear write deadline error: %v", err)
+	}
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no") // nginx不缓冲
+	for i := range jobs {
+		if jobId != "" && jobs[i].JobId != jobId {
+			continue
+		}
+		data, err := json.Marshal(job_queue.NewEvent(job_queue.EventState, &jobs[i]))
+		if err != nil {
+			continue
+		}
+		ctx.SSEvent("job", string(data))
+	}
+	ctx.Writer.Flush()
+
+	messages := sub.Channel()
+	ticker := time.NewTicker(eventsPingInterval)
+	defer ticker.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-reqCtx.Done():
+			return false
+		case msg, ok := <-messages:
+			if !ok {
+				return false
+			}
+			if jobId != "" && !matchJob(msg.Payload, jobId) {
+				return true
+			}
+			ctx.SSEvent("job", msg.Payload)
+		case <-ticker.C:
+			ctx.SSEvent("ping", time.Now().UnixMilli())
+		}
+		return true
+	})
+}
+
+// matchJob 事件是否属于jobId
+func matchJob(payload string, jobId string) bool {
+	var event job_queue.Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return false
+	}
+	return event.JobId == jobId
+}
@@ -0,0 +1,63 @@
+// -------------------------------------------------
+// Package task_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package task_apis
+
+import (
+	"errors"
+	"gcnote/server/cache"
+	"gcnote/server/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// GetTask
+// @Summary      获取一条导入结果
+// @Description  根据task_id获取当前用户的一条导入结果，用于轮询
+// @ID           get-task
+// @Tags         task
+// @Accept       json
+// @Produce      json
+// @Param        task_id  query     string  true  "导入结果id"
+// @Success      200  {object}  dto.BaseResponse{Data=cache.Task}  "成功，返回导入结果"
+// @Failure      400  {object}  dto.BaseResponse                        "参数错误(code:40000)"
+// @Failure      401  {object}  dto.BaseResponse                        "Token错误(code:40101)"
+// @Failure      404  {object}  dto.BaseResponse                        "导入结果不存在或已过期(code:40001)"
+// @Failure      500  {object}  dto.BaseResponse                        "服务器内部错误(code:50000)"
+// @Router       /task/get [get]
+func GetTask(ctx *gin.Context) {
+	var req dto.TaskGetRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	task, err := cache.GetTask(ctx, currentUserId, req.TaskId)
+	if errors.Is(err, cache.ErrTaskNotFound) {
+		ctx.JSON(http.StatusNotFound, dto.Fail(dto.RecordNotFoundErrCode))
+		return
+	}
+	if err != nil {
+		zap.S().Errorf("Failed to get task %s: %v", req.TaskId, err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(task))
+}
@@ -0,0 +1,69 @@
+// -------------------------------------------------
+// Package task_apis
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package task_apis
+
+import (
+	"gcnote/server/cache"
+	"gcnote/server/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// ListTasks
+// @Summary      获取导入结果列表
+// @Description  分页获取当前用户的文件导入结果，新的在前；导入成功的带文档id。结果保留的时间由配置job.task_ttl决定
+// @ID           list-tasks
+// @Tags         task
+// @Accept       json
+// @Produce      json
+// @Param        page       query     int  false  "页码，从1开始，默认1"
+// @Param        page_size  query     int  false  "每页条数，默认20，最大100"
+// @Success      200  {object}  dto.BaseResponse{Data=dto.TaskListResponse}  "成功，返回导入结果列表"
+// @Failure      400  {object}  dto.BaseResponse                        "参数错误(code:40000)"
+// @Failure      401  {object}  dto.BaseResponse                        "Token错误(code:40101)"
+// @Failure      500  {object}  dto.BaseResponse                        "服务器内部错误(code:50000)"
+// @Router       /task/list [get]
+func ListTasks(ctx *gin.Context) {
+	var req dto.TaskListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = dto.DefaultTaskPageSize
+	}
+	claims, exists := ctx.Get("claims")
+	if !exists {
+		zap.S().Infof("Unable to get the claims")
+		ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+		return
+	}
+	currentUser := claims.(jwt.MapClaims)
+	currentUserId := currentUser["sub"].(string)
+	if currentUserId == "" {
+		ctx.JSON(http.StatusBadRequest, dto.Fail(dto.ParamsErrCode))
+		return
+	}
+
+	tasks, total, err := cache.ListTasks(ctx, currentUserId, req.Page, req.PageSize)
+	if err != nil {
+		zap.S().Errorf("Failed to list user tasks: %v", err)
+		ctx.JSON(http.StatusInternalServerError, dto.Fail(dto.InternalErrCode))
+		return
+	}
+	ctx.JSON(http.StatusOK, dto.SuccessWithData(dto.TaskListResponse{
+		Tasks:    tasks,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}))
+}
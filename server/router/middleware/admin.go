@@ -0,0 +1,37 @@
+// -------------------------------------------------
+// Package middleware
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package middleware
+
+import (
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"slices"
+)
+
+// RequireAdmin 只允许配置文件admin_users中的用户访问，需要放在VerifyJWT之后
+func RequireAdmin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, exists := ctx.Get("claims")
+		if !exists {
+			ctx.JSON(http.StatusUnauthorized, dto.Fail(dto.UserTokenErrCode))
+			ctx.Abort()
+			return
+		}
+		userId, _ := claims.(jwt.MapClaims)["sub"].(string)
+		if userId == "" || !slices.Contains(config.ServerCfg.AdminUsers, userId) {
+			zap.S().Infof("User %v is not admin", userId)
+			ctx.JSON(http.StatusForbidden, dto.Fail(dto.UserForbiddenErrCode))
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,36 @@
+// -------------------------------------------------
+// Package middleware
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package middleware
+
+import (
+	"gcnote/server/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.ServerCfg.AdminUsers = []string{"admin"}
+	defer func() { config.ServerCfg.AdminUsers = nil }()
+
+	for userId, want := range map[string]int{"admin": http.StatusOK, "u1": http.StatusForbidden, "": http.StatusForbidden} {
+		route := gin.New()
+		route.GET("/admin", func(ctx *gin.Context) {
+			ctx.Set("claims", jwt.MapClaims{"sub": userId})
+		}, RequireAdmin(), func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		route.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		if w.Code != want {
+			t.Errorf("user %q: got %d, want %d", userId, w.Code, want)
+		}
+	}
+}
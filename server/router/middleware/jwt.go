@@ -157,3 +157,15 @@ func VerifyJWT() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// TokenFromQuery 没有token请求头时使用query参数中的token，用于浏览器EventSource这类不能设置请求头的连接，需要放在VerifyJWT之前
+func TokenFromQuery() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetHeader("token") == "" {
+			if token := ctx.Query("token"); token != "" {
+				ctx.Request.Header.Set("token", token)
+			}
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,31 @@
+// -------------------------------------------------
+// Package middleware
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package middleware
+
+import (
+	"gcnote/server/config"
+	"gcnote/server/dto"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"slices"
+)
+
+// CheckOrigin 只允许配置文件allow_origins中的前端发起请求，用于websocket这类不受CORS限制的连接。
+// 没有Origin请求头的请求不是浏览器发起的，不检查
+func CheckOrigin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader("Origin")
+		if origin != "" && !slices.Contains(config.ServerCfg.AllowOrigins(), origin) {
+			zap.S().Infof("Origin %v is not allowed", origin)
+			ctx.JSON(http.StatusForbidden, dto.Fail(dto.UserForbiddenErrCode))
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,42 @@
+// -------------------------------------------------
+// Package middleware
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package middleware
+
+import (
+	"gcnote/server/config"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.ServerCfg.Origins = []string{"https://note.example.com"}
+	defer func() { config.ServerCfg.Origins = nil }()
+
+	route := gin.New()
+	route.GET("/ws", CheckOrigin(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	for origin, want := range map[string]int{
+		"https://note.example.com": http.StatusOK,
+		"":                         http.StatusOK,
+		"https://evil.example.com": http.StatusForbidden,
+		"http://localhost:8080":    http.StatusForbidden,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		route.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("origin %q: got %d, want %d", origin, w.Code, want)
+		}
+	}
+}
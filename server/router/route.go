@@ -8,9 +8,12 @@ package router
 
 import (
 	_ "gcnote/docs"
+	"gcnote/server/config"
+	"gcnote/server/router/apis/admin_apis"
 	"gcnote/server/router/apis/index_apis"
 	"gcnote/server/router/apis/kb_apis"
 	"gcnote/server/router/apis/recycle_apis"
+	"gcnote/server/router/apis/task_apis"
 	"gcnote/server/router/apis/user_apis"
 	"gcnote/server/router/apis/utils_apis"
 	"gcnote/server/router/middleware"
@@ -27,14 +30,16 @@ func InitRouter() *gin.Engine {
 	gin.SetMode(gin.DebugMode)
 	route := gin.Default()
 	httpCfg := cors.Config{
-		AllowOrigins:     []string{"http://localhost:8080", "http://localhost:8090"}, // 允许的前端地址 "ws://localhost:8086"
+		AllowOrigins:     config.ServerCfg.AllowOrigins(), // 允许的前端地址，配置文件allow_origins
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "token"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "token", "If-Match"},
+		ExposeHeaders:    []string{"Content-Length", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
 	route.Use(cors.New(httpCfg))
+	// 导入任务由任务队列的worker执行，处理函数在启动worker之前注册
+	kb_apis.RegisterJobHandlers()
 	// 类似 fastapi的CORSMiddleware
 	//route.Use(cors.Default()) // 中间件来启用CORS支持。这将允许来自任何源的GET，POST和OPTIONS请求，并允许特定的标头和方法
 	// 用户处理
@@ -52,12 +57,20 @@ func InitRouter() *gin.Engine {
 	group2.POST("/create_index", index_apis.CreateIndex)
 	group2.POST("/delete_index", index_apis.DeleteIndex)
 	group2.POST("/rename_index", index_apis.RenameIndex)
+	group2.POST("/add_member", index_apis.AddIndexMember)
+	group2.POST("/remove_member", index_apis.RemoveIndexMember)
 	group2.GET("/show_indexes", index_apis.ShowIndexes)
 	group2.POST("/retrieval", index_apis.RetrievalIndex)
 
 	// 知识库文件创建
 	group2.POST("/create_file", kb_apis.CreateKBFile)
 	group2.POST("/add_file", kb_apis.AddKBFile)
+	group2.POST("/import_url", kb_apis.ImportURL)
+	group2.POST("/bulk_import", kb_apis.BulkImport)
+	group2.POST("/import_vault", kb_apis.ImportVault)
+	group2.POST("/import_notion", kb_apis.ImportNotion)
+	group2.POST("/import_evernote", kb_apis.ImportEvernote)
+	group2.POST("/cancel_job", kb_apis.CancelImportJob)
 	group2.POST("/show_files", kb_apis.ShowIndexFiles)
 	group2.POST("/recycle_file", kb_apis.RecycleKBFile)
 	group2.POST("/rename_file", kb_apis.RenameKBFile)
@@ -65,6 +78,10 @@ func InitRouter() *gin.Engine {
 	group2.POST("/read_file", kb_apis.ReadFile)
 	group2.POST("/recent_docs", kb_apis.RecentDocs)
 	group2.POST("/update_file", kb_apis.UpdateKBFile)
+	group2.POST("/list_revisions", kb_apis.ListRevisions)
+	group2.POST("/get_revision", kb_apis.GetRevision)
+	group2.POST("/restore_revision", kb_apis.RestoreRevision)
+	group2.POST("/diff_revisions", kb_apis.DiffRevisions)
 
 	// 回收站操作
 	group3 := route.Group("recycle").Use(middleware.VerifyJWT())
@@ -74,6 +91,22 @@ func InitRouter() *gin.Engine {
 	group3.POST("/clearup", recycle_apis.CleanupOldRecycleFiles)
 	group3.POST("/restore", recycle_apis.RestoreRecycleFile)
 
+	// 导入结果
+	group4 := route.Group("task").Use(middleware.VerifyJWT())
+	group4.GET("/list", task_apis.ListTasks)
+	group4.GET("/get", task_apis.GetTask)
+	group4.POST("/ack", task_apis.AckTasks)
+	group4.POST("/clear", task_apis.ClearTasks)
+	// 进度推送使用SSE，token可以放在query参数中
+	route.GET("/task/events", middleware.TokenFromQuery(), middleware.VerifyJWT(), task_apis.JobEvents)
+	// 协同编辑的websocket，浏览器不能设置请求头，和任务进度推送一样允许query参数中的token；
+	// websocket不受CORS限制，另外检查Origin
+	route.GET("/index/collaborate", middleware.CheckOrigin(), middleware.TokenFromQuery(), middleware.VerifyJWT(), kb_apis.Collaborate)
+
+	// 管理接口
+	group5 := route.Group("admin").Use(middleware.VerifyJWT(), middleware.RequireAdmin())
+	group5.POST("/consistency_check", admin_apis.ConsistencyCheck)
+
 	route.GET("/images/:index_id/:kb_file_id/:image_name", utils_apis.GetImage)
 	route.POST("/images/upload", utils_apis.UploadImage)
 	// swagger
@@ -0,0 +1,24 @@
+// -------------------------------------------------
+// Package wrench
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package wrench
+
+import (
+	"gcnote/server/config"
+	"gcnote/server/model"
+)
+
+// CanAccessIndex 知识库的所有者和成员可以读写其中的文档
+func CanAccessIndex(index *model.Index, userId string) (bool, error) {
+	if index.UserId == userId {
+		return true, nil
+	}
+	var count int64
+	err := config.DB.Model(&model.IndexMember{}).
+		Where("index_id = ? AND user_id = ?", index.IndexId, userId).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,36 @@
+// -------------------------------------------------
+// Package wrench
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package wrench
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// FileSHA256 文件内容的SHA-256，十六进制小写
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	h := sha256.New()
+	if _, err = io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// StringSHA256 字符串的SHA-256，十六进制小写
+func StringSHA256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
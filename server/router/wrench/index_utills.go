@@ -6,7 +6,10 @@
 
 package wrench
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+)
 
 // ValidateIndexName ？好像没啥用，因为index name应该是个uuid
 func ValidateIndexName(indexName string) bool {
@@ -24,3 +27,22 @@ func ValidateKBName(indexName string) bool {
 	// 检查字符串中是否存在匹配的字符
 	return !re.MatchString(indexName)
 }
+
+// 和ValidateKBName中不允许的符号相同
+var invalidKBNameChars = regexp.MustCompile(`[?,"/\\*<>|]`)
+
+// maxKBNameLength 生成的文档名最多保留的字符数
+const maxKBNameLength = 100
+
+// SanitizeKBName 把不允许出现在文档名中的符号替换为下划线，合并空白，过长时截断，用于从文件名、网页标题等生成文档名
+func SanitizeKBName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	name = invalidKBNameChars.ReplaceAllString(name, "_")
+	if runes := []rune(name); len(runes) > maxKBNameLength {
+		name = strings.TrimSpace(string(runes[:maxKBNameLength]))
+	}
+	if name == "" {
+		name = "untitled"
+	}
+	return name
+}
@@ -8,6 +8,9 @@ package wrench
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -21,3 +24,53 @@ func TestValidString(t *testing.T) {
 	x = ValidateIndexName("知识内容")
 	fmt.Println(x)
 }
+
+func TestSHA256(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	got, err := FileSHA256(path)
+	if err != nil || got != want {
+		t.Errorf("FileSHA256 = %s, %v", got, err)
+	}
+	if got = StringSHA256("abc"); got != want {
+		t.Errorf("StringSHA256 = %s", got)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.md")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "new" {
+		t.Errorf("content = %q, %v", content, err)
+	}
+	// 不留下临时文件
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("entries = %v", entries)
+	}
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "a.md"), []byte("x"), 0644); err == nil {
+		t.Error("expected error for missing dir")
+	}
+}
+
+func TestSanitizeKBName(t *testing.T) {
+	cases := map[string]string{
+		"  a/b:c  ":              "a_b:c",
+		"标题\n  <第一章>":            "标题 _第一章_",
+		"":                       "untitled",
+		strings.Repeat("长", 120): strings.Repeat("长", 100),
+	}
+	for name, want := range cases {
+		if got := SanitizeKBName(name); got != want || !ValidateKBName(got) {
+			t.Errorf("SanitizeKBName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// -------------------------------------------------
+// Package saga
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package saga
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 步骤的状态
+const (
+	StepDone              = "done"
+	StepFailed            = "failed"
+	StepCompensated       = "compensated"
+	StepCompensateFailed  = "compensate_failed"
+	StepDeferredFailed    = "deferred_failed" // 成功后才执行的操作失败，只记录日志
+	stepDeferredScheduled = "deferred"
+)
+
+// Step 记录的一步
+type Step struct {
+	Name  string
+	State string
+	Err   error
+
+	compensate func() error
+	deferred   func() error
+}
+
+// Saga 一次跨MySQL、文件系统、es、redis的写操作。每一步成功后记录它的补偿操作，
+// 没有调用Complete就结束时（后面的步骤失败、提前返回）按相反的顺序执行补偿，撤销已经完成的步骤。
+// 用法和gorm的事务类似：
+//
+//	sg := saga.New("create index")
+//	defer sg.Rollback()
+//	if err := sg.Do("mkdir", mkdir, rmdir); err != nil { ... return }
+//	sg.Complete()
+type Saga struct {
+	name     string
+	steps    []*Step
+	finished bool
+}
+
+func New(name string) *Saga {
+	return &Saga{name: name}
+}
+
+// Do 执行一步，成功后记录补偿操作（可以为nil，比如由事务回滚撤销的数据库操作）；
+// 失败时不会马上补偿，由Rollback统一处理
+func (s *Saga) Do(name string, action func() error, compensate func() error) error {
+	step := &Step{Name: name, compensate: compensate}
+	s.steps = append(s.steps, step)
+	if err := action(); err != nil {
+		step.State, step.Err = StepFailed, err
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	step.State = StepDone
+	return nil
+}
+
+// Defer 记录一个成功之后才执行的操作（outbox），比如删除已经移开的目录；Rollback时丢弃
+func (s *Saga) Defer(name string, fn func() error) {
+	s.steps = append(s.steps, &Step{Name: name, State: stepDeferredScheduled, deferred: fn})
+}
+
+// Complete 所有步骤都已成功，执行Defer记录的操作，之后Rollback什么都不做。
+// Defer的操作失败不影响结果，只记录日志
+func (s *Saga) Complete() {
+	if s.finished {
+		return
+	}
+	s.finished = true
+	for _, step := range s.steps {
+		if step.deferred == nil {
+			continue
+		}
+		if err := step.deferred(); err != nil {
+			step.State, step.Err = StepDeferredFailed, err
+			zap.S().Errorf("Saga %s: deferred step %s failed: %v", s.name, step.Name, err)
+			continue
+		}
+		step.State = StepDone
+	}
+}
+
+// Rollback 没有Complete时按相反的顺序补偿已经完成的步骤，某一步补偿失败时继续补偿其他步骤，
+// 返回所有补偿失败的错误；补偿失败会留下孤立的数据，需要一致性检查清理
+func (s *Saga) Rollback() error {
+	if s.finished {
+		return nil
+	}
+	s.finished = true
+	var errs []error
+	for i := len(s.steps) - 1; i >= 0; i-- {
+		step := s.steps[i]
+		if step.State != StepDone || step.compensate == nil {
+			continue
+		}
+		if err := step.compensate(); err != nil {
+			step.State, step.Err = StepCompensateFailed, err
+			errs = append(errs, fmt.Errorf("compensate %s: %w", step.Name, err))
+			continue
+		}
+		step.State = StepCompensated
+	}
+	if len(errs) > 0 {
+		zap.S().Errorf("Saga %s rolled back with errors: %v, steps: %s", s.name, errors.Join(errs...), s)
+	} else if len(s.steps) > 0 {
+		zap.S().Infof("Saga %s rolled back, steps: %s", s.name, s)
+	}
+	return errors.Join(errs...)
+}
+
+// Steps 已记录的步骤，用于日志和测试
+func (s *Saga) Steps() []Step {
+	steps := make([]Step, len(s.steps))
+	for i, step := range s.steps {
+		steps[i] = *step
+	}
+	return steps
+}
+
+func (s *Saga) String() string {
+	parts := make([]string, len(s.steps))
+	for i, step := range s.steps {
+		parts[i] = step.Name + ":" + step.State
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// Begin 开始数据库事务，补偿为回滚；事务需要用Commit提交，放在最后一步
+func (s *Saga) Begin(db *gorm.DB) (*gorm.DB, error) {
+	var tx *gorm.DB
+	err := s.Do("begin transaction", func() error {
+		tx = db.Begin()
+		return tx.Error
+	}, func() error {
+		// 已经提交的事务回滚时返回ErrInvalidTransaction，不算补偿失败
+		if err := tx.Rollback().Error; err != nil && !errors.Is(err, gorm.ErrInvalidTransaction) {
+			return err
+		}
+		return nil
+	})
+	return tx, err
+}
+
+// Commit 提交Begin开始的事务
+func (s *Saga) Commit(tx *gorm.DB) error {
+	return s.Do("commit transaction", func() error {
+		return tx.Commit().Error
+	}, nil)
+}
+
+// 移开的目录名称的后缀
+const removingSuffix = ".removing"
+
+// IsRemovingDir 是否是RemoveDir移开、等待删除的目录；进程在Complete之前退出时会留下来
+func IsRemovingDir(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, removingSuffix)
+}
+
+// RemoveDir 删除目录：先移到同级的临时名称，补偿时移回原处，Complete之后才真正删除。
+// 目录不存在时什么都不做
+func (s *Saga) RemoveDir(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	aside := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+removingSuffix)
+	err := s.Do("move aside "+path, func() error {
+		if err := os.RemoveAll(aside); err != nil {
+			return err
+		}
+		if err := os.Rename(path, aside); err != nil {
+			return err
+		}
+		// 改名不会更新目录的修改时间，记为移开的时间，一致性检查据此判断是否是遗留的目录
+		now := time.Now()
+		if err := os.Chtimes(aside, now, now); err != nil {
+			zap.S().Warnf("Failed to touch %s: %v", aside, err)
+		}
+		return nil
+	}, func() error {
+		return os.Rename(aside, path)
+	})
+	if err != nil {
+		return err
+	}
+	s.Defer("remove "+aside, func() error {
+		return os.RemoveAll(aside)
+	})
+	return nil
+}
@@ -0,0 +1,230 @@
+// -------------------------------------------------
+// Package saga
+// Author: hanzhi
+// Date: 2026/10/19
+// -------------------------------------------------
+
+package saga
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeStore 测试用的存储，按操作次数注入故障
+type fakeStore struct {
+	name   string
+	items  map[string]bool
+	calls  int
+	failAt int // 第几次操作失败，0表示不失败
+	log    *[]string
+}
+
+func newFakeStore(name string, log *[]string) *fakeStore {
+	return &fakeStore{name: name, items: map[string]bool{}, log: log}
+}
+
+func (f *fakeStore) op(action string, key string, apply func()) error {
+	f.calls++
+	if f.calls == f.failAt {
+		*f.log = append(*f.log, fmt.Sprintf("%s %s %s: fail", f.name, action, key))
+		return errors.New(f.name + " unavailable")
+	}
+	apply()
+	*f.log = append(*f.log, fmt.Sprintf("%s %s %s", f.name, action, key))
+	return nil
+}
+
+func (f *fakeStore) Put(key string) error {
+	return f.op("put", key, func() { f.items[key] = true })
+}
+
+func (f *fakeStore) Delete(key string) error {
+	return f.op("delete", key, func() { delete(f.items, key) })
+}
+
+// createIndex 模拟创建知识库：写数据库、建目录、建es索引
+func createIndex(s *Saga, db, fs, es *fakeStore) error {
+	stores := []*fakeStore{db, fs, es}
+	for _, store := range stores {
+		store := store
+		if err := s.Do("create "+store.name, func() error {
+			return store.Put("idx")
+		}, func() error {
+			return store.Delete("idx")
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSagaCompensatesInReverseOrder(t *testing.T) {
+	for failAt := 0; failAt < 3; failAt++ {
+		t.Run(fmt.Sprintf("fail step %d", failAt), func(t *testing.T) {
+			var log []string
+			db, fs, es := newFakeStore("db", &log), newFakeStore("fs", &log), newFakeStore("es", &log)
+			[]*fakeStore{db, fs, es}[failAt].failAt = 1
+
+			s := New("create index")
+			err := createIndex(s, db, fs, es)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if err = s.Rollback(); err != nil {
+				t.Fatalf("rollback: %v", err)
+			}
+			for _, store := range []*fakeStore{db, fs, es} {
+				if len(store.items) != 0 {
+					t.Errorf("%s not compensated: %v", store.name, store.items)
+				}
+			}
+			// 已完成的步骤按相反的顺序补偿
+			names := []string{"db", "fs", "es"}
+			var want []string
+			for i := 0; i < failAt; i++ {
+				want = append(want, names[i]+" put idx")
+			}
+			want = append(want, names[failAt]+" put idx: fail")
+			for i := failAt - 1; i >= 0; i-- {
+				want = append(want, names[i]+" delete idx")
+			}
+			if !reflect.DeepEqual(log, want) {
+				t.Errorf("got %v, want %v", log, want)
+			}
+			steps := s.Steps()
+			if len(steps) != failAt+1 || steps[failAt].State != StepFailed {
+				t.Fatalf("unexpected steps %+v", steps)
+			}
+			for _, step := range steps[:failAt] {
+				if step.State != StepCompensated {
+					t.Errorf("step %s state %s", step.Name, step.State)
+				}
+			}
+		})
+	}
+}
+
+func TestSagaComplete(t *testing.T) {
+	var log []string
+	db, fs, es := newFakeStore("db", &log), newFakeStore("fs", &log), newFakeStore("es", &log)
+	s := New("create index")
+	if err := createIndex(s, db, fs, es); err != nil {
+		t.Fatal(err)
+	}
+	deferred := false
+	s.Defer("cleanup", func() error {
+		deferred = true
+		return nil
+	})
+	s.Complete()
+	if err := s.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if !deferred {
+		t.Error("deferred step should run on complete")
+	}
+	for _, store := range []*fakeStore{db, fs, es} {
+		if !store.items["idx"] {
+			t.Errorf("%s should keep the item after complete", store.name)
+		}
+	}
+}
+
+func TestSagaCompensateFailure(t *testing.T) {
+	var log []string
+	db, fs, es := newFakeStore("db", &log), newFakeStore("fs", &log), newFakeStore("es", &log)
+	es.failAt = 1 // 建es索引失败
+	fs.failAt = 2 // 删除目录也失败
+	deferred := false
+	s := New("create index")
+	s.Defer("cleanup", func() error {
+		deferred = true
+		return nil
+	})
+	if err := createIndex(s, db, fs, es); err == nil {
+		t.Fatal("expected error")
+	}
+	err := s.Rollback()
+	if err == nil {
+		t.Fatal("rollback should report the failed compensation")
+	}
+	// 目录留下了，数据库仍然要回滚
+	if !fs.items["idx"] || db.items["idx"] {
+		t.Errorf("unexpected state db %v fs %v", db.items, fs.items)
+	}
+	if deferred {
+		t.Error("deferred step should not run on rollback")
+	}
+	states := map[string]string{}
+	for _, step := range s.Steps() {
+		states[step.Name] = step.State
+	}
+	want := map[string]string{
+		"cleanup": stepDeferredScheduled, "create db": StepCompensated,
+		"create fs": StepCompensateFailed, "create es": StepFailed,
+	}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("got %v, want %v", states, want)
+	}
+}
+
+func TestSagaRemoveDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "kb")
+	if err := os.MkdirAll(filepath.Join(dir, "images"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	// 后面的步骤失败，目录移回原处
+	s := New("delete")
+	if err := s.RemoveDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatal("dir should be moved aside")
+	}
+	_ = s.Do("es", func() error { return errors.New("es unavailable") }, nil)
+	if err := s.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "images")); err != nil {
+		t.Fatalf("dir should be restored: %v", err)
+	}
+
+	// 成功后才真正删除；移开的目录的修改时间是移开的时间
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(dir, old, old); err != nil {
+		t.Fatal(err)
+	}
+	s = New("delete")
+	if err := s.RemoveDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(root, ".kb"+removingSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(info.ModTime()) > time.Minute {
+		t.Errorf("aside dir mod time %v should be refreshed", info.ModTime())
+	}
+	s.Complete()
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("dir should be removed, left %v", entries)
+	}
+
+	// 不存在的目录什么都不做
+	s = New("delete")
+	if err = s.RemoveDir(dir); err != nil || len(s.Steps()) != 0 {
+		t.Errorf("missing dir: err %v steps %+v", err, s.Steps())
+	}
+}